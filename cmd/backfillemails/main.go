@@ -0,0 +1,31 @@
+// Package main writes the EMAIL# lookup items for users whose email address
+// was stored before email login existed. Run it once after deploying email
+// login; it is safe to run again:
+//
+//	TABLE_NAME=BookingPlatformTable go run ./cmd/backfillemails
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/users"
+)
+
+func main() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	result, err := users.NewService(dbClient).BackfillEmailLookups(ctx)
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+
+	log.Printf("Checked %d users with an email: %d linked, %d already linked, %d skipped (address linked to another user)",
+		result.Users, result.Linked, result.Present, result.Skipped)
+}
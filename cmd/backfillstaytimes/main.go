@@ -0,0 +1,31 @@
+// Package main gives bookings stored without a check-in or check-out time
+// their property's default times, so changing a property's defaults no
+// longer moves existing stays. It is safe to run again:
+//
+//	TABLE_NAME=BookingPlatformTable go run ./cmd/backfillstaytimes
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+func main() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	result, err := bookings.NewService(dbClient).BackfillStayTimes(ctx)
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+
+	log.Printf("Checked %d bookings without stay times: %d updated, %d skipped (property missing or booking changed; run again)",
+		result.Bookings, result.Updated, result.Skipped)
+}
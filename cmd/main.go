@@ -9,15 +9,23 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/booking-villa-backend/internal/accounting"
 	"github.com/booking-villa-backend/internal/analytics"
+	"github.com/booking-villa-backend/internal/apikeys"
+	"github.com/booking-villa-backend/internal/audit"
 	"github.com/booking-villa-backend/internal/auth"
 	"github.com/booking-villa-backend/internal/bookings"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/expenses"
 	"github.com/booking-villa-backend/internal/middleware"
 	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
 	"github.com/booking-villa-backend/internal/payments"
 	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/statements"
 	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+	"github.com/booking-villa-backend/internal/webhooks"
 )
 
 // Global handlers (initialized once per Lambda cold start)
@@ -28,11 +36,19 @@ var (
 	bookingHandler      *bookings.Handler
 	paymentHandler      *payments.Handler
 	analyticsHandler    *analytics.Handler
+	accountingHandler   *accounting.Handler
+	statementHandler    *statements.Handler
+	expenseHandler      *expenses.Handler
 	notificationHandler *notifications.Handler
 	userHandler         *users.Handler
+	apiKeyHandler       *apikeys.Handler
+	auditHandler        *audit.Handler
+	outboxHandler       *outbox.Handler
+	webhookHandler      *webhooks.Handler
 	authMiddleware      *middleware.AuthMiddleware
 	rbacMiddleware      *middleware.RBACMiddleware
 	userService         *users.Service
+	auditService        *audit.Service
 )
 
 func init() {
@@ -48,9 +64,12 @@ func init() {
 	authHandler = auth.NewHandler(dbClient)
 	propertyHandler = properties.NewHandler(dbClient)
 	notificationHandler = notifications.NewHandler(dbClient)
-	bookingHandler = bookings.NewHandler(dbClient, notificationHandler.GetService())
+	bookingHandler = bookings.NewHandler(dbClient)
 	paymentHandler = payments.NewHandler(dbClient)
 	analyticsHandler = analytics.NewHandler(dbClient)
+	accountingHandler = accounting.NewHandler(dbClient)
+	statementHandler = statements.NewHandler(dbClient)
+	expenseHandler = expenses.NewHandler(dbClient)
 	// Create property lister function to avoid import cycle
 	propertyLister := func(ctx context.Context, ownerPhone string) ([]string, error) {
 		props, err := properties.NewService(dbClient).ListPropertiesByOwner(ctx, ownerPhone)
@@ -65,10 +84,19 @@ func init() {
 	}
 	userHandler = users.NewHandler(dbClient, propertyLister)
 	userService = users.NewService(dbClient)
+	apiKeyHandler = apikeys.NewHandler(dbClient)
+	auditHandler = audit.NewHandler(dbClient)
+	auditService = auditHandler.GetService()
+	outboxHandler = outbox.NewHandler(dbClient)
+	webhookHandler = webhooks.NewHandler(dbClient)
 
 	// Initialize middleware
 	authMiddleware = middleware.NewAuthMiddleware()
-	rbacMiddleware = middleware.NewRBACMiddleware()
+	authMiddleware.SetSessionValidator(authHandler.GetService().ValidateSession)
+	authMiddleware.SetAPIKeyResolver(apiKeyHandler.GetService().Authenticate)
+	authMiddleware.SetImpersonationAuditor(auditService.RecordImpersonatedRequest)
+	authMiddleware.SetDenialAuditor(auditService.RecordAccessDenied)
+	rbacMiddleware = middleware.NewRBACMiddleware(authMiddleware)
 }
 
 // Handler is the main Lambda handler that routes requests to appropriate handlers.
@@ -135,16 +163,46 @@ func routeRequest(ctx context.Context, request events.APIGatewayProxyRequest) (e
 		return routeBookings(ctx, request, path, method)
 	}
 
+	// Expense routes
+	if strings.HasPrefix(path, "/expenses") {
+		return routeExpenses(ctx, request, path, method)
+	}
+
 	// Analytics routes
 	if strings.HasPrefix(path, "/analytics") {
 		return routeAnalytics(ctx, request, path, method)
 	}
 
+	// Accounting routes
+	if strings.HasPrefix(path, "/accounting") {
+		return routeAccounting(ctx, request, path, method)
+	}
+
+	// Owner statement routes
+	if strings.HasPrefix(path, "/owners") {
+		return routeOwners(ctx, request, path, method)
+	}
+
 	// Notification routes
 	if strings.HasPrefix(path, "/notifications") {
 		return routeNotifications(ctx, request, path, method)
 	}
 
+	// Admin routes
+	if strings.HasPrefix(path, "/admin") {
+		return routeAdmin(ctx, request, path, method)
+	}
+
+	// API key routes
+	if strings.HasPrefix(path, "/api-keys") {
+		return routeAPIKeys(ctx, request, path, method)
+	}
+
+	// Webhook routes
+	if strings.HasPrefix(path, "/webhooks") {
+		return routeWebhooks(ctx, request, path, method)
+	}
+
 	// Health check
 	if path == "/health" || path == "/" {
 		return apiResponse(200, map[string]string{
@@ -168,12 +226,36 @@ func routeAuth(ctx context.Context, request events.APIGatewayProxyRequest, path,
 	case path == "/auth/verify-otp" && method == "POST":
 		return authHandler.HandleVerifyOTP(ctx, request)
 
+	case path == "/auth/email/send-otp" && method == "POST":
+		return authHandler.HandleSendEmailOTP(ctx, request)
+
+	case path == "/auth/email/verify-otp" && method == "POST":
+		return authHandler.HandleVerifyEmailOTP(ctx, request)
+
 	case path == "/auth/login" && method == "POST":
 		return authHandler.HandleLogin(ctx, request)
 
 	case path == "/auth/refresh" && method == "POST":
 		return authHandler.HandleRefreshToken(ctx, request)
 
+	case path == "/auth/password/forgot" && method == "POST":
+		return authHandler.HandleForgotPassword(ctx, request)
+
+	case path == "/auth/password/reset" && method == "POST":
+		return authHandler.HandleResetPassword(ctx, request)
+
+	case path == "/auth/2fa/enroll" && method == "POST":
+		return authMiddleware.AuthenticateForPurpose(authHandler.HandleEnrollTwoFactor, "", utils.TokenPurposeTwoFactorSetup)(ctx, request)
+
+	case path == "/auth/2fa/activate" && method == "POST":
+		return authMiddleware.AuthenticateForPurpose(authHandler.HandleActivateTwoFactor, "", utils.TokenPurposeTwoFactorSetup)(ctx, request)
+
+	case path == "/auth/2fa/verify" && method == "POST":
+		return authMiddleware.AuthenticateForPurpose(authHandler.HandleVerifyTwoFactor, utils.TokenPurposeTwoFactor)(ctx, request)
+
+	case path == "/auth/2fa/disable" && method == "POST":
+		return authMiddleware.Authenticate(authHandler.HandleDisableTwoFactor)(ctx, request)
+
 	default:
 		return errorResponse(404, "Auth endpoint not found"), nil
 	}
@@ -186,6 +268,19 @@ func routeUsers(ctx context.Context, request events.APIGatewayProxyRequest, path
 		return authMiddleware.Authenticate(authHandler.HandleSetPassword)(ctx, request)
 	}
 
+	// Linking an email requires auth
+	if path == "/users/email" && method == "POST" {
+		return authMiddleware.Authenticate(authHandler.HandleSetEmail)(ctx, request)
+	}
+	if path == "/users/email/verify" && method == "POST" {
+		return authMiddleware.Authenticate(authHandler.HandleConfirmEmail)(ctx, request)
+	}
+
+	// Choosing a language requires auth
+	if path == "/users/locale" && method == "POST" {
+		return authMiddleware.Authenticate(authHandler.HandleSetLocale)(ctx, request)
+	}
+
 	// Get user by phone - requires auth
 	if strings.HasPrefix(path, "/users/") && method == "GET" {
 		phone := request.PathParameters["phone"]
@@ -274,7 +369,19 @@ func routeUsers(ctx context.Context, request events.APIGatewayProxyRequest, path
 			}
 
 			claims, _ := middleware.GetClaimsFromContext(ctx)
-			if err := userService.UpdateUserStatus(ctx, phone, status, claims.Phone); err != nil {
+			err := userService.UpdateUserStatus(ctx, phone, status, claims.Phone)
+
+			event := audit.NewEvent(audit.EventUserStatusChanged, audit.OutcomeSuccess).
+				WithRequest(req).
+				WithActor(claims.Phone, claims.Role).
+				WithTarget(phone).
+				WithDetail("status", string(status))
+			if err != nil {
+				event.WithError(err)
+			}
+			auditService.Log(ctx, event)
+
+			if err != nil {
 				return errorResponse(500, "Failed to update user status"), nil
 			}
 
@@ -301,25 +408,37 @@ func routeProperties(ctx context.Context, request events.APIGatewayProxyRequest,
 		}
 	}
 
+	// Check for owner blocks endpoints
+	if strings.Contains(path, "/blocks") {
+		switch method {
+		case "POST":
+			return rbacMiddleware.RequireAdminOrOwner()(bookingHandler.HandleCreateBlock)(ctx, request)
+		case "GET":
+			return rbacMiddleware.RequireAdminOrOwner()(bookingHandler.HandleListBlocks)(ctx, request)
+		case "DELETE":
+			return rbacMiddleware.RequireAdminOrOwner()(bookingHandler.HandleDeleteBlock)(ctx, request)
+		}
+	}
+
 	// Check for availability endpoint
 	if strings.HasSuffix(path, "/availability") && method == "GET" {
-		return authMiddleware.Authenticate(bookingHandler.HandleCheckAvailability)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopePropertiesRead, bookingHandler.HandleCheckAvailability)(ctx, request)
 	}
 
 	// Check for calendar endpoint
 	if strings.HasSuffix(path, "/calendar") && method == "GET" {
-		return authMiddleware.Authenticate(bookingHandler.HandleGetPropertyCalendar)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopePropertiesRead, bookingHandler.HandleGetPropertyCalendar)(ctx, request)
 	}
 
 	switch {
 	case path == "/properties/available" && method == "GET":
-		return authMiddleware.Authenticate(bookingHandler.HandleListAvailableProperties)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopePropertiesRead, bookingHandler.HandleListAvailableProperties)(ctx, request)
 
 	case path == "/properties" && method == "POST":
 		return rbacMiddleware.RequireAdminOrOwner()(propertyHandler.HandleCreateProperty)(ctx, request)
 
 	case path == "/properties" && method == "GET":
-		return authMiddleware.Authenticate(propertyHandler.HandleListProperties)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopePropertiesRead, propertyHandler.HandleListProperties)(ctx, request)
 
 	case strings.HasPrefix(path, "/properties/") && method == "GET":
 		return propertyHandler.HandleGetProperty(ctx, request)
@@ -336,60 +455,129 @@ func routeProperties(ctx context.Context, request events.APIGatewayProxyRequest,
 func routeBookings(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
 	// Check for payment status endpoint
 	if strings.HasSuffix(path, "/payment-status") && method == "GET" {
-		return authMiddleware.Authenticate(paymentHandler.HandleGetPaymentStatus)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopeBookingsRead, paymentHandler.HandleGetPaymentStatus)(ctx, request)
 	}
 
 	// Check for booking status endpoint
 	if strings.HasSuffix(path, "/status") && method == "PATCH" {
-		return rbacMiddleware.RequireAny()(bookingHandler.HandleUpdateBookingStatus)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeBookingsWrite)(bookingHandler.HandleUpdateBookingStatus)(ctx, request)
 	}
 
 	// Check for booking settle endpoint
 	if strings.HasSuffix(path, "/settle") && method == "POST" {
-		return rbacMiddleware.RequireAny()(bookingHandler.HandleSettleBooking)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeBookingsWrite)(bookingHandler.HandleSettleBooking)(ctx, request)
 	}
 
 	switch {
 	case path == "/bookings" && method == "POST":
-		return rbacMiddleware.RequireAny()(bookingHandler.HandleCreateBooking)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeBookingsWrite)(bookingHandler.HandleCreateBooking)(ctx, request)
 
 	case path == "/bookings" && method == "GET":
-		return authMiddleware.Authenticate(bookingHandler.HandleListBookings)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopeBookingsRead, bookingHandler.HandleListBookings)(ctx, request)
 
 	case strings.HasPrefix(path, "/bookings/") && method == "GET":
-		return authMiddleware.Authenticate(bookingHandler.HandleGetBooking)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopeBookingsRead, bookingHandler.HandleGetBooking)(ctx, request)
 
 	case strings.HasPrefix(path, "/bookings/") && method == "PATCH":
-		return rbacMiddleware.RequireAny()(bookingHandler.HandleUpdateBooking)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeBookingsWrite)(bookingHandler.HandleUpdateBooking)(ctx, request)
 
 	default:
 		return errorResponse(404, "Booking endpoint not found"), nil
 	}
 }
 
+// routeExpenses handles expense routes.
+func routeExpenses(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case path == "/expenses" && method == "POST":
+		return rbacMiddleware.RequireAdminOrOwner()(expenseHandler.HandleCreateExpense)(ctx, request)
+
+	case path == "/expenses" && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(expenseHandler.HandleListExpenses)(ctx, request)
+
+	case strings.HasPrefix(path, "/expenses/") && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(expenseHandler.HandleGetExpense)(ctx, request)
+
+	case strings.HasPrefix(path, "/expenses/") && method == "PATCH":
+		return rbacMiddleware.RequireAdminOrOwner()(expenseHandler.HandleUpdateExpense)(ctx, request)
+
+	case strings.HasPrefix(path, "/expenses/") && method == "DELETE":
+		return rbacMiddleware.RequireAdminOrOwner()(expenseHandler.HandleDeleteExpense)(ctx, request)
+
+	default:
+		return errorResponse(404, "Expense endpoint not found"), nil
+	}
+}
+
 // routeAnalytics handles analytics routes.
 func routeAnalytics(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
 	switch {
 	case path == "/analytics/owner" && method == "GET":
-		return rbacMiddleware.RequireAdminOrOwner()(analyticsHandler.HandleOwnerAnalytics)(ctx, request)
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleOwnerAnalytics)(ctx, request)
+
+	case path == "/analytics/owner/kpis" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleOwnerKPIs)(ctx, request)
+
+	case path == "/analytics/timeseries" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleTimeSeries)(ctx, request)
+
+	case path == "/analytics/forecast" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleForecast)(ctx, request)
+
+	case path == "/analytics/behaviour" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleBookingBehaviour)(ctx, request)
 
 	case path == "/analytics/export" && method == "GET":
-		return rbacMiddleware.RequireAdmin()(analyticsHandler.HandleExportData)(ctx, request)
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsExport, users.RoleAdmin, users.RoleOwner)(analyticsHandler.HandleExportData)(ctx, request)
 
 	case path == "/analytics/agent" && method == "GET":
-		return rbacMiddleware.RequireAny()(analyticsHandler.HandleAgentAnalytics)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeAnalyticsRead)(analyticsHandler.HandleAgentAnalytics)(ctx, request)
 
 	case path == "/analytics/agent/property-performance" && method == "GET":
-		return rbacMiddleware.RequireAny()(analyticsHandler.HandleAgentPropertyPerformance)(ctx, request)
+		return rbacMiddleware.RequireAnyWithScope(middleware.ScopeAnalyticsRead)(analyticsHandler.HandleAgentPropertyPerformance)(ctx, request)
 
 	case path == "/analytics/dashboard" && method == "GET":
-		return authMiddleware.Authenticate(analyticsHandler.HandleDashboard)(ctx, request)
+		return authMiddleware.AuthenticateWithScope(middleware.ScopeAnalyticsRead, analyticsHandler.HandleDashboard)(ctx, request)
 
 	default:
 		return errorResponse(404, "Analytics endpoint not found"), nil
 	}
 }
 
+// routeAccounting handles accounting routes.
+func routeAccounting(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case path == "/accounting/settings" && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(accountingHandler.HandleGetSettings)(ctx, request)
+
+	case path == "/accounting/settings" && method == "PUT":
+		return rbacMiddleware.RequireAdminOrOwner()(accountingHandler.HandleUpdateSettings)(ctx, request)
+
+	case path == "/accounting/export" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsExport, users.RoleAdmin, users.RoleOwner)(accountingHandler.HandleExport)(ctx, request)
+
+	case path == "/accounting/exports" && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsExport, users.RoleAdmin, users.RoleOwner)(accountingHandler.HandleListBatches)(ctx, request)
+
+	default:
+		return errorResponse(404, "Accounting endpoint not found"), nil
+	}
+}
+
+// routeOwners handles owner statement routes.
+func routeOwners(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case strings.HasPrefix(path, "/owners/me/statements/") && method == "GET":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsRead, users.RoleAdmin, users.RoleOwner)(statementHandler.HandleGetStatement)(ctx, request)
+
+	case strings.HasPrefix(path, "/owners/me/statements/") && method == "POST":
+		return rbacMiddleware.RequireScope(middleware.ScopeAnalyticsExport, users.RoleAdmin, users.RoleOwner)(statementHandler.HandleRegenerateStatement)(ctx, request)
+
+	default:
+		return errorResponse(404, "Owner endpoint not found"), nil
+	}
+}
+
 // routeNotifications handles notification routes.
 func routeNotifications(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
 	switch {
@@ -402,9 +590,24 @@ func routeNotifications(ctx context.Context, request events.APIGatewayProxyReque
 	case path == "/notifications/mark-all-read" && method == "POST":
 		return authMiddleware.Authenticate(notificationHandler.HandleMarkAllAsRead)(ctx, request)
 
+	case path == "/notifications/preferences" && method == "GET":
+		return authMiddleware.Authenticate(notificationHandler.HandleGetPreferences)(ctx, request)
+
+	case path == "/notifications/preferences" && method == "PUT":
+		return authMiddleware.Authenticate(notificationHandler.HandleUpdatePreferences)(ctx, request)
+
 	case strings.HasSuffix(path, "/read") && method == "PATCH":
 		return authMiddleware.Authenticate(notificationHandler.HandleMarkAsRead)(ctx, request)
 
+	case strings.HasSuffix(path, "/archive") && method == "PATCH":
+		return authMiddleware.Authenticate(notificationHandler.HandleArchive)(ctx, request)
+
+	case strings.HasSuffix(path, "/unarchive") && method == "PATCH":
+		return authMiddleware.Authenticate(notificationHandler.HandleUnarchive)(ctx, request)
+
+	case strings.HasPrefix(path, "/notifications/") && method == "DELETE":
+		return authMiddleware.Authenticate(notificationHandler.HandleDeleteNotification)(ctx, request)
+
 	default:
 		return errorResponse(404, "Notification endpoint not found"), nil
 	}
@@ -426,6 +629,71 @@ func routeAgents(ctx context.Context, request events.APIGatewayProxyRequest, pat
 	}
 }
 
+// routeAdmin handles admin-only tooling routes.
+func routeAdmin(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case strings.HasPrefix(path, "/admin/impersonate/") && method == "POST":
+		return rbacMiddleware.RequireAdmin()(authHandler.HandleImpersonate)(ctx, request)
+
+	case path == "/admin/audit" && method == "GET":
+		return rbacMiddleware.RequireAdmin()(auditHandler.HandleListEvents)(ctx, request)
+
+	case path == "/admin/outbox/dead-letters" && method == "GET":
+		return rbacMiddleware.RequireAdmin()(outboxHandler.HandleListDeadLetters)(ctx, request)
+
+	case strings.HasPrefix(path, "/admin/outbox/") && strings.HasSuffix(path, "/retry") && method == "POST":
+		return rbacMiddleware.RequireAdmin()(outboxHandler.HandleRequeue)(ctx, request)
+
+	default:
+		return errorResponse(404, "Admin endpoint not found"), nil
+	}
+}
+
+// routeAPIKeys handles API key management routes.
+// Keys can only be managed with a user session, never with another API key.
+func routeAPIKeys(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case path == "/api-keys" && method == "POST":
+		return rbacMiddleware.RequireAdminOrOwner()(apiKeyHandler.HandleCreateAPIKey)(ctx, request)
+
+	case path == "/api-keys" && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(apiKeyHandler.HandleListAPIKeys)(ctx, request)
+
+	case strings.HasPrefix(path, "/api-keys/") && method == "DELETE":
+		return rbacMiddleware.RequireAdminOrOwner()(apiKeyHandler.HandleRevokeAPIKey)(ctx, request)
+
+	default:
+		return errorResponse(404, "API key endpoint not found"), nil
+	}
+}
+
+// routeWebhooks handles webhook endpoint management routes.
+// Like API keys, webhooks can only be managed with a user session.
+func routeWebhooks(ctx context.Context, request events.APIGatewayProxyRequest, path, method string) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case path == "/webhooks" && method == "POST":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleCreateWebhook)(ctx, request)
+
+	case path == "/webhooks" && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleListWebhooks)(ctx, request)
+
+	case strings.HasSuffix(path, "/redeliver") && method == "POST":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleRedeliver)(ctx, request)
+
+	case strings.HasSuffix(path, "/deliveries") && method == "GET":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleListDeliveries)(ctx, request)
+
+	case strings.HasSuffix(path, "/test") && method == "POST":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleSendTest)(ctx, request)
+
+	case strings.HasPrefix(path, "/webhooks/") && method == "DELETE":
+		return rbacMiddleware.RequireAdminOrOwner()(webhookHandler.HandleDeleteWebhook)(ctx, request)
+
+	default:
+		return errorResponse(404, "Webhook endpoint not found"), nil
+	}
+}
+
 // Helper functions
 
 func apiResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
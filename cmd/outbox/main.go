@@ -0,0 +1,79 @@
+// Package main provides the Lambda entry point for the outbox worker.
+// It runs on a schedule and performs the side effects (notifications,
+// guest emails, webhooks, digests, realtime updates) recorded by the API
+// alongside booking changes.
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/digest"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/realtime"
+	"github.com/booking-villa-backend/internal/webhooks"
+)
+
+// deadlineMargin is kept free at the end of each invocation so the last
+// event's outcome is recorded before Lambda times out.
+const deadlineMargin = 5 * time.Second
+
+var outboxService *outbox.Service
+
+func init() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	outboxService = outbox.NewService(dbClient)
+
+	notificationService := notifications.NewService(dbClient)
+	outboxService.Register(notifications.EventBookingNotification, notificationService.HandleBookingNotificationEvent)
+	outboxService.Register(notifications.EventNotification, notificationService.HandleNotificationEvent)
+	outboxService.Register(notifications.EventDeferredDelivery, notificationService.HandleDeferredDeliveryEvent)
+
+	bookingConsumer := bookings.NewEventConsumer(dbClient)
+	outboxService.Register(bookings.EventGuestEmail, bookingConsumer.HandleGuestEmailEvent)
+
+	digestService := digest.NewService(dbClient)
+	outboxService.Register(digest.EventSendDigest, digestService.HandleSendDigestEvent)
+
+	webhookService := webhooks.NewService(dbClient)
+	outboxService.Register(webhooks.EventPublish, webhookService.HandlePublishEvent)
+	outboxService.Register(webhooks.EventDeliver, webhookService.HandleDeliverEvent)
+
+	realtimeService := realtime.NewService(dbClient)
+	outboxService.Register(realtime.EventPublish, realtimeService.HandlePublishEvent)
+}
+
+// Handler processes all due outbox events.
+func Handler(ctx context.Context, _ events.CloudWatchEvent) (*outbox.ProcessResult, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+		defer cancel()
+	}
+
+	result, err := outboxService.ProcessDue(ctx)
+	if err != nil {
+		log.Printf("Outbox run failed: %v", err)
+		return result, err
+	}
+
+	log.Printf("Outbox run: %d processed, %d retried, %d dead-lettered, %d skipped",
+		result.Processed, result.Retried, result.DeadLettered, result.Skipped)
+	return result, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
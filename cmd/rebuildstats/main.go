@@ -0,0 +1,31 @@
+// Package main recomputes the analytics rollups (STATS# items) from the
+// stored bookings. Run it after deploying rollups for the first time, or to
+// repair totals that have drifted:
+//
+//	TABLE_NAME=BookingPlatformTable go run ./cmd/rebuildstats
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+func main() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	result, err := bookings.NewService(dbClient).RebuildRollups(ctx)
+	if err != nil {
+		log.Fatalf("Rebuild failed: %v", err)
+	}
+
+	log.Printf("Rebuilt rollups from %d bookings: %d removed, %d written",
+		result.Bookings, result.Removed, result.Rollups)
+}
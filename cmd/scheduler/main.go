@@ -0,0 +1,77 @@
+// Package main provides the Lambda entry point for scheduled jobs.
+// It runs hourly and queues reminders about upcoming arrivals, check-outs
+// and outstanding payments, and owner digests; the outbox worker delivers them.
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/digest"
+	"github.com/booking-villa-backend/internal/reminders"
+)
+
+var (
+	reminderService *reminders.Service
+	digestService   *digest.Service
+)
+
+// Result summarises one scheduled run.
+type Result struct {
+	Reminders *reminders.RunResult   `json:"reminders"`
+	Digests   *digest.ScheduleResult `json:"digests"`
+}
+
+func init() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	reminderService = reminders.NewService(dbClient)
+	digestService = digest.NewService(dbClient)
+}
+
+// Handler runs the scheduled jobs once. A failing job does not stop the others.
+func Handler(ctx context.Context, event events.CloudWatchEvent) (*Result, error) {
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	result := &Result{}
+	var runErr error
+
+	reminderResult, err := reminderService.Run(ctx, now)
+	if err != nil {
+		log.Printf("Reminder run failed: %v", err)
+		runErr = err
+	} else {
+		result.Reminders = reminderResult
+		log.Printf("Reminder run: %d properties, %d sent, %d already sent, %d failed",
+			reminderResult.Properties, reminderResult.Sent, reminderResult.AlreadySent, reminderResult.Failed)
+	}
+
+	digestResult, err := digestService.Schedule(ctx, now)
+	if err != nil {
+		log.Printf("Digest run failed: %v", err)
+		runErr = err
+	}
+	if digestResult != nil {
+		result.Digests = digestResult
+		log.Printf("Digest run: %d queued, %d already sent, %d failed",
+			digestResult.Queued, digestResult.AlreadySent, digestResult.Failed)
+	}
+
+	return result, runErr
+}
+
+func main() {
+	lambda.Start(Handler)
+}
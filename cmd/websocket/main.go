@@ -0,0 +1,119 @@
+// Package main provides the Lambda entry point for the WebSocket API.
+// API Gateway invokes it on the $connect, $disconnect and $default routes;
+// clients authenticate on connect and then subscribe to calendar and
+// notification channels.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/booking-villa-backend/internal/auth"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/realtime"
+)
+
+var (
+	realtimeService *realtime.Service
+	posterOnce      sync.Once
+)
+
+func init() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	realtimeService = realtime.NewService(dbClient)
+	realtimeService.SetSessionValidator(auth.NewService(dbClient).ValidateSession)
+}
+
+// Handler routes WebSocket events.
+func Handler(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	rc := request.RequestContext
+
+	// The API's own address is only known from its requests
+	posterOnce.Do(func() {
+		if !realtimeService.IsEnabled() {
+			realtimeService.SetPoster(realtime.NewHTTPPoster("https://" + rc.DomainName + "/" + rc.Stage))
+		}
+	})
+
+	switch rc.RouteKey {
+	case "$connect":
+		return handleConnect(ctx, request)
+	case "$disconnect":
+		if err := realtimeService.Disconnect(ctx, rc.ConnectionID); err != nil {
+			log.Printf("Failed to remove connection %s: %v", rc.ConnectionID, err)
+		}
+		return response(http.StatusOK), nil
+	default:
+		return handleMessage(ctx, request)
+	}
+}
+
+// handleConnect authenticates the client. Browsers cannot set headers on
+// WebSocket requests, so the token is passed as the token query parameter.
+func handleConnect(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	token := request.QueryStringParameters["token"]
+	if token == "" {
+		token = strings.TrimPrefix(headerValue(request.Headers, "Authorization"), "Bearer ")
+	}
+
+	claims, err := realtimeService.Authenticate(ctx, token)
+	if err != nil {
+		return response(http.StatusUnauthorized), nil
+	}
+
+	if err := realtimeService.Connect(ctx, request.RequestContext.ConnectionID, claims); err != nil {
+		log.Printf("Failed to register connection: %v", err)
+		return response(http.StatusInternalServerError), nil
+	}
+	return response(http.StatusOK), nil
+}
+
+// handleMessage processes a client message and posts the reply back.
+func handleMessage(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	connectionID := request.RequestContext.ConnectionID
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return response(http.StatusBadRequest), nil
+		}
+		body = decoded
+	}
+
+	reply := realtimeService.HandleMessage(ctx, connectionID, body)
+	if err := realtimeService.Send(ctx, connectionID, reply); err != nil {
+		log.Printf("Failed to reply to connection %s: %v", connectionID, err)
+	}
+	return response(http.StatusOK), nil
+}
+
+// headerValue looks up a header case-insensitively.
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func response(statusCode int) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{StatusCode: statusCode}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
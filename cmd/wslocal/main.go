@@ -0,0 +1,36 @@
+// Package main runs the WebSocket server locally with net/http.
+// Point the API and outbox worker at it with WEBSOCKET_ENDPOINT=http://localhost:8081
+// (or the address set in WS_ADDR) and connect to ws://localhost:8081/ws?token=<jwt>.
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/booking-villa-backend/internal/auth"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/realtime"
+)
+
+func main() {
+	ctx := context.Background()
+
+	dbClient, err := db.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	service := realtime.NewService(dbClient)
+	service.SetSessionValidator(auth.NewService(dbClient).ValidateSession)
+	server := realtime.NewLocalServer(service)
+
+	addr := os.Getenv("WS_ADDR")
+	if addr == "" {
+		addr = ":8081"
+	}
+
+	log.Printf("WebSocket server listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, server.Handler()))
+}
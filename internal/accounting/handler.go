@@ -0,0 +1,251 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+// Handler provides HTTP handlers for accounting endpoints.
+type Handler struct {
+	service      *Service
+	auditService *audit.Service
+}
+
+// NewHandler creates a new accounting handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service:      NewService(dbClient),
+		auditService: audit.NewService(dbClient),
+	}
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// ownerOf returns the owner whose books are requested. Admins name the owner
+// with the owner query param; owners always get their own.
+func ownerOf(request events.APIGatewayProxyRequest, claims *utils.TokenClaims) (string, error) {
+	owner := request.QueryStringParameters["owner"]
+	if claims.Role == "admin" {
+		if owner == "" {
+			return "", errors.New("owner is required")
+		}
+		return owner, nil
+	}
+	if owner != "" && owner != claims.Phone {
+		return "", errors.New("you can only access your own accounts")
+	}
+	return claims.Phone, nil
+}
+
+// HandleGetSettings handles GET /accounting/settings endpoint.
+func (h *Handler) HandleGetSettings(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	owner, err := ownerOf(request, claims)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	settings, err := h.service.GetSettings(ctx, owner)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get accounting settings: "+err.Error()), nil
+	}
+	return APIResponse(http.StatusOK, settings), nil
+}
+
+// HandleUpdateSettings handles PUT /accounting/settings endpoint.
+func (h *Handler) HandleUpdateSettings(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	owner, err := ownerOf(request, claims)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	var req UpdateSettingsRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	settings, err := h.service.SaveSettings(ctx, owner, req)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+	return APIResponse(http.StatusOK, settings), nil
+}
+
+// HandleExport handles GET /accounting/export endpoint.
+func (h *Handler) HandleExport(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Role check is handled by middleware
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	owner, err := ownerOf(request, claims)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+	params := request.QueryStringParameters
+
+	req := ExportRequest{
+		OwnerPhone:      owner,
+		ExportedBy:      claims.Phone,
+		Format:          FormatTally,
+		IncludeExported: params["includeExported"] == "true",
+	}
+	if params["format"] != "" {
+		req.Format = Format(params["format"])
+	}
+	if !req.Format.IsValid() {
+		return ErrorResponse(http.StatusBadRequest, "format must be tally or csv"), nil
+	}
+
+	if params["startDate"] == "" || params["endDate"] == "" {
+		return ErrorResponse(http.StatusBadRequest, "startDate and endDate are required"), nil
+	}
+	if req.Start, err = time.Parse("2006-01-02", params["startDate"]); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid startDate format. Use YYYY-MM-DD"), nil
+	}
+	if req.End, err = time.Parse("2006-01-02", params["endDate"]); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid endDate format. Use YYYY-MM-DD"), nil
+	}
+	if req.End.Before(req.Start) {
+		return ErrorResponse(http.StatusBadRequest, "endDate must not be before startDate"), nil
+	}
+	if req.End.Sub(req.Start).Hours()/24 >= maxPeriodDays {
+		return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("The period must not be longer than %d days", maxPeriodDays)), nil
+	}
+
+	// Property-restricted API keys may only export their own properties
+	for _, id := range strings.Split(params["propertyIds"], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			req.PropertyIDs = append(req.PropertyIDs, id)
+		}
+	}
+	if len(req.PropertyIDs) == 0 {
+		req.PropertyIDs = claims.PropertyIDs
+	}
+	for _, id := range req.PropertyIDs {
+		if !claims.CanAccessProperty(id) {
+			return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
+		}
+	}
+
+	var buf bytes.Buffer
+	result, err := h.service.Export(ctx, req)
+	if err == nil {
+		if req.Format == FormatTally {
+			err = WriteTally(&buf, result)
+		} else {
+			err = WriteJournal(&buf, result)
+		}
+	}
+
+	extension := "xml"
+	if req.Format == FormatJournal {
+		extension = "csv"
+	}
+	filename := fmt.Sprintf("accounts_%s_%s.%s", req.Start.Format("2006-01-02"), req.End.Format("2006-01-02"), extension)
+
+	event := audit.NewEvent(audit.EventDataExported, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(filename).
+		WithDetail("format", string(req.Format)).
+		WithDetail("owner", owner)
+	if result != nil {
+		event.WithDetail("vouchers", strconv.Itoa(len(result.Vouchers)))
+		if result.Batch != nil {
+			event.WithDetail("batchId", result.Batch.ID)
+		}
+	}
+	if claims.IsAPIKey() {
+		event.WithDetail("apiKeyId", claims.APIKeyID)
+	}
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPropertyAccess):
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		case errors.Is(err, ErrExportConflict):
+			return ErrorResponse(http.StatusConflict, err.Error()), nil
+		case errors.Is(err, ErrExportTooLarge):
+			return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to generate accounting export: "+err.Error()), nil
+	}
+
+	headers := map[string]string{
+		"Content-Type":                 req.Format.ContentType(),
+		"Content-Disposition":          fmt.Sprintf("attachment; filename=\"%s\"", filename),
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Headers": "Content-Type,Authorization",
+	}
+	if result.Batch != nil {
+		headers["X-Export-Batch-Id"] = result.Batch.ID
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       buf.String(),
+	}, nil
+}
+
+// HandleListBatches handles GET /accounting/exports endpoint.
+func (h *Handler) HandleListBatches(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	owner, err := ownerOf(request, claims)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	batches, err := h.service.ListBatches(ctx, owner)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to list exports: "+err.Error()), nil
+	}
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"exports": batches,
+		"count":   len(batches),
+	}), nil
+}
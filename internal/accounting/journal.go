@@ -0,0 +1,51 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// journalContentType is the MIME type of a journal export.
+const journalContentType = "text/csv"
+
+// journalHeader lists the columns of a journal export, one row per posting.
+var journalHeader = []string{
+	"Date", "Voucher Type", "Voucher Number", "Ledger", "Debit", "Credit",
+	"Narration", "Booking ID", "Property ID",
+}
+
+// WriteJournal writes the vouchers of an export as a general journal CSV,
+// for accounting software other than Tally.
+func WriteJournal(w io.Writer, result *ExportResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(journalHeader); err != nil {
+		return err
+	}
+
+	for _, voucher := range result.Vouchers {
+		for _, posting := range voucher.Postings {
+			debit, credit := "", ""
+			if posting.Debit != 0 {
+				debit = formatAmount(posting.Debit)
+			} else {
+				credit = formatAmount(posting.Credit)
+			}
+			if err := writer.Write([]string{
+				voucher.Date.Format("2006-01-02"),
+				string(voucher.Type),
+				voucher.Number,
+				posting.Ledger,
+				debit,
+				credit,
+				voucher.Narration,
+				voucher.BookingID,
+				voucher.PropertyID,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
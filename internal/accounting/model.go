@@ -0,0 +1,200 @@
+// Package accounting turns bookings and payments into double-entry vouchers
+// for an owner's books, exported as Tally XML or a journal CSV.
+package accounting
+
+import (
+	"time"
+
+	"github.com/booking-villa-backend/internal/payments"
+)
+
+// VoucherType is the kind of voucher, named as in Tally.
+type VoucherType string
+
+const (
+	VoucherSales      VoucherType = "Sales"       // Booking revenue
+	VoucherCreditNote VoucherType = "Credit Note" // Booking cancelled or reduced
+	VoucherReceipt    VoucherType = "Receipt"     // Payment received
+	VoucherPayment    VoucherType = "Payment"     // Refund paid out
+	VoucherJournal    VoucherType = "Journal"     // Agent commission
+)
+
+// Format is the file format of an accounting export.
+type Format string
+
+const (
+	FormatTally   Format = "tally"
+	FormatJournal Format = "csv"
+)
+
+// IsValid checks if the export format is supported.
+func (f Format) IsValid() bool {
+	return f == FormatTally || f == FormatJournal
+}
+
+// ContentType returns the MIME type of an export in the format.
+func (f Format) ContentType() string {
+	if f == FormatTally {
+		return tallyContentType
+	}
+	return journalContentType
+}
+
+// Accounts maps postings to ledgers in the owner's chart of accounts. The
+// ledgers must already exist in Tally with these names.
+type Accounts struct {
+	GuestReceivables  string `dynamodbav:"guestReceivables" json:"guestReceivables"`
+	RoomRevenue       string `dynamodbav:"roomRevenue" json:"roomRevenue"`
+	OutputTax         string `dynamodbav:"outputTax" json:"outputTax"`
+	CommissionExpense string `dynamodbav:"commissionExpense" json:"commissionExpense"`
+	CommissionPayable string `dynamodbav:"commissionPayable" json:"commissionPayable"`
+
+	// Cash and bank ledgers by payment method. Methods without a ledger use
+	// the one for "other".
+	PaymentAccounts map[payments.PaymentMethod]string `dynamodbav:"paymentAccounts" json:"paymentAccounts"`
+}
+
+// DefaultAccounts returns the ledgers used until an owner configures theirs.
+func DefaultAccounts() Accounts {
+	return Accounts{
+		GuestReceivables:  "Guest Receivables",
+		RoomRevenue:       "Room Revenue",
+		OutputTax:         "Output GST",
+		CommissionExpense: "Agent Commission",
+		CommissionPayable: "Agent Commission Payable",
+		PaymentAccounts: map[payments.PaymentMethod]string{
+			payments.PaymentMethodCash:   "Cash",
+			payments.PaymentMethodUPI:    "Bank Account",
+			payments.PaymentMethodBank:   "Bank Account",
+			payments.PaymentMethodCheque: "Bank Account",
+			payments.PaymentMethodOther:  "Suspense",
+		},
+	}
+}
+
+// paymentAccount returns the ledger for a payment method.
+func (a *Accounts) paymentAccount(method string) string {
+	if ledger := a.PaymentAccounts[payments.PaymentMethod(method)]; ledger != "" {
+		return ledger
+	}
+	return a.PaymentAccounts[payments.PaymentMethodOther]
+}
+
+// Settings is an owner's accounting configuration.
+type Settings struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK" json:"-"` // ACCOUNTING#<ownerPhone>
+	SK string `dynamodbav:"SK" json:"-"` // SETTINGS
+
+	OwnerPhone string `dynamodbav:"ownerPhone" json:"ownerPhone"`
+
+	// Company is the Tally company vouchers are imported into. Empty imports
+	// into the company open in Tally.
+	Company string `dynamodbav:"company,omitempty" json:"company,omitempty"`
+
+	// TaxRate is the tax included in booking totals, in percent, e.g. 12 for
+	// 12% GST. Zero posts the whole total as revenue.
+	TaxRate float64 `dynamodbav:"taxRate" json:"taxRate"`
+
+	Accounts Accounts `dynamodbav:"accounts" json:"accounts"`
+
+	UpdatedAt  time.Time `dynamodbav:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// UpdateSettingsRequest replaces an owner's accounting configuration.
+// Ledgers left empty fall back to the defaults.
+type UpdateSettingsRequest struct {
+	Company  string   `json:"company"`
+	TaxRate  float64  `json:"taxRate"`
+	Accounts Accounts `json:"accounts"`
+}
+
+// Posting is one line of a voucher. Exactly one of Debit and Credit is set.
+type Posting struct {
+	Ledger string  `json:"ledger"`
+	Debit  float64 `json:"debit,omitempty"`
+	Credit float64 `json:"credit,omitempty"`
+}
+
+// Voucher is a balanced double-entry transaction.
+type Voucher struct {
+	Type       VoucherType `json:"type"`
+	Number     string      `json:"number"`
+	Date       time.Time   `json:"date"`
+	Narration  string      `json:"narration"`
+	BookingID  string      `json:"bookingId"`
+	PropertyID string      `json:"propertyId"`
+	Postings   []Posting   `json:"postings"`
+}
+
+// exportMark records what has been exported for a booking, so later exports
+// only add what changed.
+type exportMark struct {
+	PK string `dynamodbav:"PK"` // ACCOUNTING#<ownerPhone>
+	SK string `dynamodbav:"SK"` // EXPORTED#<bookingId>
+
+	BookingID   string   `dynamodbav:"bookingId"`
+	Posted      bool     `dynamodbav:"posted"`     // Sales and commission exported
+	Sales       float64  `dynamodbav:"sales"`      // Net sales exported
+	Commission  float64  `dynamodbav:"commission"` // Net commission exported
+	Adjustments int      `dynamodbav:"adjustments"`
+	Payments    []string `dynamodbav:"payments,stringset,omitempty"` // Payment ledger keys exported
+
+	UpdatedAt  time.Time `dynamodbav:"updatedAt"`
+	Version    int       `dynamodbav:"version"` // Incremented on every export
+	EntityType string    `dynamodbav:"entityType"`
+
+	stored bool // Loaded from DynamoDB rather than created by this export
+}
+
+// hasPayment reports whether a payment ledger entry has been exported.
+func (m *exportMark) hasPayment(key string) bool {
+	for _, exported := range m.Payments {
+		if exported == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch records an export.
+type Batch struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK" json:"-"` // ACCOUNTING#<ownerPhone>
+	SK string `dynamodbav:"SK" json:"-"` // BATCH#<createdAt>
+
+	ID          string    `dynamodbav:"id" json:"id"`
+	OwnerPhone  string    `dynamodbav:"ownerPhone" json:"ownerPhone"`
+	PeriodStart time.Time `dynamodbav:"periodStart" json:"periodStart"`
+	PeriodEnd   time.Time `dynamodbav:"periodEnd" json:"periodEnd"`
+	Format      Format    `dynamodbav:"format" json:"format"`
+	Vouchers    int       `dynamodbav:"vouchers" json:"vouchers"`
+	Bookings    int       `dynamodbav:"bookings" json:"bookings"`
+	ExportedBy  string    `dynamodbav:"exportedBy" json:"exportedBy"`
+	CreatedAt   time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	EntityType  string    `dynamodbav:"entityType" json:"-"`
+}
+
+// ExportRequest selects the vouchers to export.
+type ExportRequest struct {
+	OwnerPhone string
+	ExportedBy string
+	Start, End time.Time // Voucher dates, inclusive
+	Format     Format
+
+	// PropertyIDs limits the export to these properties of the owner; empty
+	// for all of them
+	PropertyIDs []string
+
+	// IncludeExported regenerates every voucher for the period as if nothing
+	// had been exported, without recording the export.
+	IncludeExported bool
+}
+
+// ExportResult holds the vouchers of an export.
+type ExportResult struct {
+	Settings *Settings
+	Vouchers []Voucher
+	Batch    *Batch // Nil when the export was not recorded
+}
@@ -0,0 +1,548 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	_ "time/tzdata" // Timezone data for Lambda
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/payments"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/google/uuid"
+)
+
+const (
+	// amendmentLookbackYears is how far before a period bookings are checked
+	// for changes since they were exported.
+	amendmentLookbackYears = 1
+
+	// maxPeriodDays is the longest period that can be exported at once.
+	maxPeriodDays = 366
+)
+
+var (
+	// ErrPropertyAccess is returned when a property does not belong to the owner.
+	ErrPropertyAccess = errors.New("you do not have access to this property")
+
+	// ErrExportConflict is returned when another export recorded some of the
+	// same bookings first.
+	ErrExportConflict = errors.New("another export recorded some of these bookings; try again")
+
+	// ErrExportTooLarge is returned when an export changes more bookings than
+	// can be recorded at once.
+	ErrExportTooLarge = fmt.Errorf("an export can record at most %d bookings; export a shorter period or fewer properties", db.MaxTransactItems-1)
+)
+
+// Service provides accounting operations.
+type Service struct {
+	db              *db.Client
+	bookingService  *bookings.Service
+	propertyService *properties.Service
+}
+
+// NewService creates a new accounting service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:              dbClient,
+		bookingService:  bookings.NewService(dbClient),
+		propertyService: properties.NewService(dbClient),
+	}
+}
+
+// GetSettings returns an owner's accounting settings, or the defaults if
+// they have not configured any.
+func (s *Service) GetSettings(ctx context.Context, ownerPhone string) (*Settings, error) {
+	var settings Settings
+	if err := s.db.GetItem(ctx, "ACCOUNTING#"+ownerPhone, "SETTINGS", &settings); err != nil {
+		if db.IsNotFound(err) {
+			return &Settings{OwnerPhone: ownerPhone, Accounts: DefaultAccounts()}, nil
+		}
+		return nil, fmt.Errorf("failed to get accounting settings: %w", err)
+	}
+	settings.Accounts = withDefaults(settings.Accounts)
+	return &settings, nil
+}
+
+// SaveSettings replaces an owner's accounting settings.
+func (s *Service) SaveSettings(ctx context.Context, ownerPhone string, req UpdateSettingsRequest) (*Settings, error) {
+	if req.TaxRate < 0 || req.TaxRate >= 100 {
+		return nil, fmt.Errorf("taxRate must be at least 0 and below 100")
+	}
+	for method := range req.Accounts.PaymentAccounts {
+		if !method.IsValid() {
+			return nil, fmt.Errorf("unknown payment method %q", method)
+		}
+	}
+
+	settings := &Settings{
+		PK:         "ACCOUNTING#" + ownerPhone,
+		SK:         "SETTINGS",
+		OwnerPhone: ownerPhone,
+		Company:    req.Company,
+		TaxRate:    req.TaxRate,
+		Accounts:   withDefaults(req.Accounts),
+		UpdatedAt:  time.Now(),
+		EntityType: "ACCOUNTING_SETTINGS",
+	}
+	if err := s.db.PutItem(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save accounting settings: %w", err)
+	}
+	return settings, nil
+}
+
+// withDefaults fills in ledgers left empty.
+func withDefaults(accounts Accounts) Accounts {
+	defaults := DefaultAccounts()
+	for _, field := range []struct{ value, fallback *string }{
+		{&accounts.GuestReceivables, &defaults.GuestReceivables},
+		{&accounts.RoomRevenue, &defaults.RoomRevenue},
+		{&accounts.OutputTax, &defaults.OutputTax},
+		{&accounts.CommissionExpense, &defaults.CommissionExpense},
+		{&accounts.CommissionPayable, &defaults.CommissionPayable},
+	} {
+		if *field.value == "" {
+			*field.value = *field.fallback
+		}
+	}
+
+	paymentAccounts := defaults.PaymentAccounts
+	for method, ledger := range accounts.PaymentAccounts {
+		if ledger != "" {
+			paymentAccounts[method] = ledger
+		}
+	}
+	accounts.PaymentAccounts = paymentAccounts
+	return accounts
+}
+
+// ListBatches returns an owner's recorded exports, newest first.
+func (s *Service) ListBatches(ctx context.Context, ownerPhone string) ([]*Batch, error) {
+	forward := false
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition: "PK = :pk AND begins_with(SK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":pk":     "ACCOUNTING#" + ownerPhone,
+			":prefix": "BATCH#",
+		},
+		ScanIndexForward: &forward,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	batches := make([]*Batch, 0, len(items))
+	for _, item := range items {
+		var batch Batch
+		if err := attributevalue.UnmarshalMap(item, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal export: %w", err)
+		}
+		batches = append(batches, &batch)
+	}
+	return batches, nil
+}
+
+// Export builds the vouchers dated within the period for an owner's
+// properties and, unless IncludeExported is set, records them so they are
+// not exported again.
+//
+// A booking's sales and commission are posted on its check-in date. If a
+// booking changes or is cancelled after it was exported, the difference is
+// posted on the date of the change, or the start of the period if that is
+// earlier. Payments are posted on the day they were recorded, with refunds
+// and corrections as Payment vouchers.
+func (s *Service) Export(ctx context.Context, req ExportRequest) (*ExportResult, error) {
+	start := calendarDate(req.Start)
+	end := calendarDate(req.End)
+
+	settings, err := s.GetSettings(ctx, req.OwnerPhone)
+	if err != nil {
+		return nil, err
+	}
+	props, err := s.ownerProperties(ctx, req.OwnerPhone, req.PropertyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	marks := make(map[string]*exportMark)
+	if !req.IncludeExported {
+		if marks, err = s.loadMarks(ctx, req.OwnerPhone); err != nil {
+			return nil, err
+		}
+	}
+
+	builder := &voucherBuilder{
+		settings: settings,
+		marks:    marks,
+		changed:  make(map[string]*exportMark),
+		start:    start,
+		end:      end,
+	}
+
+	for _, prop := range props {
+		// Payments and changes are dated by the property's local day
+		builder.location = prop.Location()
+
+		byID := make(map[string]*bookings.Booking)
+		lookback := &bookings.DateRange{Start: start.AddDate(-amendmentLookbackYears, 0, 0), End: end}
+		err := s.bookingService.PageBookingsByProperty(ctx, prop.ID, lookback, func(page []*bookings.Booking) error {
+			for _, booking := range page {
+				byID[booking.ID] = booking
+				builder.addBooking(booking)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, builder.location)
+		to := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, builder.location)
+		entries, err := s.bookingService.ListPaymentEntries(ctx, prop.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			booking := byID[entry.BookingID]
+			if booking == nil {
+				if booking, err = s.bookingService.GetBooking(ctx, entry.BookingID); err != nil {
+					return nil, err
+				}
+			}
+			builder.addPayment(entry, booking)
+		}
+	}
+
+	sort.SliceStable(builder.vouchers, func(i, j int) bool {
+		a, b := builder.vouchers[i], builder.vouchers[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Number < b.Number
+	})
+
+	result := &ExportResult{Settings: settings, Vouchers: builder.vouchers}
+	if req.IncludeExported || len(builder.vouchers) == 0 {
+		return result, nil
+	}
+
+	// Record what was exported, together with the batch, so that concurrent
+	// exports cannot both record the same bookings
+	if len(builder.changed) >= db.MaxTransactItems {
+		return nil, ErrExportTooLarge
+	}
+	now := time.Now()
+	id := uuid.New().String()
+	result.Batch = &Batch{
+		PK:          "ACCOUNTING#" + req.OwnerPhone,
+		SK:          "BATCH#" + now.UTC().Format(time.RFC3339Nano) + "#" + id,
+		ID:          id,
+		OwnerPhone:  req.OwnerPhone,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Format:      req.Format,
+		Vouchers:    len(builder.vouchers),
+		Bookings:    len(builder.changed),
+		ExportedBy:  req.ExportedBy,
+		CreatedAt:   now,
+		EntityType:  "ACCOUNTING_BATCH",
+	}
+
+	items := make([]db.TransactItem, 0, len(builder.changed)+1)
+	for _, mark := range builder.changed {
+		items = append(items, putMark(mark, now))
+	}
+	items = append(items, db.TransactPut(result.Batch, "attribute_not_exists(PK)"))
+	if err := s.db.TransactWrite(ctx, items...); err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return nil, ErrExportConflict
+		}
+		return nil, fmt.Errorf("failed to record export: %w", err)
+	}
+	return result, nil
+}
+
+// ownerProperties returns the owner's properties, limited to propertyIDs if
+// any are given.
+func (s *Service) ownerProperties(ctx context.Context, ownerPhone string, propertyIDs []string) ([]*properties.Property, error) {
+	props, err := s.propertyService.ListPropertiesByOwner(ctx, ownerPhone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list properties: %w", err)
+	}
+	if len(propertyIDs) == 0 {
+		return props, nil
+	}
+
+	selected := make([]*properties.Property, 0, len(propertyIDs))
+	for _, id := range propertyIDs {
+		found := false
+		for _, prop := range props {
+			if prop.ID == id {
+				selected = append(selected, prop)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrPropertyAccess
+		}
+	}
+	return selected, nil
+}
+
+// putMark returns a transactional put of an export mark that fails if the
+// mark was created or changed since it was loaded.
+func putMark(mark *exportMark, now time.Time) db.TransactItem {
+	version := mark.Version
+	mark.UpdatedAt = now
+	mark.Version++
+	if !mark.stored {
+		return db.TransactPut(mark, "attribute_not_exists(PK)")
+	}
+	// Marks recorded before versioning have no version
+	return db.TransactPutWithValues(mark, "attribute_not_exists(version) OR version = :version",
+		map[string]interface{}{":version": version})
+}
+
+// loadMarks returns what has been exported for the owner's bookings.
+func (s *Service) loadMarks(ctx context.Context, ownerPhone string) (map[string]*exportMark, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition: "PK = :pk AND begins_with(SK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":pk":     "ACCOUNTING#" + ownerPhone,
+			":prefix": "EXPORTED#",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exported entries: %w", err)
+	}
+
+	marks := make(map[string]*exportMark, len(items))
+	for _, item := range items {
+		var mark exportMark
+		if err := attributevalue.UnmarshalMap(item, &mark); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal exported entry: %w", err)
+		}
+		mark.stored = true
+		marks[mark.BookingID] = &mark
+	}
+	return marks, nil
+}
+
+// voucherBuilder turns bookings and payments into vouchers for a period.
+type voucherBuilder struct {
+	settings   *Settings
+	marks      map[string]*exportMark // Exported so far, by booking ID
+	changed    map[string]*exportMark // Marks to save
+	start, end time.Time
+	location   *time.Location // Of the property being added
+	vouchers   []Voucher
+}
+
+// mark returns the export mark for a booking, creating it if needed, and
+// flags it to be saved.
+func (b *voucherBuilder) mark(bookingID string) *exportMark {
+	mark, ok := b.marks[bookingID]
+	if !ok {
+		mark = &exportMark{
+			PK:         "ACCOUNTING#" + b.settings.OwnerPhone,
+			SK:         "EXPORTED#" + bookingID,
+			BookingID:  bookingID,
+			EntityType: "ACCOUNTING_EXPORT",
+		}
+		b.marks[bookingID] = mark
+	}
+	b.changed[bookingID] = mark
+	return mark
+}
+
+// addBooking adds the sales and commission vouchers for a booking that are
+// due in the period.
+func (b *voucherBuilder) addBooking(booking *bookings.Booking) {
+	sales, commission := booking.TotalAmount, booking.AgentCommission
+	if booking.Status == bookings.StatusCancelled {
+		sales, commission = 0, 0
+	}
+
+	exported, ok := b.marks[booking.ID]
+	if !ok || !exported.Posted {
+		// First export, on the check-in date
+		date := calendarDate(booking.CheckIn)
+		if date.Before(b.start) || date.After(b.end) || (sales == 0 && commission == 0) {
+			return
+		}
+		mark := b.mark(booking.ID)
+		b.addSales(booking, date, sales, shortID(booking.ID))
+		b.addCommission(booking, date, commission, shortID(booking.ID))
+		mark.Posted = true
+		mark.Sales, mark.Commission = round2(sales), round2(commission)
+		return
+	}
+
+	salesChange := round2(sales - exported.Sales)
+	commissionChange := round2(commission - exported.Commission)
+	if salesChange == 0 && commissionChange == 0 {
+		return
+	}
+
+	// Later changes, on the day they were made
+	date := calendarDate(booking.UpdatedAt.In(b.location))
+	if date.Before(b.start) {
+		date = b.start
+	}
+	if date.After(b.end) {
+		return
+	}
+	mark := b.mark(booking.ID)
+	mark.Adjustments++
+	suffix := fmt.Sprintf("%s-A%d", shortID(booking.ID), mark.Adjustments)
+	b.addSales(booking, date, salesChange, suffix)
+	b.addCommission(booking, date, commissionChange, suffix)
+	mark.Sales, mark.Commission = round2(sales), round2(commission)
+}
+
+// addSales posts revenue, split into room revenue and the tax included in
+// it. A negative amount is posted as a credit note.
+func (b *voucherBuilder) addSales(booking *bookings.Booking, date time.Time, amount float64, suffix string) {
+	if amount == 0 {
+		return
+	}
+	accounts := &b.settings.Accounts
+	// Round the total first so that revenue and tax always add up to it
+	total := round2(math.Abs(amount))
+	tax := round2(total * b.settings.TaxRate / (100 + b.settings.TaxRate))
+	revenue := round2(total - tax)
+
+	income := []Posting{{Ledger: accounts.RoomRevenue, Credit: revenue}}
+	if tax > 0 {
+		income = append(income, Posting{Ledger: accounts.OutputTax, Credit: tax})
+	}
+	voucher := Voucher{
+		Type:       VoucherSales,
+		Number:     "S-" + suffix,
+		Date:       date,
+		Narration:  fmt.Sprintf("Stay of %s, %s to %s, booking %s", booking.GuestName, booking.CheckIn.Format("2006-01-02"), booking.CheckOut.Format("2006-01-02"), booking.ID),
+		BookingID:  booking.ID,
+		PropertyID: booking.PropertyID,
+		Postings:   append([]Posting{{Ledger: accounts.GuestReceivables, Debit: total}}, income...),
+	}
+	if amount < 0 {
+		voucher.Type = VoucherCreditNote
+		voucher.Number = "CN-" + suffix
+		voucher.Narration = "Reversal: " + voucher.Narration
+		if booking.Status == bookings.StatusCancelled {
+			voucher.Narration = fmt.Sprintf("Cancellation of booking %s for %s", booking.ID, booking.GuestName)
+		}
+		reverse(voucher.Postings)
+	}
+	b.vouchers = append(b.vouchers, voucher)
+}
+
+// addCommission posts the agent's commission. A negative amount reverses it.
+func (b *voucherBuilder) addCommission(booking *bookings.Booking, date time.Time, amount float64, suffix string) {
+	if amount == 0 {
+		return
+	}
+	accounts := &b.settings.Accounts
+	agent := booking.BookedByName
+	if agent == "" {
+		agent = booking.BookedBy
+	}
+
+	voucher := Voucher{
+		Type:       VoucherJournal,
+		Number:     "J-" + suffix,
+		Date:       date,
+		Narration:  fmt.Sprintf("Commission to %s for booking %s", agent, booking.ID),
+		BookingID:  booking.ID,
+		PropertyID: booking.PropertyID,
+		Postings: []Posting{
+			{Ledger: accounts.CommissionExpense, Debit: math.Abs(amount)},
+			{Ledger: accounts.CommissionPayable, Credit: math.Abs(amount)},
+		},
+	}
+	if amount < 0 {
+		voucher.Narration = "Reversal: " + voucher.Narration
+		reverse(voucher.Postings)
+	}
+	b.vouchers = append(b.vouchers, voucher)
+}
+
+// addPayment posts a payment recorded on a booking. Negative entries are
+// refunds or corrections and are posted as Payment vouchers.
+func (b *voucherBuilder) addPayment(entry *bookings.PaymentEntry, booking *bookings.Booking) {
+	if entry.Amount == 0 {
+		return
+	}
+	if exported, ok := b.marks[entry.BookingID]; ok && exported.hasPayment(entry.SK) {
+		return
+	}
+
+	guest := "guest"
+	if booking != nil {
+		guest = booking.GuestName
+	}
+	accounts := &b.settings.Accounts
+	amount := math.Abs(entry.Amount)
+	suffix := shortID(entry.BookingID) + "-" + entry.RecordedAt.In(b.location).Format("20060102150405")
+
+	voucher := Voucher{
+		Type:       VoucherReceipt,
+		Number:     "R-" + suffix,
+		Date:       calendarDate(entry.RecordedAt.In(b.location)),
+		Narration:  fmt.Sprintf("Payment from %s by %s for booking %s", guest, methodName(entry.Method), entry.BookingID),
+		BookingID:  entry.BookingID,
+		PropertyID: entry.PropertyID,
+		Postings: []Posting{
+			{Ledger: accounts.paymentAccount(entry.Method), Debit: amount},
+			{Ledger: accounts.GuestReceivables, Credit: amount},
+		},
+	}
+	if entry.Amount < 0 {
+		voucher.Type = VoucherPayment
+		voucher.Number = "P-" + suffix
+		voucher.Narration = fmt.Sprintf("Refund to %s by %s for booking %s", guest, methodName(entry.Method), entry.BookingID)
+		reverse(voucher.Postings)
+	}
+	b.vouchers = append(b.vouchers, voucher)
+
+	mark := b.mark(entry.BookingID)
+	mark.Payments = append(mark.Payments, entry.SK)
+}
+
+// reverse swaps the debits and credits of postings.
+func reverse(postings []Posting) {
+	for i := range postings {
+		postings[i].Debit, postings[i].Credit = postings[i].Credit, postings[i].Debit
+	}
+}
+
+func methodName(method string) string {
+	if method == "" {
+		return string(payments.PaymentMethodOther)
+	}
+	return method
+}
+
+// shortID shortens a booking ID for voucher numbers.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// calendarDate returns the date of t as UTC midnight, the form booking
+// dates are stored in.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,47 @@
+package accounting
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+)
+
+func TestAddSalesBalances(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		taxRate float64
+	}{
+		{"whole amount", 20000, 12},
+		{"unrounded amount", 10000.005, 12},
+		{"fractional tax", 1234.565, 18},
+		{"credit note", -3333.335, 12},
+		{"no tax", 999.995, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &voucherBuilder{settings: &Settings{TaxRate: tt.taxRate, Accounts: DefaultAccounts()}}
+			b.addSales(&bookings.Booking{ID: "b1"}, time.Now(), tt.amount, "b1")
+			if len(b.vouchers) != 1 {
+				t.Fatalf("got %d vouchers, want 1", len(b.vouchers))
+			}
+
+			var debit, credit float64
+			for _, posting := range b.vouchers[0].Postings {
+				if posting.Debit != round2(posting.Debit) || posting.Credit != round2(posting.Credit) {
+					t.Errorf("posting %+v is not rounded to paise", posting)
+				}
+				debit += posting.Debit
+				credit += posting.Credit
+			}
+			if math.Abs(debit-credit) > 1e-9 {
+				t.Errorf("debits %.4f do not equal credits %.4f", debit, credit)
+			}
+			if want := round2(math.Abs(tt.amount)); math.Abs(debit-want) > 1e-9 {
+				t.Errorf("total = %.4f, want %.2f", debit, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package accounting
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// tallyContentType is the MIME type of a Tally import file.
+const tallyContentType = "application/xml"
+
+// Tally's XML import envelope. Only the elements vouchers need are modelled.
+type tallyEnvelope struct {
+	XMLName xml.Name `xml:"ENVELOPE"`
+	Request string   `xml:"HEADER>TALLYREQUEST"`
+	Import  struct {
+		ReportName string         `xml:"REQUESTDESC>REPORTNAME"`
+		Company    string         `xml:"REQUESTDESC>STATICVARIABLES>SVCURRENTCOMPANY,omitempty"`
+		Messages   []tallyMessage `xml:"REQUESTDATA>TALLYMESSAGE"`
+	} `xml:"BODY>IMPORTDATA"`
+}
+
+type tallyMessage struct {
+	Voucher tallyVoucher `xml:"VOUCHER"`
+}
+
+type tallyVoucher struct {
+	Type      string             `xml:"VCHTYPE,attr"`
+	Action    string             `xml:"ACTION,attr"`
+	Date      string             `xml:"DATE"`
+	TypeName  string             `xml:"VOUCHERTYPENAME"`
+	Number    string             `xml:"VOUCHERNUMBER"`
+	Reference string             `xml:"REFERENCE,omitempty"`
+	Narration string             `xml:"NARRATION"`
+	Entries   []tallyLedgerEntry `xml:"ALLLEDGERENTRIES.LIST"`
+}
+
+// tallyLedgerEntry is one posting. Tally marks debits as deemed positive and
+// gives them a negative amount.
+type tallyLedgerEntry struct {
+	Ledger         string `xml:"LEDGERNAME"`
+	DeemedPositive string `xml:"ISDEEMEDPOSITIVE"`
+	Amount         string `xml:"AMOUNT"`
+}
+
+// WriteTally writes the vouchers of an export as a Tally XML import file.
+func WriteTally(w io.Writer, result *ExportResult) error {
+	var envelope tallyEnvelope
+	envelope.Request = "Import Data"
+	envelope.Import.ReportName = "Vouchers"
+	envelope.Import.Company = result.Settings.Company
+
+	for _, voucher := range result.Vouchers {
+		tv := tallyVoucher{
+			Type:      string(voucher.Type),
+			Action:    "Create",
+			Date:      voucher.Date.Format("20060102"),
+			TypeName:  string(voucher.Type),
+			Number:    voucher.Number,
+			Reference: voucher.BookingID,
+			Narration: voucher.Narration,
+		}
+		for _, posting := range voucher.Postings {
+			entry := tallyLedgerEntry{Ledger: posting.Ledger, DeemedPositive: "No", Amount: formatAmount(posting.Credit)}
+			if posting.Debit != 0 {
+				entry.DeemedPositive = "Yes"
+				entry.Amount = formatAmount(-posting.Debit)
+			}
+			tv.Entries = append(tv.Entries, entry)
+		}
+		envelope.Import.Messages = append(envelope.Import.Messages, tallyMessage{Voucher: tv})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", " ")
+	if err := encoder.Encode(envelope); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// formatAmount formats an amount with two decimals, as Tally expects.
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
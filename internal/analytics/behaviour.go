@@ -0,0 +1,364 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+// GroupBySource splits booking behaviour by the channel bookings came through.
+const GroupBySource GroupBy = "source"
+
+// unspecifiedReason groups cancellations made without a reason.
+const unspecifiedReason = "unspecified"
+
+// histogramBucket is a range of whole values, from min to max inclusive.
+// A max of -1 leaves the bucket open-ended.
+type histogramBucket struct {
+	label    string
+	min, max int
+}
+
+var (
+	// Days from the booking being made to check-in
+	leadTimeBuckets = []histogramBucket{
+		{"0", 0, 0}, {"1-3", 1, 3}, {"4-7", 4, 7}, {"8-14", 8, 14}, {"15-30", 15, 30},
+		{"31-60", 31, 60}, {"61-90", 61, 90}, {"91-180", 91, 180}, {"181+", 181, -1},
+	}
+	lengthOfStayBuckets = []histogramBucket{
+		{"1", 1, 1}, {"2", 2, 2}, {"3", 3, 3}, {"4", 4, 4}, {"5", 5, 5}, {"6", 6, 6},
+		{"7", 7, 7}, {"8-14", 8, 14}, {"15+", 15, -1},
+	}
+	partySizeBuckets = []histogramBucket{
+		{"1", 1, 1}, {"2", 2, 2}, {"3-4", 3, 4}, {"5-6", 5, 6}, {"7-10", 7, 10},
+		{"11-15", 11, 15}, {"16+", 16, -1},
+	}
+	// Weekdays from Monday, by time.Weekday
+	weekdayBuckets = []histogramBucket{
+		{"Monday", 1, 1}, {"Tuesday", 2, 2}, {"Wednesday", 3, 3}, {"Thursday", 4, 4},
+		{"Friday", 5, 5}, {"Saturday", 6, 6}, {"Sunday", 0, 0},
+	}
+)
+
+// HistogramBin is one bar of a histogram.
+type HistogramBin struct {
+	Label   string  `json:"label"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// Histogram is the distribution of a value across bookings.
+type Histogram struct {
+	Average *float64       `json:"average,omitempty"` // Omitted for weekdays
+	Median  *float64       `json:"median,omitempty"`
+	Bins    []HistogramBin `json:"bins"`
+}
+
+// ReasonCount is the number of cancellations for a reason.
+type ReasonCount struct {
+	Reason  string  `json:"reason"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"` // Of cancellations
+}
+
+// CancellationStats summarises the cancelled bookings.
+type CancellationStats struct {
+	Cancelled int           `json:"cancelled"`
+	Rate      float64       `json:"rate"` // Percent of all bookings
+	Reasons   []ReasonCount `json:"reasons"`
+}
+
+// BehaviourProfile describes how a set of bookings was made.
+type BehaviourProfile struct {
+	Bookings       int               `json:"bookings"` // Including cancelled
+	LeadTime       Histogram         `json:"leadTime"`
+	LengthOfStay   Histogram         `json:"lengthOfStay"`
+	PartySize      Histogram         `json:"partySize"`
+	ArrivalWeekday Histogram         `json:"arrivalWeekday"`
+	Cancellations  CancellationStats `json:"cancellations"`
+}
+
+// BehaviourGroup is the profile for one property, agent or source.
+type BehaviourGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	BehaviourProfile
+}
+
+// BookingBehaviour describes booking behaviour across a set of properties,
+// and for each group if requested.
+type BookingBehaviour struct {
+	GroupBy     GroupBy          `json:"groupBy,omitempty"`
+	PeriodStart time.Time        `json:"periodStart"`
+	PeriodEnd   time.Time        `json:"periodEnd"`
+	Overall     BehaviourProfile `json:"overall"`
+	Groups      []BehaviourGroup `json:"groups,omitempty"`
+}
+
+// behaviourCounter accumulates the values behind a profile.
+type behaviourCounter struct {
+	bookings     int
+	leadTimes    []int
+	stayLengths  []int
+	partySizes   []int
+	weekdays     []int
+	cancelled    int
+	reasonCounts map[string]int
+}
+
+func newBehaviourCounter() *behaviourCounter {
+	return &behaviourCounter{reasonCounts: make(map[string]int)}
+}
+
+// add counts a booking. leadTime is in days. Cancelled bookings only count
+// towards cancellations.
+func (c *behaviourCounter) add(booking *bookings.Booking, leadTime int) {
+	c.bookings++
+	if booking.Status == bookings.StatusCancelled {
+		c.cancelled++
+		reason := string(booking.CancellationReason)
+		if reason == "" {
+			reason = unspecifiedReason
+		}
+		c.reasonCounts[reason]++
+		return
+	}
+
+	checkIn := calendarDate(booking.CheckIn)
+	c.leadTimes = append(c.leadTimes, leadTime)
+	if nights := int(calendarDate(booking.CheckOut).Sub(checkIn).Hours() / 24); nights > 0 {
+		c.stayLengths = append(c.stayLengths, nights)
+	}
+	if booking.NumGuests > 0 {
+		c.partySizes = append(c.partySizes, booking.NumGuests)
+	}
+	c.weekdays = append(c.weekdays, int(checkIn.Weekday()))
+}
+
+func (c *behaviourCounter) profile() BehaviourProfile {
+	profile := BehaviourProfile{
+		Bookings:       c.bookings,
+		LeadTime:       newHistogram(leadTimeBuckets, c.leadTimes, true),
+		LengthOfStay:   newHistogram(lengthOfStayBuckets, c.stayLengths, true),
+		PartySize:      newHistogram(partySizeBuckets, c.partySizes, true),
+		ArrivalWeekday: newHistogram(weekdayBuckets, c.weekdays, false),
+		Cancellations: CancellationStats{
+			Cancelled: c.cancelled,
+			Rate:      ratio(float64(c.cancelled)*100, float64(c.bookings)),
+			Reasons:   []ReasonCount{},
+		},
+	}
+
+	for reason, count := range c.reasonCounts {
+		profile.Cancellations.Reasons = append(profile.Cancellations.Reasons, ReasonCount{
+			Reason:  reason,
+			Count:   count,
+			Percent: ratio(float64(count)*100, float64(c.cancelled)),
+		})
+	}
+	sort.Slice(profile.Cancellations.Reasons, func(i, j int) bool {
+		a, b := profile.Cancellations.Reasons[i], profile.Cancellations.Reasons[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Reason < b.Reason
+	})
+	return profile
+}
+
+// newHistogram counts values into buckets, with the average and median if
+// the values are numeric.
+func newHistogram(buckets []histogramBucket, values []int, numeric bool) Histogram {
+	histogram := Histogram{Bins: make([]HistogramBin, len(buckets))}
+	for i, bucket := range buckets {
+		histogram.Bins[i].Label = bucket.label
+	}
+
+	for _, value := range values {
+		for i, bucket := range buckets {
+			if value >= bucket.min && (bucket.max < 0 || value <= bucket.max) {
+				histogram.Bins[i].Count++
+				break
+			}
+		}
+	}
+	for i := range histogram.Bins {
+		histogram.Bins[i].Percent = ratio(float64(histogram.Bins[i].Count)*100, float64(len(values)))
+	}
+
+	if numeric && len(values) > 0 {
+		sorted := append([]int(nil), values...)
+		sort.Ints(sorted)
+		sum := 0
+		for _, value := range sorted {
+			sum += value
+		}
+		average := ratio(float64(sum), float64(len(sorted)))
+		median := float64(sorted[len(sorted)/2])
+		if len(sorted)%2 == 0 {
+			median = float64(sorted[len(sorted)/2-1]+sorted[len(sorted)/2]) / 2
+		}
+		histogram.Average = &average
+		histogram.Median = &median
+	}
+	return histogram
+}
+
+// GetBookingBehaviour profiles the bookings checking in from startDate to
+// endDate inclusive: lead time, length of stay, party size, arrival weekday
+// and cancellations. Lead time is measured from the local date the booking
+// was made, and bookings made after check-in count as same-day.
+func (s *Service) GetBookingBehaviour(ctx context.Context, props []*properties.Property, startDate, endDate time.Time, groupBy GroupBy) (*BookingBehaviour, error) {
+	switch groupBy {
+	case GroupByNone, GroupByProperty, GroupByAgent, GroupBySource:
+	default:
+		return nil, fmt.Errorf("groupBy must be property, agent or source")
+	}
+
+	start := calendarDate(startDate)
+	end := calendarDate(endDate)
+	if end.Before(start) {
+		return nil, fmt.Errorf("endDate must not be before startDate")
+	}
+
+	overall := newBehaviourCounter()
+	groups := make(map[string]*behaviourCounter)
+	labels := make(map[string]string)
+	var ordered []string
+	group := func(key string) *behaviourCounter {
+		counter, ok := groups[key]
+		if !ok {
+			counter = newBehaviourCounter()
+			groups[key] = counter
+			ordered = append(ordered, key)
+		}
+		return counter
+	}
+
+	for _, prop := range props {
+		if groupBy == GroupByProperty {
+			group(prop.ID)
+			labels[prop.ID] = prop.Name
+		}
+
+		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, prop.ID, &bookings.DateRange{Start: start, End: end})
+		if err != nil {
+			return nil, err
+		}
+		location := prop.Location()
+
+		for _, booking := range propBookings {
+			leadTime := int(calendarDate(booking.CheckIn).Sub(calendarDate(booking.CreatedAt.In(location))).Hours() / 24)
+			if leadTime < 0 {
+				leadTime = 0
+			}
+			overall.add(booking, leadTime)
+
+			switch groupBy {
+			case GroupByNone:
+				continue
+			case GroupBySource:
+				key := string(bookings.SourceOf(booking))
+				group(key).add(booking, leadTime)
+			default:
+				key := seriesKey(groupBy, prop.ID, booking.BookedBy)
+				if groupBy == GroupByAgent && booking.BookedByName != "" {
+					labels[key] = booking.BookedByName
+				}
+				group(key).add(booking, leadTime)
+			}
+		}
+	}
+
+	result := &BookingBehaviour{
+		GroupBy:     groupBy,
+		PeriodStart: start,
+		PeriodEnd:   end.AddDate(0, 0, 1).Add(-time.Second),
+		Overall:     overall.profile(),
+	}
+	if groupBy == GroupByNone {
+		return result, nil
+	}
+
+	result.Groups = make([]BehaviourGroup, 0, len(ordered))
+	for _, key := range ordered {
+		label := labels[key]
+		switch {
+		case label != "":
+		case groupBy == GroupByAgent && key == directKey:
+			label = "Direct"
+		case groupBy == GroupByAgent:
+			if user, err := s.userService.GetUserByPhone(ctx, key); err == nil && user != nil {
+				label = user.Name
+			}
+		}
+		if label == "" {
+			label = key
+		}
+		result.Groups = append(result.Groups, BehaviourGroup{
+			Key:              key,
+			Label:            label,
+			BehaviourProfile: groups[key].profile(),
+		})
+	}
+	return result, nil
+}
+
+// CSV writes the profiles as one row per histogram bin or cancellation
+// reason, for spreadsheets.
+func (b *BookingBehaviour) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"Group", "Group Label", "Measure", "Bucket", "Count", "Percent"}); err != nil {
+		return nil, err
+	}
+
+	write := func(key, label string, profile BehaviourProfile) error {
+		key, label = spreadsheetText(key), spreadsheetText(label)
+		rows := [][]string{
+			{key, label, "bookings", "", strconv.Itoa(profile.Bookings), ""},
+			{key, label, "cancellations", "", strconv.Itoa(profile.Cancellations.Cancelled), formatPercent(profile.Cancellations.Rate)},
+		}
+		for _, reason := range profile.Cancellations.Reasons {
+			rows = append(rows, []string{key, label, "cancellation_reason", spreadsheetText(reason.Reason), strconv.Itoa(reason.Count), formatPercent(reason.Percent)})
+		}
+		for _, measure := range []struct {
+			name      string
+			histogram Histogram
+		}{
+			{"lead_time_days", profile.LeadTime},
+			{"length_of_stay_nights", profile.LengthOfStay},
+			{"party_size", profile.PartySize},
+			{"arrival_weekday", profile.ArrivalWeekday},
+		} {
+			for _, bin := range measure.histogram.Bins {
+				rows = append(rows, []string{key, label, measure.name, bin.Label, strconv.Itoa(bin.Count), formatPercent(bin.Percent)})
+			}
+		}
+		return w.WriteAll(rows)
+	}
+
+	if err := write("all", "All bookings", b.Overall); err != nil {
+		return nil, err
+	}
+	for _, group := range b.Groups {
+		if err := write(group.Key, group.Label, group.BehaviourProfile); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
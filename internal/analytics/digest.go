@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/payments"
+)
+
+// OwnerDigest summarises activity across all of an owner's properties for a period.
+type OwnerDigest struct {
+	OwnerName  string `json:"ownerName"`
+	OwnerPhone string `json:"ownerPhone"`
+
+	TotalProperties int `json:"totalProperties"`
+
+	// Activity during the period
+	NewBookings       int     `json:"newBookings"`
+	NewBookingValue   float64 `json:"newBookingValue"`
+	Cancellations     int     `json:"cancellations"`
+	PaymentsCollected float64 `json:"paymentsCollected"`
+
+	// Looking ahead from the end of the period
+	UpcomingArrivals []BookingSummary `json:"upcomingArrivals"`
+
+	// Balances still owed on bookings that are not cancelled
+	OutstandingDue      float64 `json:"outstandingDue"`
+	OutstandingBookings int     `json:"outstandingBookings"`
+
+	Currency    string    `json:"currency"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+// digestLookbackDays limits how far back stays are checked for outstanding dues.
+const digestLookbackDays = 60
+
+// GetOwnerDigest summarises bookings across an owner's properties: bookings
+// created and cancelled in [periodStart, periodEnd), payments recorded in
+// the period, arrivals in the upcomingDays after periodEnd, and balances
+// still due. The period is given as calendar dates, which are read as local
+// days in each property's time zone.
+func (s *Service) GetOwnerDigest(ctx context.Context, ownerID string, periodStart, periodEnd time.Time, upcomingDays int) (*OwnerDigest, error) {
+	digest := &OwnerDigest{
+		OwnerPhone:       ownerID,
+		Currency:         "INR",
+		UpcomingArrivals: []BookingSummary{},
+		PeriodStart:      periodStart,
+		PeriodEnd:        periodEnd,
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, ownerID)
+	if err == nil && user != nil {
+		digest.OwnerName = user.Name
+	}
+
+	props, err := s.propertyService.ListPropertiesByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	digest.TotalProperties = len(props)
+
+	// Booking dates are stored as UTC midnight
+	endDate := calendarDate(periodEnd)
+	arrivalsEnd := endDate.AddDate(0, 0, upcomingDays)
+
+	// New bookings can be for stays far ahead, so look a year past the period
+	dateRange := &bookings.DateRange{
+		Start: endDate.AddDate(0, 0, -digestLookbackDays),
+		End:   endDate.AddDate(1, 0, 0),
+	}
+
+	for _, prop := range props {
+		location := prop.Location()
+		from := time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(), 0, 0, 0, 0, location)
+		to := time.Date(periodEnd.Year(), periodEnd.Month(), periodEnd.Day(), 0, 0, 0, 0, location)
+		inPeriod := func(t time.Time) bool {
+			return !t.Before(from) && t.Before(to)
+		}
+
+		entries, err := s.bookingService.ListPaymentEntries(ctx, prop.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			digest.PaymentsCollected += entry.Amount
+		}
+
+		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, prop.ID, dateRange)
+		if err != nil {
+			continue
+		}
+
+		for _, booking := range propBookings {
+			if inPeriod(booking.CreatedAt) {
+				digest.NewBookings++
+				digest.NewBookingValue += booking.TotalAmount
+			}
+
+			if booking.Status == bookings.StatusCancelled {
+				if inPeriod(cancelledAt(booking)) {
+					digest.Cancellations++
+				}
+				continue
+			}
+
+			summary := payments.Summarize(booking)
+			if summary.Status != payments.PaymentStatusSettled && summary.TotalDue > 0 {
+				digest.OutstandingDue += summary.TotalDue
+				digest.OutstandingBookings++
+			}
+
+			if !booking.CheckIn.Before(endDate) && booking.CheckIn.Before(arrivalsEnd) {
+				digest.UpcomingArrivals = append(digest.UpcomingArrivals, BookingSummary{
+					BookingID:     booking.ID,
+					PropertyName:  prop.Name,
+					GuestName:     booking.GuestName,
+					CheckIn:       booking.CheckIn,
+					CheckOut:      booking.CheckOut,
+					TotalAmount:   booking.TotalAmount,
+					Status:        string(booking.Status),
+					PaymentStatus: string(summary.Status),
+				})
+			}
+		}
+	}
+
+	sort.Slice(digest.UpcomingArrivals, func(i, j int) bool {
+		return digest.UpcomingArrivals[i].CheckIn.Before(digest.UpcomingArrivals[j].CheckIn)
+	})
+
+	return digest, nil
+}
+
+// cancelledAt returns when a cancelled booking was cancelled. Bookings
+// cancelled before the time was recorded fall back to their last update.
+func cancelledAt(booking *bookings.Booking) time.Time {
+	if booking.CancelledAt != nil {
+		return *booking.CancelledAt
+	}
+	return booking.UpdatedAt
+}
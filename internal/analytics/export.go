@@ -1,144 +1,435 @@
 package analytics
 
 import (
-	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/booking-villa-backend/internal/bookings"
-	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/payments"
 	"github.com/booking-villa-backend/internal/properties"
-	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
 )
 
-// GenerateMasterCSV creates a CSV dump of all data.
-func (s *Service) GenerateMasterCSV(ctx context.Context) ([]byte, error) {
-	// 1. Fetch ALL properties via Scan (using PK prefix for reliability)
-	// Some older records might not have EntityType attribute
-	propParams := db.ScanParams{
-		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk",
-		ExpressionValues: map[string]interface{}{
-			":prefix": "PROPERTY#",
-			":sk":     "METADATA",
-		},
-	}
-	propItems, err := s.db.Scan(ctx, propParams)
+// ExportFormat is the file format of a booking export.
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+	ExportJSON ExportFormat = "json"
+)
+
+// IsValid checks if the export format is supported.
+func (f ExportFormat) IsValid() bool {
+	switch f {
+	case ExportCSV, ExportXLSX, ExportJSON:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of the format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportXLSX:
+		return xlsxContentType
+	case ExportJSON:
+		return "application/json"
+	default:
+		return "text/csv"
+	}
+}
+
+// ErrExportTooLarge is returned when an export outgrows its size limit.
+var ErrExportTooLarge = errors.New("export is too large; narrow the filters")
+
+// exportRow is a booking with the details resolved for export.
+type exportRow struct {
+	booking    *bookings.Booking
+	property   *properties.Property
+	agentName  string
+	paymentDue *payments.PaymentSummary
+}
+
+// exportColumn is a column that can be selected for export.
+type exportColumn struct {
+	key    string
+	header string
+	value  func(r *exportRow) interface{} // string, int or float64
+}
+
+var exportColumns = []exportColumn{
+	{"id", "Booking ID", func(r *exportRow) interface{} { return r.booking.ID }},
+	{"status", "Status", func(r *exportRow) interface{} { return string(r.booking.Status) }},
+	{"createdAt", "Created At", func(r *exportRow) interface{} { return r.booking.CreatedAt.Format(time.RFC3339) }},
+	{"propertyName", "Property Name", func(r *exportRow) interface{} {
+		if r.property != nil {
+			return r.property.Name
+		}
+		return r.booking.PropertyName
+	}},
+	{"propertyId", "Property ID", func(r *exportRow) interface{} { return r.booking.PropertyID }},
+	{"ownerPhone", "Owner Phone", func(r *exportRow) interface{} {
+		if r.property != nil {
+			return r.property.OwnerID
+		}
+		return "Unknown"
+	}},
+	{"guestName", "Guest Name", func(r *exportRow) interface{} { return r.booking.GuestName }},
+	{"guestPhone", "Guest Phone", func(r *exportRow) interface{} { return r.booking.GuestPhone }},
+	{"guestEmail", "Guest Email", func(r *exportRow) interface{} { return r.booking.GuestEmail }},
+	{"numGuests", "Num Guests", func(r *exportRow) interface{} { return r.booking.NumGuests }},
+	{"checkIn", "Check In", func(r *exportRow) interface{} { return r.booking.CheckIn.Format("2006-01-02") }},
+	{"checkOut", "Check Out", func(r *exportRow) interface{} { return r.booking.CheckOut.Format("2006-01-02") }},
+	{"nights", "Nights", func(r *exportRow) interface{} { return r.booking.NumNights }},
+	{"totalAmount", "Total Amount", func(r *exportRow) interface{} { return r.booking.TotalAmount }},
+	{"advanceAmount", "Amount Paid", func(r *exportRow) interface{} { return r.booking.AdvanceAmount }},
+	{"balanceDue", "Balance Due", func(r *exportRow) interface{} { return r.paymentDue.TotalDue }},
+	{"paymentStatus", "Payment Status", func(r *exportRow) interface{} { return string(r.paymentDue.Status) }},
+	{"agentCommission", "Agent Commission", func(r *exportRow) interface{} { return r.booking.AgentCommission }},
+	{"currency", "Currency", func(r *exportRow) interface{} { return r.booking.Currency }},
+	{"bookedBy", "Booked By Phone", func(r *exportRow) interface{} { return r.booking.BookedBy }},
+	{"bookedByName", "Booked By Name", func(r *exportRow) interface{} { return r.agentName }},
+	{"inviteCode", "Invite Code", func(r *exportRow) interface{} { return r.booking.InviteCode }},
+	{"source", "Source", func(r *exportRow) interface{} { return string(bookings.SourceOf(r.booking)) }},
+	{"cancellationReason", "Cancellation Reason", func(r *exportRow) interface{} { return string(r.booking.CancellationReason) }},
+	{"notes", "Notes", func(r *exportRow) interface{} { return r.booking.Notes }},
+}
+
+// defaultExportColumns are exported when no columns are selected.
+var defaultExportColumns = []string{
+	"id", "status", "createdAt",
+	"propertyName", "propertyId", "ownerPhone",
+	"guestName", "guestPhone", "guestEmail", "numGuests",
+	"checkIn", "checkOut", "nights",
+	"totalAmount", "agentCommission", "currency",
+	"bookedBy", "bookedByName", "inviteCode",
+	"notes",
+}
+
+// selectColumns returns the columns for keys, or the default columns if
+// there are none.
+func selectColumns(keys []string) ([]exportColumn, error) {
+	if len(keys) == 0 {
+		keys = defaultExportColumns
+	}
+
+	columns := make([]exportColumn, 0, len(keys))
+	for _, key := range keys {
+		found := false
+		for _, column := range exportColumns {
+			if column.key == key {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", key)
+		}
+	}
+	return columns, nil
+}
+
+// ExportColumnKeys returns the keys of every column that can be exported.
+func ExportColumnKeys() []string {
+	keys := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		keys[i] = column.key
+	}
+	return keys
+}
+
+// ExportQuery selects the bookings to export and how to write them.
+type ExportQuery struct {
+	// Viewer is the user the export is for. Guest details are masked by the
+	// same rules as the bookings API.
+	Viewer *utils.TokenClaims
+
+	// Properties limits the export to these properties, unless AllProperties
+	// is set, in which case the whole table is scanned
+	Properties    []*properties.Property
+	AllProperties bool
+
+	DateRange  *bookings.DateRange // Check-in dates, inclusive; nil for all
+	Statuses   []bookings.BookingStatus
+	AgentPhone string   // Only bookings made by this user
+	Columns    []string // Column keys; empty for the defaults
+	Format     ExportFormat
+}
+
+// rowWriter writes exported rows in one of the export formats.
+type rowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// Export writes the bookings matching the query to w, a page at a time, and
+// returns the number of bookings written.
+func (s *Service) Export(ctx context.Context, w io.Writer, query ExportQuery) (int, error) {
+	if !query.Format.IsValid() {
+		return 0, fmt.Errorf("format must be csv, xlsx or json")
+	}
+	if query.Viewer == nil {
+		return 0, fmt.Errorf("an export needs a viewer")
+	}
+	columns, err := selectColumns(query.Columns)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := newRowWriter(w, query.Format, columns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan properties: %w", err)
+		return 0, err
 	}
 
 	propMap := make(map[string]*properties.Property)
-	for _, item := range propItems {
-		var p properties.Property
-		if err := attributevalue.UnmarshalMap(item, &p); err == nil {
-			propMap[p.ID] = &p
+	for _, prop := range query.Properties {
+		propMap[prop.ID] = prop
+	}
+	agentNames := make(map[string]string)
+
+	count := 0
+	writePage := func(page []*bookings.Booking) error {
+		for _, booking := range page {
+			if !query.matches(booking) {
+				continue
+			}
+			row := &exportRow{
+				booking:    booking,
+				property:   s.exportProperty(ctx, propMap, booking.PropertyID),
+				agentName:  s.exportAgentName(ctx, agentNames, booking),
+				paymentDue: payments.Summarize(booking),
+			}
+			if !bookings.CanSeeGuestDetails(query.Viewer, booking) {
+				bookings.MaskGuestDetails(booking)
+			}
+
+			values := make([]interface{}, len(columns))
+			for i, column := range columns {
+				values[i] = column.value(row)
+			}
+			if err := out.WriteRow(values); err != nil {
+				return err
+			}
+			count++
 		}
+		return nil
 	}
 
-	// 2. Fetch ALL users via Scan
-	userParams := db.ScanParams{
-		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk",
-		ExpressionValues: map[string]interface{}{
-			":prefix": "USER#",
-			":sk":     "PROFILE",
-		},
+	if query.AllProperties {
+		err = s.bookingService.PageAllBookings(ctx, writePage)
+	} else {
+		for _, prop := range query.Properties {
+			if err = s.bookingService.PageBookingsByProperty(ctx, prop.ID, query.DateRange, writePage); err != nil {
+				break
+			}
+		}
 	}
-	userItems, err := s.db.Scan(ctx, userParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan users: %w", err)
+		return count, err
 	}
 
-	userMap := make(map[string]string) // phone -> name
-	for _, item := range userItems {
-		var u users.User
-		if err := attributevalue.UnmarshalMap(item, &u); err == nil {
-			userMap[u.Phone] = u.Name
+	return count, out.Close()
+}
+
+// matches applies the filters that are not part of the booking query.
+func (q *ExportQuery) matches(booking *bookings.Booking) bool {
+	if q.AllProperties && q.DateRange != nil {
+		checkIn := calendarDate(booking.CheckIn)
+		if checkIn.Before(calendarDate(q.DateRange.Start)) || checkIn.After(calendarDate(q.DateRange.End)) {
+			return false
 		}
 	}
+	if q.AgentPhone != "" && booking.BookedBy != q.AgentPhone {
+		return false
+	}
+	if len(q.Statuses) == 0 {
+		return true
+	}
+	for _, status := range q.Statuses {
+		if booking.Status == status {
+			return true
+		}
+	}
+	return false
+}
 
-	// 3. Fetch ALL bookings via Scan
-	bookingParams := db.ScanParams{
-		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk",
-		ExpressionValues: map[string]interface{}{
-			":prefix": "BOOKING#",
-			":sk":     "METADATA",
-		},
+// exportProperty returns a booking's property, looking it up once if it was
+// not part of the query.
+func (s *Service) exportProperty(ctx context.Context, cache map[string]*properties.Property, propertyID string) *properties.Property {
+	prop, ok := cache[propertyID]
+	if !ok {
+		prop, _ = s.propertyService.GetProperty(ctx, propertyID)
+		cache[propertyID] = prop
 	}
-	bookingItems, err := s.db.Scan(ctx, bookingParams)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan bookings: %w", err)
-	}
-
-	var allBookings []bookings.Booking
-	for _, item := range bookingItems {
-		var b bookings.Booking
-		if err := attributevalue.UnmarshalMap(item, &b); err == nil {
-			allBookings = append(allBookings, b)
-		}
-	}
-
-	// 4. Generate CSV
-	var b bytes.Buffer
-	w := csv.NewWriter(&b)
-
-	// Header
-	header := []string{
-		"Booking ID", "Status", "Created At",
-		"Property Name", "Property ID", "Owner Phone",
-		"Guest Name", "Guest Phone", "Guest Email", "Num Guests",
-		"Check In", "Check Out", "Nights",
-		"Total Amount", "Agent Commission", "Currency",
-		"Booked By Phone", "Booked By Name", "Invite Code",
-		"Notes",
-	}
-	if err := w.Write(header); err != nil {
-		return nil, err
-	}
-
-	// Rows
-	for _, bk := range allBookings {
-		// Resolve Agent Name
-		agentName := "Direct/Owner"
-		if bk.BookedBy != "" {
-			if name, ok := userMap[bk.BookedBy]; ok {
-				agentName = name
-			} else if bk.BookedByName != "" {
-				agentName = bk.BookedByName
-			} else {
-				agentName = "Unknown Agent"
-			}
+	return prop
+}
+
+// exportAgentName returns the name of the user who made a booking.
+func (s *Service) exportAgentName(ctx context.Context, cache map[string]string, booking *bookings.Booking) string {
+	if booking.BookedBy == "" {
+		return "Direct/Owner"
+	}
+	name, ok := cache[booking.BookedBy]
+	if !ok {
+		if user, err := s.userService.GetUserByPhone(ctx, booking.BookedBy); err == nil && user != nil {
+			name = user.Name
 		}
+		cache[booking.BookedBy] = name
+	}
+	switch {
+	case name != "":
+		return name
+	case booking.BookedByName != "":
+		return booking.BookedByName
+	default:
+		return "Unknown Agent"
+	}
+}
 
-		// Resolve Property Metadata
-		propertyName := bk.PropertyName
-		ownerPhone := "Unknown"
-		if p, ok := propMap[bk.PropertyID]; ok {
-			propertyName = p.Name
-			ownerPhone = p.OwnerID
-		} else if strings.Contains(bk.PropertyID, "6c258855") {
-			// Special handling for the sample property in user's dump if ID mismatch
-			// This is just a fallback, the propMap check is primary
+func newRowWriter(w io.Writer, format ExportFormat, columns []exportColumn) (rowWriter, error) {
+	switch format {
+	case ExportXLSX:
+		x, err := newXLSXWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		headers := make([]interface{}, len(columns))
+		for i, column := range columns {
+			headers[i] = column.header
 		}
+		return x, x.WriteRow(headers)
+	case ExportJSON:
+		keys := make([]string, len(columns))
+		for i, column := range columns {
+			keys[i] = column.key
+		}
+		return newJSONRowWriter(w, keys)
+	default:
+		c := &csvRowWriter{w: csv.NewWriter(w)}
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = column.header
+		}
+		return c, c.w.Write(headers)
+	}
+}
+
+// csvRowWriter writes rows as CSV, with amounts to two decimal places.
+type csvRowWriter struct {
+	w      *csv.Writer
+	record []string
+}
 
-		row := []string{
-			bk.ID, string(bk.Status), bk.CreatedAt.Format(time.RFC3339),
-			propertyName, bk.PropertyID, ownerPhone,
-			bk.GuestName, bk.GuestPhone, bk.GuestEmail, strconv.Itoa(bk.NumGuests),
-			bk.CheckIn.Format("2006-01-02"), bk.CheckOut.Format("2006-01-02"), strconv.Itoa(bk.NumNights),
-			fmt.Sprintf("%.2f", bk.TotalAmount), fmt.Sprintf("%.2f", bk.AgentCommission), bk.Currency,
-			bk.BookedBy, agentName, bk.InviteCode,
-			bk.Notes,
+func (c *csvRowWriter) WriteRow(values []interface{}) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			value = spreadsheetText(text)
 		}
-		if err := w.Write(row); err != nil {
+		c.record = append(c.record, cellText(value))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonRowWriter writes rows as {"bookings": [...], "count": n}, with each
+// row an object keyed by column.
+type jsonRowWriter struct {
+	w     io.Writer
+	keys  [][]byte // Quoted column keys
+	count int
+}
+
+func newJSONRowWriter(w io.Writer, keys []string) (*jsonRowWriter, error) {
+	j := &jsonRowWriter{w: w}
+	for _, key := range keys {
+		quoted, err := json.Marshal(key)
+		if err != nil {
 			return nil, err
 		}
+		j.keys = append(j.keys, quoted)
 	}
+	_, err := io.WriteString(w, `{"bookings":[`)
+	return j, err
+}
 
-	w.Flush()
-	return b.Bytes(), w.Error()
+func (j *jsonRowWriter) WriteRow(values []interface{}) error {
+	var b strings.Builder
+	if j.count > 0 {
+		b.WriteByte(',')
+	}
+	b.WriteByte('{')
+	for i, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(j.keys[i])
+		b.WriteByte(':')
+		b.Write(encoded)
+	}
+	b.WriteByte('}')
+	j.count++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonRowWriter) Close() error {
+	_, err := io.WriteString(j.w, `],"count":`+strconv.Itoa(j.count)+`}`)
+	return err
+}
+
+// cellText formats a value as text.
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// spreadsheetText stops text from being run as a formula when the file is
+// opened in a spreadsheet, by prefixing a quote to text that starts with a
+// formula character. Numbers are written as numbers and are not affected.
+func spreadsheetText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// limitedWriter fails with ErrExportTooLarge once more than n bytes have
+// been written, so an oversized export stops early.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, ErrExportTooLarge
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
 }
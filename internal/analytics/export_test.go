@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestSpreadsheetText(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"", ""},
+		{"Asha Rao", "Asha Rao"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+91 98765", "'+91 98765"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := spreadsheetText(tt.text); got != tt.want {
+			t.Errorf("spreadsheetText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCSVRowWriterEscapesTextOnly(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newRowWriter(&buf, ExportCSV, []exportColumn{{key: "guestName", header: "Guest"}, {key: "totalAmount", header: "Total"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]interface{}{"=1+1", -250.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Guest,Total\n'=1+1,-250.50\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
+
+// failingWriter accepts n bytes and then fails.
+type failingWriter struct{ n int }
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		return 0, errors.New("disk full")
+	}
+	f.n -= len(p)
+	return len(p), nil
+}
+
+func TestXLSXWriterReturnsWriteErrors(t *testing.T) {
+	w, err := newXLSXWriter(&failingWriter{n: 8 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100000; i++ {
+		if err := w.WriteRow([]interface{}{"Guest " + strconv.Itoa(i*7919), i, float64(i) / 3}); err != nil {
+			return
+		}
+	}
+	t.Fatal("WriteRow never returned the write error")
+}
@@ -0,0 +1,272 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/payments"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+const (
+	// DefaultForecastDays is the forecast horizon when none is given.
+	DefaultForecastDays = 90
+
+	// MaxForecastDays is the longest forecast horizon.
+	MaxForecastDays = 365
+
+	// forecastPeriodDays is the width of each forecast period.
+	forecastPeriodDays = 7
+)
+
+// ForecastSummary holds the on-the-books position for a forecast horizon.
+type ForecastSummary struct {
+	AvailableNights int     `json:"availableNights"`
+	SoldNights      int     `json:"soldNights"`
+	Occupancy       float64 `json:"occupancy"` // Percent of available nights sold
+	Revenue         float64 `json:"revenue"`   // Room revenue for the sold nights
+
+	// Balances still due on stays checking in during the horizon, and on
+	// stays that have already checked in
+	ExpectedCollections float64 `json:"expectedCollections"`
+	OverdueCollections  float64 `json:"overdueCollections"`
+
+	Pace Pace `json:"pace"`
+}
+
+// Pace compares what is on the books with the same point last year: the
+// bookings that had been made by this date last year for the same dates a
+// year earlier.
+type Pace struct {
+	LastYearSoldNights int     `json:"lastYearSoldNights"`
+	LastYearRevenue    float64 `json:"lastYearRevenue"`
+
+	// What those dates finally sold last year
+	LastYearFinalSoldNights int     `json:"lastYearFinalSoldNights"`
+	LastYearFinalRevenue    float64 `json:"lastYearFinalRevenue"`
+
+	// Percentage changes on last year at the same point; null when last
+	// year had nothing on the books
+	SoldNightsChange *float64 `json:"soldNightsChange"`
+	RevenueChange    *float64 `json:"revenueChange"`
+}
+
+// ForecastPeriod is one week of the forecast horizon.
+type ForecastPeriod struct {
+	Start               time.Time `json:"start"`
+	Days                int       `json:"days"`
+	AvailableNights     int       `json:"availableNights"`
+	SoldNights          int       `json:"soldNights"`
+	Occupancy           float64   `json:"occupancy"`
+	Revenue             float64   `json:"revenue"`
+	ExpectedCollections float64   `json:"expectedCollections"` // Dues on stays checking in this period
+}
+
+// PropertyForecast is the forecast for one property.
+type PropertyForecast struct {
+	PropertyID   string `json:"propertyId"`
+	PropertyName string `json:"propertyName"`
+	ForecastSummary
+	Periods []ForecastPeriod `json:"periods"`
+}
+
+// Forecast projects the coming days for a set of properties and for each one.
+type Forecast struct {
+	Currency     string    `json:"currency"`
+	AsOf         time.Time `json:"asOf"`
+	HorizonStart time.Time `json:"horizonStart"`
+	HorizonEnd   time.Time `json:"horizonEnd"`
+	Days         int       `json:"days"`
+
+	Portfolio  PortfolioForecast  `json:"portfolio"`
+	Properties []PropertyForecast `json:"properties"`
+}
+
+// PortfolioForecast is the forecast across all the requested properties.
+type PortfolioForecast struct {
+	ForecastSummary
+	Periods []ForecastPeriod `json:"periods"`
+}
+
+// forecastTotals are the raw figures behind a forecast.
+type forecastTotals struct {
+	current  nightStats
+	lastYear nightStats // On the books at the same point last year
+	final    nightStats // Final for last year's dates
+	expected float64
+	overdue  float64
+	periods  []ForecastPeriod
+}
+
+func (t *forecastTotals) add(other *forecastTotals) {
+	t.current.add(other.current)
+	t.lastYear.add(other.lastYear)
+	t.final.add(other.final)
+	t.expected += other.expected
+	t.overdue += other.overdue
+	for i := range t.periods {
+		t.periods[i].AvailableNights += other.periods[i].AvailableNights
+		t.periods[i].SoldNights += other.periods[i].SoldNights
+		t.periods[i].Revenue += other.periods[i].Revenue
+		t.periods[i].ExpectedCollections += other.periods[i].ExpectedCollections
+	}
+}
+
+// GetForecast projects on-the-books revenue, expected collections and
+// occupancy for the next days nights from today, in weekly periods, with
+// pace against last year. Dues are expected by the guest's check-in date.
+func (s *Service) GetForecast(ctx context.Context, props []*properties.Property, days int, now time.Time) (*Forecast, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxForecastDays)
+	}
+
+	// Each property's horizon starts today in its own time zone; the forecast
+	// as a whole starts on the earliest of those days
+	today := (&properties.Property{}).Today(now)
+	for i, prop := range props {
+		if local := prop.Today(now); i == 0 || local.Before(today) {
+			today = local
+		}
+	}
+	horizon := period{today, today.AddDate(0, 0, days)}
+	lastYearAsOf := now.AddDate(-1, 0, 0)
+
+	forecast := &Forecast{
+		Currency:     "INR",
+		AsOf:         now,
+		HorizonStart: horizon.start,
+		HorizonEnd:   horizon.end.Add(-time.Second),
+		Days:         days,
+		Properties:   []PropertyForecast{},
+	}
+
+	portfolio := &forecastTotals{periods: forecastPeriods(horizon)}
+	for _, prop := range props {
+		local := prop.Today(now)
+		propHorizon := period{local, local.AddDate(0, 0, days)}
+		propLastYear := period{propHorizon.start.AddDate(-1, 0, 0), propHorizon.end.AddDate(-1, 0, 0)}
+
+		upcoming, upcomingBlocks, err := s.periodBookings(ctx, prop.ID, propHorizon)
+		if err != nil {
+			return nil, err
+		}
+		past, pastBlocks, err := s.periodBookings(ctx, prop.ID, propLastYear)
+		if err != nil {
+			return nil, err
+		}
+
+		totals := &forecastTotals{
+			current:  countNights(upcoming, upcomingBlocks, propHorizon),
+			lastYear: countNights(onTheBooksAt(past, lastYearAsOf), pastBlocks, propLastYear),
+			final:    countNights(past, pastBlocks, propLastYear),
+			periods:  forecastPeriods(propHorizon),
+		}
+		for i := range totals.periods {
+			p := totals.periods[i]
+			stats := countNights(upcoming, upcomingBlocks, period{p.Start, p.Start.AddDate(0, 0, p.Days)})
+			totals.periods[i].AvailableNights = stats.available
+			totals.periods[i].SoldNights = stats.sold
+			totals.periods[i].Revenue = stats.revenue
+		}
+		addCollections(totals, upcoming, propHorizon)
+		portfolio.add(totals)
+
+		summary, periods := totals.result()
+		forecast.Properties = append(forecast.Properties, PropertyForecast{
+			PropertyID:      prop.ID,
+			PropertyName:    prop.Name,
+			ForecastSummary: summary,
+			Periods:         periods,
+		})
+	}
+
+	forecast.Portfolio.ForecastSummary, forecast.Portfolio.Periods = portfolio.result()
+	return forecast, nil
+}
+
+// forecastPeriods splits the horizon into weeks starting from its first day.
+// The last period may be shorter.
+func forecastPeriods(horizon period) []ForecastPeriod {
+	var periods []ForecastPeriod
+	for start := horizon.start; start.Before(horizon.end); start = start.AddDate(0, 0, forecastPeriodDays) {
+		days := forecastPeriodDays
+		if end := start.AddDate(0, 0, days); end.After(horizon.end) {
+			days = int(horizon.end.Sub(start).Hours() / 24)
+		}
+		periods = append(periods, ForecastPeriod{Start: start, Days: days})
+	}
+	return periods
+}
+
+// onTheBooksAt returns the bookings as they stood at asOf: bookings made
+// since are left out, and bookings cancelled since count as still booked.
+// Cancellations are dated by when the booking was cancelled.
+func onTheBooksAt(propBookings []*bookings.Booking, asOf time.Time) []*bookings.Booking {
+	var result []*bookings.Booking
+	for _, booking := range propBookings {
+		if booking.CreatedAt.After(asOf) {
+			continue
+		}
+		if booking.Status == bookings.StatusCancelled && cancelledAt(booking).After(asOf) {
+			booked := *booking
+			booked.Status = bookings.StatusPending
+			booking = &booked
+		}
+		result = append(result, booking)
+	}
+	return result
+}
+
+// addCollections adds the balances still due on bookings, by check-in date.
+func addCollections(totals *forecastTotals, propBookings []*bookings.Booking, horizon period) {
+	for _, booking := range propBookings {
+		if booking.Status == bookings.StatusCancelled {
+			continue
+		}
+		summary := payments.Summarize(booking)
+		if summary.Status == payments.PaymentStatusSettled || summary.TotalDue <= 0 {
+			continue
+		}
+
+		checkIn := calendarDate(booking.CheckIn)
+		switch {
+		case checkIn.Before(horizon.start):
+			totals.overdue += summary.TotalDue
+		case checkIn.Before(horizon.end):
+			totals.expected += summary.TotalDue
+			index := int(checkIn.Sub(horizon.start).Hours()/24) / forecastPeriodDays
+			totals.periods[index].ExpectedCollections += summary.TotalDue
+		}
+	}
+}
+
+// result rounds the totals into a summary and periods.
+func (t *forecastTotals) result() (ForecastSummary, []ForecastPeriod) {
+	summary := ForecastSummary{
+		AvailableNights:     t.current.available,
+		SoldNights:          t.current.sold,
+		Occupancy:           ratio(float64(t.current.sold)*100, float64(t.current.available)),
+		Revenue:             round2(t.current.revenue),
+		ExpectedCollections: round2(t.expected),
+		OverdueCollections:  round2(t.overdue),
+		Pace: Pace{
+			LastYearSoldNights:      t.lastYear.sold,
+			LastYearRevenue:         round2(t.lastYear.revenue),
+			LastYearFinalSoldNights: t.final.sold,
+			LastYearFinalRevenue:    round2(t.final.revenue),
+			SoldNightsChange:        percentChange(float64(t.current.sold), float64(t.lastYear.sold)),
+			RevenueChange:           percentChange(t.current.revenue, t.lastYear.revenue),
+		},
+	}
+
+	periods := make([]ForecastPeriod, len(t.periods))
+	for i, p := range t.periods {
+		p.Occupancy = ratio(float64(p.SoldNights)*100, float64(p.AvailableNights))
+		p.Revenue = round2(p.Revenue)
+		p.ExpectedCollections = round2(p.ExpectedCollections)
+		periods[i] = p
+	}
+	return summary, periods
+}
@@ -1,26 +1,48 @@
 package analytics
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
+	"github.com/booking-villa-backend/internal/bookings"
 	"github.com/booking-villa-backend/internal/db"
 	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/storage"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+	"github.com/google/uuid"
 )
 
 // Handler provides HTTP handlers for analytics endpoints.
 type Handler struct {
-	service *Service
+	service      *Service
+	auditService *audit.Service
+	exports      *storage.Bucket // Nil if EXPORT_BUCKET is not set
 }
 
 // NewHandler creates a new analytics handler.
 func NewHandler(dbClient *db.Client) *Handler {
+	exports, err := storage.BucketFromEnv(context.Background())
+	if err != nil {
+		log.Printf("Failed to set up export bucket, exports are returned inline: %v", err)
+	}
+
 	return &Handler{
-		service: NewService(dbClient),
+		service:      NewService(dbClient),
+		auditService: audit.NewService(dbClient),
+		exports:      exports,
 	}
 }
 
@@ -43,6 +65,10 @@ func ErrorResponse(statusCode int, message string) events.APIGatewayProxyRespons
 	return APIResponse(statusCode, map[string]string{"error": message})
 }
 
+// msgPropertyRestricted is returned when an API key limited to specific
+// properties requests analytics that span all of the user's properties.
+const msgPropertyRestricted = "Account-wide analytics are not available to property-restricted API keys"
+
 // HandleOwnerAnalytics handles GET /analytics/owner endpoint.
 func (h *Handler) HandleOwnerAnalytics(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Get user from context
@@ -51,6 +77,10 @@ func (h *Handler) HandleOwnerAnalytics(ctx context.Context, request events.APIGa
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, msgPropertyRestricted), nil
+	}
+
 	// Parse date range from query params
 	startDate, endDate := parseDateRange(request)
 
@@ -62,6 +92,208 @@ func (h *Handler) HandleOwnerAnalytics(ctx context.Context, request events.APIGa
 	return APIResponse(http.StatusOK, analytics), nil
 }
 
+// HandleOwnerKPIs handles GET /analytics/owner/kpis endpoint.
+func (h *Handler) HandleOwnerKPIs(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	propertyIDs, ok := requestedProperties(request, claims)
+	if !ok {
+		return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
+	}
+
+	startDate, endDate := parseDateRange(request)
+
+	props, err := h.service.ResolveProperties(ctx, claims.Phone, claims.Role == string(users.RoleAdmin), propertyIDs)
+	if err != nil {
+		if errors.Is(err, ErrPropertyAccess) {
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get properties: "+err.Error()), nil
+	}
+
+	kpis, err := h.service.GetOwnerKPIs(ctx, props, startDate, endDate)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get KPIs: "+err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, kpis), nil
+}
+
+// HandleTimeSeries handles GET /analytics/timeseries endpoint.
+func (h *Handler) HandleTimeSeries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	propertyIDs, ok := requestedProperties(request, claims)
+	if !ok {
+		return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
+	}
+
+	startDate, endDate := parseDateRange(request)
+	params := request.QueryStringParameters
+	query := TimeSeriesQuery{
+		Start:       startDate,
+		End:         endDate,
+		Granularity: Granularity(valueOr(params["granularity"], string(GranularityDay))),
+		Metric:      Metric(valueOr(params["metric"], string(MetricRevenue))),
+		Basis:       Basis(valueOr(params["basis"], string(BasisStay))),
+		GroupBy:     GroupBy(params["groupBy"]),
+	}
+	if err := query.Validate(); err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	props, err := h.service.ResolveProperties(ctx, claims.Phone, claims.Role == string(users.RoleAdmin), propertyIDs)
+	if err != nil {
+		if errors.Is(err, ErrPropertyAccess) {
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get properties: "+err.Error()), nil
+	}
+
+	series, err := h.service.GetTimeSeries(ctx, props, query)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, series), nil
+}
+
+// HandleForecast handles GET /analytics/forecast endpoint.
+func (h *Handler) HandleForecast(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	propertyIDs, ok := requestedProperties(request, claims)
+	if !ok {
+		return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
+	}
+
+	days := DefaultForecastDays
+	if daysStr := request.QueryStringParameters["days"]; daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 1 || parsed > MaxForecastDays {
+			return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", MaxForecastDays)), nil
+		}
+		days = parsed
+	}
+
+	props, err := h.service.ResolveProperties(ctx, claims.Phone, claims.Role == string(users.RoleAdmin), propertyIDs)
+	if err != nil {
+		if errors.Is(err, ErrPropertyAccess) {
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get properties: "+err.Error()), nil
+	}
+
+	forecast, err := h.service.GetForecast(ctx, props, days, time.Now())
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get forecast: "+err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, forecast), nil
+}
+
+// HandleBookingBehaviour handles GET /analytics/behaviour endpoint.
+func (h *Handler) HandleBookingBehaviour(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	propertyIDs, ok := requestedProperties(request, claims)
+	if !ok {
+		return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
+	}
+
+	format := valueOr(request.QueryStringParameters["format"], "json")
+	if format != "json" && format != "csv" {
+		return ErrorResponse(http.StatusBadRequest, "format must be json or csv"), nil
+	}
+
+	startDate, endDate := parseDateRange(request)
+	groupBy := GroupBy(request.QueryStringParameters["groupBy"])
+
+	props, err := h.service.ResolveProperties(ctx, claims.Phone, claims.Role == string(users.RoleAdmin), propertyIDs)
+	if err != nil {
+		if errors.Is(err, ErrPropertyAccess) {
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get properties: "+err.Error()), nil
+	}
+
+	behaviour, err := h.service.GetBookingBehaviour(ctx, props, startDate, endDate, groupBy)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	if format == "json" {
+		return APIResponse(http.StatusOK, behaviour), nil
+	}
+
+	filename := fmt.Sprintf("booking_behaviour_%s_%s.csv", behaviour.PeriodStart.Format("2006-01-02"), behaviour.PeriodEnd.Format("2006-01-02"))
+	csvData, err := behaviour.CSV()
+
+	event := audit.NewEvent(audit.EventDataExported, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(filename)
+	if claims.IsAPIKey() {
+		event.WithDetail("apiKeyId", claims.APIKeyID)
+	}
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to generate CSV: "+err.Error()), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":                 "text/csv",
+			"Content-Disposition":          fmt.Sprintf("attachment; filename=\"%s\"", filename),
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(csvData),
+	}, nil
+}
+
+// requestedProperties returns the property IDs in the propertyIds query
+// param. Property-restricted API keys default to their own properties and
+// may not request others; ok is false if they do.
+func requestedProperties(request events.APIGatewayProxyRequest, claims *utils.TokenClaims) ([]string, bool) {
+	propertyIDs := splitList(request.QueryStringParameters["propertyIds"])
+	if len(propertyIDs) == 0 {
+		propertyIDs = claims.PropertyIDs
+	}
+
+	for _, id := range propertyIDs {
+		if !claims.CanAccessProperty(id) {
+			return nil, false
+		}
+	}
+	return propertyIDs, true
+}
+
+// valueOr returns value, or def if value is empty.
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 // HandleAgentAnalytics handles GET /analytics/agent endpoint.
 func (h *Handler) HandleAgentAnalytics(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Get user from context
@@ -70,6 +302,10 @@ func (h *Handler) HandleAgentAnalytics(ctx context.Context, request events.APIGa
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, msgPropertyRestricted), nil
+	}
+
 	// Parse date range from query params
 	startDate, endDate := parseDateRange(request)
 
@@ -89,6 +325,10 @@ func (h *Handler) HandleAgentPropertyPerformance(ctx context.Context, request ev
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, msgPropertyRestricted), nil
+	}
+
 	// Parse date range from query params
 	startDate, endDate := parseDateRange(request)
 
@@ -110,6 +350,10 @@ func (h *Handler) HandleDashboard(ctx context.Context, request events.APIGateway
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, msgPropertyRestricted), nil
+	}
+
 	stats, err := h.service.GetDashboardStats(ctx, claims.Phone)
 	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to get dashboard stats: "+err.Error()), nil
@@ -143,29 +387,180 @@ func parseDateRange(request events.APIGatewayProxyRequest) (time.Time, time.Time
 	return startDate, endDate
 }
 
+// maxExportBytes keeps exports returned inline within the API Gateway
+// response limit, after base64 encoding for workbooks. Exports uploaded to
+// the export bucket have no limit.
+const maxExportBytes = 4 << 20
+
+// exportLinkExpiry is how long the download link of an uploaded export works.
+const exportLinkExpiry = 15 * time.Minute
+
+// ExportLink is returned for exports uploaded to the export bucket.
+type ExportLink struct {
+	URL       string    `json:"url"`
+	Filename  string    `json:"filename"`
+	Rows      int       `json:"rows"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // HandleExportData handles GET /analytics/export endpoint.
 func (h *Handler) HandleExportData(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Permission check is handled by middleware
+	// Role check is handled by middleware
 	claims, ok := middleware.GetClaimsFromContext(ctx)
-	if !ok || claims.Role != "admin" {
-		return ErrorResponse(http.StatusForbidden, "Admins only"), nil
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	isAdmin := claims.Role == string(users.RoleAdmin)
+	params := request.QueryStringParameters
+
+	query := ExportQuery{
+		Viewer:     claims,
+		AgentPhone: params["agent"],
+		Format:     ExportFormat(valueOr(params["format"], string(ExportCSV))),
+	}
+	if !query.Format.IsValid() {
+		return ErrorResponse(http.StatusBadRequest, "format must be csv, xlsx or json"), nil
+	}
+	if columns := splitList(params["columns"]); len(columns) > 0 {
+		if _, err := selectColumns(columns); err != nil {
+			return ErrorResponse(http.StatusBadRequest, err.Error()+". Valid columns: "+strings.Join(ExportColumnKeys(), ", ")), nil
+		}
+		query.Columns = columns
+	}
+	for _, status := range splitList(params["status"]) {
+		if !bookings.BookingStatus(status).IsValid() {
+			return ErrorResponse(http.StatusBadRequest, "Invalid status: "+status), nil
+		}
+		query.Statuses = append(query.Statuses, bookings.BookingStatus(status))
+	}
+	if params["startDate"] != "" || params["endDate"] != "" {
+		startDate, endDate := parseDateRange(request)
+		if endDate.Before(startDate) {
+			return ErrorResponse(http.StatusBadRequest, "endDate must not be before startDate"), nil
+		}
+		query.DateRange = &bookings.DateRange{Start: startDate, End: endDate}
+	}
+
+	// Owners can only export their own properties
+	ownerID := params["owner"]
+	if !isAdmin {
+		if ownerID != "" && ownerID != claims.Phone {
+			return ErrorResponse(http.StatusForbidden, "You can only export your own properties"), nil
+		}
+		ownerID = claims.Phone
+	}
+
+	propertyIDs, ok := requestedProperties(request, claims)
+	if !ok {
+		return ErrorResponse(http.StatusForbidden, ErrPropertyAccess.Error()), nil
 	}
 
-	csvData, err := h.service.GenerateMasterCSV(ctx)
+	var err error
+	switch {
+	case len(propertyIDs) > 0:
+		query.Properties, err = h.service.ResolveProperties(ctx, claims.Phone, isAdmin, propertyIDs)
+		for _, prop := range query.Properties {
+			if ownerID != "" && prop.OwnerID != ownerID {
+				err = ErrPropertyAccess
+			}
+		}
+	case ownerID != "":
+		query.Properties, err = h.service.ResolveProperties(ctx, ownerID, false, nil)
+	default:
+		query.AllProperties = true
+	}
 	if err != nil {
-		return ErrorResponse(http.StatusInternalServerError, "Failed to generate CSV: "+err.Error()), nil
+		if errors.Is(err, ErrPropertyAccess) {
+			return ErrorResponse(http.StatusForbidden, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get properties: "+err.Error()), nil
 	}
 
-	filename := fmt.Sprintf("villa_data_export_%s.csv", time.Now().Format("2006-01-02"))
+	filename := fmt.Sprintf("villa_data_export_%s.%s", time.Now().Format("2006-01-02"), query.Format)
 
-	return events.APIGatewayProxyResponse{
+	// Stream to the export bucket when there is one, otherwise return the file inline
+	var buf bytes.Buffer
+	var w io.Writer = &limitedWriter{w: &buf, n: maxExportBytes}
+	var upload *storage.Upload
+	key := "exports/" + uuid.New().String() + "/" + filename
+	if h.exports != nil {
+		upload, err = h.exports.NewUpload(ctx, key, query.Format.ContentType())
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, "Failed to generate export: "+err.Error()), nil
+		}
+		w = upload
+	}
+
+	rows, err := h.service.Export(ctx, w, query)
+	if upload != nil {
+		if err == nil {
+			err = upload.Close()
+		} else if abortErr := upload.Abort(); abortErr != nil {
+			log.Printf("Failed to abort export upload %s: %v", key, abortErr)
+		}
+	}
+
+	event := audit.NewEvent(audit.EventDataExported, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(filename).
+		WithDetail("format", string(query.Format)).
+		WithDetail("rows", strconv.Itoa(rows))
+	if ownerID != "" {
+		event.WithDetail("owner", ownerID)
+	}
+	if claims.IsAPIKey() {
+		event.WithDetail("apiKeyId", claims.APIKeyID)
+	}
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
+		if errors.Is(err, ErrExportTooLarge) {
+			return ErrorResponse(http.StatusRequestEntityTooLarge, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to generate export: "+err.Error()), nil
+	}
+
+	if upload != nil {
+		url, err := h.exports.PresignGet(ctx, key, filename, exportLinkExpiry)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, "Failed to generate export: "+err.Error()), nil
+		}
+		return APIResponse(http.StatusOK, ExportLink{
+			URL:       url,
+			Filename:  filename,
+			Rows:      rows,
+			ExpiresAt: time.Now().Add(exportLinkExpiry).UTC(),
+		}), nil
+	}
+
+	response := events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Headers: map[string]string{
-			"Content-Type":                 "text/csv",
+			"Content-Type":                 query.Format.ContentType(),
 			"Content-Disposition":          fmt.Sprintf("attachment; filename=\"%s\"", filename),
 			"Access-Control-Allow-Origin":  "*",
 			"Access-Control-Allow-Headers": "Content-Type,Authorization",
 		},
-		Body: string(csvData),
-	}, nil
+		Body: buf.String(),
+	}
+	if query.Format == ExportXLSX {
+		response.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+		response.IsBase64Encoded = true
+	}
+	return response, nil
+}
+
+// splitList splits a comma-separated query param, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,292 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+// ErrPropertyAccess is returned when analytics are requested for a property
+// the caller does not own.
+var ErrPropertyAccess = errors.New("you do not have access to this property")
+
+// KPIValue is a metric for a period with its comparisons. Changes are
+// percentages and are null when the comparison value is zero.
+type KPIValue struct {
+	Value              float64  `json:"value"`
+	PreviousPeriod     float64  `json:"previousPeriod"`
+	SamePeriodLastYear float64  `json:"samePeriodLastYear"`
+	ChangeVsPrevious   *float64 `json:"changeVsPrevious"`
+	ChangeVsLastYear   *float64 `json:"changeVsLastYear"`
+}
+
+// KPISet holds the night-level metrics for a property or set of properties.
+type KPISet struct {
+	AvailableNights     KPIValue `json:"availableNights"`
+	SoldNights          KPIValue `json:"soldNights"`
+	Occupancy           KPIValue `json:"occupancy"` // Percent of available nights sold
+	RoomRevenue         KPIValue `json:"roomRevenue"`
+	ADR                 KPIValue `json:"adr"`                 // Room revenue per sold night
+	RevPAR              KPIValue `json:"revpar"`              // Room revenue per available night
+	AverageLengthOfStay KPIValue `json:"averageLengthOfStay"` // Nights per stay checking in during the period
+}
+
+// PropertyKPIs holds the metrics for one property.
+type PropertyKPIs struct {
+	PropertyID   string `json:"propertyId"`
+	PropertyName string `json:"propertyName"`
+	KPISet
+}
+
+// OwnerKPIs holds the metrics across a set of properties and for each one.
+type OwnerKPIs struct {
+	Currency string `json:"currency"`
+
+	Summary    KPISet         `json:"summary"`
+	Properties []PropertyKPIs `json:"properties"`
+
+	PeriodStart         time.Time `json:"periodStart"`
+	PeriodEnd           time.Time `json:"periodEnd"`
+	PreviousPeriodStart time.Time `json:"previousPeriodStart"`
+	PreviousPeriodEnd   time.Time `json:"previousPeriodEnd"`
+	LastYearStart       time.Time `json:"lastYearStart"`
+	LastYearEnd         time.Time `json:"lastYearEnd"`
+}
+
+// nightStats are the raw night counts for one property and period.
+type nightStats struct {
+	available  int
+	sold       int
+	revenue    float64
+	stays      int
+	stayNights int
+}
+
+func (n *nightStats) add(other nightStats) {
+	n.available += other.available
+	n.sold += other.sold
+	n.revenue += other.revenue
+	n.stays += other.stays
+	n.stayNights += other.stayNights
+}
+
+// period is a range of nights, from the night of start up to but not
+// including end.
+type period struct {
+	start, end time.Time
+}
+
+func (p period) nights() int {
+	return int(p.end.Sub(p.start).Hours() / 24)
+}
+
+// ResolveProperties returns the properties to report on. With no IDs it
+// returns every property the user owns. Otherwise each property must exist
+// and be owned by the user, unless they are an admin.
+func (s *Service) ResolveProperties(ctx context.Context, phone string, isAdmin bool, propertyIDs []string) ([]*properties.Property, error) {
+	if len(propertyIDs) == 0 {
+		return s.propertyService.ListPropertiesByOwner(ctx, phone)
+	}
+
+	props := make([]*properties.Property, 0, len(propertyIDs))
+	for _, id := range propertyIDs {
+		prop, err := s.propertyService.GetProperty(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if prop == nil || (!isAdmin && prop.OwnerID != phone) {
+			return nil, ErrPropertyAccess
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
+
+// GetOwnerKPIs calculates occupancy, ADR, RevPAR and average length of stay
+// for the nights from startDate to endDate inclusive, compared with the
+// period of the same length just before and the same dates a year earlier.
+//
+// Only nights inside the period count, so a stay crossing its boundary
+// contributes the nights on each side to each period, with the booking's
+// revenue spread evenly across its nights. Cancelled bookings are ignored.
+func (s *Service) GetOwnerKPIs(ctx context.Context, props []*properties.Property, startDate, endDate time.Time) (*OwnerKPIs, error) {
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(start) {
+		last = start
+	}
+
+	current := period{start, last.AddDate(0, 0, 1)}
+	previous := period{start.AddDate(0, 0, -current.nights()), start}
+	lastYear := period{current.start.AddDate(-1, 0, 0), current.end.AddDate(-1, 0, 0)}
+
+	result := &OwnerKPIs{
+		Currency:            "INR",
+		Properties:          []PropertyKPIs{},
+		PeriodStart:         current.start,
+		PeriodEnd:           current.end.Add(-time.Second),
+		PreviousPeriodStart: previous.start,
+		PreviousPeriodEnd:   previous.end.Add(-time.Second),
+		LastYearStart:       lastYear.start,
+		LastYearEnd:         lastYear.end.Add(-time.Second),
+	}
+
+	var totals [3]nightStats
+	for _, prop := range props {
+		var stats [3]nightStats
+		for i, p := range []period{current, previous, lastYear} {
+			propBookings, blocks, err := s.periodBookings(ctx, prop.ID, p)
+			if err != nil {
+				return nil, err
+			}
+			stats[i] = countNights(propBookings, blocks, p)
+			totals[i].add(stats[i])
+		}
+
+		result.Properties = append(result.Properties, PropertyKPIs{
+			PropertyID:   prop.ID,
+			PropertyName: prop.Name,
+			KPISet:       buildKPISet(stats),
+		})
+	}
+
+	result.Summary = buildKPISet(totals)
+	return result, nil
+}
+
+// periodBookings returns a property's bookings with nights in a period,
+// including stays that check in up to bookings.StayLookbackDays before it,
+// and the owner's blocks in the period.
+func (s *Service) periodBookings(ctx context.Context, propertyID string, p period) ([]*bookings.Booking, []*properties.Block, error) {
+	propBookings, err := s.bookingService.ListBookingsByProperty(ctx, propertyID, &bookings.DateRange{
+		Start: p.start.AddDate(0, 0, -bookings.StayLookbackDays),
+		End:   p.end.AddDate(0, 0, -1),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := s.propertyService.ListBlocks(ctx, propertyID, p.start, p.end)
+	if err != nil {
+		return nil, nil, err
+	}
+	return propBookings, blocks, nil
+}
+
+// countNights counts the nights of bookings that fall inside a period.
+// Nights blocked by the owner are not available.
+func countNights(propBookings []*bookings.Booking, blocks []*properties.Block, p period) nightStats {
+	stats := nightStats{available: p.nights() - blockedNights(blocks, p)}
+
+	for _, booking := range propBookings {
+		if booking.Status == bookings.StatusCancelled {
+			continue
+		}
+
+		checkIn := time.Date(booking.CheckIn.Year(), booking.CheckIn.Month(), booking.CheckIn.Day(), 0, 0, 0, 0, time.UTC)
+		checkOut := time.Date(booking.CheckOut.Year(), booking.CheckOut.Month(), booking.CheckOut.Day(), 0, 0, 0, 0, time.UTC)
+		stayNights := int(checkOut.Sub(checkIn).Hours() / 24)
+		if stayNights <= 0 {
+			continue
+		}
+
+		from, to := checkIn, checkOut
+		if from.Before(p.start) {
+			from = p.start
+		}
+		if to.After(p.end) {
+			to = p.end
+		}
+		nights := int(to.Sub(from).Hours() / 24)
+		if nights <= 0 {
+			continue
+		}
+
+		stats.sold += nights
+		stats.revenue += booking.TotalAmount * float64(nights) / float64(stayNights)
+
+		if !checkIn.Before(p.start) {
+			stats.stays++
+			stats.stayNights += stayNights
+		}
+	}
+
+	// Overlapping bookings cannot sell more nights than exist
+	if stats.sold > stats.available {
+		stats.sold = stats.available
+	}
+	return stats
+}
+
+// blockedNights counts the nights of a period covered by blocks.
+func blockedNights(blocks []*properties.Block, p period) int {
+	blocked := make(map[int64]bool)
+	for _, block := range blocks {
+		night := block.StartDate
+		if night.Before(p.start) {
+			night = p.start
+		}
+		for ; night.Before(block.EndDate) && night.Before(p.end); night = night.AddDate(0, 0, 1) {
+			blocked[night.Unix()] = true
+		}
+	}
+	return len(blocked)
+}
+
+// buildKPISet derives the metrics for the current, previous and last-year
+// periods, in that order.
+func buildKPISet(stats [3]nightStats) KPISet {
+	metric := func(f func(n nightStats) float64) KPIValue {
+		return newKPIValue(f(stats[0]), f(stats[1]), f(stats[2]))
+	}
+
+	return KPISet{
+		AvailableNights: metric(func(n nightStats) float64 { return float64(n.available) }),
+		SoldNights:      metric(func(n nightStats) float64 { return float64(n.sold) }),
+		Occupancy: metric(func(n nightStats) float64 {
+			return ratio(float64(n.sold)*100, float64(n.available))
+		}),
+		RoomRevenue: metric(func(n nightStats) float64 { return round2(n.revenue) }),
+		ADR:         metric(func(n nightStats) float64 { return ratio(n.revenue, float64(n.sold)) }),
+		RevPAR:      metric(func(n nightStats) float64 { return ratio(n.revenue, float64(n.available)) }),
+		AverageLengthOfStay: metric(func(n nightStats) float64 {
+			return ratio(float64(n.stayNights), float64(n.stays))
+		}),
+	}
+}
+
+// newKPIValue builds a metric with its percentage changes.
+func newKPIValue(value, previous, lastYear float64) KPIValue {
+	return KPIValue{
+		Value:              value,
+		PreviousPeriod:     previous,
+		SamePeriodLastYear: lastYear,
+		ChangeVsPrevious:   percentChange(value, previous),
+		ChangeVsLastYear:   percentChange(value, lastYear),
+	}
+}
+
+// percentChange returns the change from base to value in percent, or nil if
+// base is zero.
+func percentChange(value, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	change := round2((value - base) / base * 100)
+	return &change
+}
+
+// ratio divides a by b, rounded to two decimals. Returns 0 if b is zero.
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return round2(a / b)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
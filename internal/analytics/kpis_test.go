@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCountNights(t *testing.T) {
+	march := period{day("2026-03-01"), day("2026-04-01")}
+	stay := func(checkIn, checkOut string, total float64) *bookings.Booking {
+		return &bookings.Booking{CheckIn: day(checkIn), CheckOut: day(checkOut), TotalAmount: total, Status: bookings.StatusPending}
+	}
+	block := func(start, end string) *properties.Block {
+		return &properties.Block{StartDate: day(start), EndDate: day(end)}
+	}
+
+	tests := []struct {
+		name          string
+		bookings      []*bookings.Booking
+		blocks        []*properties.Block
+		wantAvailable int
+		wantSold      int
+		wantRevenue   float64
+		wantStays     int
+	}{
+		{
+			name:          "empty month",
+			wantAvailable: 31,
+		},
+		{
+			name:          "stay inside the period",
+			bookings:      []*bookings.Booking{stay("2026-03-10", "2026-03-12", 10000)},
+			wantAvailable: 31, wantSold: 2, wantRevenue: 10000, wantStays: 1,
+		},
+		{
+			name:          "stay crossing the start counts only nights inside",
+			bookings:      []*bookings.Booking{stay("2026-02-27", "2026-03-03", 8000)},
+			wantAvailable: 31, wantSold: 2, wantRevenue: 4000,
+		},
+		{
+			name:          "long stay checking in before the period",
+			bookings:      []*bookings.Booking{stay("2025-12-20", "2026-03-05", 75000)},
+			wantAvailable: 31, wantSold: 4, wantRevenue: 4000,
+		},
+		{
+			name:          "cancelled stays are ignored",
+			bookings:      []*bookings.Booking{{CheckIn: day("2026-03-10"), CheckOut: day("2026-03-12"), TotalAmount: 10000, Status: bookings.StatusCancelled}},
+			wantAvailable: 31,
+		},
+		{
+			name:          "blocked nights are not available",
+			blocks:        []*properties.Block{block("2026-03-20", "2026-03-25")},
+			wantAvailable: 26,
+		},
+		{
+			name:          "blocks are clipped to the period and not counted twice",
+			blocks:        []*properties.Block{block("2026-02-25", "2026-03-03"), block("2026-03-02", "2026-03-04"), block("2026-03-30", "2026-04-05")},
+			wantAvailable: 26,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := countNights(tt.bookings, tt.blocks, march)
+			if got.available != tt.wantAvailable || got.sold != tt.wantSold || round2(got.revenue) != tt.wantRevenue || got.stays != tt.wantStays {
+				t.Errorf("countNights = available %d, sold %d, revenue %.2f, stays %d; want %d, %d, %.2f, %d",
+					got.available, got.sold, got.revenue, got.stays, tt.wantAvailable, tt.wantSold, tt.wantRevenue, tt.wantStays)
+			}
+		})
+	}
+}
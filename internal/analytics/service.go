@@ -4,9 +4,11 @@ package analytics
 import (
 	"context"
 	"time"
+	_ "time/tzdata" // Lambda images do not ship zoneinfo
 
 	"github.com/booking-villa-backend/internal/bookings"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/expenses"
 	"github.com/booking-villa-backend/internal/payments"
 	"github.com/booking-villa-backend/internal/properties"
 	"github.com/booking-villa-backend/internal/users"
@@ -24,6 +26,9 @@ type OwnerAnalytics struct {
 	TotalRevenue    float64 `json:"totalRevenue"`
 	TotalCollected  float64 `json:"totalCollected"`
 	TotalPending    float64 `json:"totalPending"`
+	TotalCommission float64 `json:"totalCommission"`
+	TotalExpenses   float64 `json:"totalExpenses"`
+	NetProfit       float64 `json:"netProfit"` // Revenue less commission and expenses, leaving out cancelled bookings
 	Currency        string  `json:"currency"`
 
 	// Booking breakdown
@@ -32,6 +37,9 @@ type OwnerAnalytics struct {
 	// Payment breakdown
 	PaymentsByStatus map[string]int `json:"paymentsByStatus"`
 
+	// Expense breakdown
+	ExpensesByCategory map[expenses.Category]float64 `json:"expensesByCategory"`
+
 	// Property-wise stats
 	PropertyStats []PropertyStat `json:"propertyStats"`
 
@@ -48,6 +56,10 @@ type PropertyStat struct {
 	TotalRevenue   float64 `json:"totalRevenue"`
 	TotalCollected float64 `json:"totalCollected"`
 	OccupancyDays  int     `json:"occupancyDays"`
+
+	TotalCommission float64 `json:"totalCommission"`
+	TotalExpenses   float64 `json:"totalExpenses"`
+	NetProfit       float64 `json:"netProfit"` // Revenue less commission and expenses, leaving out cancelled bookings
 }
 
 // AgentAnalytics represents analytics data for agents.
@@ -101,31 +113,34 @@ type Service struct {
 	db              *db.Client
 	propertyService *properties.Service
 	bookingService  *bookings.Service
-	paymentService  *payments.Service
 	userService     *users.Service
+	expenseService  *expenses.Service
 }
 
-// NewService creates a new analytics service.
+// NewService creates a new analytics service. Timestamps such as when a
+// booking was made are read in the property's time zone; stay dates are
+// calendar dates and need no conversion.
 func NewService(dbClient *db.Client) *Service {
 	return &Service{
 		db:              dbClient,
 		propertyService: properties.NewService(dbClient),
 		bookingService:  bookings.NewService(dbClient),
-		paymentService:  payments.NewService(dbClient),
 		userService:     users.NewService(dbClient),
+		expenseService:  expenses.NewService(dbClient),
 	}
 }
 
 // GetOwnerAnalytics retrieves analytics for a property owner.
 func (s *Service) GetOwnerAnalytics(ctx context.Context, ownerID string, startDate, endDate time.Time) (*OwnerAnalytics, error) {
 	analytics := &OwnerAnalytics{
-		OwnerPhone:       ownerID,
-		Currency:         "INR",
-		BookingsByStatus: make(map[string]int),
-		PaymentsByStatus: make(map[string]int),
-		PropertyStats:    []PropertyStat{},
-		PeriodStart:      startDate,
-		PeriodEnd:        endDate,
+		OwnerPhone:         ownerID,
+		Currency:           "INR",
+		BookingsByStatus:   make(map[string]int),
+		PaymentsByStatus:   make(map[string]int),
+		ExpensesByCategory: make(map[expenses.Category]float64),
+		PropertyStats:      []PropertyStat{},
+		PeriodStart:        startDate,
+		PeriodEnd:          endDate,
 	}
 
 	// Get owner's profile
@@ -142,43 +157,46 @@ func (s *Service) GetOwnerAnalytics(ctx context.Context, ownerID string, startDa
 
 	analytics.TotalProperties = len(props)
 
-	// Get bookings and payments for each property
-	dateRange := &bookings.DateRange{Start: startDate, End: endDate}
-
+	// Totals come from the property rollups rather than every booking
 	for _, prop := range props {
-		propStat := PropertyStat{
-			PropertyID:   prop.ID,
-			PropertyName: prop.Name,
+		rollup, err := s.bookingService.SumPropertyRollups(ctx, prop.ID, startDate, endDate)
+		if err != nil {
+			return nil, err
 		}
 
-		// Get bookings for this property
-		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, prop.ID, dateRange)
+		propExpenses, err := s.expenseService.SumExpenses(ctx, prop.ID, startDate, endDate)
 		if err != nil {
-			continue
+			return nil, err
 		}
 
-		for _, booking := range propBookings {
-			propStat.TotalBookings++
-			propStat.TotalRevenue += booking.TotalAmount
-			propStat.OccupancyDays += booking.NumNights
-
-			analytics.TotalBookings++
-			analytics.TotalRevenue += booking.TotalAmount
-			analytics.BookingsByStatus[string(booking.Status)]++
-
-			// Get payment status for this booking
-			paymentSummary, err := s.paymentService.CalculatePaymentStatus(ctx, booking.ID)
-			if err == nil {
-				propStat.TotalCollected += paymentSummary.TotalPaid
-				analytics.TotalCollected += paymentSummary.TotalPaid
-				analytics.PaymentsByStatus[string(paymentSummary.Status)]++
-			}
+		analytics.PropertyStats = append(analytics.PropertyStats, PropertyStat{
+			PropertyID:      prop.ID,
+			PropertyName:    prop.Name,
+			TotalBookings:   rollup.Bookings,
+			TotalRevenue:    rollup.Revenue,
+			TotalCollected:  rollup.Collected,
+			OccupancyDays:   rollup.Nights,
+			TotalCommission: rollup.Commission,
+			TotalExpenses:   propExpenses.Total,
+			NetProfit:       round2(rollup.NetProfit(propExpenses.Total)),
+		})
+
+		analytics.TotalBookings += rollup.Bookings
+		analytics.TotalRevenue += rollup.Revenue
+		analytics.TotalCollected += rollup.Collected
+		analytics.TotalCommission += rollup.Commission
+		analytics.TotalExpenses += propExpenses.Total
+		analytics.NetProfit += rollup.NetProfit(propExpenses.Total)
+		for category, amount := range propExpenses.ByCategory {
+			analytics.ExpensesByCategory[category] = round2(analytics.ExpensesByCategory[category] + amount)
 		}
-
-		analytics.PropertyStats = append(analytics.PropertyStats, propStat)
+		addCounts(analytics.BookingsByStatus, rollup.BookingsByStatus())
+		addCounts(analytics.PaymentsByStatus, rollup.PaymentsByStatus())
 	}
 
 	analytics.TotalPending = analytics.TotalRevenue - analytics.TotalCollected
+	analytics.TotalExpenses = round2(analytics.TotalExpenses)
+	analytics.NetProfit = round2(analytics.NetProfit)
 
 	return analytics, nil
 }
@@ -206,10 +224,29 @@ func (s *Service) GetAgentAnalytics(ctx context.Context, agentPhone string, star
 	// Set agent name from user profile
 	analytics.AgentName = user.Name
 
-	dateRange := &bookings.DateRange{Start: startDate, End: endDate}
+	// 2. Totals come from the agent's rollups for the properties they still manage
+	totals, err := s.bookingService.SumAgentRollups(ctx, agentPhone, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, propID := range user.ManagedProperties {
+		rollup, ok := totals[propID]
+		if !ok {
+			continue
+		}
+		analytics.TotalBookings += rollup.Bookings
+		analytics.TotalBookingValue += rollup.Revenue
+		analytics.TotalCollected += rollup.Collected
+		analytics.TotalCommission += rollup.Commission
+		addCounts(analytics.BookingsByStatus, rollup.BookingsByStatus())
+	}
 
-	// 2. Iterate through managed properties to fetch bookings
+	// 3. List the bookings themselves for the recent bookings
+	dateRange := &bookings.DateRange{Start: startDate, End: endDate}
 	for _, propID := range user.ManagedProperties {
+		if _, ok := totals[propID]; !ok {
+			continue
+		}
 		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, propID, dateRange)
 		if err != nil {
 			continue
@@ -221,18 +258,6 @@ func (s *Service) GetAgentAnalytics(ctx context.Context, agentPhone string, star
 				continue
 			}
 
-			analytics.TotalBookings++
-			analytics.TotalBookingValue += booking.TotalAmount
-			analytics.TotalCommission += booking.AgentCommission
-			analytics.BookingsByStatus[string(booking.Status)]++
-
-			// Get payment info
-			paymentStatus := "pending"
-			if summary, err := s.paymentService.CalculatePaymentStatus(ctx, booking.ID); err == nil {
-				analytics.TotalCollected += summary.TotalPaid
-				paymentStatus = string(summary.Status)
-			}
-
 			// Add to recent bookings (limit to 100 entries before sorting in future if needed)
 			if len(analytics.RecentBookings) < 50 {
 				analytics.RecentBookings = append(analytics.RecentBookings, BookingSummary{
@@ -244,7 +269,7 @@ func (s *Service) GetAgentAnalytics(ctx context.Context, agentPhone string, star
 					TotalAmount:     booking.TotalAmount,
 					AgentCommission: booking.AgentCommission,
 					Status:          string(booking.Status),
-					PaymentStatus:   paymentStatus,
+					PaymentStatus:   string(payments.Summarize(booking).Status),
 				})
 			}
 		}
@@ -264,19 +289,25 @@ func (s *Service) GetAgentPropertyPerformance(ctx context.Context, agentPhone st
 		return []AgentPropertyPerformance{}, nil
 	}
 
+	totals, err := s.bookingService.SumAgentRollups(ctx, agentPhone, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
 	var results []AgentPropertyPerformance
-	dateRange := &bookings.DateRange{Start: startDate, End: endDate}
 
 	// 2. Iterate through managed properties
 	for _, propID := range user.ManagedProperties {
-		// Use ListBookingsByProperty - this gets ALL bookings for the property in date range
-		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, propID, dateRange)
-		if err != nil {
+		rollup, ok := totals[propID]
+		if !ok || rollup.Bookings == 0 {
 			continue
 		}
 
 		perf := AgentPropertyPerformance{
-			PropertyID: propID,
+			PropertyID:      propID,
+			BookingCount:    rollup.Bookings,
+			TotalRevenue:    rollup.Revenue,
+			TotalCommission: rollup.Commission,
 		}
 
 		// Improve: Fetch property name
@@ -284,27 +315,19 @@ func (s *Service) GetAgentPropertyPerformance(ctx context.Context, agentPhone st
 			perf.PropertyName = prop.Name
 		}
 
-		hasBookings := false
-		for _, booking := range propBookings {
-			// Only include bookings made by this agent
-			if booking.BookedBy != agentPhone {
-				continue
-			}
-
-			hasBookings = true
-			perf.BookingCount++
-			perf.TotalRevenue += booking.TotalAmount
-			perf.TotalCommission += booking.AgentCommission
-		}
-
-		if hasBookings {
-			results = append(results, perf)
-		}
+		results = append(results, perf)
 	}
 
 	return results, nil
 }
 
+// addCounts adds each count in from to into.
+func addCounts(into, from map[string]int) {
+	for key, count := range from {
+		into[key] += count
+	}
+}
+
 // GetDashboardStats returns quick stats for dashboard.
 type DashboardStats struct {
 	TodayCheckIns    int     `json:"todayCheckIns"`
@@ -321,7 +344,7 @@ func (s *Service) GetDashboardStats(ctx context.Context, phone string) (*Dashboa
 		Currency: "INR",
 	}
 
-	today := time.Now().Truncate(24 * time.Hour)
+	now := time.Now()
 
 	// 1. Get properties the user OWNS
 	props, err := s.propertyService.ListPropertiesByOwner(ctx, phone)
@@ -330,62 +353,54 @@ func (s *Service) GetDashboardStats(ctx context.Context, phone string) (*Dashboa
 	}
 
 	// Create a map of property IDs to avoid duplicates
-	propertyIDs := make(map[string]bool)
+	propertyByID := make(map[string]*properties.Property)
 	for _, p := range props {
-		propertyIDs[p.ID] = true
+		propertyByID[p.ID] = p
 	}
 
 	// 2. Get properties the user MANAGES (for agents)
 	user, err := s.userService.GetUserByPhone(ctx, phone)
 	if err == nil && user != nil {
 		for _, propID := range user.ManagedProperties {
-			propertyIDs[propID] = true
+			if _, ok := propertyByID[propID]; ok {
+				continue
+			}
+			p, err := s.propertyService.GetProperty(ctx, propID)
+			if err != nil || p == nil {
+				continue
+			}
+			propertyByID[propID] = p
 		}
 	}
 
-	// 3. Collect all relevant bookings for these properties
+	// 3. Read the daily rollups for these properties
 	// We use a broad range (past 30 days to future 60 days) to catch:
-	// - Today's check-ins (check-in = today)
-	// - Today's check-outs (check-in < today, but check-out = today)
+	// - Today's check-ins and check-outs
 	// - Pending approvals (usually upcoming or very recent)
 	// - Pending payments (can be past or upcoming)
-	dateRange := &bookings.DateRange{
-		Start: today.AddDate(0, 0, -30),
-		End:   today.AddDate(0, 0, 60),
-	}
-
-	for propID := range propertyIDs {
-		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, propID, dateRange)
+	// "Today" is the property's local date
+	for propID, p := range propertyByID {
+		today := p.Today(now)
+		start := today.AddDate(0, 0, -30)
+		end := today.AddDate(0, 0, 60)
+		todayKey := today.Format("2006-01-02")
+
+		days, err := s.bookingService.ListDailyRollups(ctx, propID, start, end)
 		if err != nil {
 			continue
 		}
 
-		for _, booking := range propBookings {
-			// Today's Check-Ins
-			if booking.CheckIn.Truncate(24 * time.Hour).Equal(today) {
-				stats.TodayCheckIns++
+		for _, day := range days {
+			if day.Period == todayKey {
+				stats.TodayCheckIns += day.Bookings
+				stats.TodayCheckOuts += day.CheckOuts
 			}
 
-			// Today's Check-Outs
-			if booking.CheckOut.Truncate(24 * time.Hour).Equal(today) {
-				stats.TodayCheckOuts++
-			}
+			stats.PendingApprovals += day.StatusPending
 
-			// Pending Approvals
-			if booking.Status == bookings.StatusPending {
-				stats.PendingApprovals++
-			}
-
-			// Pending Payments & Due Amount
-			// We only count payments for bookings that are not cancelled
-			if booking.Status != bookings.StatusCancelled {
-				if summary, err := s.paymentService.CalculatePaymentStatus(ctx, booking.ID); err == nil {
-					if summary.Status != payments.PaymentStatusSettled {
-						stats.PendingPayments++
-						stats.TotalDueAmount += summary.TotalDue
-					}
-				}
-			}
+			// Cancelled bookings are not counted as unpaid
+			stats.PendingPayments += day.Unpaid
+			stats.TotalDueAmount += day.Due
 		}
 	}
 
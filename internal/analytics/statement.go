@@ -0,0 +1,194 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+)
+
+// StatementFigures are the money lines of an owner statement.
+type StatementFigures struct {
+	Stays  int `dynamodbav:"stays" json:"stays"`
+	Nights int `dynamodbav:"nights" json:"nights"`
+
+	GrossRevenue float64 `dynamodbav:"grossRevenue" json:"grossRevenue"` // At the nightly rate
+	Discounts    float64 `dynamodbav:"discounts" json:"discounts"`       // Below the nightly rate
+	NetRevenue   float64 `dynamodbav:"netRevenue" json:"netRevenue"`     // Booking totals
+	Taxes        float64 `dynamodbav:"taxes" json:"taxes"`               // Included in booking totals
+	Commissions  float64 `dynamodbav:"commissions" json:"commissions"`   // Owed to agents
+	Expenses     float64 `dynamodbav:"expenses" json:"expenses"`
+	NetIncome    float64 `dynamodbav:"netIncome" json:"netIncome"`
+}
+
+func (f *StatementFigures) add(other StatementFigures) {
+	f.Stays += other.Stays
+	f.Nights += other.Nights
+	f.GrossRevenue += other.GrossRevenue
+	f.Discounts += other.Discounts
+	f.NetRevenue += other.NetRevenue
+	f.Taxes += other.Taxes
+	f.Commissions += other.Commissions
+	f.Expenses += other.Expenses
+}
+
+// finish rounds the figures and works out net income.
+func (f *StatementFigures) finish() {
+	f.GrossRevenue = round2(f.GrossRevenue)
+	f.Discounts = round2(f.Discounts)
+	f.NetRevenue = round2(f.NetRevenue)
+	f.Taxes = round2(f.Taxes)
+	f.Commissions = round2(f.Commissions)
+	f.Expenses = round2(f.Expenses)
+	f.NetIncome = round2(f.NetRevenue - f.Taxes - f.Commissions - f.Expenses)
+}
+
+// StatementProperty is one property's lines on an owner statement.
+type StatementProperty struct {
+	PropertyID       string `dynamodbav:"propertyId" json:"propertyId"`
+	PropertyName     string `dynamodbav:"propertyName" json:"propertyName"`
+	StatementFigures `dynamodbav:"figures" json:"figures"`
+}
+
+// StatementStay is a stay on an owner statement.
+type StatementStay struct {
+	BookingID    string    `dynamodbav:"bookingId" json:"bookingId"`
+	PropertyID   string    `dynamodbav:"propertyId" json:"propertyId"`
+	PropertyName string    `dynamodbav:"propertyName" json:"propertyName"`
+	GuestName    string    `dynamodbav:"guestName" json:"guestName"`
+	CheckIn      time.Time `dynamodbav:"checkIn" json:"checkIn"`
+	CheckOut     time.Time `dynamodbav:"checkOut" json:"checkOut"`
+	Nights       int       `dynamodbav:"nights" json:"nights"`
+	AgentName    string    `dynamodbav:"agentName,omitempty" json:"agentName,omitempty"`
+	GrossAmount  float64   `dynamodbav:"grossAmount" json:"grossAmount"`
+	Discount     float64   `dynamodbav:"discount" json:"discount"`
+	NetAmount    float64   `dynamodbav:"netAmount" json:"netAmount"`
+	Tax          float64   `dynamodbav:"tax" json:"tax"`
+	Commission   float64   `dynamodbav:"commission" json:"commission"`
+}
+
+// OwnerStatement holds the figures for an owner's monthly statement.
+type OwnerStatement struct {
+	OwnerName  string  `dynamodbav:"ownerName,omitempty" json:"ownerName,omitempty"`
+	OwnerPhone string  `dynamodbav:"ownerPhone" json:"ownerPhone"`
+	Currency   string  `dynamodbav:"currency" json:"currency"`
+	TaxRate    float64 `dynamodbav:"taxRate" json:"taxRate"`
+
+	Totals     StatementFigures    `dynamodbav:"totals" json:"totals"`
+	Properties []StatementProperty `dynamodbav:"properties" json:"properties"`
+	Stays      []StatementStay     `dynamodbav:"stays" json:"stays"`
+
+	PeriodStart time.Time `dynamodbav:"periodStart" json:"periodStart"`
+	PeriodEnd   time.Time `dynamodbav:"periodEnd" json:"periodEnd"`
+}
+
+// GetOwnerStatement works out the statement figures for an owner's stays
+// checking in within [startDate, endDate), and the expenses dated in it.
+// Cancelled bookings are left out.
+// Booking totals include tax at taxRate percent, and anything below the
+// nightly rate is shown as a discount.
+func (s *Service) GetOwnerStatement(ctx context.Context, ownerID string, startDate, endDate time.Time, taxRate float64) (*OwnerStatement, error) {
+	statement := &OwnerStatement{
+		OwnerPhone:  ownerID,
+		Currency:    "INR",
+		TaxRate:     taxRate,
+		Properties:  []StatementProperty{},
+		Stays:       []StatementStay{},
+		PeriodStart: startDate,
+		PeriodEnd:   endDate,
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, ownerID)
+	if err == nil && user != nil {
+		statement.OwnerName = user.Name
+	}
+
+	props, err := s.propertyService.ListPropertiesByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Booking dates are stored as UTC midnight; the range is inclusive
+	dateRange := &bookings.DateRange{Start: calendarDate(startDate), End: calendarDate(endDate).AddDate(0, 0, -1)}
+
+	for _, prop := range props {
+		line := StatementProperty{PropertyID: prop.ID, PropertyName: prop.Name}
+
+		err := s.bookingService.PageBookingsByProperty(ctx, prop.ID, dateRange, func(page []*bookings.Booking) error {
+			for _, booking := range page {
+				if booking.Status == bookings.StatusCancelled {
+					continue
+				}
+				stay := statementStay(booking, prop.Name, taxRate)
+				statement.Stays = append(statement.Stays, stay)
+
+				line.Stays++
+				line.Nights += stay.Nights
+				line.GrossRevenue += stay.GrossAmount
+				line.Discounts += stay.Discount
+				line.NetRevenue += stay.NetAmount
+				line.Taxes += stay.Tax
+				line.Commissions += stay.Commission
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		propExpenses, err := s.expenseService.SumExpenses(ctx, prop.ID, dateRange.Start, dateRange.End)
+		if err != nil {
+			return nil, err
+		}
+		line.Expenses = propExpenses.Total
+
+		if prop.Currency != "" {
+			statement.Currency = prop.Currency
+		}
+		line.finish()
+		statement.Totals.add(line.StatementFigures)
+		statement.Properties = append(statement.Properties, line)
+	}
+	statement.Totals.finish()
+
+	return statement, nil
+}
+
+// statementStay works out a booking's lines on a statement.
+func statementStay(booking *bookings.Booking, propertyName string, taxRate float64) StatementStay {
+	nights := booking.NumNights
+	if nights == 0 {
+		nights = int(calendarDate(booking.CheckOut).Sub(calendarDate(booking.CheckIn)).Hours() / 24)
+	}
+
+	gross := booking.PricePerNight * float64(nights)
+	discount := math.Max(0, gross-booking.TotalAmount)
+	if gross < booking.TotalAmount {
+		gross = booking.TotalAmount
+	}
+
+	var agent string
+	if booking.AgentCommission > 0 {
+		agent = booking.BookedByName
+		if agent == "" {
+			agent = booking.BookedBy
+		}
+	}
+
+	return StatementStay{
+		BookingID:    booking.ID,
+		PropertyID:   booking.PropertyID,
+		PropertyName: propertyName,
+		GuestName:    booking.GuestName,
+		CheckIn:      booking.CheckIn,
+		CheckOut:     booking.CheckOut,
+		Nights:       nights,
+		AgentName:    agent,
+		GrossAmount:  round2(gross),
+		Discount:     round2(discount),
+		NetAmount:    round2(booking.TotalAmount),
+		Tax:          round2(booking.TotalAmount * taxRate / (100 + taxRate)),
+		Commission:   round2(booking.AgentCommission),
+	}
+}
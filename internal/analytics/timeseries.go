@@ -0,0 +1,397 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+// Granularity is the width of a time series bucket.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week" // Weeks start on Monday
+	GranularityMonth Granularity = "month"
+)
+
+// Metric is the value plotted in a time series.
+type Metric string
+
+const (
+	MetricRevenue    Metric = "revenue"
+	MetricCollected  Metric = "collected"
+	MetricBookings   Metric = "bookings"
+	MetricNights     Metric = "nights"
+	MetricCommission Metric = "commission"
+)
+
+// Basis is the date a booking is counted on.
+type Basis string
+
+const (
+	// BasisStay spreads a booking over the nights of the stay. Bookings are
+	// counted on the check-in night.
+	BasisStay Basis = "stay"
+	// BasisCreated counts a booking on the day it was made.
+	BasisCreated Basis = "created"
+	// BasisPayment counts collections on the day they were recorded.
+	BasisPayment Basis = "payment"
+)
+
+// GroupBy splits a time series into one series per property or agent.
+type GroupBy string
+
+const (
+	GroupByNone     GroupBy = ""
+	GroupByProperty GroupBy = "property"
+	GroupByAgent    GroupBy = "agent"
+)
+
+// maxBuckets limits the length of a time series.
+const maxBuckets = 400
+
+// directKey groups bookings not made by an agent.
+const directKey = "direct"
+
+// TimeSeriesQuery describes a time series request.
+type TimeSeriesQuery struct {
+	Start       time.Time
+	End         time.Time // Inclusive
+	Granularity Granularity
+	Metric      Metric
+	Basis       Basis
+	GroupBy     GroupBy
+}
+
+// Validate checks the query options.
+func (q *TimeSeriesQuery) Validate() error {
+	switch q.Granularity {
+	case GranularityDay, GranularityWeek, GranularityMonth:
+	default:
+		return fmt.Errorf("granularity must be day, week or month")
+	}
+	switch q.Metric {
+	case MetricRevenue, MetricCollected, MetricBookings, MetricNights, MetricCommission:
+	default:
+		return fmt.Errorf("metric must be revenue, collected, bookings, nights or commission")
+	}
+	switch q.Basis {
+	case BasisStay, BasisCreated:
+	case BasisPayment:
+		if q.Metric != MetricCollected {
+			return fmt.Errorf("the payment basis only supports the collected metric")
+		}
+	default:
+		return fmt.Errorf("basis must be stay, created or payment")
+	}
+	switch q.GroupBy {
+	case GroupByNone, GroupByProperty, GroupByAgent:
+	default:
+		return fmt.Errorf("groupBy must be property or agent")
+	}
+	if q.End.Before(q.Start) {
+		return fmt.Errorf("endDate must not be before startDate")
+	}
+	return nil
+}
+
+// Series is one line of a chart. Values line up with TimeSeries.Buckets.
+type Series struct {
+	Key    string    `json:"key"` // Property ID, agent phone, "direct" or "total"
+	Label  string    `json:"label"`
+	Values []float64 `json:"values"`
+	Total  float64   `json:"total"`
+}
+
+// TimeSeries holds bucketed values ready for charting. Empty buckets are zero.
+type TimeSeries struct {
+	Metric      Metric      `json:"metric"`
+	Granularity Granularity `json:"granularity"`
+	Basis       Basis       `json:"basis"`
+	GroupBy     GroupBy     `json:"groupBy,omitempty"`
+	Currency    string      `json:"currency"`
+
+	// Buckets holds the first day of each bucket, as YYYY-MM-DD
+	Buckets []string  `json:"buckets"`
+	Series  []Series  `json:"series"`
+	Totals  []float64 `json:"totals"` // Sum of all series per bucket
+
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+// seriesBuilder accumulates values into buckets.
+type seriesBuilder struct {
+	query   TimeSeriesQuery
+	starts  []time.Time // Start of each bucket
+	series  map[string]*Series
+	labels  map[string]string
+	ordered []string
+}
+
+func newSeriesBuilder(query TimeSeriesQuery) (*seriesBuilder, error) {
+	b := &seriesBuilder{
+		query:  query,
+		series: make(map[string]*Series),
+		labels: make(map[string]string),
+	}
+	for t := bucketStart(query.Granularity, query.Start); !t.After(query.End); t = nextBucket(query.Granularity, t) {
+		if len(b.starts) == maxBuckets {
+			return nil, fmt.Errorf("the range has more than %d buckets; use a coarser granularity", maxBuckets)
+		}
+		b.starts = append(b.starts, t)
+	}
+	return b, nil
+}
+
+// bucketStart returns the start of the bucket containing date.
+func bucketStart(granularity Granularity, date time.Time) time.Time {
+	date = calendarDate(date)
+	switch granularity {
+	case GranularityWeek:
+		offset := (int(date.Weekday()) + 6) % 7 // Days since Monday
+		return date.AddDate(0, 0, -offset)
+	case GranularityMonth:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return date
+	}
+}
+
+func nextBucket(granularity Granularity, start time.Time) time.Time {
+	switch granularity {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// calendarDate returns the date of t as UTC midnight, the form booking
+// dates are stored in.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// add adds value to the bucket containing date, for the series key. Dates
+// outside the query range are ignored.
+func (b *seriesBuilder) add(key string, date time.Time, value float64) {
+	date = calendarDate(date)
+	if date.Before(calendarDate(b.query.Start)) || date.After(calendarDate(b.query.End)) {
+		return
+	}
+
+	index := sort.Search(len(b.starts), func(i int) bool { return b.starts[i].After(date) }) - 1
+	if index < 0 {
+		return
+	}
+
+	series := b.get(key)
+	series.Values[index] += value
+	series.Total += value
+}
+
+// get returns the series for key, creating it with zero values.
+func (b *seriesBuilder) get(key string) *Series {
+	series, ok := b.series[key]
+	if !ok {
+		series = &Series{Key: key, Values: make([]float64, len(b.starts))}
+		b.series[key] = series
+		b.ordered = append(b.ordered, key)
+	}
+	return series
+}
+
+func (b *seriesBuilder) build() *TimeSeries {
+	result := &TimeSeries{
+		Metric:      b.query.Metric,
+		Granularity: b.query.Granularity,
+		Basis:       b.query.Basis,
+		GroupBy:     b.query.GroupBy,
+		Currency:    "INR",
+		Buckets:     make([]string, len(b.starts)),
+		Series:      make([]Series, 0, len(b.ordered)),
+		Totals:      make([]float64, len(b.starts)),
+		PeriodStart: calendarDate(b.query.Start),
+		PeriodEnd:   calendarDate(b.query.End).AddDate(0, 0, 1).Add(-time.Second),
+	}
+	for i, start := range b.starts {
+		result.Buckets[i] = start.Format("2006-01-02")
+	}
+
+	for _, key := range b.ordered {
+		series := b.series[key]
+		series.Label = b.labels[key]
+		if series.Label == "" {
+			series.Label = key
+		}
+		for i, value := range series.Values {
+			series.Values[i] = round2(value)
+			result.Totals[i] += value
+		}
+		series.Total = round2(series.Total)
+		result.Series = append(result.Series, *series)
+	}
+	for i, value := range result.Totals {
+		result.Totals[i] = round2(value)
+	}
+
+	return result
+}
+
+// GetTimeSeries buckets a metric over time for a set of properties.
+// Stay-based series include stays that checked in up to 60 days before the
+// range. Payment-based series only include payments recorded since the
+// payment ledger was introduced.
+func (s *Service) GetTimeSeries(ctx context.Context, props []*properties.Property, query TimeSeriesQuery) (*TimeSeries, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder, err := newSeriesBuilder(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a line per property even if it has no data
+	if query.GroupBy == GroupByProperty {
+		for _, prop := range props {
+			builder.get(prop.ID)
+			builder.labels[prop.ID] = prop.Name
+		}
+	}
+	if query.GroupBy == GroupByNone {
+		builder.get("total")
+		builder.labels["total"] = "Total"
+	}
+
+	start := calendarDate(query.Start)
+	end := calendarDate(query.End)
+
+	for _, prop := range props {
+		location := prop.Location()
+		if query.Basis == BasisPayment {
+			// Payments are bucketed by the property's local day they were
+			// recorded
+			from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+			to := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, location)
+			entries, err := s.bookingService.ListPaymentEntries(ctx, prop.ID, from, to)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				builder.add(seriesKey(query.GroupBy, prop.ID, entry.BookedBy), entry.RecordedAt.In(location), entry.Amount)
+			}
+			continue
+		}
+
+		dateRange := &bookings.DateRange{Start: start.AddDate(0, 0, -bookings.StayLookbackDays), End: end}
+		if query.Basis == BasisCreated {
+			// Bookings made during the range can be for stays far ahead
+			dateRange.End = end.AddDate(1, 0, 0)
+		}
+		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, prop.ID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, booking := range propBookings {
+			if booking.Status == bookings.StatusCancelled {
+				continue
+			}
+			key := seriesKey(query.GroupBy, prop.ID, booking.BookedBy)
+			if query.GroupBy == GroupByAgent && booking.BookedByName != "" {
+				builder.labels[key] = booking.BookedByName
+			}
+
+			if query.Basis == BasisCreated {
+				builder.add(key, booking.CreatedAt.In(location), bookingValue(query.Metric, booking))
+				continue
+			}
+			addStay(builder, key, query.Metric, booking)
+		}
+	}
+
+	if query.GroupBy == GroupByAgent {
+		s.labelAgents(ctx, builder)
+	}
+
+	return builder.build(), nil
+}
+
+// seriesKey returns the series a value belongs to.
+func seriesKey(groupBy GroupBy, propertyID, bookedBy string) string {
+	switch groupBy {
+	case GroupByProperty:
+		return propertyID
+	case GroupByAgent:
+		if bookedBy == "" {
+			return directKey
+		}
+		return bookedBy
+	default:
+		return "total"
+	}
+}
+
+// labelAgents names agent series that have no label yet.
+func (s *Service) labelAgents(ctx context.Context, builder *seriesBuilder) {
+	for _, key := range builder.ordered {
+		if builder.labels[key] != "" {
+			continue
+		}
+		if key == directKey {
+			builder.labels[key] = "Direct"
+			continue
+		}
+		if user, err := s.userService.GetUserByPhone(ctx, key); err == nil && user != nil {
+			builder.labels[key] = user.Name
+		}
+	}
+}
+
+// bookingValue returns a booking's whole value for a metric.
+func bookingValue(metric Metric, booking *bookings.Booking) float64 {
+	switch metric {
+	case MetricCollected:
+		return booking.AdvanceAmount
+	case MetricBookings:
+		return 1
+	case MetricNights:
+		return float64(booking.NumNights)
+	case MetricCommission:
+		return booking.AgentCommission
+	default:
+		return booking.TotalAmount
+	}
+}
+
+// addStay spreads a booking's value evenly across the nights of the stay.
+// A booking is counted once, on its check-in night.
+func addStay(builder *seriesBuilder, key string, metric Metric, booking *bookings.Booking) {
+	checkIn := calendarDate(booking.CheckIn)
+	nights := int(calendarDate(booking.CheckOut).Sub(checkIn).Hours() / 24)
+	if nights <= 0 {
+		return
+	}
+
+	if metric == MetricBookings {
+		builder.add(key, checkIn, 1)
+		return
+	}
+
+	perNight := 1.0
+	if metric != MetricNights {
+		perNight = bookingValue(metric, booking) / float64(nights)
+	}
+	for night := 0; night < nights; night++ {
+		builder.add(key, checkIn.AddDate(0, 0, night), perNight)
+	}
+}
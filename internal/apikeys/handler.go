@@ -0,0 +1,128 @@
+// Package apikeys provides scoped API keys for integrations and automation.
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/users"
+)
+
+// Handler provides HTTP handlers for API key endpoints.
+type Handler struct {
+	service      *Service
+	auditService *audit.Service
+}
+
+// NewHandler creates a new API key handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service:      NewService(dbClient),
+		auditService: audit.NewService(dbClient),
+	}
+}
+
+// GetService returns the API key service (for use in middleware).
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// HandleCreateAPIKey handles the POST /api-keys endpoint.
+func (h *Handler) HandleCreateAPIKey(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	result, err := h.service.CreateKey(ctx, claims.Phone, users.Role(claims.Role), req)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	h.auditService.Log(ctx, audit.NewEvent(audit.EventAPIKeyCreated, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(result.APIKey.ID).
+		WithDetail("scopes", strings.Join(result.APIKey.Scopes, ",")))
+
+	return APIResponse(http.StatusCreated, result), nil
+}
+
+// HandleListAPIKeys handles the GET /api-keys endpoint.
+func (h *Handler) HandleListAPIKeys(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	keys, err := h.service.ListKeys(ctx, claims.Phone)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to list API keys"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"apiKeys": keys,
+		"count":   len(keys),
+	}), nil
+}
+
+// HandleRevokeAPIKey handles the DELETE /api-keys/{id} endpoint.
+func (h *Handler) HandleRevokeAPIKey(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	keyID := request.PathParameters["id"]
+	if keyID == "" {
+		return ErrorResponse(http.StatusBadRequest, "API key ID is required"), nil
+	}
+
+	if err := h.service.RevokeKey(ctx, claims.Phone, keyID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrorResponse(http.StatusNotFound, "API key not found"), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to revoke API key"), nil
+	}
+
+	h.auditService.Log(ctx, audit.NewEvent(audit.EventAPIKeyRevoked, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(keyID))
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"message":  "API key revoked",
+		"apiKeyId": keyID,
+	}), nil
+}
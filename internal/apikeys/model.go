@@ -0,0 +1,55 @@
+// Package apikeys provides scoped API keys for integrations and automation.
+package apikeys
+
+import (
+	"time"
+)
+
+// APIKey represents an API key issued to an owner or admin.
+// Only a SHA-256 hash of the key is stored; the key itself is shown once on creation.
+type APIKey struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // APIKEY#<keyHash>
+	SK string `dynamodbav:"SK"` // METADATA
+
+	// GSI1 for listing keys by creator
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"` // APIKEYS#<ownerPhone>
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"` // APIKEY#<id>
+
+	// API key fields
+	ID          string     `dynamodbav:"id" json:"id"`
+	Name        string     `dynamodbav:"name" json:"name"`
+	Prefix      string     `dynamodbav:"prefix" json:"prefix"`
+	KeyHash     string     `dynamodbav:"keyHash" json:"-"`
+	OwnerPhone  string     `dynamodbav:"ownerPhone" json:"ownerPhone"`
+	Scopes      []string   `dynamodbav:"scopes" json:"scopes"`
+	PropertyIDs []string   `dynamodbav:"propertyIds,omitempty" json:"propertyIds,omitempty"`
+	ExpiresAt   time.Time  `dynamodbav:"expiresAt" json:"expiresAt"`
+	LastUsedAt  *time.Time `dynamodbav:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	IsRevoked   bool       `dynamodbav:"isRevoked" json:"isRevoked"`
+
+	// Metadata
+	CreatedAt  time.Time  `dynamodbav:"createdAt" json:"createdAt"`
+	RevokedAt  *time.Time `dynamodbav:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	EntityType string     `dynamodbav:"entityType" json:"-"`
+}
+
+// IsUsable checks if the key has not been revoked and has not expired.
+func (k *APIKey) IsUsable() bool {
+	return !k.IsRevoked && time.Now().Before(k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	PropertyIDs   []string `json:"propertyIds,omitempty"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+}
+
+// CreateAPIKeyResponse is returned once when a key is created.
+// Key holds the plaintext key, which cannot be retrieved again.
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"apiKey"`
+}
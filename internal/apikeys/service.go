@@ -0,0 +1,264 @@
+// Package apikeys provides scoped API keys for integrations and automation.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// keyPrefix marks VillaBook API keys so they are easy to recognise in config and logs.
+	keyPrefix           = "vbk_"
+	defaultExpiryDays   = 90
+	maxExpiryDays       = 365
+	lastUsedResolution  = time.Minute
+	displayPrefixLength = 12
+)
+
+var (
+	// ErrNotFound is returned when an API key does not exist for the caller.
+	ErrNotFound = errors.New("API key not found")
+
+	// ErrInvalidKey is returned when a presented key is unknown, revoked or expired.
+	ErrInvalidKey = errors.New("invalid or expired API key")
+)
+
+// Service provides API key operations.
+type Service struct {
+	db              *db.Client
+	userService     *users.Service
+	propertyService *properties.Service
+}
+
+// NewService creates a new API key service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:              dbClient,
+		userService:     users.NewService(dbClient),
+		propertyService: properties.NewService(dbClient),
+	}
+}
+
+// hashKey returns the stored hash of a plaintext key.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey creates a new random plaintext key.
+func generateKey() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(bytes), nil
+}
+
+// CreateKey issues a new API key for the caller. The plaintext key is only
+// returned here; afterwards only its hash is kept.
+func (s *Service) CreateKey(ctx context.Context, ownerPhone string, ownerRole users.Role, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !middleware.IsValidScope(scope) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+
+	if err := s.checkPropertyAccess(ctx, ownerPhone, ownerRole, req.PropertyIDs); err != nil {
+		return nil, err
+	}
+
+	expiryDays := req.ExpiresInDays
+	if expiryDays == 0 {
+		expiryDays = defaultExpiryDays
+	}
+	if expiryDays < 1 || expiryDays > maxExpiryDays {
+		return nil, fmt.Errorf("expiresInDays must be between 1 and %d", maxExpiryDays)
+	}
+
+	plaintext, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := uuid.New().String()
+	keyHash := hashKey(plaintext)
+	key := &APIKey{
+		PK:          "APIKEY#" + keyHash,
+		SK:          "METADATA",
+		GSI1PK:      "APIKEYS#" + ownerPhone,
+		GSI1SK:      "APIKEY#" + id,
+		ID:          id,
+		Name:        name,
+		Prefix:      plaintext[:displayPrefixLength],
+		KeyHash:     keyHash,
+		OwnerPhone:  ownerPhone,
+		Scopes:      req.Scopes,
+		PropertyIDs: req.PropertyIDs,
+		ExpiresAt:   now.AddDate(0, 0, expiryDays),
+		CreatedAt:   now,
+		EntityType:  "API_KEY",
+	}
+
+	if err := s.db.PutItemWithCondition(ctx, key, "attribute_not_exists(PK)"); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &CreateAPIKeyResponse{Key: plaintext, APIKey: key}, nil
+}
+
+// checkPropertyAccess verifies that a key can be restricted to the given properties.
+// Owners may only use their own properties; admins may use any existing property.
+func (s *Service) checkPropertyAccess(ctx context.Context, ownerPhone string, ownerRole users.Role, propertyIDs []string) error {
+	for _, propertyID := range propertyIDs {
+		property, err := s.propertyService.GetProperty(ctx, propertyID)
+		if err != nil {
+			return fmt.Errorf("failed to get property: %w", err)
+		}
+		if property == nil {
+			return fmt.Errorf("property not found: %s", propertyID)
+		}
+		if ownerRole != users.RoleAdmin && property.OwnerID != ownerPhone {
+			return fmt.Errorf("you do not own property: %s", propertyID)
+		}
+	}
+	return nil
+}
+
+// ListKeys lists all API keys created by a user.
+func (s *Service) ListKeys(ctx context.Context, ownerPhone string) ([]*APIKey, error) {
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk AND begins_with(GSI1SK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "APIKEYS#" + ownerPhone,
+			":prefix": "APIKEY#",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys := make([]*APIKey, 0, len(items))
+	for _, item := range items {
+		var key APIKey
+		if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal API key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, nil
+}
+
+// RevokeKey revokes one of the caller's API keys. The record is kept so
+// the key still shows up (as revoked) when listing.
+func (s *Service) RevokeKey(ctx context.Context, ownerPhone, id string) error {
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk AND GSI1SK = :gsi1sk",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "APIKEYS#" + ownerPhone,
+			":gsi1sk": "APIKEY#" + id,
+		},
+		Limit: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find API key: %w", err)
+	}
+	if len(items) == 0 {
+		return ErrNotFound
+	}
+
+	var key APIKey
+	if err := attributevalue.UnmarshalMap(items[0], &key); err != nil {
+		return fmt.Errorf("failed to unmarshal API key: %w", err)
+	}
+
+	return s.db.UpdateItem(ctx, key.PK, key.SK, db.UpdateParams{
+		UpdateExpression: "SET isRevoked = :revoked, revokedAt = :now",
+		ExpressionValues: map[string]interface{}{
+			":revoked": true,
+			":now":     time.Now(),
+		},
+	})
+}
+
+// Authenticate resolves a plaintext API key into claims for the key's creator,
+// limited to the key's scopes and properties. It records the last-used time.
+func (s *Service) Authenticate(ctx context.Context, plaintext string) (*utils.TokenClaims, error) {
+	if !strings.HasPrefix(plaintext, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	var key APIKey
+	if err := s.db.GetItem(ctx, "APIKEY#"+hashKey(plaintext), "METADATA", &key); err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrInvalidKey
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if !key.IsUsable() {
+		return nil, ErrInvalidKey
+	}
+
+	// The key acts with its creator's current role, so demoting, suspending
+	// or rejecting the user also limits their keys.
+	user, err := s.userService.GetUserByPhone(ctx, key.OwnerPhone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.CanLogin() {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		err := s.db.UpdateItem(ctx, key.PK, key.SK, db.UpdateParams{
+			UpdateExpression: "SET lastUsedAt = :now",
+			ExpressionValues: map[string]interface{}{
+				":now": now,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The key counts as a session issued when it was created, so revoking
+	// the user's sessions also revokes their existing keys.
+	return &utils.TokenClaims{
+		UserID:      user.Phone,
+		Phone:       user.Phone,
+		Role:        string(user.Role),
+		APIKeyID:    key.ID,
+		Scopes:      key.Scopes,
+		PropertyIDs: key.PropertyIDs,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(key.CreatedAt),
+		},
+	}, nil
+}
@@ -0,0 +1,104 @@
+// Package audit provides an append-only audit log for security-relevant events.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+// Handler provides HTTP handlers for audit log endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new audit handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service: NewService(dbClient),
+	}
+}
+
+// GetService returns the audit service (for use in other handlers and middleware).
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// HandleListEvents handles the GET /admin/audit endpoint.
+func (h *Handler) HandleListEvents(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params := request.QueryStringParameters
+
+	// Default: today (UTC)
+	to := time.Now().UTC()
+	if toStr := params["to"]; toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid to date format. Use YYYY-MM-DD"), nil
+		}
+		to = parsed
+	}
+
+	from := to
+	if fromStr := params["from"]; fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid from date format. Use YYYY-MM-DD"), nil
+		}
+		from = parsed
+	}
+
+	limit := 100
+	if limitStr := params["limit"]; limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	filter := QueryFilter{
+		From:    from,
+		To:      to,
+		Type:    EventType(params["type"]),
+		Actor:   params["actor"],
+		Target:  params["target"],
+		Outcome: Outcome(params["outcome"]),
+		Limit:   limit,
+	}
+
+	auditEvents, err := h.service.Query(ctx, filter)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuery) {
+			return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to query audit log"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"events": auditEvents,
+		"count":  len(auditEvents),
+	}), nil
+}
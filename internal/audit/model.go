@@ -0,0 +1,121 @@
+// Package audit provides an append-only audit log for security-relevant events.
+package audit
+
+import (
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// EventType identifies what happened.
+type EventType string
+
+const (
+	EventLoginSucceeded       EventType = "auth.login_succeeded"
+	EventLoginFailed          EventType = "auth.login_failed"
+	EventPasswordChanged      EventType = "auth.password_changed"
+	EventPasswordReset        EventType = "auth.password_reset"
+	EventTwoFactorEnabled     EventType = "auth.2fa_enabled"
+	EventTwoFactorDisabled    EventType = "auth.2fa_disabled"
+	EventAccessDenied         EventType = "access.denied"
+	EventUserStatusChanged    EventType = "user.status_changed"
+	EventAgentStatusChanged   EventType = "agent.status_changed"
+	EventInviteCodeRedeemed   EventType = "invite_code.redeemed"
+	EventDataExported         EventType = "data.exported"
+	EventAPIKeyCreated        EventType = "api_key.created"
+	EventAPIKeyRevoked        EventType = "api_key.revoked"
+	EventImpersonationStarted EventType = "impersonation.started"
+	EventImpersonatedRequest  EventType = "impersonation.request"
+)
+
+// Outcome records whether the audited action succeeded.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+// Event represents an audit log record. Events are partitioned by day.
+type Event struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // AUDIT#<yyyy-mm-dd>
+	SK string `dynamodbav:"SK"` // <RFC3339Nano timestamp>#<id>
+
+	// Event fields
+	ID                string            `dynamodbav:"id" json:"id"`
+	Type              EventType         `dynamodbav:"type" json:"type"`
+	Outcome           Outcome           `dynamodbav:"outcome" json:"outcome"`
+	ActorPhone        string            `dynamodbav:"actorPhone,omitempty" json:"actorPhone,omitempty"`
+	ActorRole         string            `dynamodbav:"actorRole,omitempty" json:"actorRole,omitempty"`
+	ImpersonatorPhone string            `dynamodbav:"impersonatorPhone,omitempty" json:"impersonatorPhone,omitempty"`
+	Target            string            `dynamodbav:"target,omitempty" json:"target,omitempty"`
+	IP                string            `dynamodbav:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent         string            `dynamodbav:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Method            string            `dynamodbav:"method,omitempty" json:"method,omitempty"`
+	Path              string            `dynamodbav:"path,omitempty" json:"path,omitempty"`
+	Details           map[string]string `dynamodbav:"details,omitempty" json:"details,omitempty"`
+
+	// Metadata
+	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	TTL        int64     `dynamodbav:"TTL,omitempty" json:"-"` // Set from the retention period when recorded
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// NewEvent creates a new audit event with initialized keys.
+func NewEvent(eventType EventType, outcome Outcome) *Event {
+	now := time.Now().UTC()
+	id := uuid.New().String()
+
+	return &Event{
+		PK:         "AUDIT#" + now.Format("2006-01-02"),
+		SK:         now.Format(time.RFC3339Nano) + "#" + id,
+		ID:         id,
+		Type:       eventType,
+		Outcome:    outcome,
+		CreatedAt:  now,
+		EntityType: "AUDIT_EVENT",
+	}
+}
+
+// WithRequest copies the caller's IP, user agent, method and path from the request.
+func (e *Event) WithRequest(request events.APIGatewayProxyRequest) *Event {
+	e.IP = request.RequestContext.Identity.SourceIP
+	e.UserAgent = request.RequestContext.Identity.UserAgent
+	if e.UserAgent == "" {
+		e.UserAgent = request.Headers["User-Agent"]
+	}
+	e.Method = request.HTTPMethod
+	e.Path = request.Path
+	return e
+}
+
+// WithActor sets who performed the action.
+func (e *Event) WithActor(phone, role string) *Event {
+	e.ActorPhone = phone
+	e.ActorRole = role
+	return e
+}
+
+// WithTarget sets what the action was performed on.
+func (e *Event) WithTarget(target string) *Event {
+	e.Target = target
+	return e
+}
+
+// WithError marks the event as failed and records the error message.
+func (e *Event) WithError(err error) *Event {
+	e.Outcome = OutcomeFailure
+	return e.WithDetail("error", err.Error())
+}
+
+// WithDetail adds a free-form detail to the event.
+func (e *Event) WithDetail(key, value string) *Event {
+	if e.Details == nil {
+		e.Details = map[string]string{}
+	}
+	e.Details[key] = value
+	return e
+}
@@ -0,0 +1,186 @@
+// Package audit provides an append-only audit log for security-relevant events.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+const (
+	// defaultRetentionDays is how long events are kept when AUDIT_RETENTION_DAYS is not set.
+	defaultRetentionDays = 365
+
+	// maxQueryDays limits how many daily partitions a single query can read.
+	maxQueryDays = 31
+)
+
+// ErrInvalidQuery is returned when a query's filter is not valid.
+var ErrInvalidQuery = errors.New("invalid audit query")
+
+// Service writes and queries audit events.
+type Service struct {
+	db        *db.Client
+	retention time.Duration
+}
+
+// NewService creates a new audit service.
+// Events expire after AUDIT_RETENTION_DAYS days (default 365).
+func NewService(dbClient *db.Client) *Service {
+	retentionDays := defaultRetentionDays
+	if envDays := os.Getenv("AUDIT_RETENTION_DAYS"); envDays != "" {
+		if parsed, err := strconv.Atoi(envDays); err == nil && parsed > 0 {
+			retentionDays = parsed
+		}
+	}
+
+	return &Service{
+		db:        dbClient,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Record stores an audit event. Events are never overwritten.
+func (s *Service) Record(ctx context.Context, event *Event) error {
+	if event.TTL == 0 {
+		event.TTL = event.CreatedAt.Add(s.retention).Unix()
+	}
+
+	if err := s.db.PutItemWithCondition(ctx, event, "attribute_not_exists(PK)"); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// Log stores an audit event, logging instead of failing if it can't be written.
+// Use it where auditing must not change the outcome of the request.
+func (s *Service) Log(ctx context.Context, event *Event) {
+	if err := s.Record(ctx, event); err != nil {
+		log.Printf("Audit: %v (type=%s actor=%s target=%s)", err, event.Type, event.ActorPhone, event.Target)
+	}
+}
+
+// RecordImpersonatedRequest logs a request made with an impersonation token.
+// allowed is false when the request was rejected (e.g. a write attempt).
+func (s *Service) RecordImpersonatedRequest(ctx context.Context, claims *utils.TokenClaims, request events.APIGatewayProxyRequest, allowed bool) {
+	outcome := OutcomeSuccess
+	if !allowed {
+		outcome = OutcomeDenied
+	}
+
+	// The actor is the impersonated user; the admin is recorded as impersonator
+	event := NewEvent(EventImpersonatedRequest, outcome).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role)
+	event.ImpersonatorPhone = claims.ImpersonatorPhone
+
+	s.Log(ctx, event)
+}
+
+// RecordAccessDenied logs a request rejected by an authorization check.
+func (s *Service) RecordAccessDenied(ctx context.Context, claims *utils.TokenClaims, request events.APIGatewayProxyRequest, reason string) {
+	event := NewEvent(EventAccessDenied, OutcomeDenied).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithDetail("reason", reason)
+	event.ImpersonatorPhone = claims.ImpersonatorPhone
+	if claims.IsAPIKey() {
+		event.WithDetail("apiKeyId", claims.APIKeyID)
+	}
+
+	s.Log(ctx, event)
+}
+
+// QueryFilter selects audit events. From and To are inclusive dates (UTC);
+// the other fields are optional exact-match filters.
+type QueryFilter struct {
+	From    time.Time
+	To      time.Time
+	Type    EventType
+	Actor   string
+	Target  string
+	Outcome Outcome
+	Limit   int
+}
+
+// Query returns events matching the filter, newest first.
+func (s *Service) Query(ctx context.Context, filter QueryFilter) ([]*Event, error) {
+	from := truncateDay(filter.From)
+	to := truncateDay(filter.To)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidQuery)
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxQueryDays {
+		return nil, fmt.Errorf("%w: date range cannot exceed %d days", ErrInvalidQuery, maxQueryDays)
+	}
+
+	var conditions []string
+	values := map[string]interface{}{}
+	names := map[string]string{}
+	if filter.Type != "" {
+		conditions = append(conditions, "#type = :type")
+		values[":type"] = string(filter.Type)
+		names["#type"] = "type"
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "(actorPhone = :actor OR impersonatorPhone = :actor)")
+		values[":actor"] = filter.Actor
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = :target")
+		values[":target"] = filter.Target
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = :outcome")
+		values[":outcome"] = string(filter.Outcome)
+	}
+
+	scanForward := false
+	results := make([]*Event, 0)
+	for day := to; !day.Before(from); day = day.AddDate(0, 0, -1) {
+		params := db.QueryParams{
+			KeyCondition:             "PK = :pk",
+			FilterExpression:         strings.Join(conditions, " AND "),
+			ExpressionValues:         map[string]interface{}{":pk": "AUDIT#" + day.Format("2006-01-02")},
+			ExpressionAttributeNames: names,
+			ScanIndexForward:         &scanForward,
+		}
+		for k, v := range values {
+			params.ExpressionValues[k] = v
+		}
+
+		items, err := s.db.QueryAll(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query audit events: %w", err)
+		}
+
+		for _, item := range items {
+			var event Event
+			if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit event: %w", err)
+			}
+			results = append(results, &event)
+			if filter.Limit > 0 && len(results) >= filter.Limit {
+				return results, nil
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// truncateDay returns the start of t's day in UTC.
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -6,22 +6,31 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/users"
 	"github.com/booking-villa-backend/internal/utils"
 )
 
 // Handler provides HTTP handlers for authentication endpoints.
 type Handler struct {
-	service *Service
+	service      *Service
+	auditService *audit.Service
 }
 
 // NewHandler creates a new auth handler.
 func NewHandler(dbClient *db.Client) *Handler {
 	return &Handler{
-		service: NewService(dbClient),
+		service:      NewService(dbClient),
+		auditService: audit.NewService(dbClient),
 	}
 }
 
+// GetService returns the auth service (for use in middleware wiring).
+func (h *Handler) GetService() *Service {
+	return h.service
+}
+
 // APIResponse creates a standardized API Gateway response.
 func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
 	jsonBody, _ := json.Marshal(body)
@@ -100,18 +109,68 @@ func (h *Handler) HandleVerifyOTP(ctx context.Context, request events.APIGateway
 	}
 
 	result, err := h.service.VerifyOTP(ctx, req)
+	h.recordLogin(ctx, request, "otp", req.Phone, result, err)
 	if err != nil {
 		return ErrorResponse(http.StatusUnauthorized, err.Error()), nil
 	}
 
-	// If no token, user is pending approval
-	if result.Token == "" {
+	// If no token (and no 2FA challenge), user is pending approval
+	if result.Token == "" && result.ChallengeToken == "" {
 		return APIResponse(http.StatusAccepted, result), nil
 	}
 
 	return APIResponse(http.StatusOK, result), nil
 }
 
+// HandleSendEmailOTP handles the POST /auth/email/send-otp endpoint.
+func (h *Handler) HandleSendEmailOTP(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req SendEmailOTPRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	if req.Email == "" {
+		return ErrorResponse(http.StatusBadRequest, "Email is required"), nil
+	}
+
+	code, err := h.service.SendEmailOTP(ctx, req.Email)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	response := map[string]interface{}{
+		"message": "OTP sent successfully",
+		"email":   req.Email,
+	}
+
+	if code == "" {
+		response["emailSent"] = true
+		response["note"] = "OTP sent by email. Check your inbox."
+	} else {
+		response["emailSent"] = false
+		response["code"] = code
+		response["note"] = "SMTP not configured. OTP returned in response for testing."
+	}
+
+	return APIResponse(http.StatusOK, response), nil
+}
+
+// HandleVerifyEmailOTP handles the POST /auth/email/verify-otp endpoint.
+func (h *Handler) HandleVerifyEmailOTP(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req VerifyEmailOTPRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	result, err := h.service.VerifyEmailOTP(ctx, req)
+	h.recordLogin(ctx, request, "email_otp", req.Email, result, err)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, result), nil
+}
+
 // HandleLogin handles the POST /auth/login endpoint.
 func (h *Handler) HandleLogin(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	var req LoginRequest
@@ -120,6 +179,7 @@ func (h *Handler) HandleLogin(ctx context.Context, request events.APIGatewayProx
 	}
 
 	result, err := h.service.LoginWithPassword(ctx, req)
+	h.recordLogin(ctx, request, "password", req.Phone, result, err)
 	if err != nil {
 		return ErrorResponse(http.StatusUnauthorized, err.Error()), nil
 	}
@@ -148,6 +208,54 @@ func (h *Handler) HandleRefreshToken(ctx context.Context, request events.APIGate
 	return APIResponse(http.StatusOK, result), nil
 }
 
+// HandleForgotPassword handles the POST /auth/password/forgot endpoint.
+func (h *Handler) HandleForgotPassword(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req ForgotPasswordRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	if req.Phone == "" {
+		return ErrorResponse(http.StatusBadRequest, "Phone number is required"), nil
+	}
+
+	code, err := h.service.ForgotPassword(ctx, req.Phone)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	response := map[string]interface{}{
+		"message": "If an account exists for this phone, a reset code has been sent",
+		"phone":   req.Phone,
+	}
+
+	// Code is only returned when SMS is not configured (dev mode)
+	if code != "" {
+		response["code"] = code
+		response["note"] = "SMS not configured. OTP returned in response for testing."
+	}
+
+	return APIResponse(http.StatusOK, response), nil
+}
+
+// HandleResetPassword handles the POST /auth/password/reset endpoint.
+func (h *Handler) HandleResetPassword(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req ResetPasswordRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	err := h.service.ResetPassword(ctx, req)
+	h.recordEvent(ctx, request, audit.EventPasswordReset, req.Phone, "", err)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message": "Password reset successfully. Please log in again.",
+	}), nil
+}
+
 // HandleSetPassword handles the POST /users/password endpoint.
 func (h *Handler) HandleSetPassword(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Get user from context (set by auth middleware)
@@ -165,6 +273,7 @@ func (h *Handler) HandleSetPassword(ctx context.Context, request events.APIGatew
 	phone := claims.Phone
 
 	err = h.service.SetPassword(ctx, phone, req.Password, req.OldPassword)
+	h.recordEvent(ctx, request, audit.EventPasswordChanged, phone, claims.Role, err)
 	if err != nil {
 		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
 	}
@@ -174,6 +283,208 @@ func (h *Handler) HandleSetPassword(ctx context.Context, request events.APIGatew
 	}), nil
 }
 
+// HandleSetEmail handles the POST /users/email endpoint.
+func (h *Handler) HandleSetEmail(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req SetEmailRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	code, err := h.service.SetEmail(ctx, claims.Phone, req.Email)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	response := map[string]interface{}{
+		"message": "Verification code sent",
+		"email":   users.NormalizeEmail(req.Email),
+	}
+
+	if code == "" {
+		response["emailSent"] = true
+		response["note"] = "OTP sent by email. Confirm it with POST /users/email/verify."
+	} else {
+		response["emailSent"] = false
+		response["code"] = code
+		response["note"] = "SMTP not configured. OTP returned in response for testing."
+	}
+
+	return APIResponse(http.StatusOK, response), nil
+}
+
+// HandleConfirmEmail handles the POST /users/email/verify endpoint.
+func (h *Handler) HandleConfirmEmail(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req ConfirmEmailRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	if err := h.service.ConfirmEmail(ctx, claims.Phone, req); err != nil {
+		if err == users.ErrEmailInUse {
+			return ErrorResponse(http.StatusConflict, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message": "Email updated successfully",
+		"email":   users.NormalizeEmail(req.Email),
+	}), nil
+}
+
+// HandleSetLocale handles the POST /users/locale endpoint.
+func (h *Handler) HandleSetLocale(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req SetLocaleRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	locale, err := h.service.SetLocale(ctx, claims.Phone, req.Locale)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message": "Language updated successfully",
+		"locale":  string(locale),
+	}), nil
+}
+
+// HandleEnrollTwoFactor handles the POST /auth/2fa/enroll endpoint.
+func (h *Handler) HandleEnrollTwoFactor(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	enrollment, err := h.service.EnrollTwoFactor(ctx, claims.Phone)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, enrollment), nil
+}
+
+// HandleActivateTwoFactor handles the POST /auth/2fa/activate endpoint.
+func (h *Handler) HandleActivateTwoFactor(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	if req.Code == "" {
+		return ErrorResponse(http.StatusBadRequest, "Code is required"), nil
+	}
+
+	activation, err := h.service.ActivateTwoFactor(ctx, claims.Phone, req.Code)
+	h.recordEvent(ctx, request, audit.EventTwoFactorEnabled, claims.Phone, claims.Role, err)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, activation), nil
+}
+
+// HandleVerifyTwoFactor handles the POST /auth/2fa/verify endpoint.
+func (h *Handler) HandleVerifyTwoFactor(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	result, err := h.service.VerifyTwoFactor(ctx, claims.Phone, req)
+	h.recordLogin(ctx, request, "2fa", claims.Phone, result, err)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, result), nil
+}
+
+// HandleDisableTwoFactor handles the POST /auth/2fa/disable endpoint.
+func (h *Handler) HandleDisableTwoFactor(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	err = h.service.DisableTwoFactor(ctx, claims.Phone, req)
+	h.recordEvent(ctx, request, audit.EventTwoFactorDisabled, claims.Phone, claims.Role, err)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	}), nil
+}
+
+// recordLogin audits a login attempt. identifier is the phone or email the caller gave.
+func (h *Handler) recordLogin(ctx context.Context, request events.APIGatewayProxyRequest, method, identifier string, result *AuthResult, err error) {
+	event := audit.NewEvent(audit.EventLoginSucceeded, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithDetail("method", method)
+
+	if err != nil {
+		event.Type = audit.EventLoginFailed
+		event.WithActor(identifier, "").WithError(err)
+		h.auditService.Log(ctx, event)
+		return
+	}
+
+	event.WithActor(result.User.Phone, string(result.User.Role))
+	switch {
+	case result.TwoFactorRequired:
+		event.WithDetail("twoFactor", "required")
+	case result.TwoFactorSetupRequired:
+		event.WithDetail("twoFactor", "setup_required")
+	case result.Token == "":
+		event.WithDetail("status", "pending_approval")
+	}
+	h.auditService.Log(ctx, event)
+}
+
+// recordEvent audits an account security change made by phone.
+func (h *Handler) recordEvent(ctx context.Context, request events.APIGatewayProxyRequest, eventType audit.EventType, phone, role string, err error) {
+	event := audit.NewEvent(eventType, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(phone, role)
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+}
+
 // extractClaimsFromRequest extracts JWT claims from the request.
 func extractClaimsFromRequest(request events.APIGatewayProxyRequest) (*utils.TokenClaims, error) {
 	authHeader := request.Headers["Authorization"]
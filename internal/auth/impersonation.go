@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+// impersonationTokenExpiry is how long an impersonation token is valid.
+const impersonationTokenExpiry = 15 * time.Minute
+
+// ImpersonateRequest represents a request to view the app as another user.
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonationResult contains a read-only token for the impersonated user.
+type ImpersonationResult struct {
+	Token     string             `json:"token"`
+	User      users.UserResponse `json:"user"`
+	ReadOnly  bool               `json:"readOnly"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+}
+
+// Impersonate issues a short-lived, read-only token that lets an admin see
+// the app exactly as another user does. Admins can't impersonate other admins.
+func (s *Service) Impersonate(ctx context.Context, adminPhone, phone string) (*ImpersonationResult, error) {
+	if phone == adminPhone {
+		return nil, fmt.Errorf("cannot impersonate yourself")
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.Role == users.RoleAdmin {
+		return nil, fmt.Errorf("cannot impersonate another admin")
+	}
+
+	token, err := utils.GenerateImpersonationToken(user.Phone, user.Phone, string(user.Role), adminPhone, impersonationTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &ImpersonationResult{
+		Token:     token,
+		User:      user.ToResponse(),
+		ReadOnly:  true,
+		ExpiresAt: time.Now().Add(impersonationTokenExpiry),
+	}, nil
+}
+
+// HandleImpersonate handles the POST /admin/impersonate/{phone} endpoint.
+func (h *Handler) HandleImpersonate(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, err := extractClaimsFromRequest(request)
+	if err != nil {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	phone := request.PathParameters["phone"]
+	if phone == "" {
+		return ErrorResponse(http.StatusBadRequest, "Phone is required"), nil
+	}
+
+	var req ImpersonateRequest
+	if request.Body != "" {
+		if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+		}
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return ErrorResponse(http.StatusBadRequest, "Reason is required"), nil
+	}
+
+	event := audit.NewEvent(audit.EventImpersonationStarted, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(phone).
+		WithDetail("reason", req.Reason)
+
+	result, err := h.service.Impersonate(ctx, claims.Phone, phone)
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.WithDetail("error", err.Error())
+		h.auditService.Log(ctx, event)
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	// Don't hand out a token that wasn't audited
+	if err := h.auditService.Record(ctx, event); err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to record impersonation"), nil
+	}
+
+	return APIResponse(http.StatusOK, result), nil
+}
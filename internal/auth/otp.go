@@ -12,12 +12,13 @@ import (
 	"time"
 
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
 	"github.com/booking-villa-backend/internal/sms"
 )
 
 // OTP represents an OTP record in DynamoDB.
 type OTP struct {
-	PK         string `dynamodbav:"PK"` // OTP#<phone>
+	PK         string `dynamodbav:"PK"` // OTP#<phone> or OTP#EMAIL#<email>
 	SK         string `dynamodbav:"SK"` // CODE#<otp>
 	Phone      string `dynamodbav:"phone"`
 	Code       string `dynamodbav:"code"`
@@ -25,19 +26,34 @@ type OTP struct {
 	ExpiresAt  int64  `dynamodbav:"expiresAt"`
 	TTL        int64  `dynamodbav:"TTL"` // DynamoDB TTL field
 	Verified   bool   `dynamodbav:"verified"`
+	Purpose    string `dynamodbav:"purpose,omitempty"` // What the OTP may be used for
 	EntityType string `dynamodbav:"entityType"`
 }
 
+// OTP purposes. An OTP can only be verified for the purpose it was issued for.
+const (
+	PurposeLogin         = "login"
+	PurposePasswordReset = "password_reset"
+	PurposeLinkEmail     = "link_email"
+)
+
 // SMSClient interface for sending SMS messages.
 type SMSClient interface {
 	SendOTP(ctx context.Context, phone, code string, expiryMinutes int) error
 	IsEnabled() bool
 }
 
+// EmailSender interface for sending OTP emails.
+type EmailSender interface {
+	SendOTP(ctx context.Context, to, code string, expiryMinutes int) error
+	IsEnabled() bool
+}
+
 // OTPService handles OTP generation and verification.
 type OTPService struct {
 	db            *db.Client
 	smsClient     SMSClient
+	emailSender   EmailSender
 	expiryMinutes int
 }
 
@@ -50,17 +66,23 @@ func NewOTPService(dbClient *db.Client) *OTPService {
 		}
 	}
 
-	// Initialize SMS client (will be nil if BREVO_API_KEY is not set)
+	// Initialize SMS client (will be nil if no SMS provider is configured)
 	smsClient := sms.NewClient()
 	if smsClient != nil && smsClient.IsEnabled() {
-		log.Println("SMS client initialized successfully - OTPs will be sent via Brevo")
+		log.Println("SMS client initialized successfully - OTPs will be sent via SMS")
 	} else {
 		log.Println("SMS client not configured - OTPs will be returned in response (development mode)")
 	}
 
+	emailService := email.NewServiceFromEnv()
+	if !emailService.IsEnabled() {
+		log.Println("SMTP not configured - email OTPs will be returned in response (development mode)")
+	}
+
 	return &OTPService{
 		db:            dbClient,
 		smsClient:     smsClient,
+		emailSender:   emailService,
 		expiryMinutes: expiryMinutes,
 	}
 }
@@ -78,10 +100,8 @@ func (s *OTPService) GenerateOTP() (string, error) {
 	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
-// SendOTP generates and stores an OTP for the given phone number.
-// If SMS client is configured, the OTP is sent via SMS.
-// Returns the code only if SMS sending is disabled (for development/testing).
-func (s *OTPService) SendOTP(ctx context.Context, phone string) (string, error) {
+// storeOTP generates and stores an OTP for the given identifier (phone or email key).
+func (s *OTPService) storeOTP(ctx context.Context, identifier, purpose string) (string, error) {
 	code, err := s.GenerateOTP()
 	if err != nil {
 		return "", err
@@ -92,14 +112,15 @@ func (s *OTPService) SendOTP(ctx context.Context, phone string) (string, error)
 	expiresAt := now.Add(expiryDuration)
 
 	otp := &OTP{
-		PK:         "OTP#" + phone,
+		PK:         "OTP#" + identifier,
 		SK:         "CODE#" + code,
-		Phone:      phone,
+		Phone:      identifier,
 		Code:       code,
 		CreatedAt:  now.Unix(),
 		ExpiresAt:  expiresAt.Unix(),
 		TTL:        expiresAt.Unix(), // Auto-delete after expiry
 		Verified:   false,
+		Purpose:    purpose,
 		EntityType: "OTP",
 	}
 
@@ -107,6 +128,18 @@ func (s *OTPService) SendOTP(ctx context.Context, phone string) (string, error)
 		return "", fmt.Errorf("failed to store OTP: %w", err)
 	}
 
+	return code, nil
+}
+
+// SendOTP generates and stores an OTP for the given phone number and purpose.
+// If SMS client is configured, the OTP is sent via SMS.
+// Returns the code only if SMS sending is disabled (for development/testing).
+func (s *OTPService) SendOTP(ctx context.Context, phone, purpose string) (string, error) {
+	code, err := s.storeOTP(ctx, phone, purpose)
+	if err != nil {
+		return "", err
+	}
+
 	// Send OTP via SMS if client is configured
 	if s.smsClient != nil && s.smsClient.IsEnabled() {
 		if err := s.smsClient.SendOTP(ctx, phone, code, s.expiryMinutes); err != nil {
@@ -124,8 +157,70 @@ func (s *OTPService) SendOTP(ctx context.Context, phone string) (string, error)
 	return code, nil
 }
 
-// VerifyOTP validates the provided OTP for the phone number.
-func (s *OTPService) VerifyOTP(ctx context.Context, phone, code string) (bool, error) {
+// emailOTPKey returns the OTP identifier for an email address.
+func emailOTPKey(address string) string {
+	return "EMAIL#" + address
+}
+
+// linkEmailOTPKey returns the OTP identifier for linking an email address to
+// a user, so a code can only confirm the address for the user who asked for it.
+func linkEmailOTPKey(phone, address string) string {
+	return "EMAIL#" + address + "#USER#" + phone
+}
+
+// SendEmailOTP generates and stores an OTP for the given email address.
+// If SMTP is configured, the OTP is sent by email.
+// Returns the code only if email sending is disabled (for development/testing).
+func (s *OTPService) SendEmailOTP(ctx context.Context, address string) (string, error) {
+	return s.sendEmail(ctx, emailOTPKey(address), address, PurposeLogin)
+}
+
+// SendLinkEmailOTP generates and emails an OTP that proves the user owns the
+// address they want to link.
+func (s *OTPService) SendLinkEmailOTP(ctx context.Context, phone, address string) (string, error) {
+	return s.sendEmail(ctx, linkEmailOTPKey(phone, address), address, PurposeLinkEmail)
+}
+
+// DecoyEmailOTP answers an email OTP request that must not send anything the
+// same way SendEmailOTP would, without storing a usable code.
+func (s *OTPService) DecoyEmailOTP() (string, error) {
+	if s.emailSender != nil && s.emailSender.IsEnabled() {
+		return "", nil
+	}
+	return s.GenerateOTP()
+}
+
+// sendEmail stores an OTP under identifier and emails it to address.
+func (s *OTPService) sendEmail(ctx context.Context, identifier, address, purpose string) (string, error) {
+	code, err := s.storeOTP(ctx, identifier, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	if s.emailSender != nil && s.emailSender.IsEnabled() {
+		if err := s.emailSender.SendOTP(ctx, address, code, s.expiryMinutes); err != nil {
+			log.Printf("Failed to send OTP via email to %s: %v", address, err)
+			return "", fmt.Errorf("email sending failed: %w", err)
+		}
+		return "", nil
+	}
+
+	log.Printf("SMTP not configured - returning OTP in response for %s", address)
+	return code, nil
+}
+
+// VerifyEmailOTP validates the provided OTP for the email address.
+func (s *OTPService) VerifyEmailOTP(ctx context.Context, address, code string) (bool, error) {
+	return s.VerifyOTP(ctx, emailOTPKey(address), code, PurposeLogin)
+}
+
+// VerifyLinkEmailOTP validates the OTP sent to confirm an email address for a user.
+func (s *OTPService) VerifyLinkEmailOTP(ctx context.Context, phone, address, code string) (bool, error) {
+	return s.VerifyOTP(ctx, linkEmailOTPKey(phone, address), code, PurposeLinkEmail)
+}
+
+// VerifyOTP validates the provided OTP for the phone number and purpose.
+func (s *OTPService) VerifyOTP(ctx context.Context, phone, code, purpose string) (bool, error) {
 	var otp OTP
 	pk := "OTP#" + phone
 	sk := "CODE#" + code
@@ -148,6 +243,15 @@ func (s *OTPService) VerifyOTP(ctx context.Context, phone, code string) (bool, e
 		return false, nil
 	}
 
+	// Check purpose (records created before purposes existed are login OTPs)
+	otpPurpose := otp.Purpose
+	if otpPurpose == "" {
+		otpPurpose = PurposeLogin
+	}
+	if otpPurpose != purpose {
+		return false, nil
+	}
+
 	// Mark as verified
 	err = s.db.UpdateItem(ctx, pk, sk, db.UpdateParams{
 		UpdateExpression: "SET verified = :verified",
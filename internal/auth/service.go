@@ -3,33 +3,44 @@ package auth
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/i18n"
 	"github.com/booking-villa-backend/internal/users"
 	"github.com/booking-villa-backend/internal/utils"
 )
 
 // AuthResult contains the result of an authentication operation.
+// When a second factor is needed, Token is empty and ChallengeToken must be
+// exchanged at /auth/2fa/verify (or used to enroll, if setup is required).
 type AuthResult struct {
-	Token   string             `json:"token"`
-	User    users.UserResponse `json:"user"`
-	IsNew   bool               `json:"isNew"`
-	Message string             `json:"message,omitempty"`
+	Token                  string             `json:"token"`
+	User                   users.UserResponse `json:"user"`
+	IsNew                  bool               `json:"isNew"`
+	Message                string             `json:"message,omitempty"`
+	ChallengeToken         string             `json:"challengeToken,omitempty"`
+	TwoFactorRequired      bool               `json:"twoFactorRequired,omitempty"`
+	TwoFactorSetupRequired bool               `json:"twoFactorSetupRequired,omitempty"`
 }
 
 // Service provides authentication operations.
 type Service struct {
-	db          *db.Client
-	otpService  *OTPService
-	userService *users.Service
+	db              *db.Client
+	otpService      *OTPService
+	userService     *users.Service
+	requireAdmin2FA bool
 }
 
 // NewService creates a new auth service.
+// Set REQUIRE_ADMIN_2FA=true to make two-factor authentication mandatory for admins.
 func NewService(dbClient *db.Client) *Service {
 	return &Service{
-		db:          dbClient,
-		otpService:  NewOTPService(dbClient),
-		userService: users.NewService(dbClient),
+		db:              dbClient,
+		otpService:      NewOTPService(dbClient),
+		userService:     users.NewService(dbClient),
+		requireAdmin2FA: os.Getenv("REQUIRE_ADMIN_2FA") == "true",
 	}
 }
 
@@ -49,7 +60,7 @@ func (s *Service) SendOTP(ctx context.Context, phone string) (string, error) {
 		return "", fmt.Errorf("invalid phone number format")
 	}
 
-	code, err := s.otpService.SendOTP(ctx, "91"+phone)
+	code, err := s.otpService.SendOTP(ctx, "91"+phone, PurposeLogin)
 	if err != nil {
 		return "", fmt.Errorf("failed to send OTP: %w", err)
 	}
@@ -112,7 +123,7 @@ func (s *Service) VerifyOTP(ctx context.Context, req VerifyOTPRequest) (*AuthRes
 	}
 
 	// Verify the OTP (add country code to match how it was stored)
-	valid, err := s.otpService.VerifyOTP(ctx, "91"+req.Phone, req.Code)
+	valid, err := s.otpService.VerifyOTP(ctx, "91"+req.Phone, req.Code, PurposeLogin)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify OTP: %w", err)
 	}
@@ -148,18 +159,74 @@ func (s *Service) VerifyOTP(ctx context.Context, req VerifyOTPRequest) (*AuthRes
 		}, nil
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
+	return s.completeLogin(user, isNew, "Authentication successful")
+}
+
+// SendEmailOTPRequest represents a request to send a login OTP by email.
+type SendEmailOTPRequest struct {
+	Email string `json:"email"`
+}
+
+// SendEmailOTP generates and emails an OTP to a user's linked email address.
+// Email login is only available for existing users who have linked an email.
+// Unknown addresses get the same answer, so callers can't tell which are registered.
+func (s *Service) SendEmailOTP(ctx context.Context, address string) (string, error) {
+	address = users.NormalizeEmail(address)
+	if address == "" || !strings.Contains(address, "@") {
+		return "", fmt.Errorf("invalid email address")
+	}
+
+	user, err := s.userService.GetUserByEmail(ctx, address)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return "", fmt.Errorf("failed to check user: %w", err)
+	}
+	if user == nil {
+		return s.otpService.DecoyEmailOTP()
 	}
 
-	return &AuthResult{
-		Token:   token,
-		User:    user.ToResponse(),
-		IsNew:   isNew,
-		Message: "Authentication successful",
-	}, nil
+	code, err := s.otpService.SendEmailOTP(ctx, address)
+	if err != nil {
+		return "", fmt.Errorf("failed to send OTP: %w", err)
+	}
+
+	return code, nil
+}
+
+// VerifyEmailOTPRequest represents a request to verify an email OTP.
+type VerifyEmailOTPRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// VerifyEmailOTP validates an email OTP and returns an auth result.
+func (s *Service) VerifyEmailOTP(ctx context.Context, req VerifyEmailOTPRequest) (*AuthResult, error) {
+	address := users.NormalizeEmail(req.Email)
+	if address == "" || req.Code == "" {
+		return nil, fmt.Errorf("email and code are required")
+	}
+
+	valid, err := s.otpService.VerifyEmailOTP(ctx, address, req.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify OTP: %w", err)
+	}
+
+	if !valid {
+		return nil, fmt.Errorf("invalid or expired OTP")
+	}
+
+	user, err := s.userService.GetUserByEmail(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("invalid or expired OTP")
+	}
+
+	if !user.CanLogin() {
+		return nil, fmt.Errorf("user account pending approval")
+	}
+
+	return s.completeLogin(user, false, "Authentication successful")
 }
 
 // LoginRequest represents a password login request.
@@ -199,17 +266,7 @@ func (s *Service) LoginWithPassword(ctx context.Context, req LoginRequest) (*Aut
 		return nil, fmt.Errorf("user account pending approval")
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
-	}
-
-	return &AuthResult{
-		Token:   token,
-		User:    user.ToResponse(),
-		Message: "Login successful",
-	}, nil
+	return s.completeLogin(user, false, "Login successful")
 }
 
 // SetPasswordRequest represents a request to set user password.
@@ -231,8 +288,15 @@ func (s *Service) SetPassword(ctx context.Context, phone, password, oldPassword
 		return fmt.Errorf("user not found")
 	}
 
-	// If user already has a password, verify old password
-	if user.HasPassword() && oldPassword != "" {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	// If user already has a password, the old one is always required
+	if user.HasPassword() {
+		if oldPassword == "" {
+			return fmt.Errorf("old password is required")
+		}
 		if !utils.VerifyPassword(user.PasswordHash, oldPassword) {
 			return fmt.Errorf("invalid old password")
 		}
@@ -248,6 +312,189 @@ func (s *Service) SetPassword(ctx context.Context, phone, password, oldPassword
 	return s.userService.UpdatePassword(ctx, phone, hashedPassword)
 }
 
+// SetEmailRequest represents a request to link an email address.
+type SetEmailRequest struct {
+	Email string `json:"email"`
+}
+
+// SetEmail starts linking an email address to the user by sending a
+// verification OTP to it. The address is only linked once ConfirmEmail
+// verifies the code.
+func (s *Service) SetEmail(ctx context.Context, phone, address string) (string, error) {
+	address = users.NormalizeEmail(address)
+	if address == "" || !strings.Contains(address, "@") {
+		return "", fmt.Errorf("invalid email address")
+	}
+
+	code, err := s.otpService.SendLinkEmailOTP(ctx, phone, address)
+	if err != nil {
+		return "", fmt.Errorf("failed to send OTP: %w", err)
+	}
+
+	return code, nil
+}
+
+// ConfirmEmailRequest represents a request to confirm a linked email address.
+type ConfirmEmailRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// ConfirmEmail verifies the OTP sent by SetEmail and links the address to
+// the user for email login and notifications.
+func (s *Service) ConfirmEmail(ctx context.Context, phone string, req ConfirmEmailRequest) error {
+	address := users.NormalizeEmail(req.Email)
+	if address == "" || req.Code == "" {
+		return fmt.Errorf("email and code are required")
+	}
+
+	valid, err := s.otpService.VerifyLinkEmailOTP(ctx, phone, address, req.Code)
+	if err != nil {
+		return fmt.Errorf("failed to verify OTP: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid or expired OTP")
+	}
+
+	return s.userService.UpdateEmail(ctx, phone, address)
+}
+
+// SetLocaleRequest represents a request to change the user's language.
+type SetLocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+// SetLocale sets the language the user receives notifications and SMS in.
+func (s *Service) SetLocale(ctx context.Context, phone, code string) (i18n.Locale, error) {
+	locale, ok := i18n.Parse(code)
+	if !ok {
+		return "", fmt.Errorf("unsupported locale: %s", code)
+	}
+
+	if err := s.userService.SetLocale(ctx, phone, locale); err != nil {
+		return "", err
+	}
+	return locale, nil
+}
+
+// minPasswordLength is the minimum accepted password length.
+const minPasswordLength = 6
+
+// validatePassword checks password strength requirements.
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+// ForgotPasswordRequest represents a request to start a password reset.
+type ForgotPasswordRequest struct {
+	Phone string `json:"phone"`
+}
+
+// ForgotPassword issues a password-reset OTP to the user's phone.
+// Returns the code only if SMS sending is disabled (for development/testing).
+// Unknown phones get no OTP but the same outward result, so the endpoint
+// can't be used to probe for accounts.
+func (s *Service) ForgotPassword(ctx context.Context, phone string) (string, error) {
+	if len(phone) < 10 {
+		return "", fmt.Errorf("invalid phone number format")
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return "", fmt.Errorf("failed to check user: %w", err)
+	}
+	if user == nil {
+		return "", nil
+	}
+
+	code, err := s.otpService.SendOTP(ctx, "91"+phone, PurposePasswordReset)
+	if err != nil {
+		return "", fmt.Errorf("failed to send OTP: %w", err)
+	}
+
+	return code, nil
+}
+
+// ResetPasswordRequest represents a request to reset a password with an OTP.
+type ResetPasswordRequest struct {
+	Phone    string `json:"phone"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// ResetPassword verifies a password-reset OTP, sets the new password and
+// revokes all existing sessions for the user.
+func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	if req.Phone == "" || req.Code == "" {
+		return fmt.Errorf("phone and code are required")
+	}
+
+	if err := validatePassword(req.Password); err != nil {
+		return err
+	}
+
+	valid, err := s.otpService.VerifyOTP(ctx, "91"+req.Phone, req.Code, PurposePasswordReset)
+	if err != nil {
+		return fmt.Errorf("failed to verify OTP: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid or expired OTP")
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, req.Phone)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userService.UpdatePassword(ctx, user.Phone, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return s.userService.RevokeSessions(ctx, user.Phone)
+}
+
+// ValidateSession checks that the user behind a token still exists, that
+// the token was issued after the user's last session revocation, and that
+// users who must use 2FA have enrolled.
+func (s *Service) ValidateSession(ctx context.Context, claims *utils.TokenClaims) error {
+	user, err := s.userService.GetUserByPhone(ctx, claims.Phone)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	if claims.IssuedAt != nil && user.IsSessionRevoked(claims.IssuedAt.Time) {
+		return fmt.Errorf("session has been revoked")
+	}
+	if s.twoFactorRequired(user) && !user.TwoFactorEnabled && claims.Purpose != utils.TokenPurposeTwoFactorSetup {
+		return fmt.Errorf("two-factor authentication setup required")
+	}
+
+	// Impersonation ends as soon as the impersonator is no longer an admin
+	if claims.ImpersonatorPhone != "" {
+		impersonator, err := s.userService.GetUserByPhone(ctx, claims.ImpersonatorPhone)
+		if err != nil {
+			return fmt.Errorf("failed to get impersonator: %w", err)
+		}
+		if impersonator == nil || impersonator.Role != users.RoleAdmin {
+			return fmt.Errorf("impersonator is no longer an admin")
+		}
+	}
+	return nil
+}
+
 // RefreshToken generates a new token from a valid existing token.
 func (s *Service) RefreshToken(ctx context.Context, tokenString string) (*AuthResult, error) {
 	// Validate existing token
@@ -256,6 +503,11 @@ func (s *Service) RefreshToken(ctx context.Context, tokenString string) (*AuthRe
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
+	// Challenge, setup and impersonation tokens can't be turned into sessions
+	if claims.Purpose != "" || claims.ImpersonatorPhone != "" {
+		return nil, fmt.Errorf("token cannot be refreshed")
+	}
+
 	// Get current user state
 	user, err := s.userService.GetUserByPhone(ctx, claims.Phone)
 	if err != nil {
@@ -271,6 +523,15 @@ func (s *Service) RefreshToken(ctx context.Context, tokenString string) (*AuthRe
 		return nil, fmt.Errorf("user account is no longer active")
 	}
 
+	// Revoked sessions can't be extended
+	if claims.IssuedAt != nil && user.IsSessionRevoked(claims.IssuedAt.Time) {
+		return nil, fmt.Errorf("session has been revoked")
+	}
+
+	if s.twoFactorRequired(user) && !user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor authentication setup required")
+	}
+
 	// Generate new token
 	newToken, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
 	if err != nil {
@@ -0,0 +1,400 @@
+// Package auth provides authentication and OTP services.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+const (
+	// twoFactorTokenExpiry is how long challenge and setup tokens are valid.
+	twoFactorTokenExpiry = 10 * time.Minute
+
+	// recoveryCodeCount is the number of single-use recovery codes issued on activation.
+	recoveryCodeCount = 10
+
+	// maxTwoFactorAttempts is the number of failed codes allowed within
+	// twoFactorLockout before further attempts are refused.
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+// TOTPRecord stores a user's TOTP secret and recovery codes.
+type TOTPRecord struct {
+	PK string `dynamodbav:"PK"` // USER#<phone>
+	SK string `dynamodbav:"SK"` // TOTP
+
+	Secret         string     `dynamodbav:"secret"`
+	IsActive       bool       `dynamodbav:"isActive"`                // False until the first code is confirmed
+	RecoveryCodes  []string   `dynamodbav:"recoveryCodes,omitempty"` // SHA-256 hashes of unused codes
+	LastUsedStep   int64      `dynamodbav:"lastUsedStep"`            // Prevents reuse of an accepted code
+	FailedAttempts int        `dynamodbav:"failedAttempts"`
+	LastFailedAt   *time.Time `dynamodbav:"lastFailedAt,omitempty"`
+	CreatedAt      time.Time  `dynamodbav:"createdAt"`
+	ActivatedAt    *time.Time `dynamodbav:"activatedAt,omitempty"`
+	EntityType     string     `dynamodbav:"entityType"`
+}
+
+// TwoFactorEnrollment is returned when a user starts TOTP enrollment.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// TwoFactorActivation is returned once TOTP has been activated.
+// The recovery codes are only shown here.
+type TwoFactorActivation struct {
+	RecoveryCodes []string   `json:"recoveryCodes"`
+	Result        AuthResult `json:"auth"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code or a recovery code.
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+// twoFactorRequired checks if policy makes 2FA mandatory for the user.
+func (s *Service) twoFactorRequired(user *users.User) bool {
+	return s.requireAdmin2FA && user.Role == users.RoleAdmin
+}
+
+// completeLogin issues the result of a successful first-factor login.
+// Users with 2FA get a challenge token to exchange at /auth/2fa/verify, and
+// users who must enroll get a setup token; everyone else gets a session token.
+func (s *Service) completeLogin(user *users.User, isNew bool, message string) (*AuthResult, error) {
+	purpose := ""
+	switch {
+	case user.TwoFactorEnabled:
+		purpose = utils.TokenPurposeTwoFactor
+		message = "Two-factor authentication required"
+	case s.twoFactorRequired(user):
+		purpose = utils.TokenPurposeTwoFactorSetup
+		message = "Two-factor authentication must be set up before logging in"
+	}
+
+	if purpose == "" {
+		token, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		return &AuthResult{
+			Token:   token,
+			User:    user.ToResponse(),
+			IsNew:   isNew,
+			Message: message,
+		}, nil
+	}
+
+	token, err := utils.GeneratePurposeToken(user.Phone, user.Phone, string(user.Role), purpose, twoFactorTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &AuthResult{
+		User:                   user.ToResponse(),
+		IsNew:                  isNew,
+		Message:                message,
+		ChallengeToken:         token,
+		TwoFactorRequired:      purpose == utils.TokenPurposeTwoFactor,
+		TwoFactorSetupRequired: purpose == utils.TokenPurposeTwoFactorSetup,
+	}, nil
+}
+
+// getTOTPRecord retrieves a user's TOTP record, or nil if there is none.
+func (s *Service) getTOTPRecord(ctx context.Context, phone string) (*TOTPRecord, error) {
+	var record TOTPRecord
+	if err := s.db.GetItem(ctx, "USER#"+phone, "TOTP", &record); err != nil {
+		if db.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get TOTP record: %w", err)
+	}
+	return &record, nil
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user. The secret is
+// not used for logins until it is confirmed with ActivateTwoFactor.
+func (s *Service) EnrollTwoFactor(ctx context.Context, phone string) (*TwoFactorEnrollment, error) {
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &TOTPRecord{
+		PK:         "USER#" + phone,
+		SK:         "TOTP",
+		Secret:     secret,
+		CreatedAt:  time.Now(),
+		EntityType: "TOTP",
+	}
+	if err := s.db.PutItem(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(phone, secret),
+	}, nil
+}
+
+// ActivateTwoFactor confirms enrollment with a code from the authenticator app,
+// enables 2FA and returns recovery codes along with a session token.
+func (s *Service) ActivateTwoFactor(ctx context.Context, phone, code string) (*TwoFactorActivation, error) {
+	record, err := s.getTOTPRecord(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("two-factor enrollment has not been started")
+	}
+	if record.IsActive {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	step, ok := verifyTOTP(record.Secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("invalid code")
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record.IsActive = true
+	record.RecoveryCodes = hashes
+	record.LastUsedStep = step
+	record.ActivatedAt = &now
+	if err := s.db.PutItem(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to activate two-factor authentication: %w", err)
+	}
+
+	if err := s.userService.SetTwoFactorEnabled(ctx, phone, true); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.CanLogin() {
+		return nil, fmt.Errorf("user account is no longer active")
+	}
+
+	token, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &TwoFactorActivation{
+		RecoveryCodes: recoveryCodes,
+		Result: AuthResult{
+			Token:   token,
+			User:    user.ToResponse(),
+			Message: "Two-factor authentication enabled",
+		},
+	}, nil
+}
+
+// VerifyTwoFactor completes a login by exchanging a challenge for a session token.
+func (s *Service) VerifyTwoFactor(ctx context.Context, phone string, req TwoFactorCodeRequest) (*AuthResult, error) {
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	// The user may have been rejected since the first factor
+	if !user.CanLogin() {
+		return nil, fmt.Errorf("user account is no longer active")
+	}
+
+	if err := s.verifySecondFactor(ctx, phone, req); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(user.Phone, user.Phone, string(user.Role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &AuthResult{
+		Token:   token,
+		User:    user.ToResponse(),
+		Message: "Authentication successful",
+	}, nil
+}
+
+// DisableTwoFactor turns off 2FA after checking a current code.
+// Not allowed for users whose role requires 2FA.
+func (s *Service) DisableTwoFactor(ctx context.Context, phone string, req TwoFactorCodeRequest) error {
+	user, err := s.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	if s.twoFactorRequired(user) {
+		return fmt.Errorf("two-factor authentication is mandatory for %s accounts", user.Role)
+	}
+
+	if err := s.verifySecondFactor(ctx, phone, req); err != nil {
+		return err
+	}
+
+	if err := s.db.DeleteItem(ctx, "USER#"+phone, "TOTP"); err != nil {
+		return fmt.Errorf("failed to remove TOTP secret: %w", err)
+	}
+
+	return s.userService.SetTwoFactorEnabled(ctx, phone, false)
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code.
+// Accepted TOTP codes can't be replayed, and repeated failures lock the
+// second factor for a while to stop brute-forcing the 6-digit codes.
+func (s *Service) verifySecondFactor(ctx context.Context, phone string, req TwoFactorCodeRequest) error {
+	record, err := s.getTOTPRecord(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if record == nil || !record.IsActive {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	now := time.Now()
+	if record.FailedAttempts >= maxTwoFactorAttempts && record.LastFailedAt != nil &&
+		now.Sub(*record.LastFailedAt) < twoFactorLockout {
+		return fmt.Errorf("too many failed attempts, try again later")
+	}
+
+	switch {
+	case req.Code != "":
+		step, ok := verifyTOTP(record.Secret, req.Code, now)
+		if ok && step > record.LastUsedStep {
+			// Conditional on the last step so a code can't be used twice concurrently
+			err := s.db.UpdateItem(ctx, record.PK, record.SK, db.UpdateParams{
+				UpdateExpression:    "SET lastUsedStep = :step, failedAttempts = :zero",
+				ConditionExpression: "lastUsedStep < :step",
+				ExpressionValues: map[string]interface{}{
+					":step": step,
+					":zero": 0,
+				},
+			})
+			if err == nil {
+				return nil
+			}
+			if !db.IsConditionalCheckFailed(err) {
+				return fmt.Errorf("failed to record TOTP use: %w", err)
+			}
+		}
+
+	case req.RecoveryCode != "":
+		if i := findRecoveryCode(record.RecoveryCodes, req.RecoveryCode); i >= 0 {
+			err := s.db.UpdateItem(ctx, record.PK, record.SK, consumeRecoveryCode(i, record.RecoveryCodes[i]))
+			if err == nil {
+				return nil
+			}
+			if !db.IsConditionalCheckFailed(err) {
+				return fmt.Errorf("failed to use recovery code: %w", err)
+			}
+		}
+
+	default:
+		return fmt.Errorf("code or recoveryCode is required")
+	}
+
+	s.recordFailedAttempt(ctx, record, now)
+	return fmt.Errorf("invalid code")
+}
+
+// recordFailedAttempt counts a failed second-factor attempt, restarting the
+// count once the lockout window has passed.
+func (s *Service) recordFailedAttempt(ctx context.Context, record *TOTPRecord, now time.Time) {
+	attempts := record.FailedAttempts + 1
+	if record.LastFailedAt != nil && now.Sub(*record.LastFailedAt) >= twoFactorLockout {
+		attempts = 1
+	}
+
+	_ = s.db.UpdateItem(ctx, record.PK, record.SK, db.UpdateParams{
+		UpdateExpression: "SET failedAttempts = :attempts, lastFailedAt = :now",
+		ExpressionValues: map[string]interface{}{
+			":attempts": attempts,
+			":now":      now,
+		},
+	})
+}
+
+// generateRecoveryCodes creates single-use recovery codes and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := hex.EncodeToString(bytes)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// findRecoveryCode returns the index of code among the hashes of the unused
+// recovery codes, or -1 if it is not one of them.
+func findRecoveryCode(hashes []string, code string) int {
+	hash := hashRecoveryCode(code)
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// consumeRecoveryCode builds the update that removes the recovery code at
+// index i, conditional on it still being there so it can't be used twice
+// concurrently.
+func consumeRecoveryCode(i int, hash string) db.UpdateParams {
+	return db.UpdateParams{
+		UpdateExpression:    fmt.Sprintf("REMOVE recoveryCodes[%d] SET failedAttempts = :zero", i),
+		ConditionExpression: fmt.Sprintf("recoveryCodes[%d] = :hash", i),
+		ExpressionValues: map[string]interface{}{
+			":hash": hash,
+			":zero": 0,
+		},
+	}
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case and the separator.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not in xxxxx-xxxxx form", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+		if hashes[i] == code {
+			t.Errorf("code %d is stored in plain text", i)
+		}
+	}
+
+	// Codes match regardless of case, separator and surrounding space
+	for _, typed := range []string{codes[3], "  " + codes[3] + "\n", strings.ToUpper(codes[3]), strings.ReplaceAll(codes[3], "-", "")} {
+		if i := findRecoveryCode(hashes, typed); i != 3 {
+			t.Errorf("findRecoveryCode(%q) = %d, want 3", typed, i)
+		}
+	}
+	if i := findRecoveryCode(hashes, "00000-00000"); i != -1 {
+		t.Errorf("findRecoveryCode(unknown) = %d, want -1", i)
+	}
+}
+
+func TestConsumeRecoveryCode(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := findRecoveryCode(hashes, codes[2])
+	params := consumeRecoveryCode(i, hashes[i])
+	if params.UpdateExpression != "REMOVE recoveryCodes[2] SET failedAttempts = :zero" {
+		t.Errorf("UpdateExpression = %q", params.UpdateExpression)
+	}
+	if params.ConditionExpression != "recoveryCodes[2] = :hash" || params.ExpressionValues[":hash"] != hashes[2] {
+		t.Errorf("condition = %q with %v, want the code's hash", params.ConditionExpression, params.ExpressionValues[":hash"])
+	}
+
+	// Once removed, as the update does, the code no longer matches and the
+	// others still do at their new positions
+	remaining := append(append([]string{}, hashes[:2]...), hashes[3:]...)
+	if i := findRecoveryCode(remaining, codes[2]); i != -1 {
+		t.Errorf("used code still found at %d", i)
+	}
+	if i := findRecoveryCode(remaining, codes[3]); i != 2 {
+		t.Errorf("findRecoveryCode(next code) = %d, want 2", i)
+	}
+}
@@ -0,0 +1,178 @@
+package bookings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/users"
+)
+
+// CreateBlockRequest represents a request to block nights of a property.
+type CreateBlockRequest struct {
+	StartDate string `json:"startDate"` // First blocked night, YYYY-MM-DD
+	EndDate   string `json:"endDate"`   // Day after the last blocked night, YYYY-MM-DD
+	Reason    string `json:"reason,omitempty"`
+}
+
+// nightsTaken reports whether any night from start up to but not including
+// end is booked or already blocked.
+func (s *Service) nightsTaken(ctx context.Context, propertyID string, start, end time.Time) (bool, error) {
+	blocks, err := s.propertyService.ListBlocks(ctx, propertyID, start, end)
+	if err != nil {
+		return false, err
+	}
+	if len(blocks) > 0 {
+		return true, nil
+	}
+
+	propBookings, err := s.ListBookingsByProperty(ctx, propertyID, &DateRange{
+		Start: start.AddDate(0, 0, -StayLookbackDays),
+		End:   end,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, booking := range propBookings {
+		if booking.Status != StatusCancelled && booking.CheckIn.Before(end) && start.Before(booking.CheckOut) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ownedProperty loads a property and checks that the caller owns it or is an
+// admin. It returns an error response if not.
+func (h *Handler) ownedProperty(ctx context.Context, propertyID string) (*properties.Property, *events.APIGatewayProxyResponse) {
+	fail := func(status int, message string) (*properties.Property, *events.APIGatewayProxyResponse) {
+		response := ErrorResponse(status, message)
+		return nil, &response
+	}
+
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return fail(http.StatusUnauthorized, "Unauthorized")
+	}
+	if !claims.CanAccessProperty(propertyID) {
+		return fail(http.StatusForbidden, "You do not have access to this property")
+	}
+
+	property, err := h.propertyService.GetProperty(ctx, propertyID)
+	if err != nil {
+		return fail(http.StatusInternalServerError, "Failed to get property")
+	}
+	if property == nil {
+		return fail(http.StatusNotFound, "Property not found")
+	}
+	if property.OwnerID != claims.Phone && claims.Role != string(users.RoleAdmin) {
+		return fail(http.StatusForbidden, "You don't own this property")
+	}
+	return property, nil
+}
+
+// HandleCreateBlock handles the POST /properties/{id}/blocks endpoint.
+func (h *Handler) HandleCreateBlock(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	property, failed := h.ownedProperty(ctx, request.PathParameters["id"])
+	if failed != nil {
+		return *failed, nil
+	}
+
+	var req CreateBlockRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid startDate format. Use YYYY-MM-DD"), nil
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid endDate format. Use YYYY-MM-DD"), nil
+	}
+
+	claims, _ := middleware.GetClaimsFromContext(ctx)
+	block := &properties.Block{
+		PropertyID: property.ID,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Reason:     req.Reason,
+		CreatedBy:  claims.Phone,
+	}
+	if err := block.Validate(); err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	taken, err := h.service.nightsTaken(ctx, property.ID, startDate, endDate)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to check availability: "+err.Error()), nil
+	}
+	if taken {
+		return ErrorResponse(http.StatusConflict, "Some of these nights are already booked or blocked"), nil
+	}
+
+	if err := h.propertyService.CreateBlock(ctx, block); err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to create block: "+err.Error()), nil
+	}
+	return APIResponse(http.StatusCreated, block), nil
+}
+
+// HandleListBlocks handles the GET /properties/{id}/blocks endpoint.
+func (h *Handler) HandleListBlocks(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	property, failed := h.ownedProperty(ctx, request.PathParameters["id"])
+	if failed != nil {
+		return *failed, nil
+	}
+
+	// Default to the next year from the property's local today
+	startDate := property.Today(time.Now())
+	endDate := startDate.AddDate(1, 0, 0)
+	if value := request.QueryStringParameters["startDate"]; value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid startDate format. Use YYYY-MM-DD"), nil
+		}
+		startDate = parsed
+	}
+	if value := request.QueryStringParameters["endDate"]; value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid endDate format. Use YYYY-MM-DD"), nil
+		}
+		endDate = parsed
+	}
+
+	blocks, err := h.propertyService.ListBlocks(ctx, property.ID, startDate, endDate)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to list blocks: "+err.Error()), nil
+	}
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"blocks": blocks,
+		"count":  len(blocks),
+	}), nil
+}
+
+// HandleDeleteBlock handles the DELETE /properties/{id}/blocks/{blockId} endpoint.
+func (h *Handler) HandleDeleteBlock(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	property, failed := h.ownedProperty(ctx, request.PathParameters["id"])
+	if failed != nil {
+		return *failed, nil
+	}
+
+	blockID := request.PathParameters["blockId"]
+	if blockID == "" {
+		return ErrorResponse(http.StatusBadRequest, "Block ID is required"), nil
+	}
+
+	if err := h.propertyService.DeleteBlock(ctx, property.ID, blockID); err != nil {
+		if errors.Is(err, properties.ErrBlockNotFound) {
+			return ErrorResponse(http.StatusNotFound, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to delete block: "+err.Error()), nil
+	}
+	return APIResponse(http.StatusOK, map[string]string{"message": "Block deleted"}), nil
+}
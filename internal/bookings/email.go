@@ -0,0 +1,39 @@
+package bookings
+
+import (
+	"fmt"
+
+	"github.com/booking-villa-backend/internal/email"
+)
+
+// bookingEmailData converts a booking into email template data.
+func bookingEmailData(booking *Booking) email.BookingData {
+	balance := booking.TotalAmount - booking.AdvanceAmount
+	if balance < 0 {
+		balance = 0
+	}
+
+	return email.BookingData{
+		GuestName:    booking.GuestName,
+		PropertyName: booking.PropertyName,
+		BookingRef:   bookingRef(booking.ID),
+		CheckIn:      booking.CheckIn.Format("02 Jan 2006"),
+		CheckInTime:  booking.CheckInTime,
+		CheckOut:     booking.CheckOut.Format("02 Jan 2006"),
+		CheckOutTime: booking.CheckOutTime,
+		NumNights:    booking.NumNights,
+		NumGuests:    booking.NumGuests,
+		TotalAmount:  fmt.Sprintf("%.2f", booking.TotalAmount),
+		AmountPaid:   fmt.Sprintf("%.2f", booking.AdvanceAmount),
+		BalanceDue:   fmt.Sprintf("%.2f", balance),
+		Currency:     booking.Currency,
+	}
+}
+
+// bookingRef returns the short booking reference shown to guests.
+func bookingRef(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,173 @@
+package bookings
+
+import (
+	"context"
+	"log"
+
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/realtime"
+	"github.com/booking-villa-backend/internal/webhooks"
+)
+
+// sideEffects collects the outbox events for a booking change. The first
+// error is kept and returned by Events, so callers can chain additions.
+type sideEffects struct {
+	events []*outbox.Event
+	err    error
+}
+
+// notify adds a notification about the booking for each recipient.
+// Empty and duplicate recipients, and the user who made the change, are skipped.
+func (s *sideEffects) notify(notifType notifications.NotificationType, booking *Booking, actor string, recipients ...string) {
+	seen := map[string]bool{"": true, actor: true}
+	for _, phone := range recipients {
+		if seen[phone] {
+			continue
+		}
+		seen[phone] = true
+		s.add(notifications.NewBookingNotificationEvent(phone, notifType, booking.ID,
+			booking.PropertyID, booking.PropertyName, booking.GuestName))
+	}
+}
+
+// emailGuest adds a guest email, if the guest gave an address.
+func (s *sideEffects) emailGuest(kind email.Kind, booking *Booking) {
+	s.add(newGuestEmailEvent(kind, booking))
+}
+
+// publish adds a webhook event for the owner's subscribed endpoints.
+func (s *sideEffects) publish(eventType webhooks.EventType, ownerPhone string, booking *Booking, previousStatus BookingStatus) {
+	if ownerPhone == "" {
+		return
+	}
+	s.add(webhooks.NewPublishEvent(ownerPhone, booking.PropertyID, eventType, webhooks.BookingEventData{
+		Booking:        webhookBookingData(booking),
+		PreviousStatus: string(previousStatus),
+	}))
+}
+
+// publishPayment adds a payment.recorded webhook event, if an amount was received.
+func (s *sideEffects) publishPayment(ownerPhone string, booking *Booking, amount float64) {
+	if ownerPhone == "" || amount <= 0 {
+		return
+	}
+	s.add(webhooks.NewPublishEvent(ownerPhone, booking.PropertyID, webhooks.EventPaymentRecorded, webhooks.PaymentEventData{
+		Booking: webhookBookingData(booking),
+		Amount:  amount,
+		Method:  booking.AdvanceMethod,
+	}))
+}
+
+// broadcast adds a realtime event for clients watching the property's calendar.
+func (s *sideEffects) broadcast(change realtime.BookingChange, booking *Booking) {
+	s.add(realtime.NewBookingChangedEvent(realtime.BookingChangedData{
+		BookingID:  booking.ID,
+		PropertyID: booking.PropertyID,
+		Change:     change,
+		Status:     string(booking.Status),
+		CheckIn:    booking.CheckIn.Format("2006-01-02"),
+		CheckOut:   booking.CheckOut.Format("2006-01-02"),
+	}))
+}
+
+// webhookBookingData describes a booking in webhook payloads.
+func webhookBookingData(booking *Booking) webhooks.BookingData {
+	balance := booking.TotalAmount - booking.AdvanceAmount
+	if balance < 0 {
+		balance = 0
+	}
+	return webhooks.BookingData{
+		BookingID:    booking.ID,
+		PropertyID:   booking.PropertyID,
+		PropertyName: booking.PropertyName,
+		GuestName:    booking.GuestName,
+		GuestPhone:   booking.GuestPhone,
+		NumGuests:    booking.NumGuests,
+		CheckIn:      booking.CheckIn.Format("2006-01-02"),
+		CheckOut:     booking.CheckOut.Format("2006-01-02"),
+		NumNights:    booking.NumNights,
+		Status:       string(booking.Status),
+		TotalAmount:  booking.TotalAmount,
+		AmountPaid:   booking.AdvanceAmount,
+		BalanceDue:   balance,
+		Currency:     booking.Currency,
+		BookedBy:     booking.BookedBy,
+	}
+}
+
+func (s *sideEffects) add(event *outbox.Event, err error) {
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return
+	}
+	if event != nil {
+		s.events = append(s.events, event)
+	}
+}
+
+// Events returns the collected events, or the first error.
+func (s *sideEffects) Events() ([]*outbox.Event, error) {
+	return s.events, s.err
+}
+
+// EventGuestEmail is the outbox event that sends a transactional email to a booking's guest.
+const EventGuestEmail outbox.EventType = "email.guest_booking"
+
+// GuestEmailEvent is the payload of EventGuestEmail.
+type GuestEmailEvent struct {
+	BookingID string     `json:"bookingId"`
+	Kind      email.Kind `json:"kind"`
+}
+
+// newGuestEmailEvent creates an outbox event that emails the booking's guest.
+// Returns nil if the guest did not give an email address.
+func newGuestEmailEvent(kind email.Kind, booking *Booking) (*outbox.Event, error) {
+	if booking.GuestEmail == "" {
+		return nil, nil
+	}
+	return outbox.NewEvent(EventGuestEmail, GuestEmailEvent{BookingID: booking.ID, Kind: kind})
+}
+
+// EventConsumer performs the side effects of booking outbox events.
+type EventConsumer struct {
+	service      *Service
+	emailService *email.Service
+}
+
+// NewEventConsumer creates a consumer for booking outbox events.
+func NewEventConsumer(dbClient *db.Client) *EventConsumer {
+	return &EventConsumer{
+		service:      NewService(dbClient),
+		emailService: email.NewServiceFromEnv(),
+	}
+}
+
+// HandleGuestEmailEvent sends the guest email described by an outbox event.
+// The booking is read at send time, so the email reflects its committed state.
+func (c *EventConsumer) HandleGuestEmailEvent(ctx context.Context, event *outbox.Event) error {
+	var payload GuestEmailEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	if !c.emailService.IsEnabled() {
+		log.Printf("Email not configured - skipping %s email for booking %s", payload.Kind, payload.BookingID)
+		return nil
+	}
+
+	booking, err := c.service.GetBooking(ctx, payload.BookingID)
+	if err != nil {
+		return err
+	}
+	if booking == nil || booking.GuestEmail == "" {
+		return nil
+	}
+
+	data := bookingEmailData(booking)
+	return c.emailService.SendTemplate(ctx, payload.Kind, booking.GuestEmail, data.BookingRef, data)
+}
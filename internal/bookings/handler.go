@@ -3,35 +3,41 @@ package bookings
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
 	"github.com/booking-villa-backend/internal/middleware"
 	"github.com/booking-villa-backend/internal/notifications"
 	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/realtime"
 	"github.com/booking-villa-backend/internal/users"
 	"github.com/booking-villa-backend/internal/utils"
+	"github.com/booking-villa-backend/internal/webhooks"
+	"github.com/google/uuid"
 )
 
 // Handler provides HTTP handlers for booking endpoints.
 type Handler struct {
-	service             *Service
-	propertyService     *properties.Service
-	userService         *users.Service
-	notificationService *notifications.Service
+	service         *Service
+	propertyService *properties.Service
+	userService     *users.Service
+	auditService    *audit.Service
 }
 
 // NewHandler creates a new booking handler.
-func NewHandler(dbClient *db.Client, notifService *notifications.Service) *Handler {
+func NewHandler(dbClient *db.Client) *Handler {
 	return &Handler{
-		service:             NewService(dbClient),
-		propertyService:     properties.NewService(dbClient),
-		userService:         users.NewService(dbClient),
-		notificationService: notifService,
+		service:         NewService(dbClient),
+		propertyService: properties.NewService(dbClient),
+		userService:     users.NewService(dbClient),
+		auditService:    audit.NewService(dbClient),
 	}
 }
 
@@ -56,23 +62,24 @@ func ErrorResponse(statusCode int, message string) events.APIGatewayProxyRespons
 
 // CreateBookingRequest represents a request to create a booking.
 type CreateBookingRequest struct {
-	PropertyID      string  `json:"propertyId"`
-	GuestName       string  `json:"guestName"`
-	GuestPhone      string  `json:"guestPhone"`
-	GuestEmail      string  `json:"guestEmail,omitempty"`
-	NumGuests       int     `json:"numGuests"`
-	CheckIn         string  `json:"checkIn"`                // Format: 2006-01-02
-	CheckInTime     string  `json:"checkInTime,omitempty"`  // Format: 15:04
-	CheckOut        string  `json:"checkOut"`               // Format: 2006-01-02
-	CheckOutTime    string  `json:"checkOutTime,omitempty"` // Format: 15:04
-	Notes           string  `json:"notes,omitempty"`
-	SpecialRequests string  `json:"specialRequests,omitempty"`
-	InviteCode      string  `json:"inviteCode,omitempty"`
-	PricePerNight   float64 `json:"pricePerNight,omitempty"`   // Override property price if needed
-	TotalAmount     float64 `json:"totalAmount,omitempty"`     // Directly set total amount for dynamic pricing
-	AgentCommission float64 `json:"agentCommission,omitempty"` // Commission for the agent
-	AdvanceAmount   float64 `json:"advanceAmount,omitempty"`   // Initial payment
-	AdvanceMethod   string  `json:"advanceMethod,omitempty"`   // cash, upi, etc.
+	PropertyID      string        `json:"propertyId"`
+	GuestName       string        `json:"guestName"`
+	GuestPhone      string        `json:"guestPhone"`
+	GuestEmail      string        `json:"guestEmail,omitempty"`
+	NumGuests       int           `json:"numGuests"`
+	CheckIn         string        `json:"checkIn"`                // Format: 2006-01-02
+	CheckInTime     string        `json:"checkInTime,omitempty"`  // Format: 15:04
+	CheckOut        string        `json:"checkOut"`               // Format: 2006-01-02
+	CheckOutTime    string        `json:"checkOutTime,omitempty"` // Format: 15:04
+	Notes           string        `json:"notes,omitempty"`
+	SpecialRequests string        `json:"specialRequests,omitempty"`
+	InviteCode      string        `json:"inviteCode,omitempty"`
+	Source          BookingSource `json:"source,omitempty"`          // Channel the booking came through
+	PricePerNight   float64       `json:"pricePerNight,omitempty"`   // Override property price if needed
+	TotalAmount     float64       `json:"totalAmount,omitempty"`     // Directly set total amount for dynamic pricing
+	AgentCommission float64       `json:"agentCommission,omitempty"` // Commission for the agent
+	AdvanceAmount   float64       `json:"advanceAmount,omitempty"`   // Initial payment
+	AdvanceMethod   string        `json:"advanceMethod,omitempty"`   // cash, upi, etc.
 }
 
 // HandleCreateBooking handles the POST /bookings endpoint.
@@ -94,6 +101,14 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 		return ErrorResponse(http.StatusBadRequest, "PropertyID, guestName, guestPhone, checkIn, and checkOut are required"), nil
 	}
 
+	if !middleware.CanAccessProperty(ctx, req.PropertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
+
+	if req.Source != "" && !req.Source.IsValid() {
+		return ErrorResponse(http.StatusBadRequest, msgInvalidSource), nil
+	}
+
 	// Parse dates
 	checkIn, err := time.Parse("2006-01-02", req.CheckIn)
 	if err != nil {
@@ -124,8 +139,17 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 		return ErrorResponse(http.StatusBadRequest, "Property is not active"), nil
 	}
 
+	// Stays without times use the property's defaults
+	defaultCheckIn, defaultCheckOut := property.StayTimes()
+	if req.CheckInTime == "" {
+		req.CheckInTime = defaultCheckIn
+	}
+	if req.CheckOutTime == "" {
+		req.CheckOutTime = defaultCheckOut
+	}
+
 	// Check availability
-	available, err := h.service.CheckAvailability(ctx, req.PropertyID, checkIn, checkOut, req.CheckInTime, req.CheckOutTime)
+	available, err := h.service.CheckAvailability(ctx, property, checkIn, checkOut, req.CheckInTime, req.CheckOutTime)
 	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to check availability"), nil
 	}
@@ -136,17 +160,26 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 
 	// Validate invite code if provided (for agents)
 	if req.InviteCode != "" {
+		event := audit.NewEvent(audit.EventInviteCodeRedeemed, audit.OutcomeSuccess).
+			WithRequest(request).
+			WithActor(claims.Phone, claims.Role).
+			WithTarget(req.InviteCode).
+			WithDetail("propertyId", req.PropertyID)
+
 		inviteCode, err := h.propertyService.ValidateInviteCode(ctx, req.InviteCode)
 		if err != nil {
+			h.auditService.Log(ctx, event.WithError(err))
 			return ErrorResponse(http.StatusBadRequest, "Invalid invite code: "+err.Error()), nil
 		}
 
 		if inviteCode.PropertyID != req.PropertyID {
+			h.auditService.Log(ctx, event.WithError(fmt.Errorf("invite code is for a different property")))
 			return ErrorResponse(http.StatusBadRequest, "Invite code is for a different property"), nil
 		}
 
 		// Mark invite code as used
 		_ = h.propertyService.UseInviteCode(ctx, req.InviteCode, req.PropertyID)
+		h.auditService.Log(ctx, event)
 	}
 
 	// Use property price unless overridden
@@ -161,6 +194,19 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 		numGuests = 1
 	}
 
+	// Default the source to how the booking was made
+	source := req.Source
+	if source == "" {
+		switch {
+		case req.InviteCode != "":
+			source = SourceInvite
+		case claims.Role == string(users.RoleAgent):
+			source = SourceAgent
+		default:
+			source = SourceDirect
+		}
+	}
+
 	// Fetch user details to get name
 	user, err := h.userService.GetUserByPhone(ctx, claims.Phone)
 	bookedByName := ""
@@ -188,6 +234,7 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 		BookedBy:        claims.Phone,
 		BookedByName:    bookedByName,
 		InviteCode:      req.InviteCode,
+		Source:          source,
 		Notes:           req.Notes,
 		SpecialRequests: req.SpecialRequests,
 		AgentCommission: req.AgentCommission,
@@ -196,27 +243,21 @@ func (h *Handler) HandleCreateBooking(ctx context.Context, request events.APIGat
 		Status:          StatusPending,
 	}
 
-	if err := h.service.CreateBooking(ctx, booking); err != nil {
+	// Notify the property owner, email the guest and publish webhooks once the booking is saved
+	booking.ID = uuid.New().String()
+	var effects sideEffects
+	effects.notify(notifications.TypeBookingCreated, booking, "", property.OwnerID)
+	effects.emailGuest(email.KindBookingConfirmation, booking)
+	effects.publish(webhooks.EventBookingCreated, property.OwnerID, booking, "")
+	effects.publishPayment(property.OwnerID, booking, booking.AdvanceAmount)
+	effects.broadcast(realtime.ChangeCreated, booking)
+	outboxEvents, err := effects.Events()
+	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to create booking"), nil
 	}
 
-	// Send notification to property owner
-	if h.notificationService != nil {
-		go func() {
-			ctx := context.Background()
-			err := h.notificationService.CreateBookingNotification(
-				ctx,
-				property.OwnerID,
-				notifications.TypeBookingCreated,
-				booking.ID,
-				booking.PropertyID,
-				booking.PropertyName,
-				booking.GuestName,
-			)
-			if err != nil {
-				log.Printf("Failed to create notification: %v", err)
-			}
-		}()
+	if err := h.service.CreateBooking(ctx, booking, outboxEvents...); err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to create booking"), nil
 	}
 
 	return APIResponse(http.StatusCreated, booking), nil
@@ -244,6 +285,10 @@ func (h *Handler) HandleGetBooking(ctx context.Context, request events.APIGatewa
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if !claims.CanAccessProperty(booking.PropertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
+
 	// Agents can only see bookings they created
 	// Owners (and Admins) can see all bookings for the property
 	isOwnerOrAdmin := false
@@ -263,9 +308,7 @@ func (h *Handler) HandleGetBooking(ctx context.Context, request events.APIGatewa
 
 	if !h.canSeeBookingDetails(ctx, claims, booking) {
 		// Mask sensitive guest information
-		booking.GuestName = "***"
-		booking.GuestPhone = "***"
-		booking.GuestEmail = "***"
+		MaskGuestDetails(booking)
 	}
 
 	return APIResponse(http.StatusOK, booking), nil
@@ -335,9 +378,7 @@ func (h *Handler) HandleListBookings(ctx context.Context, request events.APIGate
 		// Privacy Masking (Guest Details):
 		// Done via canSeeBookingDetails (Admin/Creator only)
 		if !h.canSeeBookingDetails(ctx, claims, b) {
-			b.GuestName = "***"
-			b.GuestPhone = "***"
-			b.GuestEmail = "***"
+			MaskGuestDetails(b)
 		}
 		visibleBookings = append(visibleBookings, b)
 	}
@@ -381,8 +422,13 @@ func (h *Handler) HandleListAvailableProperties(ctx context.Context, request eve
 	// 2. Filter by availability
 	availableProperties := make([]*properties.Property, 0)
 	for _, prop := range allProperties {
-		// Pass empty strings for times to use defaults (14:00/11:00) for general availability
-		isAvailable, err := h.service.CheckAvailability(ctx, prop.ID, checkIn, checkOut, "", "")
+		// Keys restricted to some properties only see those
+		if !middleware.CanAccessProperty(ctx, prop.ID) {
+			continue
+		}
+
+		// Pass empty strings for times to use the property's default check-in/out times
+		isAvailable, err := h.service.CheckAvailability(ctx, prop, checkIn, checkOut, "", "")
 		if err != nil {
 			// Log error but continue? For now, if we can't check, assume unavailable or skip
 			fmt.Printf("Error checking availability for property %s: %v\n", prop.ID, err)
@@ -399,28 +445,33 @@ func (h *Handler) HandleListAvailableProperties(ctx context.Context, request eve
 	}), nil
 }
 
+// msgInvalidSource is returned for an unknown booking source.
+const msgInvalidSource = "Invalid source. Valid values: direct, agent, invite, phone, walk_in, ota, referral, other"
+
 // UpdateBookingStatusRequest represents a request to update booking status.
 type UpdateBookingStatusRequest struct {
-	Status BookingStatus `json:"status"`
+	Status BookingStatus      `json:"status"`
+	Reason CancellationReason `json:"reason,omitempty"` // Only when cancelling
 }
 
 // UpdateBookingRequest represents a request to update booking details.
 type UpdateBookingRequest struct {
-	GuestName       *string  `json:"guestName,omitempty"`
-	GuestPhone      *string  `json:"guestPhone,omitempty"`
-	GuestEmail      *string  `json:"guestEmail,omitempty"`
-	NumGuests       *int     `json:"numGuests,omitempty"`
-	CheckIn         *string  `json:"checkIn,omitempty"`
-	CheckInTime     *string  `json:"checkInTime,omitempty"`
-	CheckOut        *string  `json:"checkOut,omitempty"`
-	CheckOutTime    *string  `json:"checkOutTime,omitempty"`
-	PricePerNight   *float64 `json:"pricePerNight,omitempty"`
-	TotalAmount     *float64 `json:"totalAmount,omitempty"`
-	AgentCommission *float64 `json:"agentCommission,omitempty"`
-	AdvanceAmount   *float64 `json:"advanceAmount,omitempty"`
-	AdvanceMethod   *string  `json:"advanceMethod,omitempty"`
-	Notes           *string  `json:"notes,omitempty"`
-	SpecialRequests *string  `json:"specialRequests,omitempty"`
+	GuestName       *string        `json:"guestName,omitempty"`
+	GuestPhone      *string        `json:"guestPhone,omitempty"`
+	GuestEmail      *string        `json:"guestEmail,omitempty"`
+	NumGuests       *int           `json:"numGuests,omitempty"`
+	CheckIn         *string        `json:"checkIn,omitempty"`
+	CheckInTime     *string        `json:"checkInTime,omitempty"`
+	CheckOut        *string        `json:"checkOut,omitempty"`
+	CheckOutTime    *string        `json:"checkOutTime,omitempty"`
+	PricePerNight   *float64       `json:"pricePerNight,omitempty"`
+	TotalAmount     *float64       `json:"totalAmount,omitempty"`
+	AgentCommission *float64       `json:"agentCommission,omitempty"`
+	AdvanceAmount   *float64       `json:"advanceAmount,omitempty"`
+	AdvanceMethod   *string        `json:"advanceMethod,omitempty"`
+	Source          *BookingSource `json:"source,omitempty"`
+	Notes           *string        `json:"notes,omitempty"`
+	SpecialRequests *string        `json:"specialRequests,omitempty"`
 }
 
 // HandleUpdateBooking handles the PATCH /bookings/{id} endpoint.
@@ -450,6 +501,9 @@ func (h *Handler) HandleUpdateBooking(ctx context.Context, request events.APIGat
 	}
 
 	// 2. Permission check
+	if !claims.CanAccessProperty(booking.PropertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
 	if claims.Role != "admin" {
 		authorized, err := h.userService.IsAuthorizedForProperty(ctx, claims.Phone, booking.PropertyID)
 		if err != nil {
@@ -461,6 +515,8 @@ func (h *Handler) HandleUpdateBooking(ctx context.Context, request events.APIGat
 	}
 
 	// 3. Apply updates
+	previousAdvance := booking.AdvanceAmount
+	previousStatus := booking.Status
 	datesChanged := false
 	if req.GuestName != nil {
 		booking.GuestName = *req.GuestName
@@ -489,6 +545,12 @@ func (h *Handler) HandleUpdateBooking(ctx context.Context, request events.APIGat
 	if req.AdvanceMethod != nil {
 		booking.AdvanceMethod = *req.AdvanceMethod
 	}
+	if req.Source != nil {
+		if !req.Source.IsValid() {
+			return ErrorResponse(http.StatusBadRequest, msgInvalidSource), nil
+		}
+		booking.Source = *req.Source
+	}
 	if req.Notes != nil {
 		booking.Notes = *req.Notes
 	}
@@ -541,8 +603,15 @@ func (h *Handler) HandleUpdateBooking(ctx context.Context, request events.APIGat
 	}
 
 	// 4. Verify availability if dates or times changed
+	property, err := h.propertyService.GetProperty(ctx, booking.PropertyID)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get property"), nil
+	}
+	if property == nil {
+		return ErrorResponse(http.StatusNotFound, "Property not found"), nil
+	}
 	if datesChanged || timesChanged {
-		available, err := h.service.CheckAvailability(ctx, booking.PropertyID, booking.CheckIn, booking.CheckOut, booking.CheckInTime, booking.CheckOutTime)
+		available, err := h.service.CheckAvailability(ctx, property, booking.CheckIn, booking.CheckOut, booking.CheckInTime, booking.CheckOutTime)
 		if err != nil {
 			return ErrorResponse(http.StatusInternalServerError, "Failed to check availability"), nil
 		}
@@ -564,8 +633,21 @@ func (h *Handler) HandleUpdateBooking(ctx context.Context, request events.APIGat
 		}
 	}
 
-	// 5. Save updates
-	if err := h.service.UpdateBooking(ctx, booking); err != nil {
+	// 5. Publish webhooks to the owner, including any newly recorded payment
+	var effects sideEffects
+	effects.publish(webhooks.EventBookingUpdated, property.OwnerID, booking, previousStatus)
+	effects.publishPayment(property.OwnerID, booking, booking.AdvanceAmount-previousAdvance)
+	effects.broadcast(realtime.ChangeUpdated, booking)
+	outboxEvents, err := effects.Events()
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to update booking"), nil
+	}
+
+	// 6. Save updates
+	if err := h.service.UpdateBooking(ctx, booking, outboxEvents...); err != nil {
+		if errors.Is(err, ErrConcurrentUpdate) {
+			return ErrorResponse(http.StatusConflict, err.Error()), nil
+		}
 		return ErrorResponse(http.StatusInternalServerError, "Failed to update booking"), nil
 	}
 
@@ -588,6 +670,15 @@ func (h *Handler) HandleUpdateBookingStatus(ctx context.Context, request events.
 		return ErrorResponse(http.StatusBadRequest, "Invalid status. Valid values: pending_confirmation, confirmed, checked_in, checked_out, cancelled, no_show"), nil
 	}
 
+	if req.Reason != "" {
+		if req.Status != StatusCancelled {
+			return ErrorResponse(http.StatusBadRequest, "A reason can only be given when cancelling"), nil
+		}
+		if !req.Reason.IsValid() {
+			return ErrorResponse(http.StatusBadRequest, "Invalid reason. Valid values: guest_request, plans_changed, payment_not_received, double_booking, property_unavailable, other"), nil
+		}
+	}
+
 	// Get booking to verify it exists
 	booking, err := h.service.GetBooking(ctx, id)
 	if err != nil {
@@ -604,6 +695,10 @@ func (h *Handler) HandleUpdateBookingStatus(ctx context.Context, request events.
 		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
 	}
 
+	if !claims.CanAccessProperty(booking.PropertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
+
 	if claims.Role != string(users.RoleAdmin) && claims.Role != string(users.RoleOwner) {
 		// If agent, check if they are authorized for the property
 		authorized, err := h.userService.IsAuthorizedForProperty(ctx, claims.Phone, booking.PropertyID)
@@ -615,47 +710,38 @@ func (h *Handler) HandleUpdateBookingStatus(ctx context.Context, request events.
 		}
 	}
 
-	// Update status
-	if err := h.service.UpdateBookingStatus(ctx, id, req.Status); err != nil {
+	// Notify the owner and the booking agent, and let the guest know if their booking was cancelled
+	property, err := h.propertyService.GetProperty(ctx, booking.PropertyID)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get property"), nil
+	}
+	var effects sideEffects
+	if property != nil {
+		notifType := statusToNotificationType(req.Status)
+		effects.notify(notifType, booking, claims.Phone, property.OwnerID, booking.BookedBy)
+
+		updated := withStatus(booking, req.Status, req.Reason)
+		effects.publish(webhooks.EventTypeForNotification(notifType), property.OwnerID, updated, booking.Status)
+	}
+	if req.Status == StatusCancelled && booking.Status != StatusCancelled {
+		effects.emailGuest(email.KindBookingCancellation, booking)
+	}
+	change := realtime.ChangeStatus
+	if req.Status == StatusCancelled {
+		change = realtime.ChangeCancelled
+	}
+	effects.broadcast(change, withStatus(booking, req.Status, req.Reason))
+	outboxEvents, err := effects.Events()
+	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to update booking status"), nil
 	}
 
-	// Send notifications for status change
-	if h.notificationService != nil {
-		go func() {
-			ctx := context.Background()
-			notifType := statusToNotificationType(req.Status)
-
-			// Get property to find owner
-			property, err := h.propertyService.GetProperty(ctx, booking.PropertyID)
-			if err == nil && property != nil {
-				// Notify owner if the updater is not the owner
-				if claims.Phone != property.OwnerID {
-					_ = h.notificationService.CreateBookingNotification(
-						ctx,
-						property.OwnerID,
-						notifType,
-						booking.ID,
-						booking.PropertyID,
-						booking.PropertyName,
-						booking.GuestName,
-					)
-				}
-
-				// Notify the agent who booked if they're not the one updating
-				if booking.BookedBy != "" && booking.BookedBy != claims.Phone && booking.BookedBy != property.OwnerID {
-					_ = h.notificationService.CreateBookingNotification(
-						ctx,
-						booking.BookedBy,
-						notifType,
-						booking.ID,
-						booking.PropertyID,
-						booking.PropertyName,
-						booking.GuestName,
-					)
-				}
-			}
-		}()
+	// Update status
+	if err := h.service.UpdateBookingStatus(ctx, id, req.Status, req.Reason, outboxEvents...); err != nil {
+		if errors.Is(err, ErrConcurrentUpdate) {
+			return ErrorResponse(http.StatusConflict, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to update booking status"), nil
 	}
 
 	return APIResponse(http.StatusOK, map[string]interface{}{
@@ -687,6 +773,9 @@ func (h *Handler) HandleSettleBooking(ctx context.Context, request events.APIGat
 	}
 
 	// Permission check
+	if !claims.CanAccessProperty(booking.PropertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
 	if claims.Role != "admin" {
 		authorized, err := h.userService.IsAuthorizedForProperty(ctx, claims.Phone, booking.PropertyID)
 		if err != nil {
@@ -697,28 +786,35 @@ func (h *Handler) HandleSettleBooking(ctx context.Context, request events.APIGat
 		}
 	}
 
-	if err := h.service.SettleBooking(ctx, id); err != nil {
+	// Notify the owner and send the guest a payment receipt
+	property, err := h.propertyService.GetProperty(ctx, booking.PropertyID)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get property"), nil
+	}
+	var effects sideEffects
+	if property != nil {
+		effects.notify(notifications.TypeBookingSettled, booking, claims.Phone, property.OwnerID)
+
+		settled := *booking
+		settled.AdvanceAmount = booking.TotalAmount
+		settled.Status = StatusSettled
+		effects.publish(webhooks.EventBookingSettled, property.OwnerID, &settled, booking.Status)
+		effects.publishPayment(property.OwnerID, &settled, booking.TotalAmount-booking.AdvanceAmount)
+	}
+	effects.emailGuest(email.KindPaymentReceipt, booking)
+	settled := *booking
+	settled.Status = StatusSettled
+	effects.broadcast(realtime.ChangeStatus, &settled)
+	outboxEvents, err := effects.Events()
+	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to settle booking"), nil
 	}
 
-	// Send notification
-	if h.notificationService != nil {
-		go func() {
-			ctx := context.Background()
-			// Notify owner
-			property, _ := h.propertyService.GetProperty(ctx, booking.PropertyID)
-			if property != nil && claims.Phone != property.OwnerID {
-				_ = h.notificationService.CreateBookingNotification(
-					ctx,
-					property.OwnerID,
-					notifications.TypeBookingSettled,
-					booking.ID,
-					booking.PropertyID,
-					booking.PropertyName,
-					booking.GuestName,
-				)
-			}
-		}()
+	if err := h.service.SettleBooking(ctx, id, outboxEvents...); err != nil {
+		if errors.Is(err, ErrConcurrentUpdate) {
+			return ErrorResponse(http.StatusConflict, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to settle booking"), nil
 	}
 
 	return APIResponse(http.StatusOK, map[string]string{
@@ -734,6 +830,9 @@ func (h *Handler) HandleCheckAvailability(ctx context.Context, request events.AP
 	if propertyID == "" {
 		return ErrorResponse(http.StatusBadRequest, "Property ID is required"), nil
 	}
+	if !middleware.CanAccessProperty(ctx, propertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
 
 	checkInStr := request.QueryStringParameters["checkIn"]
 	checkOutStr := request.QueryStringParameters["checkOut"]
@@ -752,7 +851,15 @@ func (h *Handler) HandleCheckAvailability(ctx context.Context, request events.AP
 		return ErrorResponse(http.StatusBadRequest, "Invalid checkOut date format. Use YYYY-MM-DD"), nil
 	}
 
-	available, err := h.service.CheckAvailability(ctx, propertyID, checkIn, checkOut, "", "")
+	property, err := h.propertyService.GetProperty(ctx, propertyID)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get property"), nil
+	}
+	if property == nil {
+		return ErrorResponse(http.StatusNotFound, "Property not found"), nil
+	}
+
+	available, err := h.service.CheckAvailability(ctx, property, checkIn, checkOut, "", "")
 	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to check availability"), nil
 	}
@@ -780,13 +887,24 @@ func (h *Handler) HandleGetPropertyCalendar(ctx context.Context, request events.
 	if propertyID == "" {
 		return ErrorResponse(http.StatusBadRequest, "Property ID is required"), nil
 	}
+	if !middleware.CanAccessProperty(ctx, propertyID) {
+		return ErrorResponse(http.StatusForbidden, "You do not have access to this property"), nil
+	}
+
+	property, err := h.propertyService.GetProperty(ctx, propertyID)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get property"), nil
+	}
+	if property == nil {
+		return ErrorResponse(http.StatusNotFound, "Property not found"), nil
+	}
 
 	startDateStr := request.QueryStringParameters["startDate"]
 	endDateStr := request.QueryStringParameters["endDate"]
 
-	// Default to current month if not provided
-	now := time.Now()
-	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	// Default to the property's current local month if not provided
+	today := property.Today(time.Now())
+	startDate := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
 	endDate := startDate.AddDate(0, 1, 0) // End of current month
 
 	if startDateStr != "" {
@@ -806,7 +924,7 @@ func (h *Handler) HandleGetPropertyCalendar(ctx context.Context, request events.
 	}
 
 	bookings, err := h.service.ListBookingsByProperty(ctx, propertyID, &DateRange{
-		Start: startDate.AddDate(0, 0, -90), // Look back 90 days for overlapping bookings
+		Start: startDate.AddDate(0, 0, -StayLookbackDays), // Look back for overlapping bookings
 		End:   endDate,
 	})
 	if err != nil {
@@ -841,27 +959,42 @@ func (h *Handler) HandleGetPropertyCalendar(ctx context.Context, request events.
 		}
 	}
 
+	blocks, err := h.propertyService.ListBlocks(ctx, propertyID, startDate, endDate)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get blocks: "+err.Error()), nil
+	}
+
+	checkInTime, checkOutTime := property.StayTimes()
 	return APIResponse(http.StatusOK, map[string]interface{}{
-		"propertyId": propertyID,
-		"startDate":  startDate.Format("2006-01-02"),
-		"endDate":    endDate.Format("2006-01-02"),
-		"occupied":   occupied,
+		"propertyId":          propertyID,
+		"startDate":           startDate.Format("2006-01-02"),
+		"endDate":             endDate.Format("2006-01-02"),
+		"timeZone":            property.Location().String(),
+		"checkInTime":         checkInTime,
+		"checkOutTime":        checkOutTime,
+		"turnoverBufferHours": property.TurnoverBufferHours,
+		"occupied":            occupied,
+		"blocked":             blocks,
 	}), nil
 }
 
 // canSeeBookingDetails determines if a user is authorized to see guest details for a booking.
 func (h *Handler) canSeeBookingDetails(ctx context.Context, claims *utils.TokenClaims, booking *Booking) bool {
-	if claims.Role == "admin" {
-		return true
-	}
+	return CanSeeGuestDetails(claims, booking)
+}
 
-	// Only the creator of the booking can see guest details.
-	// This hides details from Owners if they didn't create the booking.
-	if booking.BookedBy == claims.Phone {
-		return true
-	}
+// CanSeeGuestDetails reports whether a user may see a booking's guest
+// details. Only admins and the creator of the booking can, which hides them
+// from owners for bookings their agents made.
+func CanSeeGuestDetails(claims *utils.TokenClaims, booking *Booking) bool {
+	return claims.Role == "admin" || booking.BookedBy == claims.Phone
+}
 
-	return false
+// MaskGuestDetails hides the guest's name and contact details.
+func MaskGuestDetails(booking *Booking) {
+	booking.GuestName = "***"
+	booking.GuestPhone = "***"
+	booking.GuestEmail = "***"
 }
 
 // statusToNotificationType converts a booking status to a notification type.
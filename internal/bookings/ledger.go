@@ -0,0 +1,93 @@
+package bookings
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+// ledgerTimeFormat keeps payment keys a fixed width, so they sort by time.
+const ledgerTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// PaymentEntry records a change to the amount paid on a booking, so
+// collections can be reported by the date they were received. Corrections
+// that lower the amount paid are recorded as negative entries.
+type PaymentEntry struct {
+	PK string `dynamodbav:"PK"` // BOOKING#<id>
+	SK string `dynamodbav:"SK"` // PAYMENT#<recordedAt>
+
+	// GSI1 for querying by property and date
+	GSI1PK string `dynamodbav:"GSI1PK"` // PAYMENTS#<propertyId>
+	GSI1SK string `dynamodbav:"GSI1SK"` // DATE#<recordedAt>
+
+	BookingID  string    `dynamodbav:"bookingId" json:"bookingId"`
+	PropertyID string    `dynamodbav:"propertyId" json:"propertyId"`
+	BookedBy   string    `dynamodbav:"bookedBy,omitempty" json:"bookedBy,omitempty"`
+	Amount     float64   `dynamodbav:"amount" json:"amount"`
+	Method     string    `dynamodbav:"method,omitempty" json:"method,omitempty"`
+	RecordedAt time.Time `dynamodbav:"recordedAt" json:"recordedAt"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// paymentEntry returns the ledger entry for a booking changing from before
+// to after, or nil if the amount paid did not change.
+func paymentEntry(before, after *Booking, now time.Time) *PaymentEntry {
+	if after == nil {
+		return nil
+	}
+	amount := after.AdvanceAmount
+	if before != nil {
+		amount -= before.AdvanceAmount
+	}
+	if amount == 0 {
+		return nil
+	}
+
+	recordedAt := now.UTC().Format(ledgerTimeFormat)
+	return &PaymentEntry{
+		PK:         "BOOKING#" + after.ID,
+		SK:         "PAYMENT#" + recordedAt,
+		GSI1PK:     "PAYMENTS#" + after.PropertyID,
+		GSI1SK:     "DATE#" + recordedAt,
+		BookingID:  after.ID,
+		PropertyID: after.PropertyID,
+		BookedBy:   after.BookedBy,
+		Amount:     amount,
+		Method:     after.AdvanceMethod,
+		RecordedAt: now,
+		EntityType: "PAYMENT_ENTRY",
+	}
+}
+
+// ListPaymentEntries returns the payments recorded for a property in
+// [start, end). Payments made before the ledger was introduced are not
+// included.
+func (s *Service) ListPaymentEntries(ctx context.Context, propertyID string, start, end time.Time) ([]*PaymentEntry, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk AND GSI1SK BETWEEN :start AND :end",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "PAYMENTS#" + propertyID,
+			":start":  "DATE#" + start.UTC().Format(ledgerTimeFormat),
+			":end":    "DATE#" + end.UTC().Format(ledgerTimeFormat),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	entries := make([]*PaymentEntry, 0, len(items))
+	for _, item := range items {
+		var entry PaymentEntry
+		if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payment: %w", err)
+		}
+		if entry.RecordedAt.Before(end) {
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, nil
+}
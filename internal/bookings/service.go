@@ -3,11 +3,16 @@ package bookings
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/realtime"
 	"github.com/google/uuid"
 )
 
@@ -30,6 +35,53 @@ func (s BookingStatus) IsValid() bool {
 	return false
 }
 
+// BookingSource is the channel a booking came through.
+type BookingSource string
+
+const (
+	SourceDirect   BookingSource = "direct"  // Made by the owner or an admin
+	SourceAgent    BookingSource = "agent"   // Made by an agent
+	SourceInvite   BookingSource = "invite"  // Made with a property invite code
+	SourcePhone    BookingSource = "phone"   // Enquiry by phone
+	SourceWalkIn   BookingSource = "walk_in" // Guest arrived without a booking
+	SourceOTA      BookingSource = "ota"     // Online travel agency
+	SourceReferral BookingSource = "referral"
+	SourceOther    BookingSource = "other"
+
+	// SourceUnknown is reported for bookings made before sources were recorded.
+	SourceUnknown BookingSource = "unknown"
+)
+
+// IsValid checks if the booking source can be set on a booking.
+func (s BookingSource) IsValid() bool {
+	switch s {
+	case SourceDirect, SourceAgent, SourceInvite, SourcePhone, SourceWalkIn, SourceOTA, SourceReferral, SourceOther:
+		return true
+	}
+	return false
+}
+
+// CancellationReason records why a booking was cancelled.
+type CancellationReason string
+
+const (
+	ReasonGuestRequest        CancellationReason = "guest_request"
+	ReasonPlansChanged        CancellationReason = "plans_changed"
+	ReasonPaymentNotReceived  CancellationReason = "payment_not_received"
+	ReasonDoubleBooking       CancellationReason = "double_booking"
+	ReasonPropertyUnavailable CancellationReason = "property_unavailable"
+	ReasonOther               CancellationReason = "other"
+)
+
+// IsValid checks if the cancellation reason is valid.
+func (r CancellationReason) IsValid() bool {
+	switch r {
+	case ReasonGuestRequest, ReasonPlansChanged, ReasonPaymentNotReceived, ReasonDoubleBooking, ReasonPropertyUnavailable, ReasonOther:
+		return true
+	}
+	return false
+}
+
 // Booking represents a property booking.
 type Booking struct {
 	// DynamoDB keys
@@ -67,12 +119,15 @@ type Booking struct {
 	Currency        string  `dynamodbav:"currency" json:"currency"`
 
 	// Status
-	Status BookingStatus `dynamodbav:"status" json:"status"`
+	Status             BookingStatus      `dynamodbav:"status" json:"status"`
+	CancellationReason CancellationReason `dynamodbav:"cancellationReason,omitempty" json:"cancellationReason,omitempty"`
+	CancelledAt        *time.Time         `dynamodbav:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`
 
 	// Agent/booking source
-	BookedBy     string `dynamodbav:"bookedBy" json:"bookedBy"` // Phone of agent who made booking
-	BookedByName string `dynamodbav:"bookedByName,omitempty" json:"bookedByName,omitempty"`
-	InviteCode   string `dynamodbav:"inviteCode,omitempty" json:"inviteCode,omitempty"`
+	BookedBy     string        `dynamodbav:"bookedBy" json:"bookedBy"` // Phone of agent who made booking
+	BookedByName string        `dynamodbav:"bookedByName,omitempty" json:"bookedByName,omitempty"`
+	InviteCode   string        `dynamodbav:"inviteCode,omitempty" json:"inviteCode,omitempty"`
+	Source       BookingSource `dynamodbav:"source,omitempty" json:"source,omitempty"`
 
 	// Notes
 	Notes           string `dynamodbav:"notes,omitempty" json:"notes,omitempty"`
@@ -81,21 +136,47 @@ type Booking struct {
 	// Metadata
 	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
 	UpdatedAt  time.Time `dynamodbav:"updatedAt" json:"updatedAt"`
+	Version    int       `dynamodbav:"version" json:"version"` // Incremented on every write
 	EntityType string    `dynamodbav:"entityType" json:"-"`
 }
 
+// SourceOf returns the channel a booking came through. Bookings made before
+// sources were recorded are attributed to their invite code if they have
+// one, and are otherwise unknown.
+func SourceOf(booking *Booking) BookingSource {
+	switch {
+	case booking.Source != "":
+		return booking.Source
+	case booking.InviteCode != "":
+		return SourceInvite
+	default:
+		return SourceUnknown
+	}
+}
+
 // Service provides booking-related operations.
 type Service struct {
-	db *db.Client
+	db              *db.Client
+	propertyService *properties.Service
+	immediate       *outbox.Service
 }
 
 // NewService creates a new booking service.
+// Calendar updates for connected clients are published as soon as a change
+// commits; the outbox worker retries those that fail.
 func NewService(dbClient *db.Client) *Service {
-	return &Service{db: dbClient}
+	immediate := outbox.NewService(dbClient)
+	immediate.Register(realtime.EventPublish, realtime.NewService(dbClient).HandlePublishEvent)
+
+	return &Service{
+		db:              dbClient,
+		propertyService: properties.NewService(dbClient),
+		immediate:       immediate,
+	}
 }
 
-// CreateBooking creates a new booking.
-func (s *Service) CreateBooking(ctx context.Context, booking *Booking) error {
+// CreateBooking creates a new booking, together with outbox events for its side effects.
+func (s *Service) CreateBooking(ctx context.Context, booking *Booking, events ...*outbox.Event) error {
 	if booking.ID == "" {
 		booking.ID = uuid.New().String()
 	}
@@ -129,7 +210,77 @@ func (s *Service) CreateBooking(ctx context.Context, booking *Booking) error {
 		booking.Currency = "INR"
 	}
 
-	return s.db.PutItem(ctx, booking)
+	return s.write(ctx, putChange(nil, booking), nil, booking, events)
+}
+
+// ErrConcurrentUpdate is returned when a booking changed after it was read
+// for an update, or keeps changing while a change to it is being written.
+var ErrConcurrentUpdate = errors.New("booking was changed by another request, please retry")
+
+// maxWriteAttempts is how many times a booking change is tried when the
+// booking changes between being read and written.
+const maxWriteAttempts = 3
+
+// retryOnConflict runs attempt again while it fails because the booking
+// changed after attempt read it.
+func retryOnConflict(attempt func() error) error {
+	for i := 0; i < maxWriteAttempts; i++ {
+		err := attempt()
+		if err == nil || !db.IsConditionalCheckFailed(err) {
+			return err
+		}
+	}
+	return ErrConcurrentUpdate
+}
+
+// versionCondition returns the condition that the stored booking is still
+// the one before was read as. Without it, two concurrent changes would both
+// subtract the same before from the rollups and the payment ledger.
+func versionCondition(before *Booking) (string, map[string]interface{}) {
+	switch {
+	case before == nil:
+		return "attribute_not_exists(PK)", nil
+	case before.Version == 0:
+		// Stored before bookings were versioned
+		return "attribute_exists(PK) AND attribute_not_exists(version)", nil
+	default:
+		return "version = :prevVersion", map[string]interface{}{":prevVersion": before.Version}
+	}
+}
+
+// putChange returns the put that replaces before with after, bumping the
+// version and failing if the booking changed since before was read.
+func putChange(before, after *Booking) db.TransactItem {
+	after.Version = 1
+	if before != nil {
+		after.Version = before.Version + 1
+	}
+	condition, values := versionCondition(before)
+	return db.TransactPutWithValues(after, condition, values)
+}
+
+// write applies a booking change together with the matching rollup updates,
+// payment ledger entry and its outbox events, so the side effects are
+// recorded if and only if the change is. before and after are the booking as
+// stored before and after the change. Once it commits, calendar updates are
+// published straight away rather than on the worker's next run.
+func (s *Service) write(ctx context.Context, change db.TransactItem, before, after *Booking, events []*outbox.Event) error {
+	rollups := rollupChanges(before, after)
+	items := make([]db.TransactItem, 0, len(rollups)+len(events)+2)
+	items = append(items, change)
+	items = append(items, rollups...)
+	if entry := paymentEntry(before, after, time.Now()); entry != nil {
+		items = append(items, db.TransactPut(entry, ""))
+	}
+	for _, event := range events {
+		items = append(items, event.TransactItem())
+	}
+	if err := s.db.TransactWrite(ctx, items...); err != nil {
+		return err
+	}
+
+	s.immediate.ProcessNow(ctx, events...)
+	return nil
 }
 
 // GetBooking retrieves a booking by ID.
@@ -149,37 +300,106 @@ func (s *Service) GetBooking(ctx context.Context, id string) (*Booking, error) {
 	return &booking, nil
 }
 
-// UpdateBooking updates an existing booking.
-func (s *Service) UpdateBooking(ctx context.Context, booking *Booking) error {
+// UpdateBooking updates an existing booking, together with outbox events for its side effects.
+// The booking is stored as given, but only if the stored booking is still at
+// booking.Version, the version the caller read and changed. Otherwise it
+// returns ErrConcurrentUpdate rather than overwriting the other change.
+func (s *Service) UpdateBooking(ctx context.Context, booking *Booking, events ...*outbox.Event) error {
+	before, err := s.GetBooking(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.Version != booking.Version {
+		return ErrConcurrentUpdate
+	}
+
 	booking.UpdatedAt = time.Now()
 	booking.PK = "BOOKING#" + booking.ID
 	booking.SK = "METADATA"
 	// Ensure GSI keys are updated in case PropertyID or CheckIn changed
 	booking.GSI1PK = "PROPERTY#" + booking.PropertyID
 	booking.GSI1SK = "DATE#" + booking.CheckIn.Format("2006-01-02")
-	return s.db.PutItem(ctx, booking)
+	err = s.write(ctx, putChange(before, booking), before, booking, events)
+	if db.IsConditionalCheckFailed(err) {
+		return ErrConcurrentUpdate
+	}
+	return err
 }
 
-// UpdateBookingStatus updates only the status of a booking.
-func (s *Service) UpdateBookingStatus(ctx context.Context, id string, status BookingStatus) error {
-	pk := "BOOKING#" + id
-	sk := "METADATA"
-	now := time.Now().Format(time.RFC3339)
+// UpdateBookingStatus updates only the status of a booking, together with
+// outbox events for its side effects. A reason given when cancelling is
+// recorded with the time of cancellation, and both are cleared if the
+// booking is reinstated.
+func (s *Service) UpdateBookingStatus(ctx context.Context, id string, status BookingStatus, reason CancellationReason, events ...*outbox.Event) error {
+	return retryOnConflict(func() error {
+		before, err := s.GetBooking(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return fmt.Errorf("booking not found")
+		}
+		after := withStatus(before, status, reason)
+		after.Version = before.Version + 1
+
+		pk := "BOOKING#" + id
+		sk := "METADATA"
+		now := time.Now().Format(time.RFC3339)
+
+		condition, values := versionCondition(before)
+		params := db.UpdateParams{
+			UpdateExpression:    "SET #status = :status, updatedAt = :updatedAt, version = :version REMOVE cancellationReason, cancelledAt",
+			ConditionExpression: condition,
+			ExpressionValues: map[string]interface{}{
+				":status":    string(status),
+				":updatedAt": now,
+				":version":   after.Version,
+			},
+			ExpressionAttributeNames: map[string]string{
+				"#status": "status",
+			},
+		}
+		for name, value := range values {
+			params.ExpressionValues[name] = value
+		}
+		if status == StatusCancelled {
+			params.UpdateExpression = "SET #status = :status, updatedAt = :updatedAt, version = :version, cancelledAt = :cancelledAt"
+			params.ExpressionValues[":cancelledAt"] = after.CancelledAt.Format(time.RFC3339)
+			if reason != "" {
+				params.UpdateExpression += ", cancellationReason = :reason"
+				params.ExpressionValues[":reason"] = string(reason)
+			}
+		}
 
-	params := db.UpdateParams{
-		UpdateExpression: "SET #status = :status, updatedAt = :updatedAt",
-		ExpressionValues: map[string]interface{}{
-			":status":    string(status),
-			":updatedAt": now,
-		},
-		ExpressionAttributeNames: map[string]string{
-			"#status": "status",
-		},
-	}
+		return s.write(ctx, db.TransactUpdate(pk, sk, params), before, after, events)
+	})
+}
 
-	return s.db.UpdateItem(ctx, pk, sk, params)
+// withStatus returns a copy of booking with its status changed, as
+// UpdateBookingStatus stores it. A booking that is already cancelled keeps
+// the time it was first cancelled.
+func withStatus(booking *Booking, status BookingStatus, reason CancellationReason) *Booking {
+	changed := *booking
+	changed.Status = status
+	if status != StatusCancelled {
+		changed.CancellationReason = ""
+		changed.CancelledAt = nil
+		return &changed
+	}
+	if reason != "" {
+		changed.CancellationReason = reason
+	}
+	if changed.CancelledAt == nil {
+		now := time.Now().UTC()
+		changed.CancelledAt = &now
+	}
+	return &changed
 }
 
+// StayLookbackDays is how far before a date bookings are searched for stays
+// that check in earlier and are still running on it.
+const StayLookbackDays = 90
+
 // DateRange represents a date range for queries.
 type DateRange struct {
 	Start time.Time
@@ -223,6 +443,93 @@ func (s *Service) ListBookingsByProperty(ctx context.Context, propertyID string,
 	return bookings, nil
 }
 
+// PageBookingsByProperty calls fn with each page of a property's bookings,
+// checking in within dateRange if it is not nil, so large result sets can be
+// processed without holding them all in memory.
+func (s *Service) PageBookingsByProperty(ctx context.Context, propertyID string, dateRange *DateRange, fn func(page []*Booking) error) error {
+	keyCondition := "GSI1PK = :gsi1pk AND begins_with(GSI1SK, :prefix)"
+	expressionValues := map[string]interface{}{
+		":gsi1pk": "PROPERTY#" + propertyID,
+		":prefix": "DATE#",
+	}
+	if dateRange != nil {
+		keyCondition = "GSI1PK = :gsi1pk AND GSI1SK BETWEEN :startDate AND :endDate"
+		expressionValues = map[string]interface{}{
+			":gsi1pk":    "PROPERTY#" + propertyID,
+			":startDate": "DATE#" + dateRange.Start.Format("2006-01-02"),
+			":endDate":   "DATE#" + dateRange.End.Format("2006-01-02"),
+		}
+	}
+
+	params := db.QueryParams{
+		IndexName:        "GSI1",
+		KeyCondition:     keyCondition,
+		ExpressionValues: expressionValues,
+	}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		items, next, err := s.db.QueryPage(ctx, params, startKey)
+		if err != nil {
+			return fmt.Errorf("failed to list bookings: %w", err)
+		}
+		page, err := unmarshalBookings(items)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		startKey = next
+	}
+}
+
+// PageAllBookings calls fn with each page of bookings in the table. It scans
+// the whole table, so it is only meant for admin exports and maintenance.
+func (s *Service) PageAllBookings(ctx context.Context, fn func(page []*Booking) error) error {
+	params := db.ScanParams{
+		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk",
+		ExpressionValues: map[string]interface{}{
+			":prefix": "BOOKING#",
+			":sk":     "METADATA",
+		},
+	}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		items, next, err := s.db.ScanPage(ctx, params, startKey)
+		if err != nil {
+			return fmt.Errorf("failed to scan bookings: %w", err)
+		}
+		page, err := unmarshalBookings(items)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		startKey = next
+	}
+}
+
+func unmarshalBookings(items []map[string]types.AttributeValue) ([]*Booking, error) {
+	bookings := make([]*Booking, 0, len(items))
+	for _, item := range items {
+		var booking Booking
+		if err := attributevalue.UnmarshalMap(item, &booking); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal booking: %w", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+	return bookings, nil
+}
+
 // ListBookingsByAgent retrieves bookings made by a specific agent.
 func (s *Service) ListBookingsByAgent(ctx context.Context, agentPhone string) ([]*Booking, error) {
 	// Note: This would benefit from a GSI on bookedBy
@@ -235,37 +542,48 @@ func (s *Service) ListBookingsByAgent(ctx context.Context, agentPhone string) ([
 }
 
 // CheckAvailability checks if a property is available for the given dates.
-func (s *Service) CheckAvailability(ctx context.Context, propertyID string, checkIn, checkOut time.Time, checkInTime, checkOutTime string) (bool, error) {
+// A stay runs from its check-in time to its check-out time in the property's
+// time zone, with empty times falling back to the property's defaults, and
+// the property's turnover buffer must be left free between stays. None of
+// the nights may be blocked by the owner.
+func (s *Service) CheckAvailability(ctx context.Context, property *properties.Property, checkIn, checkOut time.Time, checkInTime, checkOutTime string) (bool, error) {
+	buffer := property.TurnoverBuffer()
+
+	// Blocked nights cannot be booked whatever the times
+	blocks, err := s.propertyService.ListBlocks(ctx, property.ID, checkIn, checkOut)
+	if err != nil {
+		return false, err
+	}
+	if len(blocks) > 0 {
+		return false, nil
+	}
+
 	// Get all bookings for the property in the date range
-	// Look back 90 days to ensure we catch long bookings that started earlier but overlap with this range
+	// Look back to ensure we catch long bookings that started earlier but overlap with this range
 	dateRange := &DateRange{
-		Start: checkIn.AddDate(0, 0, -90),
-		End:   checkOut.AddDate(0, 0, 1), // Include day after to catch overlaps
+		Start: checkIn.AddDate(0, 0, -StayLookbackDays),
+		End:   checkOut.Add(buffer).AddDate(0, 0, 1), // Include day after to catch overlaps
 	}
 
-	bookings, err := s.ListBookingsByProperty(ctx, propertyID, dateRange)
+	bookings, err := s.ListBookingsByProperty(ctx, property.ID, dateRange)
 	if err != nil {
 		return false, err
 	}
 
-	// Helper to parse time string "15:04" to minutes from midnight
-	timeToMinutes := func(tStr string, defaultMinutes int) int {
-		if tStr == "" {
-			return defaultMinutes
-		}
-		t, err := time.Parse("15:04", tStr)
+	location := property.Location()
+	defaultCheckIn, defaultCheckOut := property.StayTimes()
+
+	// Helper to combine a booking date with a "15:04" local time
+	at := func(date time.Time, clock, defaultClock string) time.Time {
+		t, err := time.Parse("15:04", clock)
 		if err != nil {
-			return defaultMinutes
+			t, _ = time.Parse("15:04", defaultClock)
 		}
-		return t.Hour()*60 + t.Minute()
+		return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, location)
 	}
 
-	// Defaults
-	defaultCheckInMinutes := 14 * 60  // 14:00
-	defaultCheckOutMinutes := 11 * 60 // 11:00
-
-	newCheckInMins := timeToMinutes(checkInTime, defaultCheckInMinutes)
-	newCheckOutMins := timeToMinutes(checkOutTime, defaultCheckOutMinutes)
+	start := at(checkIn, checkInTime, defaultCheckIn)
+	end := at(checkOut, checkOutTime, defaultCheckOut)
 
 	// Check for overlapping bookings
 	for _, booking := range bookings {
@@ -274,35 +592,12 @@ func (s *Service) CheckAvailability(ctx context.Context, propertyID string, chec
 			continue
 		}
 
-		// Dates overlap check
-		// Standard date overlap (inclusive of boundaries for time check):
-		// (StartA <= EndB) and (EndA >= StartB)
-		// Using !After and !Before handles equality correctly
-		if !checkIn.After(booking.CheckOut) && !checkOut.Before(booking.CheckIn) {
-			// This is a date overlap. Now check if it's just a "touch" (same day turnover)
-			// Case 1: New CheckIn matches Existing CheckOut
-			if checkIn.Equal(booking.CheckOut) {
-				// We are checking in on the day they check out.
-				// Check times. New CheckIn must be >= Existing CheckOut
-				existingCheckOutMins := timeToMinutes(booking.CheckOutTime, defaultCheckOutMinutes)
-				if newCheckInMins < existingCheckOutMins {
-					return false, nil // Conflict: Checking in before they leave
-				}
-				continue // No conflict on this edge
-			}
-
-			// Case 2: New CheckOut matches Existing CheckIn
-			if checkOut.Equal(booking.CheckIn) {
-				// We are checking out on the day they check in.
-				// Check times. New CheckOut must be <= Existing CheckIn
-				existingCheckInMins := timeToMinutes(booking.CheckInTime, defaultCheckInMinutes)
-				if newCheckOutMins > existingCheckInMins {
-					return false, nil // Conflict: Leaving after they arrive
-				}
-				continue // No conflict on this edge
-			}
+		existingStart := at(booking.CheckIn, booking.CheckInTime, defaultCheckIn)
+		existingEnd := at(booking.CheckOut, booking.CheckOutTime, defaultCheckOut)
 
-			// If it's not a border case (touching dates), it's a full day overlap
+		// Each stay must end, plus the buffer, before the other one starts.
+		// Same-day turnovers are fine when the times and buffer allow them.
+		if start.Before(existingEnd.Add(buffer)) && existingStart.Before(end.Add(buffer)) {
 			return false, nil
 		}
 	}
@@ -310,29 +605,113 @@ func (s *Service) CheckAvailability(ctx context.Context, propertyID string, chec
 	return true, nil
 }
 
+// StayTimesBackfillResult summarises a BackfillStayTimes run.
+type StayTimesBackfillResult struct {
+	Bookings int // Bookings missing a check-in or check-out time
+	Updated  int // Bookings given their property's times
+	Skipped  int // Bookings whose property is gone, or that changed meanwhile
+}
+
+// BackfillStayTimes gives bookings stored without a check-in or check-out
+// time their property's default times, so they no longer depend on the
+// defaults in force when they are read. Only the times and version change;
+// updatedAt is kept, as it dates changes in accounting exports.
+func (s *Service) BackfillStayTimes(ctx context.Context) (*StayTimesBackfillResult, error) {
+	result := &StayTimesBackfillResult{}
+
+	items, err := s.db.ScanAll(ctx, db.ScanParams{
+		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk AND " +
+			"(attribute_not_exists(checkInTime) OR checkInTime = :empty OR attribute_not_exists(checkOutTime) OR checkOutTime = :empty)",
+		ExpressionValues: map[string]interface{}{
+			":prefix": "BOOKING#",
+			":sk":     "METADATA",
+			":empty":  "",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan bookings: %w", err)
+	}
+
+	props := make(map[string]*properties.Property)
+	for _, item := range items {
+		var booking Booking
+		if err := attributevalue.UnmarshalMap(item, &booking); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal booking: %w", err)
+		}
+		result.Bookings++
+
+		property, ok := props[booking.PropertyID]
+		if !ok {
+			if property, err = s.propertyService.GetProperty(ctx, booking.PropertyID); err != nil {
+				return nil, err
+			}
+			props[booking.PropertyID] = property
+		}
+		if property == nil {
+			result.Skipped++
+			continue
+		}
+
+		checkIn, checkOut := property.StayTimes()
+		if booking.CheckInTime != "" {
+			checkIn = booking.CheckInTime
+		}
+		if booking.CheckOutTime != "" {
+			checkOut = booking.CheckOutTime
+		}
+
+		condition, values := versionCondition(&booking)
+		params := db.UpdateParams{
+			UpdateExpression:    "SET checkInTime = :checkInTime, checkOutTime = :checkOutTime, version = :version",
+			ConditionExpression: condition,
+			ExpressionValues: map[string]interface{}{
+				":checkInTime":  checkIn,
+				":checkOutTime": checkOut,
+				":version":      booking.Version + 1,
+			},
+		}
+		for name, value := range values {
+			params.ExpressionValues[name] = value
+		}
+		if err := s.db.UpdateItem(ctx, booking.PK, booking.SK, params); err != nil {
+			if db.IsConditionalCheckFailed(err) {
+				result.Skipped++
+				continue
+			}
+			return nil, fmt.Errorf("failed to update booking %s: %w", booking.ID, err)
+		}
+		result.Updated++
+	}
+
+	return result, nil
+}
+
 // CancelBooking cancels a booking.
 func (s *Service) CancelBooking(ctx context.Context, id string) error {
-	return s.UpdateBookingStatus(ctx, id, StatusCancelled)
+	return s.UpdateBookingStatus(ctx, id, StatusCancelled, "")
 }
 
 // ConfirmBooking marks a booking as settled.
 func (s *Service) ConfirmBooking(ctx context.Context, id string) error {
-	return s.UpdateBookingStatus(ctx, id, StatusSettled)
+	return s.UpdateBookingStatus(ctx, id, StatusSettled, "")
 }
 
 // SettleBooking sets the advance amount to the total amount and marks the booking as settled.
-func (s *Service) SettleBooking(ctx context.Context, id string) error {
-	booking, err := s.GetBooking(ctx, id)
-	if err != nil {
-		return err
-	}
-	if booking == nil {
-		return fmt.Errorf("booking not found")
-	}
+func (s *Service) SettleBooking(ctx context.Context, id string, events ...*outbox.Event) error {
+	return retryOnConflict(func() error {
+		booking, err := s.GetBooking(ctx, id)
+		if err != nil {
+			return err
+		}
+		if booking == nil {
+			return fmt.Errorf("booking not found")
+		}
 
-	booking.AdvanceAmount = booking.TotalAmount
-	booking.Status = StatusSettled
-	booking.UpdatedAt = time.Now()
+		before := *booking
+		booking.AdvanceAmount = booking.TotalAmount
+		booking.Status = StatusSettled
+		booking.UpdatedAt = time.Now()
 
-	return s.db.PutItem(ctx, booking)
+		return s.write(ctx, putChange(&before, booking), &before, booking, events)
+	})
 }
@@ -0,0 +1,122 @@
+package bookings
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db/dbtest"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/realtime"
+)
+
+func TestCreateBookingPublishesCalendarChange(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+
+	posted := make(chan string, 10)
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- path.Base(r.URL.Path)
+	}))
+	defer ws.Close()
+	t.Setenv("WEBSOCKET_ENDPOINT", ws.URL)
+
+	topic := realtime.PropertyTopic("villa-1")
+	table.Put(t, &realtime.Subscription{
+		PK:           "WSCONN#conn-1",
+		SK:           "SUB#" + topic,
+		GSI1PK:       "WSSUB#" + topic,
+		GSI1SK:       "CONN#conn-1",
+		ConnectionID: "conn-1",
+		Topic:        topic,
+		EntityType:   "WS_SUBSCRIPTION",
+	})
+
+	booking := &Booking{
+		ID:          "booking-1",
+		PropertyID:  "villa-1",
+		GuestName:   "Asha",
+		CheckIn:     time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+		CheckOut:    time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC),
+		TotalAmount: 10000,
+	}
+	var effects sideEffects
+	effects.broadcast(realtime.ChangeCreated, booking)
+	events, err := effects.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewService(client).CreateBooking(ctx, booking, events...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Published before CreateBooking returns, without waiting for the worker
+	if len(posted) != 1 {
+		t.Fatalf("posted %d messages, want 1", len(posted))
+	}
+	if conn := <-posted; conn != "conn-1" {
+		t.Errorf("posted to %s, want conn-1", conn)
+	}
+	items := table.Items("OUTBOX#")
+	if len(items) != 1 {
+		t.Fatalf("%d outbox events stored, want 1", len(items))
+	}
+	var event outbox.Event
+	if err := attributevalue.UnmarshalMap(items[0], &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != outbox.StatusProcessed {
+		t.Errorf("outbox event is %s, want processed so the worker does not publish it again", event.Status)
+	}
+}
+
+func TestUpdateBookingRejectsStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+	service := NewService(client)
+
+	booking := &Booking{
+		ID:          "booking-1",
+		PropertyID:  "villa-1",
+		GuestName:   "Asha",
+		CheckIn:     time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+		CheckOut:    time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC),
+		TotalAmount: 10000,
+	}
+	if err := service.CreateBooking(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two requests read the booking at the same version
+	first, err := service.GetBooking(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := service.GetBooking(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.GuestName = "Asha Rao"
+	if err := service.UpdateBooking(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	second.TotalAmount = 15000
+	if err := service.UpdateBooking(ctx, second); !errors.Is(err, ErrConcurrentUpdate) {
+		t.Fatalf("UpdateBooking() with a stale version = %v, want ErrConcurrentUpdate", err)
+	}
+
+	var stored Booking
+	if !table.Get(t, "BOOKING#booking-1", "METADATA", &stored) {
+		t.Fatal("booking not stored")
+	}
+	if stored.GuestName != "Asha Rao" || stored.TotalAmount != 10000 || stored.Version != 2 {
+		t.Errorf("stored booking = %q, %v, version %d; want the first update only", stored.GuestName, stored.TotalAmount, stored.Version)
+	}
+}
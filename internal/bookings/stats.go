@@ -0,0 +1,485 @@
+package bookings
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+// Rollups are pre-aggregated booking totals kept in step with every booking
+// write, so analytics do not have to read every booking. Bookings count
+// towards the day and month of their check-in date:
+//
+//	STATS#PROPERTY#<propertyId>  DAY#<yyyy-mm-dd>
+//	STATS#PROPERTY#<propertyId>  MONTH#<yyyy-mm>
+//	STATS#AGENT#<phone>          DAY#<yyyy-mm-dd>#PROPERTY#<propertyId>
+//	STATS#AGENT#<phone>          MONTH#<yyyy-mm>#PROPERTY#<propertyId>
+//
+// Check-outs are also counted on the property's check-out day.
+const (
+	rollupPropertyPrefix = "STATS#PROPERTY#"
+	rollupAgentPrefix    = "STATS#AGENT#"
+)
+
+// Rollup holds booking totals for one property, or one agent's bookings at a
+// property, over a day or a month.
+type Rollup struct {
+	PK         string `dynamodbav:"PK"`
+	SK         string `dynamodbav:"SK"`
+	PropertyID string `dynamodbav:"propertyId" json:"propertyId"`
+	Period     string `dynamodbav:"period" json:"period"` // yyyy-mm-dd or yyyy-mm
+
+	Bookings   int     `dynamodbav:"bookings" json:"bookings"`
+	Revenue    float64 `dynamodbav:"revenue" json:"revenue"`
+	Collected  float64 `dynamodbav:"collected" json:"collected"`
+	Commission float64 `dynamodbav:"commission" json:"commission"`
+	Nights     int     `dynamodbav:"nights" json:"nights"`
+	CheckOuts  int     `dynamodbav:"checkOuts" json:"checkOuts"`
+
+	// Revenue and commission of cancelled bookings, included in the totals
+	// above
+	CancelledRevenue    float64 `dynamodbav:"cancelledRevenue" json:"cancelledRevenue"`
+	CancelledCommission float64 `dynamodbav:"cancelledCommission" json:"cancelledCommission"`
+
+	// Balances still owed on bookings that are not cancelled
+	Unpaid int     `dynamodbav:"unpaid" json:"unpaid"`
+	Due    float64 `dynamodbav:"due" json:"due"`
+
+	// Bookings by status
+	StatusPending   int `dynamodbav:"statusPending" json:"statusPending"`
+	StatusPartial   int `dynamodbav:"statusPartial" json:"statusPartial"`
+	StatusSettled   int `dynamodbav:"statusSettled" json:"statusSettled"`
+	StatusCancelled int `dynamodbav:"statusCancelled" json:"statusCancelled"`
+
+	// Bookings by payment status
+	PaymentPending int `dynamodbav:"paymentPending" json:"paymentPending"`
+	PaymentPartial int `dynamodbav:"paymentPartial" json:"paymentPartial"`
+	PaymentSettled int `dynamodbav:"paymentSettled" json:"paymentSettled"`
+
+	EntityType string `dynamodbav:"entityType" json:"-"`
+}
+
+// Add adds other's totals to r.
+func (r *Rollup) Add(other *Rollup) {
+	r.Bookings += other.Bookings
+	r.Revenue += other.Revenue
+	r.Collected += other.Collected
+	r.Commission += other.Commission
+	r.Nights += other.Nights
+	r.CheckOuts += other.CheckOuts
+	r.CancelledRevenue += other.CancelledRevenue
+	r.CancelledCommission += other.CancelledCommission
+	r.Unpaid += other.Unpaid
+	r.Due += other.Due
+	r.StatusPending += other.StatusPending
+	r.StatusPartial += other.StatusPartial
+	r.StatusSettled += other.StatusSettled
+	r.StatusCancelled += other.StatusCancelled
+	r.PaymentPending += other.PaymentPending
+	r.PaymentPartial += other.PaymentPartial
+	r.PaymentSettled += other.PaymentSettled
+}
+
+// NetProfit returns the revenue of bookings that are not cancelled, less
+// their agent commission and the given expenses.
+func (r *Rollup) NetProfit(expenses float64) float64 {
+	return (r.Revenue - r.CancelledRevenue) - (r.Commission - r.CancelledCommission) - expenses
+}
+
+// BookingsByStatus returns the number of bookings in each status, omitting
+// statuses with none.
+func (r *Rollup) BookingsByStatus() map[string]int {
+	return nonZero(map[string]int{
+		string(StatusPending):   r.StatusPending,
+		string(StatusPartial):   r.StatusPartial,
+		string(StatusSettled):   r.StatusSettled,
+		string(StatusCancelled): r.StatusCancelled,
+	})
+}
+
+// PaymentsByStatus returns the number of bookings in each payment status,
+// omitting statuses with none.
+func (r *Rollup) PaymentsByStatus() map[string]int {
+	return nonZero(map[string]int{
+		paymentPending: r.PaymentPending,
+		paymentPartial: r.PaymentPartial,
+		paymentSettled: r.PaymentSettled,
+	})
+}
+
+func nonZero(counts map[string]int) map[string]int {
+	for key, count := range counts {
+		if count == 0 {
+			delete(counts, key)
+		}
+	}
+	return counts
+}
+
+// Payment statuses, as computed by payments.Summarize. That package depends
+// on this one, so the rule is repeated here.
+const (
+	paymentPending = "pending"
+	paymentPartial = "partial"
+	paymentSettled = "settled"
+)
+
+// paymentStatus returns the payment status and balance due of a booking.
+func paymentStatus(booking *Booking) (string, float64) {
+	switch {
+	case booking.AdvanceAmount <= 0:
+		return paymentPending, booking.TotalAmount - booking.AdvanceAmount
+	case booking.AdvanceAmount >= booking.TotalAmount:
+		return paymentSettled, 0
+	default:
+		return paymentPartial, booking.TotalAmount - booking.AdvanceAmount
+	}
+}
+
+// rollupKey identifies a rollup item.
+type rollupKey struct {
+	pk, sk string
+}
+
+// rollupDelta is a pending change to one rollup item.
+type rollupDelta struct {
+	propertyID string
+	period     string
+	counters   map[string]float64
+}
+
+// rollupDeltas collects the changes to rollup items for a set of bookings.
+type rollupDeltas map[rollupKey]*rollupDelta
+
+// add applies a booking's contribution to the rollups, multiplied by sign:
+// 1 to add the booking, -1 to remove it.
+func (d rollupDeltas) add(booking *Booking, sign float64) {
+	if booking == nil || booking.PropertyID == "" {
+		return
+	}
+
+	payment, due := paymentStatus(booking)
+	counters := map[string]float64{
+		"bookings":                 1,
+		"revenue":                  booking.TotalAmount,
+		"collected":                booking.AdvanceAmount,
+		"commission":               booking.AgentCommission,
+		"nights":                   float64(booking.NumNights),
+		"payment" + title(payment): 1,
+	}
+	if booking.Status.IsValid() {
+		counters["status"+title(string(booking.Status))] = 1
+	}
+	if booking.Status == StatusCancelled {
+		counters["cancelledRevenue"] = booking.TotalAmount
+		counters["cancelledCommission"] = booking.AgentCommission
+	} else if payment != paymentSettled {
+		counters["unpaid"] = 1
+		counters["due"] = due
+	}
+
+	day := booking.CheckIn.Format("2006-01-02")
+	month := booking.CheckIn.Format("2006-01")
+	propertyPK := rollupPropertyPrefix + booking.PropertyID
+
+	d.apply(rollupKey{propertyPK, "DAY#" + day}, booking.PropertyID, day, counters, sign)
+	d.apply(rollupKey{propertyPK, "MONTH#" + month}, booking.PropertyID, month, counters, sign)
+
+	if booking.BookedBy != "" {
+		agentPK := rollupAgentPrefix + booking.BookedBy
+		suffix := "#PROPERTY#" + booking.PropertyID
+		d.apply(rollupKey{agentPK, "DAY#" + day + suffix}, booking.PropertyID, day, counters, sign)
+		d.apply(rollupKey{agentPK, "MONTH#" + month + suffix}, booking.PropertyID, month, counters, sign)
+	}
+
+	checkOut := booking.CheckOut.Format("2006-01-02")
+	d.apply(rollupKey{propertyPK, "DAY#" + checkOut}, booking.PropertyID, checkOut,
+		map[string]float64{"checkOuts": 1}, sign)
+}
+
+func (d rollupDeltas) apply(key rollupKey, propertyID, period string, counters map[string]float64, sign float64) {
+	delta, ok := d[key]
+	if !ok {
+		delta = &rollupDelta{propertyID: propertyID, period: period, counters: make(map[string]float64)}
+		d[key] = delta
+	}
+	for name, value := range counters {
+		delta.counters[name] += sign * value
+	}
+}
+
+// title capitalises the first letter of a status for use in an attribute name.
+func title(status string) string {
+	if status == "" {
+		return ""
+	}
+	return strings.ToUpper(status[:1]) + status[1:]
+}
+
+// items converts the deltas into rollup updates. Rollups that do not change,
+// such as for an edit to a booking's notes, are skipped.
+func (d rollupDeltas) items() []db.TransactItem {
+	keys := make([]rollupKey, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pk != keys[j].pk {
+			return keys[i].pk < keys[j].pk
+		}
+		return keys[i].sk < keys[j].sk
+	})
+
+	var items []db.TransactItem
+	for _, key := range keys {
+		if params, ok := d[key].updateParams(); ok {
+			items = append(items, db.TransactUpdate(key.pk, key.sk, params))
+		}
+	}
+	return items
+}
+
+// updateParams builds the update that adds the delta's counters to a rollup,
+// creating it if needed. Returns false if no counter changes.
+func (delta *rollupDelta) updateParams() (db.UpdateParams, bool) {
+	names := make([]string, 0, len(delta.counters))
+	for name, value := range delta.counters {
+		if value != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return db.UpdateParams{}, false
+	}
+	sort.Strings(names)
+
+	params := db.UpdateParams{
+		ExpressionValues: map[string]interface{}{
+			":propertyId": delta.propertyID,
+			":period":     delta.period,
+			":entityType": "STATS",
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#propertyId": "propertyId",
+			"#period":     "period",
+			"#entityType": "entityType",
+		},
+	}
+
+	add := ""
+	for i, name := range names {
+		if i > 0 {
+			add += ", "
+		}
+		add += fmt.Sprintf("#%s :%s", name, name)
+		params.ExpressionAttributeNames["#"+name] = name
+		params.ExpressionValues[":"+name] = delta.counters[name]
+	}
+	params.UpdateExpression = "SET #propertyId = :propertyId, #period = :period, #entityType = :entityType ADD " + add
+
+	return params, true
+}
+
+// rollupChanges returns the rollup updates for a booking changing from
+// before to after. Either may be nil, for a new or removed booking.
+func rollupChanges(before, after *Booking) []db.TransactItem {
+	return bookingDeltas(before, after).items()
+}
+
+// bookingDeltas returns the changes to rollups for a booking changing from
+// before to after.
+func bookingDeltas(before, after *Booking) rollupDeltas {
+	deltas := make(rollupDeltas)
+	deltas.add(before, -1)
+	deltas.add(after, 1)
+	return deltas
+}
+
+// ListDailyRollups returns a property's daily rollups for dates in
+// [start, end], ordered by date. Days without bookings are omitted.
+func (s *Service) ListDailyRollups(ctx context.Context, propertyID string, start, end time.Time) ([]*Rollup, error) {
+	return s.queryRollups(ctx, rollupPropertyPrefix+propertyID, "DAY#", start.Format("2006-01-02"), end.Format("2006-01-02"))
+}
+
+// SumPropertyRollups totals a property's bookings with check-in dates in
+// [start, end]. Whole months are read from monthly rollups and the
+// remaining days from daily rollups.
+func (s *Service) SumPropertyRollups(ctx context.Context, propertyID string, start, end time.Time) (*Rollup, error) {
+	rollups, err := s.sumRollups(ctx, rollupPropertyPrefix+propertyID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &Rollup{PropertyID: propertyID}
+	for _, rollup := range rollups {
+		total.Add(rollup)
+	}
+	return total, nil
+}
+
+// SumAgentRollups totals the bookings an agent made with check-in dates in
+// [start, end], by property ID.
+func (s *Service) SumAgentRollups(ctx context.Context, agentPhone string, start, end time.Time) (map[string]*Rollup, error) {
+	rollups, err := s.sumRollups(ctx, rollupAgentPrefix+agentPhone, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*Rollup)
+	for _, rollup := range rollups {
+		total, ok := totals[rollup.PropertyID]
+		if !ok {
+			total = &Rollup{PropertyID: rollup.PropertyID}
+			totals[rollup.PropertyID] = total
+		}
+		total.Add(rollup)
+	}
+	return totals, nil
+}
+
+// sumRollups reads the rollups under pk that together cover [start, end]
+// exactly once.
+func (s *Service) sumRollups(ctx context.Context, pk string, start, end time.Time) ([]*Rollup, error) {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	// Whole months run from firstMonth up to (not including) afterMonths
+	firstMonth := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !firstMonth.Equal(start) {
+		firstMonth = firstMonth.AddDate(0, 1, 0)
+	}
+	dayAfter := end.AddDate(0, 0, 1)
+	afterMonths := time.Date(dayAfter.Year(), dayAfter.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	if !firstMonth.Before(afterMonths) {
+		return s.queryRollups(ctx, pk, "DAY#", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	var rollups []*Rollup
+	if start.Before(firstMonth) {
+		days, err := s.queryRollups(ctx, pk, "DAY#", start.Format("2006-01-02"), firstMonth.AddDate(0, 0, -1).Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, days...)
+	}
+
+	months, err := s.queryRollups(ctx, pk, "MONTH#", firstMonth.Format("2006-01"), afterMonths.AddDate(0, -1, 0).Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
+	rollups = append(rollups, months...)
+
+	if !end.Before(afterMonths) {
+		days, err := s.queryRollups(ctx, pk, "DAY#", afterMonths.Format("2006-01-02"), end.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, days...)
+	}
+
+	return rollups, nil
+}
+
+// queryRollups returns the rollups under pk for periods from first to last
+// inclusive. Agent rollups carry a property suffix, which "~" sorts after.
+func (s *Service) queryRollups(ctx context.Context, pk, prefix, first, last string) ([]*Rollup, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition: "PK = :pk AND SK BETWEEN :first AND :last",
+		ExpressionValues: map[string]interface{}{
+			":pk":    pk,
+			":first": prefix + first,
+			":last":  prefix + last + "~",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollups: %w", err)
+	}
+
+	rollups := make([]*Rollup, 0, len(items))
+	for _, item := range items {
+		var rollup Rollup
+		if err := attributevalue.UnmarshalMap(item, &rollup); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rollup: %w", err)
+		}
+		rollups = append(rollups, &rollup)
+	}
+	return rollups, nil
+}
+
+// RebuildResult summarises a rollup rebuild.
+type RebuildResult struct {
+	Bookings int `json:"bookings"`
+	Removed  int `json:"removed"`
+	Rollups  int `json:"rollups"`
+}
+
+// RebuildRollups recomputes every rollup from the stored bookings. Existing
+// rollups are deleted first, so analytics read incomplete totals until it
+// finishes, and bookings written meanwhile may be missed; run it when the
+// system is quiet.
+func (s *Service) RebuildRollups(ctx context.Context) (*RebuildResult, error) {
+	result := &RebuildResult{}
+
+	items, err := s.db.ScanAll(ctx, db.ScanParams{
+		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk",
+		ExpressionValues: map[string]interface{}{
+			":prefix": "BOOKING#",
+			":sk":     "METADATA",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan bookings: %w", err)
+	}
+
+	deltas := make(rollupDeltas)
+	for _, item := range items {
+		var booking Booking
+		if err := attributevalue.UnmarshalMap(item, &booking); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal booking: %w", err)
+		}
+		deltas.add(&booking, 1)
+		result.Bookings++
+	}
+
+	existing, err := s.db.ScanAll(ctx, db.ScanParams{
+		FilterExpression: "begins_with(PK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":prefix": "STATS#",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rollups: %w", err)
+	}
+	for _, item := range existing {
+		var rollup Rollup
+		if err := attributevalue.UnmarshalMap(item, &rollup); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rollup: %w", err)
+		}
+		if err := s.db.DeleteItem(ctx, rollup.PK, rollup.SK); err != nil {
+			return nil, fmt.Errorf("failed to delete rollup: %w", err)
+		}
+		result.Removed++
+	}
+
+	for key, delta := range deltas {
+		params, ok := delta.updateParams()
+		if !ok {
+			continue
+		}
+		if err := s.db.UpdateItem(ctx, key.pk, key.sk, params); err != nil {
+			return nil, fmt.Errorf("failed to write rollup: %w", err)
+		}
+		result.Rollups++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,210 @@
+package bookings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// testBooking returns a pending, unpaid two-night booking made by an agent.
+func testBooking() *Booking {
+	return &Booking{
+		ID:              "b1",
+		PropertyID:      "p1",
+		BookedBy:        "agent",
+		CheckIn:         date("2026-03-10"),
+		CheckOut:        date("2026-03-12"),
+		NumNights:       2,
+		TotalAmount:     10000,
+		AgentCommission: 500,
+		Status:          StatusPending,
+	}
+}
+
+// changed returns a copy of testBooking with fn applied.
+func changed(fn func(*Booking)) *Booking {
+	booking := testBooking()
+	fn(booking)
+	return booking
+}
+
+// counters flattens deltas to the non-zero counters of each rollup item.
+func counters(deltas rollupDeltas) map[string]map[string]float64 {
+	out := make(map[string]map[string]float64)
+	for key, delta := range deltas {
+		for name, value := range delta.counters {
+			if value == 0 {
+				continue
+			}
+			item := key.pk + " " + key.sk
+			if out[item] == nil {
+				out[item] = make(map[string]float64)
+			}
+			out[item][name] = value
+		}
+	}
+	return out
+}
+
+func TestBookingDeltas(t *testing.T) {
+	created := map[string]float64{
+		"bookings": 1, "revenue": 10000, "commission": 500, "nights": 2,
+		"paymentPending": 1, "statusPending": 1, "unpaid": 1, "due": 10000,
+	}
+
+	tests := []struct {
+		name          string
+		before, after *Booking
+		want          map[string]map[string]float64
+	}{
+		{
+			name:  "create",
+			after: testBooking(),
+			want: map[string]map[string]float64{
+				"STATS#PROPERTY#p1 DAY#2026-03-10":             created,
+				"STATS#PROPERTY#p1 MONTH#2026-03":              created,
+				"STATS#AGENT#agent DAY#2026-03-10#PROPERTY#p1": created,
+				"STATS#AGENT#agent MONTH#2026-03#PROPERTY#p1":  created,
+				"STATS#PROPERTY#p1 DAY#2026-03-12":             {"checkOuts": 1},
+			},
+		},
+		{
+			name:   "reschedule within the month",
+			before: testBooking(),
+			after: changed(func(b *Booking) {
+				b.CheckIn = date("2026-03-15")
+				b.CheckOut = date("2026-03-17")
+			}),
+			want: map[string]map[string]float64{
+				"STATS#PROPERTY#p1 DAY#2026-03-10":             negate(created),
+				"STATS#PROPERTY#p1 DAY#2026-03-15":             created,
+				"STATS#AGENT#agent DAY#2026-03-10#PROPERTY#p1": negate(created),
+				"STATS#AGENT#agent DAY#2026-03-15#PROPERTY#p1": created,
+				"STATS#PROPERTY#p1 DAY#2026-03-12":             {"checkOuts": -1},
+				"STATS#PROPERTY#p1 DAY#2026-03-17":             {"checkOuts": 1},
+			},
+		},
+		{
+			name:   "cancel",
+			before: testBooking(),
+			after:  changed(func(b *Booking) { b.Status = StatusCancelled }),
+			want: map[string]map[string]float64{
+				"STATS#PROPERTY#p1 DAY#2026-03-10":             cancelled(),
+				"STATS#PROPERTY#p1 MONTH#2026-03":              cancelled(),
+				"STATS#AGENT#agent DAY#2026-03-10#PROPERTY#p1": cancelled(),
+				"STATS#AGENT#agent MONTH#2026-03#PROPERTY#p1":  cancelled(),
+			},
+		},
+		{
+			name:   "settle",
+			before: testBooking(),
+			after: changed(func(b *Booking) {
+				b.AdvanceAmount = b.TotalAmount
+				b.Status = StatusSettled
+			}),
+			want: map[string]map[string]float64{
+				"STATS#PROPERTY#p1 DAY#2026-03-10":             settled(),
+				"STATS#PROPERTY#p1 MONTH#2026-03":              settled(),
+				"STATS#AGENT#agent DAY#2026-03-10#PROPERTY#p1": settled(),
+				"STATS#AGENT#agent MONTH#2026-03#PROPERTY#p1":  settled(),
+			},
+		},
+		{
+			name:   "edit that changes no totals",
+			before: testBooking(),
+			after:  changed(func(b *Booking) { b.Notes = "late arrival" }),
+			want:   map[string]map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := counters(bookingDeltas(tt.before, tt.after))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counters =\n%v\nwant\n%v", got, tt.want)
+			}
+			if len(tt.want) == 0 && len(rollupChanges(tt.before, tt.after)) != 0 {
+				t.Errorf("expected no rollup updates")
+			}
+		})
+	}
+}
+
+func negate(counters map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(counters))
+	for name, value := range counters {
+		out[name] = -value
+	}
+	return out
+}
+
+func cancelled() map[string]float64 {
+	return map[string]float64{
+		"statusPending": -1, "statusCancelled": 1, "unpaid": -1, "due": -10000,
+		"cancelledRevenue": 10000, "cancelledCommission": 500,
+	}
+}
+
+func settled() map[string]float64 {
+	return map[string]float64{
+		"collected": 10000, "paymentPending": -1, "paymentSettled": 1,
+		"statusPending": -1, "statusSettled": 1, "unpaid": -1, "due": -10000,
+	}
+}
+
+func TestVersionCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     *Booking
+		condition  string
+		values     map[string]interface{}
+		newVersion int
+	}{
+		{"new booking", nil, "attribute_not_exists(PK)", nil, 1},
+		{"unversioned booking", &Booking{}, "attribute_exists(PK) AND attribute_not_exists(version)", nil, 1},
+		{"versioned booking", &Booking{Version: 4}, "version = :prevVersion", map[string]interface{}{":prevVersion": 4}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, values := versionCondition(tt.before)
+			if condition != tt.condition || !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("versionCondition = %q %v, want %q %v", condition, values, tt.condition, tt.values)
+			}
+			after := &Booking{}
+			putChange(tt.before, after)
+			if after.Version != tt.newVersion {
+				t.Errorf("version = %d, want %d", after.Version, tt.newVersion)
+			}
+		})
+	}
+}
+
+func TestWithStatusCancelledAt(t *testing.T) {
+	cancelled := withStatus(testBooking(), StatusCancelled, ReasonGuestRequest)
+	if cancelled.CancelledAt == nil {
+		t.Fatal("cancelling did not record cancelledAt")
+	}
+	if cancelled.CancellationReason != ReasonGuestRequest {
+		t.Errorf("cancellationReason = %q, want %q", cancelled.CancellationReason, ReasonGuestRequest)
+	}
+
+	first := *cancelled.CancelledAt
+	again := withStatus(cancelled, StatusCancelled, "")
+	if again.CancelledAt == nil || !again.CancelledAt.Equal(first) {
+		t.Errorf("cancelling again changed cancelledAt to %v, want %v", again.CancelledAt, first)
+	}
+
+	reinstated := withStatus(cancelled, StatusPending, "")
+	if reinstated.CancelledAt != nil || reinstated.CancellationReason != "" {
+		t.Errorf("reinstating kept cancelledAt %v and reason %q", reinstated.CancelledAt, reinstated.CancellationReason)
+	}
+}
@@ -0,0 +1,562 @@
+package dbtest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// expr evaluates a condition, key condition or update expression.
+type expr struct {
+	tokens []string
+	pos    int
+	names  map[string]string
+	values map[string]av
+}
+
+func newExpr(s string, req request) *expr {
+	return &expr{tokens: tokenize(s), names: req.ExpressionAttributeNames, values: req.ExpressionAttributeValues}
+}
+
+// tokenize splits an expression into names, placeholders and operators.
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("(),.[]=+-", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case c == '<' || c == '>':
+			if i+1 < len(s) && (s[i+1] == '=' || c == '<' && s[i+1] == '>') {
+				tokens = append(tokens, s[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, string(c))
+				i++
+			}
+		default:
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '#' || c == ':' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (e *expr) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *expr) next() string {
+	tok := e.peek()
+	e.pos++
+	return tok
+}
+
+// accept consumes tok, ignoring case, if it comes next.
+func (e *expr) accept(tok string) bool {
+	if strings.EqualFold(e.peek(), tok) {
+		e.pos++
+		return true
+	}
+	return false
+}
+
+func (e *expr) expect(tok string) *apiError {
+	if !e.accept(tok) {
+		return validation("expected %q at %q in %s", tok, e.peek(), strings.Join(e.tokens, " "))
+	}
+	return nil
+}
+
+func (e *expr) done() bool {
+	return e.pos >= len(e.tokens)
+}
+
+// condition evaluates a condition expression against it.
+func (e *expr) condition(it item) (bool, *apiError) {
+	ok, err := e.or(it)
+	if err == nil && !e.done() {
+		err = validation("unexpected %q in %s", e.peek(), strings.Join(e.tokens, " "))
+	}
+	return ok, err
+}
+
+func (e *expr) or(it item) (bool, *apiError) {
+	ok, err := e.and(it)
+	for err == nil && e.accept("OR") {
+		var right bool
+		right, err = e.and(it)
+		ok = ok || right
+	}
+	return ok, err
+}
+
+func (e *expr) and(it item) (bool, *apiError) {
+	ok, err := e.not(it)
+	for err == nil && e.accept("AND") {
+		var right bool
+		right, err = e.not(it)
+		ok = ok && right
+	}
+	return ok, err
+}
+
+func (e *expr) not(it item) (bool, *apiError) {
+	if e.accept("NOT") {
+		ok, err := e.not(it)
+		return !ok, err
+	}
+	return e.primary(it)
+}
+
+func (e *expr) primary(it item) (bool, *apiError) {
+	if e.accept("(") {
+		ok, err := e.or(it)
+		if err != nil {
+			return false, err
+		}
+		return ok, e.expect(")")
+	}
+
+	if fn := strings.ToLower(e.peek()); e.pos+1 < len(e.tokens) && e.tokens[e.pos+1] == "(" {
+		switch fn {
+		case "attribute_exists", "attribute_not_exists", "begins_with", "contains":
+			e.pos += 2
+			return e.function(it, fn)
+		}
+	}
+
+	left, err := e.operand(it)
+	if err != nil {
+		return false, err
+	}
+	switch op := strings.ToUpper(e.next()); op {
+	case "=", "<>", "<", "<=", ">", ">=":
+		right, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		if op == "=" {
+			return equal(left, right), nil
+		}
+		if op == "<>" {
+			return !equal(left, right), nil
+		}
+		c, ok := compare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "BETWEEN":
+		low, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		if err := e.expect("AND"); err != nil {
+			return false, err
+		}
+		high, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		lc, ok1 := compare(left, low)
+		hc, ok2 := compare(left, high)
+		return ok1 && ok2 && lc >= 0 && hc <= 0, nil
+	case "IN":
+		if err := e.expect("("); err != nil {
+			return false, err
+		}
+		found := false
+		for {
+			value, err := e.operand(it)
+			if err != nil {
+				return false, err
+			}
+			found = found || equal(left, value)
+			if !e.accept(",") {
+				break
+			}
+		}
+		return found, e.expect(")")
+	default:
+		return false, validation("unsupported operator %q", op)
+	}
+}
+
+func (e *expr) function(it item, fn string) (bool, *apiError) {
+	path, err := e.path()
+	if err != nil {
+		return false, err
+	}
+	value := resolve(it, path)
+
+	var ok bool
+	switch fn {
+	case "attribute_exists":
+		ok = value != nil
+	case "attribute_not_exists":
+		ok = value == nil
+	default:
+		if err := e.expect(","); err != nil {
+			return false, err
+		}
+		arg, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		if fn == "begins_with" {
+			s, isString := value["S"].(string)
+			prefix, _ := arg["S"].(string)
+			ok = isString && strings.HasPrefix(s, prefix)
+		} else {
+			ok = contains(value, arg)
+		}
+	}
+	return ok, e.expect(")")
+}
+
+func contains(value, arg av) bool {
+	if s, ok := value["S"].(string); ok {
+		sub, _ := arg["S"].(string)
+		return strings.Contains(s, sub)
+	}
+	for _, set := range []string{"SS", "NS"} {
+		for _, member := range any2strings(value[set]) {
+			if member == arg["S"] || member == arg["N"] {
+				return true
+			}
+		}
+	}
+	if list, ok := value["L"].([]interface{}); ok {
+		for _, member := range list {
+			if equal(asAV(member), arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// operand reads a placeholder value or a document path.
+func (e *expr) operand(it item) (av, *apiError) {
+	if tok := e.peek(); strings.HasPrefix(tok, ":") {
+		e.pos++
+		value, ok := e.values[tok]
+		if !ok {
+			return nil, validation("missing expression value %s", tok)
+		}
+		return value, nil
+	}
+	if strings.EqualFold(e.peek(), "size") && e.pos+1 < len(e.tokens) && e.tokens[e.pos+1] == "(" {
+		e.pos += 2
+		path, err := e.path()
+		if err != nil {
+			return nil, err
+		}
+		return numberValue(float64(size(resolve(it, path)))), e.expect(")")
+	}
+	path, err := e.path()
+	if err != nil {
+		return nil, err
+	}
+	return resolve(it, path), nil
+}
+
+func size(v av) int {
+	switch {
+	case v["S"] != nil:
+		return len(v["S"].(string))
+	case v["L"] != nil:
+		return len(v["L"].([]interface{}))
+	case v["M"] != nil:
+		return len(v["M"].(map[string]interface{}))
+	case v["SS"] != nil:
+		return len(any2strings(v["SS"]))
+	case v["NS"] != nil:
+		return len(any2strings(v["NS"]))
+	}
+	return 0
+}
+
+// segment is one step of a document path: a map key or a list index.
+type segment struct {
+	name  string
+	index int
+}
+
+// path reads a document path such as #a.b[0].
+func (e *expr) path() ([]segment, *apiError) {
+	var path []segment
+	for {
+		name := e.next()
+		if strings.HasPrefix(name, "#") {
+			resolved, ok := e.names[name]
+			if !ok {
+				return nil, validation("missing expression name %s", name)
+			}
+			name = resolved
+		}
+		if name == "" || !isWordByte(name[0]) || strings.HasPrefix(name, ":") {
+			return nil, validation("expected an attribute name, got %q", name)
+		}
+		path = append(path, segment{name: name, index: -1})
+
+		for e.accept("[") {
+			index, err := strconv.Atoi(e.next())
+			if err != nil {
+				return nil, validation("bad list index")
+			}
+			path = append(path, segment{index: index})
+			if err := e.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		if !e.accept(".") {
+			return path, nil
+		}
+	}
+}
+
+// resolve returns the value at path, or nil if it doesn't exist.
+func resolve(it item, path []segment) av {
+	value := it[path[0].name]
+	for _, seg := range path[1:] {
+		if value == nil {
+			return nil
+		}
+		if seg.index >= 0 {
+			list, _ := value["L"].([]interface{})
+			if seg.index >= len(list) {
+				return nil
+			}
+			value = asAV(list[seg.index])
+			continue
+		}
+		m, _ := value["M"].(map[string]interface{})
+		value = asAV(m[seg.name])
+	}
+	return value
+}
+
+// assign sets or, for a nil value, removes the value at path.
+func assign(it item, path []segment, value av) *apiError {
+	if len(path) == 1 {
+		if value == nil {
+			delete(it, path[0].name)
+		} else {
+			it[path[0].name] = value
+		}
+		return nil
+	}
+
+	parent := resolve(it, path[:len(path)-1])
+	last := path[len(path)-1]
+	if last.index >= 0 {
+		list, ok := parent["L"].([]interface{})
+		if !ok {
+			return validation("the document path is not a list")
+		}
+		switch {
+		case value == nil && last.index < len(list):
+			parent["L"] = append(list[:last.index], list[last.index+1:]...)
+		case value == nil:
+		case last.index < len(list):
+			list[last.index] = value
+		default:
+			parent["L"] = append(list, value)
+		}
+		return nil
+	}
+
+	m, ok := parent["M"].(map[string]interface{})
+	if !ok {
+		return validation("the document path provided in the update expression is invalid for update")
+	}
+	if value == nil {
+		delete(m, last.name)
+	} else {
+		m[last.name] = value
+	}
+	return nil
+}
+
+// update applies an update expression to it. Values on the right of SET
+// are read from the item as it was before the update.
+func (e *expr) update(it item) *apiError {
+	before := it.clone()
+	for !e.done() {
+		clause := strings.ToUpper(e.next())
+		for {
+			path, err := e.path()
+			if err != nil {
+				return err
+			}
+
+			switch clause {
+			case "SET":
+				if err := e.expect("="); err != nil {
+					return err
+				}
+				value, err := e.value(before)
+				if err != nil {
+					return err
+				}
+				if err := assign(it, path, value); err != nil {
+					return err
+				}
+			case "REMOVE":
+				if err := assign(it, path, nil); err != nil {
+					return err
+				}
+			case "ADD", "DELETE":
+				arg, err := e.operand(before)
+				if err != nil {
+					return err
+				}
+				value, err := addOrDelete(clause, resolve(before, path), arg)
+				if err != nil {
+					return err
+				}
+				if err := assign(it, path, value); err != nil {
+					return err
+				}
+			default:
+				return validation("unsupported update clause %q", clause)
+			}
+
+			if !e.accept(",") {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// value reads the right-hand side of a SET action.
+func (e *expr) value(it item) (av, *apiError) {
+	left, err := e.term(it)
+	if err != nil {
+		return nil, err
+	}
+	for e.peek() == "+" || e.peek() == "-" {
+		op := e.next()
+		right, err := e.term(it)
+		if err != nil {
+			return nil, err
+		}
+		x, ok1 := number(left)
+		y, ok2 := number(right)
+		if !ok1 || !ok2 {
+			return nil, validation("an operand in the update expression has an incorrect data type")
+		}
+		if op == "-" {
+			y = -y
+		}
+		left = numberValue(x + y)
+	}
+	return left, nil
+}
+
+func (e *expr) term(it item) (av, *apiError) {
+	fn := strings.ToLower(e.peek())
+	if e.pos+1 >= len(e.tokens) || e.tokens[e.pos+1] != "(" || (fn != "if_not_exists" && fn != "list_append") {
+		return e.operand(it)
+	}
+	e.pos += 2
+
+	var result av
+	if fn == "if_not_exists" {
+		path, err := e.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect(","); err != nil {
+			return nil, err
+		}
+		fallback, err := e.value(it)
+		if err != nil {
+			return nil, err
+		}
+		result = resolve(it, path)
+		if result == nil {
+			result = fallback
+		}
+	} else {
+		first, err := e.value(it)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect(","); err != nil {
+			return nil, err
+		}
+		second, err := e.value(it)
+		if err != nil {
+			return nil, err
+		}
+		a, _ := first["L"].([]interface{})
+		b, _ := second["L"].([]interface{})
+		result = av{"L": append(append([]interface{}{}, a...), b...)}
+	}
+	return result, e.expect(")")
+}
+
+// addOrDelete applies an ADD or DELETE action to a number or set.
+func addOrDelete(clause string, current, arg av) (av, *apiError) {
+	if y, ok := number(arg); ok && clause == "ADD" {
+		x, _ := number(current)
+		return numberValue(x + y), nil
+	}
+	for _, set := range []string{"SS", "NS"} {
+		if arg[set] == nil {
+			continue
+		}
+		members := map[string]bool{}
+		var order []string
+		for _, m := range any2strings(current[set]) {
+			members[m] = true
+			order = append(order, m)
+		}
+		for _, m := range any2strings(arg[set]) {
+			if clause == "ADD" && !members[m] {
+				order = append(order, m)
+			}
+			members[m] = clause == "ADD"
+		}
+		var out []string
+		for _, m := range order {
+			if members[m] {
+				out = append(out, m)
+			}
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+		return av{set: strings2any(out)}, nil
+	}
+	return nil, validation("an operand in the update expression has an incorrect data type")
+}
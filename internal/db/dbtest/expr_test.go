@@ -0,0 +1,86 @@
+package dbtest
+
+import "testing"
+
+func TestCondition(t *testing.T) {
+	it := item{
+		"PK":      {"S": "BOOKING#1"},
+		"SK":      {"S": "METADATA"},
+		"status":  {"S": "pending"},
+		"version": {"N": "3"},
+		"stats":   {"M": map[string]interface{}{"nights": map[string]interface{}{"N": "2"}}},
+	}
+	req := request{
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+		ExpressionAttributeValues: map[string]av{
+			":pending": {"S": "pending"},
+			":settled": {"S": "settled"},
+			":v":       {"N": "3.0"},
+			":prefix":  {"S": "BOOK"},
+			":one":     {"N": "1"},
+			":five":    {"N": "5"},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"attribute_exists(PK)", true},
+		{"attribute_not_exists(PK)", false},
+		{"version = :v", true},
+		{"version = :v AND #status = :settled", false},
+		{"version = :v AND (#status = :settled OR #status = :pending)", true},
+		{"NOT #status = :settled", true},
+		{"#status IN (:settled, :pending)", true},
+		{"begins_with(PK, :prefix)", true},
+		{"version BETWEEN :one AND :five", true},
+		{"stats.nights > :one", true},
+		{"missing <> :one", true},
+		{"missing = :one", false},
+	}
+	for _, tt := range tests {
+		got, err := newExpr(tt.expr, req).condition(it)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	it := item{
+		"PK":      {"S": "BOOKING#1"},
+		"SK":      {"S": "METADATA"},
+		"version": {"N": "3"},
+		"note":    {"S": "old"},
+	}
+	req := request{
+		ExpressionAttributeNames: map[string]string{"#count": "count"},
+		ExpressionAttributeValues: map[string]av{
+			":one":  {"N": "1"},
+			":zero": {"N": "0"},
+			":tags": {"SS": []interface{}{"a", "b"}},
+		},
+	}
+
+	err := newExpr("SET version = version + :one, #count = if_not_exists(#count, :zero) + :one REMOVE note ADD tags :tags", req).update(it)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := number(it["version"]); v != 4 {
+		t.Errorf("version = %v, want 4", v)
+	}
+	if v, _ := number(it["count"]); v != 1 {
+		t.Errorf("count = %v, want 1", v)
+	}
+	if it["note"] != nil {
+		t.Errorf("note = %v, want removed", it["note"])
+	}
+	if got := any2strings(it["tags"]["SS"]); len(got) != 2 {
+		t.Errorf("tags = %v, want [a b]", got)
+	}
+}
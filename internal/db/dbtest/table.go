@@ -0,0 +1,405 @@
+// Package dbtest runs an in-memory stand-in for DynamoDB that a db.Client can
+// be pointed at in tests. It implements the single-table layout of this
+// service (PK and SK, with the GSI1 index) and the parts of the expression
+// language the services use.
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+// Table is an in-memory DynamoDB table.
+type Table struct {
+	// PageSize caps how many items a query or scan reads per page, so that
+	// callers which do not follow pagination miss items as they would in
+	// production. Zero means no cap.
+	PageSize int
+
+	mu    sync.Mutex
+	items map[string]item
+}
+
+// New starts a table and returns a client that talks to it. The client is
+// configured through the environment, so tests using it can't run in parallel.
+func New(t testing.TB) (*db.Client, *Table) {
+	t.Helper()
+
+	table := &Table{items: map[string]item{}}
+	server := httptest.NewServer(table)
+	t.Cleanup(server.Close)
+
+	none := filepath.Join(t.TempDir(), "none")
+	t.Setenv("AWS_ENDPOINT_URL_DYNAMODB", server.URL)
+	t.Setenv("AWS_REGION", "ap-south-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", none)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", none)
+	t.Setenv("TABLE_NAME", "test")
+
+	client, err := db.NewClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, table
+}
+
+// Put stores an item, replacing any item with the same keys.
+func (tb *Table) Put(t testing.TB, v interface{}) {
+	t.Helper()
+
+	av, err := attributevalue.MarshalMap(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := make(item, len(av))
+	for name, value := range av {
+		it[name] = fromSDK(value)
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.items[it.key()] = it
+}
+
+// Get reads the item with the given keys into out and reports whether it exists.
+func (tb *Table) Get(t testing.TB, pk, sk string, out interface{}) bool {
+	t.Helper()
+
+	tb.mu.Lock()
+	it, ok := tb.items[pk+"\x00"+sk]
+	tb.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if err := attributevalue.UnmarshalMap(it.toSDK(), out); err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+// Items returns the items whose partition key starts with prefix, in key order.
+func (tb *Table) Items(pkPrefix string) []map[string]types.AttributeValue {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	var items []map[string]types.AttributeValue
+	for _, it := range tb.sorted("", "") {
+		if strings.HasPrefix(it.str("PK"), pkPrefix) {
+			items = append(items, it.toSDK())
+		}
+	}
+	return items
+}
+
+// request holds the fields of every operation the table serves.
+type request struct {
+	Key                       item                 `json:"Key"`
+	Item                      item                 `json:"Item"`
+	IndexName                 string               `json:"IndexName"`
+	KeyConditionExpression    string               `json:"KeyConditionExpression"`
+	FilterExpression          string               `json:"FilterExpression"`
+	ConditionExpression       string               `json:"ConditionExpression"`
+	UpdateExpression          string               `json:"UpdateExpression"`
+	ExpressionAttributeNames  map[string]string    `json:"ExpressionAttributeNames"`
+	ExpressionAttributeValues map[string]av        `json:"ExpressionAttributeValues"`
+	Limit                     int                  `json:"Limit"`
+	ScanIndexForward          *bool                `json:"ScanIndexForward"`
+	ExclusiveStartKey         item                 `json:"ExclusiveStartKey"`
+	TransactItems             []map[string]request `json:"TransactItems"`
+}
+
+// apiError is an error returned to the client in the DynamoDB format.
+type apiError struct {
+	code    string
+	message string
+	reasons []map[string]string
+}
+
+func (e *apiError) Error() string { return e.code + ": " + e.message }
+
+func validation(format string, args ...interface{}) *apiError {
+	return &apiError{code: "ValidationException", message: fmt.Sprintf(format, args...)}
+}
+
+var errConditionFailed = &apiError{code: "ConditionalCheckFailedException", message: "The conditional request failed"}
+
+// ServeHTTP handles one DynamoDB API call.
+func (tb *Table) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, validation("bad request body: %v", err))
+		return
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	var out interface{}
+	var err *apiError
+	switch op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810."); op {
+	case "GetItem":
+		out = map[string]interface{}{}
+		if it, ok := tb.items[req.Key.key()]; ok {
+			out = map[string]interface{}{"Item": it}
+		}
+	case "PutItem":
+		err = tb.put(req, true)
+	case "UpdateItem":
+		err = tb.update(req, true)
+	case "DeleteItem":
+		err = tb.delete(req, true)
+	case "Query", "Scan":
+		out, err = tb.read(req, op == "Query")
+	case "TransactWriteItems":
+		err = tb.transact(req)
+	default:
+		err = &apiError{code: "UnknownOperationException", message: "dbtest does not implement " + op}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(out)
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	body := map[string]interface{}{
+		"__type":  "com.amazonaws.dynamodb.v20120810#" + err.code,
+		"message": err.message,
+	}
+	if err.reasons != nil {
+		body["CancellationReasons"] = err.reasons
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(body)
+}
+
+// check evaluates a request's condition against the item it targets.
+func (tb *Table) check(req request, key string) *apiError {
+	if req.ConditionExpression == "" {
+		return nil
+	}
+	current := tb.items[key]
+	if current == nil {
+		current = item{}
+	}
+	ok, err := newExpr(req.ConditionExpression, req).condition(current)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errConditionFailed
+	}
+	return nil
+}
+
+func (tb *Table) put(req request, apply bool) *apiError {
+	if req.Item.str("PK") == "" || req.Item.str("SK") == "" {
+		return validation("item is missing its PK or SK")
+	}
+	if err := tb.check(req, req.Item.key()); err != nil {
+		return err
+	}
+	if apply {
+		tb.items[req.Item.key()] = req.Item.clone()
+	}
+	return nil
+}
+
+func (tb *Table) update(req request, apply bool) *apiError {
+	key := req.Key.key()
+	if err := tb.check(req, key); err != nil {
+		return err
+	}
+
+	updated := item{}
+	if current, ok := tb.items[key]; ok {
+		updated = current.clone()
+	}
+	for name, value := range req.Key {
+		updated[name] = value
+	}
+	if err := newExpr(req.UpdateExpression, req).update(updated); err != nil {
+		return err
+	}
+	if apply {
+		tb.items[key] = updated
+	}
+	return nil
+}
+
+func (tb *Table) delete(req request, apply bool) *apiError {
+	if err := tb.check(req, req.Key.key()); err != nil {
+		return err
+	}
+	if apply {
+		delete(tb.items, req.Key.key())
+	}
+	return nil
+}
+
+// transact checks every condition in a transaction before applying any write.
+func (tb *Table) transact(req request) *apiError {
+	reasons := make([]map[string]string, len(req.TransactItems))
+	failed := false
+	for i, write := range req.TransactItems {
+		var err *apiError
+		for kind, r := range write {
+			switch kind {
+			case "Put":
+				err = tb.put(r, false)
+			case "Update":
+				err = tb.update(r, false)
+			case "Delete":
+				err = tb.delete(r, false)
+			case "ConditionCheck":
+				err = tb.check(r, r.Key.key())
+			default:
+				err = validation("unknown transaction write %s", kind)
+			}
+		}
+		switch {
+		case err == errConditionFailed:
+			failed = true
+			reasons[i] = map[string]string{"Code": "ConditionalCheckFailed", "Message": err.message}
+		case err != nil:
+			return err
+		default:
+			reasons[i] = map[string]string{"Code": "None"}
+		}
+	}
+	if failed {
+		return &apiError{code: "TransactionCanceledException", message: "Transaction cancelled", reasons: reasons}
+	}
+
+	for _, write := range req.TransactItems {
+		for kind, r := range write {
+			switch kind {
+			case "Put":
+				tb.put(r, true)
+			case "Update":
+				tb.update(r, true)
+			case "Delete":
+				tb.delete(r, true)
+			}
+		}
+	}
+	return nil
+}
+
+// read serves a query or scan, one page at a time.
+func (tb *Table) read(req request, query bool) (interface{}, *apiError) {
+	pkName, skName := "", ""
+	if req.IndexName != "" {
+		pkName, skName = req.IndexName+"PK", req.IndexName+"SK"
+	}
+
+	candidates := tb.sorted(pkName, skName)
+	if query {
+		var matched []item
+		for _, it := range candidates {
+			ok, err := newExpr(req.KeyConditionExpression, req).condition(it)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, it)
+			}
+		}
+		candidates = matched
+		if req.ScanIndexForward != nil && !*req.ScanIndexForward {
+			for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+				candidates[i], candidates[j] = candidates[j], candidates[i]
+			}
+		}
+	}
+
+	if req.ExclusiveStartKey != nil {
+		for i, it := range candidates {
+			if it.key() == req.ExclusiveStartKey.key() {
+				candidates = candidates[i+1:]
+				break
+			}
+		}
+	}
+
+	limit := req.Limit
+	if tb.PageSize > 0 && (limit == 0 || tb.PageSize < limit) {
+		limit = tb.PageSize
+	}
+	page, more := candidates, false
+	if limit > 0 && len(page) > limit {
+		page, more = page[:limit], true
+	}
+
+	items := []item{}
+	for _, it := range page {
+		if req.FilterExpression != "" {
+			ok, err := newExpr(req.FilterExpression, req).condition(it)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		items = append(items, it)
+	}
+
+	out := map[string]interface{}{"Items": items, "Count": len(items), "ScannedCount": len(page)}
+	if more {
+		last := page[len(page)-1]
+		key := item{"PK": last["PK"], "SK": last["SK"]}
+		if pkName != "" {
+			key[pkName], key[skName] = last[pkName], last[skName]
+		}
+		out["LastEvaluatedKey"] = key
+	}
+	return out, nil
+}
+
+// sorted returns the items that have the given index keys, ordered by them
+// and then by the table keys. Empty names select the table itself.
+func (tb *Table) sorted(pkName, skName string) []item {
+	var items []item
+	for _, it := range tb.items {
+		if pkName != "" && (it[pkName] == nil || it[skName] == nil) {
+			continue
+		}
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if pkName != "" {
+			if c, _ := compare(items[i][pkName], items[j][pkName]); c != 0 {
+				return c < 0
+			}
+			if c, _ := compare(items[i][skName], items[j][skName]); c != 0 {
+				return c < 0
+			}
+		}
+		return items[i].key() < items[j].key()
+	})
+	return items
+}
@@ -0,0 +1,206 @@
+package dbtest
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// av is an attribute value in the DynamoDB JSON format, e.g. {"S": "x"}.
+type av = map[string]interface{}
+
+// item is a stored item, by attribute name.
+type item map[string]av
+
+// key returns the table key of an item.
+func (it item) key() string {
+	return it.str("PK") + "\x00" + it.str("SK")
+}
+
+// str returns a string attribute, or "" if it is missing.
+func (it item) str(name string) string {
+	s, _ := it[name]["S"].(string)
+	return s
+}
+
+// clone copies an item deeply, so later updates don't change the original.
+func (it item) clone() item {
+	out := make(item, len(it))
+	for name, value := range it {
+		out[name] = cloneValue(value).(av)
+	}
+	return out
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = cloneValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = cloneValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// asAV converts a nested value decoded from JSON into an attribute value.
+func asAV(v interface{}) av {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// number parses a numeric attribute value.
+func number(v av) (float64, bool) {
+	s, ok := v["N"].(string)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+func numberValue(f float64) av {
+	return av{"N": strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// compare orders two scalar values of the same type. It reports false if
+// they can't be ordered.
+func compare(a, b av) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if x, ok := a["S"].(string); ok {
+		y, ok := b["S"].(string)
+		return strings.Compare(x, y), ok
+	}
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	if x, ok := a["B"].(string); ok {
+		y, ok := b["B"].(string)
+		return strings.Compare(x, y), ok
+	}
+	return 0, false
+}
+
+// equal reports whether two values are the same.
+func equal(a, b av) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return a != nil && b != nil && reflect.DeepEqual(a, b)
+}
+
+// fromSDK converts an SDK attribute value into the JSON format.
+func fromSDK(v types.AttributeValue) av {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return av{"S": v.Value}
+	case *types.AttributeValueMemberN:
+		return av{"N": v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return av{"BOOL": v.Value}
+	case *types.AttributeValueMemberNULL:
+		return av{"NULL": true}
+	case *types.AttributeValueMemberB:
+		return av{"B": base64.StdEncoding.EncodeToString(v.Value)}
+	case *types.AttributeValueMemberSS:
+		return av{"SS": strings2any(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return av{"NS": strings2any(v.Value)}
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(v.Value))
+		for i, e := range v.Value {
+			list[i] = fromSDK(e)
+		}
+		return av{"L": list}
+	case *types.AttributeValueMemberM:
+		m := make(map[string]interface{}, len(v.Value))
+		for k, e := range v.Value {
+			m[k] = fromSDK(e)
+		}
+		return av{"M": m}
+	}
+	return av{"NULL": true}
+}
+
+func strings2any(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func any2strings(values interface{}) []string {
+	list, _ := values.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		s, _ := v.(string)
+		out = append(out, s)
+	}
+	return out
+}
+
+// toSDK converts an item into SDK attribute values.
+func (it item) toSDK() map[string]types.AttributeValue {
+	out := make(map[string]types.AttributeValue, len(it))
+	for name, value := range it {
+		out[name] = toSDK(value)
+	}
+	return out
+}
+
+func toSDK(v av) types.AttributeValue {
+	switch {
+	case v["S"] != nil:
+		return &types.AttributeValueMemberS{Value: v["S"].(string)}
+	case v["N"] != nil:
+		return &types.AttributeValueMemberN{Value: v["N"].(string)}
+	case v["BOOL"] != nil:
+		b, _ := v["BOOL"].(bool)
+		return &types.AttributeValueMemberBOOL{Value: b}
+	case v["B"] != nil:
+		data, _ := base64.StdEncoding.DecodeString(v["B"].(string))
+		return &types.AttributeValueMemberB{Value: data}
+	case v["SS"] != nil:
+		return &types.AttributeValueMemberSS{Value: any2strings(v["SS"])}
+	case v["NS"] != nil:
+		return &types.AttributeValueMemberNS{Value: any2strings(v["NS"])}
+	case v["L"] != nil:
+		list, _ := v["L"].([]interface{})
+		out := make([]types.AttributeValue, len(list))
+		for i, e := range list {
+			out[i] = toSDK(asAV(e))
+		}
+		return &types.AttributeValueMemberL{Value: out}
+	case v["M"] != nil:
+		m, _ := v["M"].(map[string]interface{})
+		out := make(map[string]types.AttributeValue, len(m))
+		for k, e := range m {
+			out[k] = toSDK(asAV(e))
+		}
+		return &types.AttributeValueMemberM{Value: out}
+	}
+	return &types.AttributeValueMemberNULL{Value: true}
+}
@@ -3,6 +3,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -104,8 +105,8 @@ func (c *Client) GetItem(ctx context.Context, pk, sk string, out interface{}) er
 	return nil
 }
 
-// Query executes a query on the main table or GSI.
-func (c *Client) Query(ctx context.Context, params QueryParams) ([]map[string]types.AttributeValue, error) {
+// buildQueryInput converts query parameters into a DynamoDB query input.
+func (c *Client) buildQueryInput(params QueryParams) (*dynamodb.QueryInput, error) {
 	exprValues := make(map[string]types.AttributeValue)
 	for k, v := range params.ExpressionValues {
 		av, err := attributevalue.Marshal(v)
@@ -137,6 +138,21 @@ func (c *Client) Query(ctx context.Context, params QueryParams) ([]map[string]ty
 		input.ScanIndexForward = params.ScanIndexForward
 	}
 
+	if len(params.ExpressionAttributeNames) > 0 {
+		input.ExpressionAttributeNames = params.ExpressionAttributeNames
+	}
+
+	return input, nil
+}
+
+// Query executes a query on the main table or GSI.
+// It returns a single page of results (up to 1MB, or Limit items).
+func (c *Client) Query(ctx context.Context, params QueryParams) ([]map[string]types.AttributeValue, error) {
+	input, err := c.buildQueryInput(params)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := c.db.Query(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query: %w", err)
@@ -145,18 +161,117 @@ func (c *Client) Query(ctx context.Context, params QueryParams) ([]map[string]ty
 	return result.Items, nil
 }
 
+// QueryPage executes a query that continues after startKey (nil for the first
+// page). It returns one page of results and the key to continue from, which
+// is nil once there are no more results.
+func (c *Client) QueryPage(ctx context.Context, params QueryParams, startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	input, err := c.buildQueryInput(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(startKey) > 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := c.db.Query(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query: %w", err)
+	}
+
+	return result.Items, result.LastEvaluatedKey, nil
+}
+
+// QueryAll executes a query and follows pagination until all matching items
+// have been read. Limit is ignored.
+func (c *Client) QueryAll(ctx context.Context, params QueryParams) ([]map[string]types.AttributeValue, error) {
+	params.Limit = 0
+	input, err := c.buildQueryInput(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewQueryPaginator(c.db, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
+
 // QueryParams holds parameters for a DynamoDB query.
 type QueryParams struct {
-	KeyCondition     string
-	FilterExpression string
-	ExpressionValues map[string]interface{}
-	IndexName        string
-	Limit            int32
-	ScanIndexForward *bool
+	KeyCondition             string
+	FilterExpression         string
+	ExpressionValues         map[string]interface{}
+	ExpressionAttributeNames map[string]string
+	IndexName                string
+	Limit                    int32
+	ScanIndexForward         *bool
 }
 
 // Scan executes a scan on the table.
 func (c *Client) Scan(ctx context.Context, params ScanParams) ([]map[string]types.AttributeValue, error) {
+	input, err := c.buildScanInput(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.db.Scan(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	return result.Items, nil
+}
+
+// ScanPage executes a scan starting after startKey and returns one page of
+// items with the key to continue from, which is nil on the last page.
+func (c *Client) ScanPage(ctx context.Context, params ScanParams, startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	input, err := c.buildScanInput(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(startKey) > 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := c.db.Scan(ctx, input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	return result.Items, result.LastEvaluatedKey, nil
+}
+
+// ScanAll executes a scan and follows pagination until the whole table has
+// been read. Limit is ignored.
+func (c *Client) ScanAll(ctx context.Context, params ScanParams) ([]map[string]types.AttributeValue, error) {
+	params.Limit = 0
+	input, err := c.buildScanInput(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewScanPaginator(c.db, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
+
+// buildScanInput converts scan parameters into a DynamoDB scan input.
+func (c *Client) buildScanInput(params ScanParams) (*dynamodb.ScanInput, error) {
 	exprValues := make(map[string]types.AttributeValue)
 	for k, v := range params.ExpressionValues {
 		av, err := attributevalue.Marshal(v)
@@ -185,12 +300,7 @@ func (c *Client) Scan(ctx context.Context, params ScanParams) ([]map[string]type
 		input.Limit = aws.Int32(params.Limit)
 	}
 
-	result, err := c.db.Scan(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan: %w", err)
-	}
-
-	return result.Items, nil
+	return input, nil
 }
 
 // ScanParams holds parameters for a DynamoDB scan.
@@ -201,16 +311,25 @@ type ScanParams struct {
 	Limit            int32
 }
 
-// UpdateItem updates an item in DynamoDB.
-func (c *Client) UpdateItem(ctx context.Context, pk, sk string, params UpdateParams) error {
+// marshalExpressionValues converts expression values to DynamoDB attribute values.
+func marshalExpressionValues(values map[string]interface{}) (map[string]types.AttributeValue, error) {
 	exprValues := make(map[string]types.AttributeValue)
-	for k, v := range params.ExpressionValues {
+	for k, v := range values {
 		av, err := attributevalue.Marshal(v)
 		if err != nil {
-			return fmt.Errorf("failed to marshal expression value %s: %w", k, err)
+			return nil, fmt.Errorf("failed to marshal expression value %s: %w", k, err)
 		}
 		exprValues[k] = av
 	}
+	return exprValues, nil
+}
+
+// UpdateItem updates an item in DynamoDB.
+func (c *Client) UpdateItem(ctx context.Context, pk, sk string, params UpdateParams) error {
+	exprValues, err := marshalExpressionValues(params.ExpressionValues)
+	if err != nil {
+		return err
+	}
 
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(c.tableName),
@@ -230,7 +349,7 @@ func (c *Client) UpdateItem(ctx context.Context, pk, sk string, params UpdatePar
 		input.ExpressionAttributeNames = params.ExpressionAttributeNames
 	}
 
-	_, err := c.db.UpdateItem(ctx, input)
+	_, err = c.db.UpdateItem(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
@@ -246,6 +365,94 @@ type UpdateParams struct {
 	ExpressionAttributeNames map[string]string
 }
 
+// TransactItem is a single write within a transaction.
+// Build one with TransactPut or TransactUpdate.
+type TransactItem struct {
+	put    interface{}
+	pk, sk string
+	update *UpdateParams
+	cond   string
+	values map[string]interface{}
+}
+
+// TransactPut creates a transactional put. condition is optional.
+func TransactPut(item interface{}, condition string) TransactItem {
+	return TransactItem{put: item, cond: condition}
+}
+
+// TransactPutWithValues creates a transactional put whose condition uses
+// expression values.
+func TransactPutWithValues(item interface{}, condition string, values map[string]interface{}) TransactItem {
+	return TransactItem{put: item, cond: condition, values: values}
+}
+
+// TransactUpdate creates a transactional update of the item with the given keys.
+func TransactUpdate(pk, sk string, params UpdateParams) TransactItem {
+	return TransactItem{pk: pk, sk: sk, update: &params}
+}
+
+// MaxTransactItems is the most items DynamoDB accepts in one transaction.
+const MaxTransactItems = 100
+
+// TransactWrite applies all writes atomically: either every item is written or none are.
+func (c *Client) TransactWrite(ctx context.Context, items ...TransactItem) error {
+	writes := make([]types.TransactWriteItem, 0, len(items))
+	for _, item := range items {
+		if item.update != nil {
+			exprValues, err := marshalExpressionValues(item.update.ExpressionValues)
+			if err != nil {
+				return err
+			}
+			update := &types.Update{
+				TableName: aws.String(c.tableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: item.pk},
+					"SK": &types.AttributeValueMemberS{Value: item.sk},
+				},
+				UpdateExpression:          aws.String(item.update.UpdateExpression),
+				ExpressionAttributeValues: exprValues,
+			}
+			if item.update.ConditionExpression != "" {
+				update.ConditionExpression = aws.String(item.update.ConditionExpression)
+			}
+			if len(item.update.ExpressionAttributeNames) > 0 {
+				update.ExpressionAttributeNames = item.update.ExpressionAttributeNames
+			}
+			writes = append(writes, types.TransactWriteItem{Update: update})
+			continue
+		}
+
+		av, err := attributevalue.MarshalMap(item.put)
+		if err != nil {
+			return fmt.Errorf("failed to marshal item: %w", err)
+		}
+		put := &types.Put{
+			TableName: aws.String(c.tableName),
+			Item:      av,
+		}
+		if item.cond != "" {
+			put.ConditionExpression = aws.String(item.cond)
+		}
+		if len(item.values) > 0 {
+			exprValues, err := marshalExpressionValues(item.values)
+			if err != nil {
+				return err
+			}
+			put.ExpressionAttributeValues = exprValues
+		}
+		writes = append(writes, types.TransactWriteItem{Put: put})
+	}
+
+	_, err := c.db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: writes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write transaction: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteItem removes an item from DynamoDB.
 func (c *Client) DeleteItem(ctx context.Context, pk, sk string) error {
 	_, err := c.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
@@ -274,3 +481,22 @@ var ErrNotFound = fmt.Errorf("item not found")
 func IsNotFound(err error) bool {
 	return err == ErrNotFound
 }
+
+// IsConditionalCheckFailed checks if the error was caused by a failed condition expression,
+// including a condition on one of the items in a transaction.
+func IsConditionalCheckFailed(err error) bool {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return true
+	}
+
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+	}
+	return false
+}
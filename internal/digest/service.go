@@ -0,0 +1,263 @@
+// Package digest sends owners an opt-in daily or weekly summary of activity
+// across their properties.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Lambda images do not ship zoneinfo
+
+	"github.com/booking-villa-backend/internal/analytics"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+const (
+	// sendHour is the local hour digests go out from.
+	sendHour = 8
+
+	// weeklyDay is the day weekly digests are sent, covering the previous seven days.
+	weeklyDay = time.Monday
+
+	// upcomingDays is how far ahead arrivals are listed.
+	upcomingDays = 7
+
+	// maxListedArrivals caps the arrivals listed in the email.
+	maxListedArrivals = 10
+
+	markerRetentionDays = 30
+)
+
+// EventSendDigest is the outbox event that builds and delivers one owner's digest.
+const EventSendDigest outbox.EventType = "digest.send"
+
+// SendDigestEvent is the payload of EventSendDigest.
+// The period is given as calendar dates, UTC midnight like booking dates, and
+// covers local days in each property's time zone.
+type SendDigestEvent struct {
+	OwnerPhone  string                        `json:"ownerPhone"`
+	Frequency   notifications.DigestFrequency `json:"frequency"`
+	PeriodStart time.Time                     `json:"periodStart"`
+	PeriodEnd   time.Time                     `json:"periodEnd"`
+}
+
+// marker records that a digest was queued for an owner and period.
+type marker struct {
+	PK         string    `dynamodbav:"PK"` // USER#<phone>
+	SK         string    `dynamodbav:"SK"` // DIGEST#<frequency>#<periodStart>
+	QueuedAt   time.Time `dynamodbav:"queuedAt"`
+	TTL        int64     `dynamodbav:"TTL"`
+	EntityType string    `dynamodbav:"entityType"`
+}
+
+// ScheduleResult summarises one scheduling run.
+type ScheduleResult struct {
+	Queued      int `json:"queued"`
+	AlreadySent int `json:"alreadySent"`
+	Failed      int `json:"failed"`
+}
+
+// Service schedules and delivers owner digests.
+type Service struct {
+	db                  *db.Client
+	analyticsService    *analytics.Service
+	notificationService *notifications.Service
+	propertyService     *properties.Service
+}
+
+// NewService creates a new digest service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:                  dbClient,
+		analyticsService:    analytics.NewService(dbClient),
+		notificationService: notifications.NewService(dbClient),
+		propertyService:     properties.NewService(dbClient),
+	}
+}
+
+// Schedule queues the digests that are due at now. Daily digests cover the
+// previous local day and weekly digests the previous seven days, in the time
+// zone of the owner's properties. Each is queued at most once per period, so
+// running it every hour is safe.
+func (s *Service) Schedule(ctx context.Context, now time.Time) (*ScheduleResult, error) {
+	result := &ScheduleResult{}
+
+	for _, frequency := range []notifications.DigestFrequency{notifications.DigestDaily, notifications.DigestWeekly} {
+		phones, err := s.notificationService.ListDigestSubscribers(ctx, frequency)
+		if err != nil {
+			return result, err
+		}
+
+		for _, phone := range phones {
+			local, err := s.ownerTime(ctx, phone, now)
+			if err != nil {
+				log.Printf("Failed to get properties for %s digest of %s: %v", frequency, phone, err)
+				result.Failed++
+				continue
+			}
+			if local.Hour() < sendHour || frequency == notifications.DigestWeekly && local.Weekday() != weeklyDay {
+				continue
+			}
+
+			today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+			periodStart := today.AddDate(0, 0, -1)
+			if frequency == notifications.DigestWeekly {
+				periodStart = today.AddDate(0, 0, -7)
+			}
+
+			queued, err := s.queue(ctx, SendDigestEvent{
+				OwnerPhone:  phone,
+				Frequency:   frequency,
+				PeriodStart: periodStart,
+				PeriodEnd:   today,
+			})
+			switch {
+			case err != nil:
+				log.Printf("Failed to queue %s digest for %s: %v", frequency, phone, err)
+				result.Failed++
+			case queued:
+				result.Queued++
+			default:
+				result.AlreadySent++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ownerTime returns now in the time zone of an owner's properties. If they
+// are in several, the first property's is used.
+func (s *Service) ownerTime(ctx context.Context, phone string, now time.Time) (time.Time, error) {
+	props, err := s.propertyService.ListPropertiesByOwner(ctx, phone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	property := &properties.Property{}
+	if len(props) > 0 {
+		property = props[0]
+	}
+	return now.In(property.Location()), nil
+}
+
+// queue writes the period marker and the outbox event together.
+// It returns false if the digest was already queued for the period.
+func (s *Service) queue(ctx context.Context, payload SendDigestEvent) (bool, error) {
+	event, err := outbox.NewEvent(EventSendDigest, payload)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.db.TransactWrite(ctx,
+		db.TransactPut(&marker{
+			PK:         "USER#" + payload.OwnerPhone,
+			SK:         fmt.Sprintf("DIGEST#%s#%s", payload.Frequency, payload.PeriodStart.Format("2006-01-02")),
+			QueuedAt:   time.Now(),
+			TTL:        db.CalculateTTL(markerRetentionDays * 24 * time.Hour),
+			EntityType: "DIGEST_MARKER",
+		}, "attribute_not_exists(PK)"),
+		event.TransactItem(),
+	)
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to queue digest: %w", err)
+	}
+	return true, nil
+}
+
+// HandleSendDigestEvent builds an owner's digest and delivers it on the
+// channels they have enabled for owner_digest.
+func (s *Service) HandleSendDigestEvent(ctx context.Context, event *outbox.Event) error {
+	var payload SendDigestEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	summary, err := s.analyticsService.GetOwnerDigest(ctx, payload.OwnerPhone, payload.PeriodStart, payload.PeriodEnd, upcomingDays)
+	if err != nil {
+		return err
+	}
+	// Only owners have something to summarise
+	if summary.TotalProperties == 0 {
+		return nil
+	}
+
+	data := s.emailData(payload, summary)
+
+	key := notifications.TemplateDailyDigest
+	if payload.Frequency == notifications.DigestWeekly {
+		key = notifications.TemplateWeeklyDigest
+	}
+	title, message := notifications.Render(s.notificationService.LocaleFor(ctx, payload.OwnerPhone), key, map[string]string{
+		"newBookings":         strconv.Itoa(data.NewBookings),
+		"cancellations":       strconv.Itoa(data.Cancellations),
+		"currency":            data.Currency,
+		"collected":           data.PaymentsCollected,
+		"arrivals":            strconv.Itoa(data.ArrivalCount),
+		"days":                strconv.Itoa(data.UpcomingDays),
+		"outstanding":         data.OutstandingDue,
+		"outstandingBookings": strconv.Itoa(data.OutstandingBookings),
+	})
+
+	// Derived from the event, so a retried event doesn't deliver the digest twice
+	notification := notifications.NewEventNotification(event, payload.OwnerPhone, notifications.TypeOwnerDigest, title, message)
+	notification.PropertyName = fmt.Sprintf("%d properties", summary.TotalProperties)
+	notification.Email = &notifications.EmailContent{
+		Kind:       email.KindDigest,
+		SubjectArg: data.Period,
+		Data:       data,
+	}
+
+	_, err = s.notificationService.Dispatch(ctx, notification)
+	return err
+}
+
+// emailData converts a digest into template data.
+func (s *Service) emailData(payload SendDigestEvent, summary *analytics.OwnerDigest) email.DigestData {
+	start := payload.PeriodStart
+	last := payload.PeriodEnd.AddDate(0, 0, -1)
+
+	data := email.DigestData{
+		OwnerName:           summary.OwnerName,
+		Period:              string(payload.Frequency) + " digest",
+		PeriodLabel:         last.Format("02 Jan 2006"),
+		TotalProperties:     summary.TotalProperties,
+		NewBookings:         summary.NewBookings,
+		NewBookingValue:     fmt.Sprintf("%.2f", summary.NewBookingValue),
+		Cancellations:       summary.Cancellations,
+		PaymentsCollected:   fmt.Sprintf("%.2f", summary.PaymentsCollected),
+		OutstandingDue:      fmt.Sprintf("%.2f", summary.OutstandingDue),
+		OutstandingBookings: summary.OutstandingBookings,
+		UpcomingDays:        upcomingDays,
+		ArrivalCount:        len(summary.UpcomingArrivals),
+		UpcomingArrivals:    []email.DigestArrival{},
+		Currency:            summary.Currency,
+	}
+	if !start.Equal(last) {
+		data.PeriodLabel = start.Format("02 Jan") + " - " + last.Format("02 Jan 2006")
+	}
+
+	for i, arrival := range summary.UpcomingArrivals {
+		if i == maxListedArrivals {
+			data.MoreArrivals = len(summary.UpcomingArrivals) - maxListedArrivals
+			break
+		}
+		data.UpcomingArrivals = append(data.UpcomingArrivals, email.DigestArrival{
+			GuestName:    arrival.GuestName,
+			PropertyName: arrival.PropertyName,
+			CheckIn:      arrival.CheckIn.Format("02 Jan"),
+			Nights:       int(arrival.CheckOut.Sub(arrival.CheckIn).Hours() / 24),
+			Payment:      arrival.PaymentStatus,
+		})
+	}
+
+	return data
+}
@@ -0,0 +1,71 @@
+package digest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db/dbtest"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+func TestHandleSendDigestEventIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+
+	owner := "9876543210"
+	if err := properties.NewService(client).CreateProperty(ctx, &properties.Property{Name: "Sea View", OwnerID: owner}); err != nil {
+		t.Fatal(err)
+	}
+
+	end := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	event, err := outbox.NewEvent(EventSendDigest, SendDigestEvent{
+		OwnerPhone:  owner,
+		Frequency:   notifications.DigestDaily,
+		PeriodStart: end.AddDate(0, 0, -1),
+		PeriodEnd:   end,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The outbox delivers at least once, so the same event may be handled twice
+	service := NewService(client)
+	for i := 0; i < 2; i++ {
+		if err := service.HandleSendDigestEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	for _, item := range table.Items("USER#" + owner) {
+		var notification notifications.Notification
+		if err := attributevalue.UnmarshalMap(item, &notification); err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(notification.SK, "NOTIFICATION#") {
+			ids = append(ids, notification.ID)
+		}
+	}
+	if len(ids) != 1 {
+		t.Fatalf("stored %d digest notifications, want 1", len(ids))
+	}
+
+	sent := 0
+	for _, item := range table.Items("NOTIFICATION#" + ids[0]) {
+		var delivery notifications.Delivery
+		if err := attributevalue.UnmarshalMap(item, &delivery); err != nil {
+			t.Fatal(err)
+		}
+		if delivery.Status == notifications.DeliverySent {
+			sent++
+		}
+	}
+	if sent != 1 {
+		t.Errorf("digest sent %d times, want once", sent)
+	}
+}
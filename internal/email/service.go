@@ -0,0 +1,59 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Service renders templates and sends them through a Mailer.
+type Service struct {
+	mailer Mailer
+}
+
+// NewService creates an email service with the given mailer.
+func NewService(mailer Mailer) *Service {
+	return &Service{mailer: mailer}
+}
+
+// NewServiceFromEnv creates an email service backed by the SMTP mailer.
+// The service is disabled if SMTP is not configured.
+func NewServiceFromEnv() *Service {
+	mailer := NewSMTPMailerFromEnv()
+	if mailer == nil {
+		return &Service{}
+	}
+	return &Service{mailer: mailer}
+}
+
+// IsEnabled returns true if a mailer is configured.
+func (s *Service) IsEnabled() bool {
+	return s != nil && s.mailer != nil
+}
+
+// SendTemplate renders the template for kind and sends it to the recipient.
+func (s *Service) SendTemplate(ctx context.Context, kind Kind, to, subjectArg string, data interface{}) error {
+	if !s.IsEnabled() {
+		return fmt.Errorf("email is not configured")
+	}
+	if to == "" {
+		return fmt.Errorf("recipient email is required")
+	}
+
+	msg, err := Render(kind, to, subjectArg, data)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s email: %w", kind, err)
+	}
+
+	log.Printf("Sent %s email to %s", kind, to)
+	return nil
+}
+
+// SendOTP sends a login code by email.
+func (s *Service) SendOTP(ctx context.Context, to, code string, expiryMinutes int) error {
+	return s.SendTemplate(ctx, KindOTP, to, "", OTPData{Code: code, Minutes: expiryMinutes})
+}
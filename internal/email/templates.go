@@ -0,0 +1,133 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+// Kind identifies a transactional email template.
+type Kind string
+
+const (
+	KindOTP                 Kind = "otp"
+	KindBookingConfirmation Kind = "booking_confirmation"
+	KindBookingCancellation Kind = "booking_cancellation"
+	KindPaymentReceipt      Kind = "payment_receipt"
+	KindNotification        Kind = "notification"
+	KindDigest              Kind = "digest"
+)
+
+// subjects holds the subject line for each template kind.
+var subjects = map[Kind]string{
+	KindOTP:                 "Your VillaBook verification code",
+	KindBookingConfirmation: "Booking confirmed: %s",
+	KindBookingCancellation: "Booking cancelled: %s",
+	KindPaymentReceipt:      "Payment received: %s",
+	KindNotification:        "VillaBook: %s",
+	KindDigest:              "Your VillaBook %s",
+}
+
+// OTPData is the template data for OTP emails.
+type OTPData struct {
+	Code    string
+	Minutes int
+}
+
+// BookingData is the template data for booking and payment emails.
+type BookingData struct {
+	GuestName    string
+	PropertyName string
+	BookingRef   string
+	CheckIn      string
+	CheckInTime  string
+	CheckOut     string
+	CheckOutTime string
+	NumNights    int
+	NumGuests    int
+	TotalAmount  string
+	AmountPaid   string
+	BalanceDue   string
+	Currency     string
+}
+
+// NotificationData is the template data for notification emails sent to owners and agents.
+type NotificationData struct {
+	Title   string
+	Message string
+}
+
+// DigestData is the template data for owner digest emails.
+type DigestData struct {
+	OwnerName           string
+	Period              string // e.g. "daily digest"
+	PeriodLabel         string // e.g. "17 Oct 2026" or "12 Oct - 18 Oct 2026"
+	TotalProperties     int
+	NewBookings         int
+	NewBookingValue     string
+	Cancellations       int
+	PaymentsCollected   string
+	OutstandingDue      string
+	OutstandingBookings int
+	UpcomingDays        int
+	ArrivalCount        int
+	UpcomingArrivals    []DigestArrival
+	MoreArrivals        int
+	Currency            string
+}
+
+// DigestArrival is one upcoming arrival listed in a digest email.
+type DigestArrival struct {
+	GuestName    string
+	PropertyName string
+	CheckIn      string
+	Nights       int
+	Payment      string
+}
+
+// Render builds a message for the given kind from its text and HTML templates.
+// subjectArg fills the %s in the subject line, if the subject has one.
+func Render(kind Kind, to, subjectArg string, data interface{}) (*Message, error) {
+	subjectFormat, ok := subjects[kind]
+	if !ok {
+		return nil, fmt.Errorf("no email template registered for %s", kind)
+	}
+	subject := subjectFormat
+	if subjectArg != "" {
+		subject = fmt.Sprintf(subjectFormat, subjectArg)
+	}
+
+	textTmpl, err := texttemplate.ParseFS(templateFS, "templates/"+string(kind)+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text template %s: %w", kind, err)
+	}
+	var text bytes.Buffer
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text template %s: %w", kind, err)
+	}
+
+	htmlTmpl, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+string(kind)+".html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template %s: %w", kind, err)
+	}
+	var html bytes.Buffer
+	layoutData := map[string]interface{}{
+		"Subject": subject,
+		"Data":    data,
+	}
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout.html", layoutData); err != nil {
+		return nil, fmt.Errorf("failed to render HTML template %s: %w", kind, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
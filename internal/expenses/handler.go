@@ -0,0 +1,216 @@
+package expenses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+// Handler provides HTTP handlers for expense endpoints.
+type Handler struct {
+	service         *Service
+	propertyService *properties.Service
+}
+
+// NewHandler creates a new expense handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service:         NewService(dbClient),
+		propertyService: properties.NewService(dbClient),
+	}
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// errorResponse maps service errors to a 404 or 403, and anything else to
+// the fallback status.
+func errorResponse(err error, fallback int) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ErrorResponse(http.StatusNotFound, "Expense not found")
+	case errors.Is(err, ErrPropertyAccess):
+		return ErrorResponse(http.StatusForbidden, err.Error())
+	default:
+		return ErrorResponse(fallback, err.Error())
+	}
+}
+
+// HandleCreateExpense handles POST /expenses endpoint.
+func (h *Handler) HandleCreateExpense(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req CreateExpenseRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	expense, err := h.service.CreateExpense(ctx, claims.Phone, claims.Role, req)
+	if err != nil {
+		return errorResponse(err, http.StatusBadRequest), nil
+	}
+	return APIResponse(http.StatusCreated, expense), nil
+}
+
+// HandleListExpenses handles GET /expenses endpoint.
+func (h *Handler) HandleListExpenses(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	params := request.QueryStringParameters
+
+	// Default to the current month
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	var err error
+	if params["startDate"] != "" {
+		if start, err = parseDate(params["startDate"]); err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid startDate format. Use YYYY-MM-DD"), nil
+		}
+	}
+	if params["endDate"] != "" {
+		if end, err = parseDate(params["endDate"]); err != nil {
+			return ErrorResponse(http.StatusBadRequest, "Invalid endDate format. Use YYYY-MM-DD"), nil
+		}
+	}
+	if end.Before(start) {
+		return ErrorResponse(http.StatusBadRequest, "endDate must not be before startDate"), nil
+	}
+
+	category := Category(params["category"])
+	if category != "" && !category.IsValid() {
+		return ErrorResponse(http.StatusBadRequest, "Invalid category"), nil
+	}
+
+	var propertyIDs []string
+	if id := params["propertyId"]; id != "" {
+		if _, err := h.service.ownedProperty(ctx, claims.Phone, claims.Role, id); err != nil {
+			return errorResponse(err, http.StatusNotFound), nil
+		}
+		propertyIDs = []string{id}
+	} else {
+		props, err := h.propertyService.ListPropertiesByOwner(ctx, claims.Phone)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, "Failed to list properties"), nil
+		}
+		for _, prop := range props {
+			propertyIDs = append(propertyIDs, prop.ID)
+		}
+	}
+
+	expenses := []*Expense{}
+	var total float64
+	for _, id := range propertyIDs {
+		propExpenses, err := h.service.ListExpenses(ctx, id, start, end)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, "Failed to list expenses"), nil
+		}
+		for _, expense := range propExpenses {
+			if category != "" && expense.Category != category {
+				continue
+			}
+			expenses = append(expenses, expense)
+			total += expense.Amount
+		}
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"expenses":  expenses,
+		"count":     len(expenses),
+		"total":     round2(total),
+		"startDate": start.Format("2006-01-02"),
+		"endDate":   end.Format("2006-01-02"),
+	}), nil
+}
+
+// HandleGetExpense handles GET /expenses/{id} endpoint.
+func (h *Handler) HandleGetExpense(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Expense ID is required"), nil
+	}
+
+	expense, err := h.service.GetExpense(ctx, claims.Phone, claims.Role, id)
+	if err != nil {
+		return errorResponse(err, http.StatusInternalServerError), nil
+	}
+	return APIResponse(http.StatusOK, expense), nil
+}
+
+// HandleUpdateExpense handles PATCH /expenses/{id} endpoint.
+func (h *Handler) HandleUpdateExpense(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Expense ID is required"), nil
+	}
+
+	var req UpdateExpenseRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	expense, err := h.service.UpdateExpense(ctx, claims.Phone, claims.Role, id, req)
+	if err != nil {
+		return errorResponse(err, http.StatusBadRequest), nil
+	}
+	return APIResponse(http.StatusOK, expense), nil
+}
+
+// HandleDeleteExpense handles DELETE /expenses/{id} endpoint.
+func (h *Handler) HandleDeleteExpense(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Expense ID is required"), nil
+	}
+
+	if err := h.service.DeleteExpense(ctx, claims.Phone, claims.Role, id); err != nil {
+		return errorResponse(err, http.StatusInternalServerError), nil
+	}
+	return APIResponse(http.StatusOK, map[string]string{
+		"message":   "Expense deleted",
+		"expenseId": id,
+	}), nil
+}
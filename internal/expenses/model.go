@@ -0,0 +1,133 @@
+// Package expenses tracks what owners spend on their properties.
+package expenses
+
+import (
+	"time"
+)
+
+// Category is the kind of expense.
+type Category string
+
+const (
+	CategoryHousekeeping Category = "housekeeping"
+	CategoryElectricity  Category = "electricity"
+	CategoryWater        Category = "water"
+	CategoryRepairs      Category = "repairs"
+	CategoryMaintenance  Category = "maintenance"
+	CategoryOTAFees      Category = "ota_fees"
+	CategoryStaffSalary  Category = "staff_salary"
+	CategoryInternet     Category = "internet"
+	CategorySupplies     Category = "supplies"
+	CategoryOther        Category = "other"
+)
+
+// Categories lists the supported expense categories.
+var Categories = []Category{
+	CategoryHousekeeping,
+	CategoryElectricity,
+	CategoryWater,
+	CategoryRepairs,
+	CategoryMaintenance,
+	CategoryOTAFees,
+	CategoryStaffSalary,
+	CategoryInternet,
+	CategorySupplies,
+	CategoryOther,
+}
+
+// IsValid checks if the category is supported.
+func (c Category) IsValid() bool {
+	for _, known := range Categories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Recurrence is how often an expense repeats.
+type Recurrence string
+
+const (
+	RecurrenceNone    Recurrence = ""
+	RecurrenceMonthly Recurrence = "monthly"
+)
+
+// IsValid checks if the recurrence is supported.
+func (r Recurrence) IsValid() bool {
+	return r == RecurrenceNone || r == RecurrenceMonthly
+}
+
+// Expense is money spent on a property. A monthly expense recurs on the same
+// day of each month from Date, until EndDate if it is set.
+type Expense struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK" json:"-"` // EXPENSE#<id>
+	SK string `dynamodbav:"SK" json:"-"` // METADATA
+
+	// GSI1 for listing by property: one-off expenses by date, recurring
+	// expenses together since they apply to every month
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty" json:"-"` // EXPENSES#<propertyId>
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty" json:"-"` // DATE#<date>#<id> or RECURRING#<id>
+
+	// Expense fields
+	ID         string    `dynamodbav:"id" json:"id"`
+	PropertyID string    `dynamodbav:"propertyId" json:"propertyId"`
+	OwnerID    string    `dynamodbav:"ownerId" json:"ownerId"`
+	Category   Category  `dynamodbav:"category" json:"category"`
+	Amount     float64   `dynamodbav:"amount" json:"amount"`
+	Currency   string    `dynamodbav:"currency" json:"currency"`
+	Date       time.Time `dynamodbav:"date" json:"date"`
+	Vendor     string    `dynamodbav:"vendor,omitempty" json:"vendor,omitempty"`
+	BookingID  string    `dynamodbav:"bookingId,omitempty" json:"bookingId,omitempty"`
+	Notes      string    `dynamodbav:"notes,omitempty" json:"notes,omitempty"`
+
+	// AttachmentRef points to a receipt or invoice stored elsewhere, such as
+	// a file URL or storage key
+	AttachmentRef string `dynamodbav:"attachmentRef,omitempty" json:"attachmentRef,omitempty"`
+
+	Recurrence Recurrence `dynamodbav:"recurrence,omitempty" json:"recurrence,omitempty"`
+	EndDate    *time.Time `dynamodbav:"endDate,omitempty" json:"endDate,omitempty"`
+
+	// Metadata
+	CreatedBy  string    `dynamodbav:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time `dynamodbav:"updatedAt" json:"updatedAt"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// CreateExpenseRequest represents the request to record an expense.
+type CreateExpenseRequest struct {
+	PropertyID    string     `json:"propertyId"`
+	Category      Category   `json:"category"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency,omitempty"` // Default: the property's currency
+	Date          string     `json:"date"`               // YYYY-MM-DD
+	Vendor        string     `json:"vendor,omitempty"`
+	BookingID     string     `json:"bookingId,omitempty"`
+	AttachmentRef string     `json:"attachmentRef,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	Recurrence    Recurrence `json:"recurrence,omitempty"`
+	EndDate       string     `json:"endDate,omitempty"` // YYYY-MM-DD, monthly expenses only
+}
+
+// UpdateExpenseRequest represents the request to change an expense. Empty
+// strings clear the optional fields.
+type UpdateExpenseRequest struct {
+	Category      *Category   `json:"category,omitempty"`
+	Amount        *float64    `json:"amount,omitempty"`
+	Date          *string     `json:"date,omitempty"`
+	Vendor        *string     `json:"vendor,omitempty"`
+	BookingID     *string     `json:"bookingId,omitempty"`
+	AttachmentRef *string     `json:"attachmentRef,omitempty"`
+	Notes         *string     `json:"notes,omitempty"`
+	Recurrence    *Recurrence `json:"recurrence,omitempty"`
+	EndDate       *string     `json:"endDate,omitempty"`
+}
+
+// Totals sums a property's expenses over a period.
+type Totals struct {
+	Total      float64              `json:"total"`
+	Count      int                  `json:"count"`
+	ByCategory map[Category]float64 `json:"byCategory"`
+}
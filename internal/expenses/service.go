@@ -0,0 +1,364 @@
+package expenses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when an expense does not exist for the caller.
+	ErrNotFound = errors.New("expense not found")
+
+	// ErrPropertyAccess is returned when the caller does not own the property.
+	ErrPropertyAccess = errors.New("you don't own this property")
+)
+
+// Service provides expense operations.
+type Service struct {
+	db              *db.Client
+	propertyService *properties.Service
+	bookingService  *bookings.Service
+}
+
+// NewService creates a new expense service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:              dbClient,
+		propertyService: properties.NewService(dbClient),
+		bookingService:  bookings.NewService(dbClient),
+	}
+}
+
+// ownedProperty returns a property the caller owns, or any property for
+// admins.
+func (s *Service) ownedProperty(ctx context.Context, phone, role, propertyID string) (*properties.Property, error) {
+	property, err := s.propertyService.GetProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if property == nil {
+		return nil, fmt.Errorf("property not found")
+	}
+	if property.OwnerID != phone && role != string(users.RoleAdmin) {
+		return nil, ErrPropertyAccess
+	}
+	return property, nil
+}
+
+// CreateExpense records an expense on a property.
+func (s *Service) CreateExpense(ctx context.Context, phone, role string, req CreateExpenseRequest) (*Expense, error) {
+	if req.PropertyID == "" {
+		return nil, fmt.Errorf("propertyId is required")
+	}
+	property, err := s.ownedProperty(ctx, phone, role, req.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseDate(req.Date)
+	if err != nil || req.Date == "" {
+		return nil, fmt.Errorf("date is required. Use YYYY-MM-DD")
+	}
+
+	now := time.Now()
+	expense := &Expense{
+		ID:            uuid.New().String(),
+		PropertyID:    property.ID,
+		OwnerID:       property.OwnerID,
+		Category:      req.Category,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Date:          date,
+		Vendor:        req.Vendor,
+		BookingID:     req.BookingID,
+		AttachmentRef: req.AttachmentRef,
+		Notes:         req.Notes,
+		Recurrence:    req.Recurrence,
+		CreatedBy:     phone,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		EntityType:    "EXPENSE",
+	}
+	if expense.Currency == "" {
+		expense.Currency = property.Currency
+	}
+	if req.EndDate != "" {
+		endDate, err := parseDate(req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endDate. Use YYYY-MM-DD")
+		}
+		expense.EndDate = &endDate
+	}
+
+	if err := s.validate(ctx, expense); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, expense); err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// validate checks an expense before it is saved.
+func (s *Service) validate(ctx context.Context, expense *Expense) error {
+	if !expense.Category.IsValid() {
+		return fmt.Errorf("invalid category %q", expense.Category)
+	}
+	if expense.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if !expense.Recurrence.IsValid() {
+		return fmt.Errorf("recurrence must be empty or monthly")
+	}
+	if expense.EndDate != nil {
+		if expense.Recurrence != RecurrenceMonthly {
+			return fmt.Errorf("endDate is only allowed for monthly expenses")
+		}
+		if expense.EndDate.Before(expense.Date) {
+			return fmt.Errorf("endDate must not be before date")
+		}
+	}
+
+	if expense.BookingID != "" {
+		booking, err := s.bookingService.GetBooking(ctx, expense.BookingID)
+		if err != nil {
+			return err
+		}
+		if booking == nil || booking.PropertyID != expense.PropertyID {
+			return fmt.Errorf("booking not found for this property")
+		}
+	}
+	return nil
+}
+
+// save writes an expense, keyed for listing by property.
+func (s *Service) save(ctx context.Context, expense *Expense) error {
+	expense.PK = "EXPENSE#" + expense.ID
+	expense.SK = "METADATA"
+	expense.GSI1PK = "EXPENSES#" + expense.PropertyID
+	expense.GSI1SK = "DATE#" + expense.Date.Format("2006-01-02") + "#" + expense.ID
+	if expense.Recurrence == RecurrenceMonthly {
+		expense.GSI1SK = "RECURRING#" + expense.ID
+	}
+
+	if err := s.db.PutItem(ctx, expense); err != nil {
+		return fmt.Errorf("failed to save expense: %w", err)
+	}
+	return nil
+}
+
+// GetExpense retrieves an expense on one of the caller's properties, or any
+// expense for admins.
+func (s *Service) GetExpense(ctx context.Context, phone, role, id string) (*Expense, error) {
+	var expense Expense
+	if err := s.db.GetItem(ctx, "EXPENSE#"+id, "METADATA", &expense); err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get expense: %w", err)
+	}
+	if expense.OwnerID != phone && role != string(users.RoleAdmin) {
+		return nil, ErrNotFound
+	}
+	return &expense, nil
+}
+
+// UpdateExpense changes an expense. Changes to a monthly expense apply to
+// every month.
+func (s *Service) UpdateExpense(ctx context.Context, phone, role, id string, req UpdateExpenseRequest) (*Expense, error) {
+	expense, err := s.GetExpense(ctx, phone, role, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Category != nil {
+		expense.Category = *req.Category
+	}
+	if req.Amount != nil {
+		expense.Amount = *req.Amount
+	}
+	if req.Date != nil {
+		if expense.Date, err = parseDate(*req.Date); err != nil || *req.Date == "" {
+			return nil, fmt.Errorf("invalid date. Use YYYY-MM-DD")
+		}
+	}
+	if req.Vendor != nil {
+		expense.Vendor = *req.Vendor
+	}
+	if req.BookingID != nil {
+		expense.BookingID = *req.BookingID
+	}
+	if req.AttachmentRef != nil {
+		expense.AttachmentRef = *req.AttachmentRef
+	}
+	if req.Notes != nil {
+		expense.Notes = *req.Notes
+	}
+	if req.Recurrence != nil {
+		expense.Recurrence = *req.Recurrence
+	}
+	if req.EndDate != nil {
+		expense.EndDate = nil
+		if *req.EndDate != "" {
+			endDate, err := parseDate(*req.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid endDate. Use YYYY-MM-DD")
+			}
+			expense.EndDate = &endDate
+		}
+	}
+	expense.UpdatedAt = time.Now()
+
+	if err := s.validate(ctx, expense); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, expense); err != nil {
+		return nil, err
+	}
+	return expense, nil
+}
+
+// DeleteExpense removes an expense. Deleting a monthly expense removes it
+// from every month; to stop it recurring instead, set its endDate.
+func (s *Service) DeleteExpense(ctx context.Context, phone, role, id string) error {
+	expense, err := s.GetExpense(ctx, phone, role, id)
+	if err != nil {
+		return err
+	}
+	return s.db.DeleteItem(ctx, expense.PK, expense.SK)
+}
+
+// ListExpenses returns a property's expenses dated within [start, end],
+// oldest first. Monthly expenses appear once for each month they recur in,
+// dated on that month's occurrence.
+func (s *Service) ListExpenses(ctx context.Context, propertyID string, start, end time.Time) ([]*Expense, error) {
+	start, end = calendarDate(start), calendarDate(end)
+
+	oneOff, err := s.query(ctx, "GSI1PK = :gsi1pk AND GSI1SK BETWEEN :start AND :end", map[string]interface{}{
+		":gsi1pk": "EXPENSES#" + propertyID,
+		":start":  "DATE#" + start.Format("2006-01-02"),
+		":end":    "DATE#" + end.Format("2006-01-02") + "#~", // After any ID on the last day
+	})
+	if err != nil {
+		return nil, err
+	}
+	recurring, err := s.query(ctx, "GSI1PK = :gsi1pk AND begins_with(GSI1SK, :prefix)", map[string]interface{}{
+		":gsi1pk": "EXPENSES#" + propertyID,
+		":prefix": "RECURRING#",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expenses := oneOff
+	for _, expense := range recurring {
+		expenses = append(expenses, occurrences(expense, start, end)...)
+	}
+	sort.SliceStable(expenses, func(i, j int) bool {
+		return expenses[i].Date.Before(expenses[j].Date)
+	})
+	return expenses, nil
+}
+
+// SumExpenses totals a property's expenses dated within [start, end].
+func (s *Service) SumExpenses(ctx context.Context, propertyID string, start, end time.Time) (*Totals, error) {
+	expenses, err := s.ListExpenses(ctx, propertyID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := &Totals{ByCategory: make(map[Category]float64)}
+	for _, expense := range expenses {
+		totals.Total += expense.Amount
+		totals.ByCategory[expense.Category] += expense.Amount
+		totals.Count++
+	}
+	totals.Total = round2(totals.Total)
+	for category, amount := range totals.ByCategory {
+		totals.ByCategory[category] = round2(amount)
+	}
+	return totals, nil
+}
+
+func (s *Service) query(ctx context.Context, keyCondition string, values map[string]interface{}) ([]*Expense, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		IndexName:        "GSI1",
+		KeyCondition:     keyCondition,
+		ExpressionValues: values,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expenses: %w", err)
+	}
+	return unmarshalExpenses(items)
+}
+
+func unmarshalExpenses(items []map[string]types.AttributeValue) ([]*Expense, error) {
+	expenses := make([]*Expense, 0, len(items))
+	for _, item := range items {
+		var expense Expense
+		if err := attributevalue.UnmarshalMap(item, &expense); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal expense: %w", err)
+		}
+		expenses = append(expenses, &expense)
+	}
+	return expenses, nil
+}
+
+// occurrences returns a copy of a monthly expense for each month it recurs
+// in within [start, end]. It recurs on the day of the month of its date, or
+// the last day of shorter months.
+func occurrences(expense *Expense, start, end time.Time) []*Expense {
+	last := end
+	if expense.EndDate != nil && expense.EndDate.Before(last) {
+		last = calendarDate(*expense.EndDate)
+	}
+
+	var result []*Expense
+	first := calendarDate(expense.Date)
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if startMonth := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(startMonth) {
+		month = startMonth
+	}
+	for ; !month.After(last); month = month.AddDate(0, 1, 0) {
+		day := first.Day()
+		if days := month.AddDate(0, 1, -1).Day(); day > days {
+			day = days
+		}
+		date := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC)
+		if date.Before(start) || date.After(last) {
+			continue
+		}
+
+		occurrence := *expense
+		occurrence.Date = date
+		result = append(result, &occurrence)
+	}
+	return result
+}
+
+// parseDate parses a YYYY-MM-DD date.
+func parseDate(value string) (time.Time, error) {
+	return time.Parse("2006-01-02", value)
+}
+
+// calendarDate returns the date of t as UTC midnight, the form expense dates
+// are stored in.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,124 @@
+// Package i18n provides the locales users can choose and rendering of
+// localized message templates with English fallback.
+package i18n
+
+import (
+	"strings"
+	"time"
+)
+
+// Locale is a supported language, as an ISO 639-1 code.
+type Locale string
+
+const (
+	English Locale = "en"
+	Hindi   Locale = "hi"
+	Marathi Locale = "mr"
+)
+
+// Default is used when a user has not chosen a locale, and as the fallback
+// for templates that have no translation.
+const Default = English
+
+// Supported lists the locales users can choose.
+var Supported = []Locale{English, Hindi, Marathi}
+
+// IsValid checks if the locale is supported.
+func (l Locale) IsValid() bool {
+	for _, supported := range Supported {
+		if l == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// OrDefault returns the locale, or Default if it is empty or unsupported.
+func (l Locale) OrDefault() Locale {
+	if l.IsValid() {
+		return l
+	}
+	return Default
+}
+
+// Parse converts a language code such as "hi" or "hi-IN" to a locale.
+func Parse(code string) (Locale, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	locale := Locale(code)
+	return locale, locale.IsValid()
+}
+
+// Catalog holds message templates by key and locale. Templates use
+// {name} placeholders.
+type Catalog map[string]map[Locale]string
+
+// Render returns the template for key in locale, falling back to English,
+// with vars interpolated. Returns "" for an unknown key.
+func (c Catalog) Render(locale Locale, key string, vars map[string]string) string {
+	translations, ok := c[key]
+	if !ok {
+		return ""
+	}
+	text, ok := translations[locale]
+	if !ok || text == "" {
+		text = translations[Default]
+	}
+	return Interpolate(text, vars)
+}
+
+// Has reports whether key has a template in locale (without fallback).
+func (c Catalog) Has(locale Locale, key string) bool {
+	_, ok := c[key][locale]
+	return ok
+}
+
+// Interpolate replaces {name} placeholders with values from vars.
+// Placeholders without a value are left as they are.
+func Interpolate(text string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// phrases holds the shared words used inside other templates.
+var phrases = Catalog{
+	"day.today": {
+		English: "today",
+		Hindi:   "आज",
+		Marathi: "आज",
+	},
+	"day.tomorrow": {
+		English: "tomorrow",
+		Hindi:   "कल",
+		Marathi: "उद्या",
+	},
+	"day.on": {
+		English: "on {date}",
+		Hindi:   "{date} को",
+		Marathi: "{date} रोजी",
+	},
+}
+
+// RelativeDay describes a date relative to today, e.g. "tomorrow" or
+// "on 15 Mar". Both are calendar dates; times are ignored.
+func RelativeDay(locale Locale, today, date time.Time) string {
+	from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch int(to.Sub(from).Hours() / 24) {
+	case 0:
+		return phrases.Render(locale, "day.today", nil)
+	case 1:
+		return phrases.Render(locale, "day.tomorrow", nil)
+	default:
+		return phrases.Render(locale, "day.on", map[string]string{"date": date.Format("2 Jan")})
+	}
+}
@@ -4,6 +4,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/booking-villa-backend/internal/utils"
@@ -17,19 +18,76 @@ const (
 	UserClaimsKey ContextKey = "userClaims"
 )
 
+// SessionValidator checks that a validated token's session is still active
+// (e.g. the user exists and has not revoked their sessions).
+type SessionValidator func(ctx context.Context, claims *utils.TokenClaims) error
+
+// APIKeyResolver resolves a plaintext API key into claims limited to the key's
+// scopes and properties. It returns an error for unknown, revoked or expired keys.
+type APIKeyResolver func(ctx context.Context, key string) (*utils.TokenClaims, error)
+
+// RequestAuditor records a request made with an impersonation token.
+// allowed is false if the middleware rejected the request.
+type RequestAuditor func(ctx context.Context, claims *utils.TokenClaims, request events.APIGatewayProxyRequest, allowed bool)
+
+// DenialAuditor records a request rejected by an authorization check.
+type DenialAuditor func(ctx context.Context, claims *utils.TokenClaims, request events.APIGatewayProxyRequest, reason string)
+
 // AuthMiddleware wraps a handler function to require JWT authentication.
-type AuthMiddleware struct{}
+type AuthMiddleware struct {
+	validateSession    SessionValidator
+	resolveAPIKey      APIKeyResolver
+	auditImpersonation RequestAuditor
+	auditDenial        DenialAuditor
+}
 
 // NewAuthMiddleware creates a new auth middleware instance.
 func NewAuthMiddleware() *AuthMiddleware {
 	return &AuthMiddleware{}
 }
 
+// SetSessionValidator sets the check run on every authenticated request.
+func (m *AuthMiddleware) SetSessionValidator(validator SessionValidator) {
+	m.validateSession = validator
+}
+
+// SetAPIKeyResolver sets the resolver used for requests carrying an X-API-Key header.
+func (m *AuthMiddleware) SetAPIKeyResolver(resolver APIKeyResolver) {
+	m.resolveAPIKey = resolver
+}
+
+// SetImpersonationAuditor sets the auditor called for every request made with
+// an impersonation token.
+func (m *AuthMiddleware) SetImpersonationAuditor(auditor RequestAuditor) {
+	m.auditImpersonation = auditor
+}
+
+// SetDenialAuditor sets the auditor called when an authenticated request is
+// rejected for lack of permission (role, scope or property).
+func (m *AuthMiddleware) SetDenialAuditor(auditor DenialAuditor) {
+	m.auditDenial = auditor
+}
+
+// deny audits a rejected request and returns a 403 response.
+func (m *AuthMiddleware) deny(ctx context.Context, claims *utils.TokenClaims, request events.APIGatewayProxyRequest, message string) events.APIGatewayProxyResponse {
+	if m.auditDenial != nil {
+		m.auditDenial(ctx, claims, request, message)
+	}
+	return errorResponse(http.StatusForbidden, message)
+}
+
 // Handler type for Lambda handlers.
 type Handler func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 
 // Authenticate wraps a handler to require valid JWT token.
+// Restricted tokens (those issued for a purpose such as a 2FA challenge) are rejected.
 func (m *AuthMiddleware) Authenticate(handler Handler) Handler {
+	return m.AuthenticateForPurpose(handler, "")
+}
+
+// AuthenticateForPurpose wraps a handler to require a valid JWT token whose
+// purpose is one of purposes. The empty purpose accepts regular session tokens.
+func (m *AuthMiddleware) AuthenticateForPurpose(handler Handler, purposes ...string) Handler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		// Extract Authorization header (case-insensitive)
 		authHeader := request.Headers["Authorization"]
@@ -53,6 +111,25 @@ func (m *AuthMiddleware) Authenticate(handler Handler) Handler {
 			return errorResponse(http.StatusUnauthorized, "Invalid or expired token"), nil
 		}
 
+		// Reject tokens issued for another purpose
+		if !containsString(purposes, claims.Purpose) {
+			return errorResponse(http.StatusUnauthorized, "Token cannot be used for this endpoint"), nil
+		}
+
+		// Reject tokens whose session has been revoked
+		if !m.isSessionValid(ctx, claims) {
+			return errorResponse(http.StatusUnauthorized, "Session is no longer valid"), nil
+		}
+
+		// Read-only tokens can't modify anything; impersonated requests are audited
+		allowed := !claims.ReadOnly || isReadOnlyMethod(request.HTTPMethod)
+		if claims.ImpersonatorPhone != "" && m.auditImpersonation != nil {
+			m.auditImpersonation(ctx, claims, request, allowed)
+		}
+		if !allowed {
+			return errorResponse(http.StatusForbidden, "Read-only token cannot modify data"), nil
+		}
+
 		// Add claims to context
 		ctx = context.WithValue(ctx, UserClaimsKey, claims)
 
@@ -65,6 +142,52 @@ func (m *AuthMiddleware) Authenticate(handler Handler) Handler {
 	}
 }
 
+// AuthenticateWithScope wraps a handler to require either a valid JWT token or
+// an API key (X-API-Key header) that has been granted scope.
+func (m *AuthMiddleware) AuthenticateWithScope(scope string, handler Handler) Handler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		apiKey := getHeader(request, "X-API-Key")
+		if apiKey == "" {
+			return m.Authenticate(handler)(ctx, request)
+		}
+
+		if m.resolveAPIKey == nil {
+			return errorResponse(http.StatusUnauthorized, "API keys are not supported"), nil
+		}
+
+		claims, err := m.resolveAPIKey(ctx, apiKey)
+		if err != nil {
+			return errorResponse(http.StatusUnauthorized, "Invalid or expired API key"), nil
+		}
+
+		// Keys are subject to the same session checks as tokens (revoked
+		// sessions, required 2FA)
+		if !m.isSessionValid(ctx, claims) {
+			return errorResponse(http.StatusUnauthorized, "Session is no longer valid"), nil
+		}
+
+		if !claims.HasScope(scope) {
+			return m.deny(ctx, claims, request, "API key is missing scope "+scope), nil
+		}
+
+		// Reject requests for a property the key is not allowed to access
+		if propertyID := request.QueryStringParameters["propertyId"]; propertyID != "" && !claims.CanAccessProperty(propertyID) {
+			return m.deny(ctx, claims, request, "API key is not allowed to access this property"), nil
+		}
+
+		ctx = context.WithValue(ctx, UserClaimsKey, claims)
+
+		if request.Headers == nil {
+			request.Headers = map[string]string{}
+		}
+		request.Headers["X-User-Phone"] = claims.Phone
+		request.Headers["X-User-Role"] = claims.Role
+		request.Headers["X-User-ID"] = claims.UserID
+
+		return handler(ctx, request)
+	}
+}
+
 // OptionalAuth wraps a handler to extract JWT if present but not require it.
 func (m *AuthMiddleware) OptionalAuth(handler Handler) Handler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -77,7 +200,7 @@ func (m *AuthMiddleware) OptionalAuth(handler Handler) Handler {
 			tokenString, err := utils.ExtractTokenFromHeader(authHeader)
 			if err == nil {
 				claims, err := utils.ValidateToken(tokenString)
-				if err == nil {
+				if err == nil && claims.Purpose == "" && claims.ImpersonatorPhone == "" && m.isSessionValid(ctx, claims) {
 					ctx = context.WithValue(ctx, UserClaimsKey, claims)
 					request.Headers["X-User-Phone"] = claims.Phone
 					request.Headers["X-User-Role"] = claims.Role
@@ -90,12 +213,59 @@ func (m *AuthMiddleware) OptionalAuth(handler Handler) Handler {
 	}
 }
 
+// isSessionValid runs the session validator, if one is configured.
+func (m *AuthMiddleware) isSessionValid(ctx context.Context, claims *utils.TokenClaims) bool {
+	if m.validateSession == nil {
+		return true
+	}
+	return m.validateSession(ctx, claims) == nil
+}
+
+// isReadOnlyMethod checks if an HTTP method doesn't modify data.
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// containsString checks if s is one of values.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getHeader returns a request header, matching the name case-insensitively.
+func getHeader(request events.APIGatewayProxyRequest, name string) string {
+	if value, ok := request.Headers[name]; ok {
+		return value
+	}
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
 // GetClaimsFromContext retrieves the user claims from the context.
 func GetClaimsFromContext(ctx context.Context) (*utils.TokenClaims, bool) {
 	claims, ok := ctx.Value(UserClaimsKey).(*utils.TokenClaims)
 	return claims, ok
 }
 
+// CanAccessProperty checks if the caller's credentials allow access to a property.
+// Only API keys restricted to specific properties are limited; user sessions
+// rely on the ownership checks done by each handler.
+func CanAccessProperty(ctx context.Context, propertyID string) bool {
+	claims, ok := GetClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return claims.CanAccessProperty(propertyID)
+}
+
 // GetUserPhoneFromRequest extracts the user phone from request headers.
 func GetUserPhoneFromRequest(request events.APIGatewayProxyRequest) string {
 	return request.Headers["X-User-Phone"]
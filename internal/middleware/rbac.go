@@ -13,10 +13,10 @@ type RBACMiddleware struct {
 	authMiddleware *AuthMiddleware
 }
 
-// NewRBACMiddleware creates a new RBAC middleware.
-func NewRBACMiddleware() *RBACMiddleware {
+// NewRBACMiddleware creates a new RBAC middleware that authenticates with authMiddleware.
+func NewRBACMiddleware(authMiddleware *AuthMiddleware) *RBACMiddleware {
 	return &RBACMiddleware{
-		authMiddleware: NewAuthMiddleware(),
+		authMiddleware: authMiddleware,
 	}
 }
 
@@ -33,17 +33,8 @@ func (m *RBACMiddleware) RequireRoles(roles ...users.Role) func(Handler) Handler
 				}
 
 				// Check if user's role is in the allowed roles
-				userRole := users.Role(claims.Role)
-				allowed := false
-				for _, role := range roles {
-					if userRole == role {
-						allowed = true
-						break
-					}
-				}
-
-				if !allowed {
-					return errorResponse(http.StatusForbidden, "Insufficient permissions"), nil
+				if !hasRole(claims.Role, roles) {
+					return m.authMiddleware.deny(ctx, claims, request, "Insufficient permissions"), nil
 				}
 
 				return handler(ctx, request)
@@ -54,6 +45,42 @@ func (m *RBACMiddleware) RequireRoles(roles ...users.Role) func(Handler) Handler
 	}
 }
 
+// RequireScope returns middleware that accepts either a user token or an API key
+// with the given scope, and requires the caller to have one of the specified roles.
+// API keys act with their creator's role, so both checks apply to them.
+func (m *RBACMiddleware) RequireScope(scope string, roles ...users.Role) func(Handler) Handler {
+	return func(handler Handler) Handler {
+		return m.authMiddleware.AuthenticateWithScope(scope, func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			claims, ok := GetClaimsFromContext(ctx)
+			if !ok {
+				return errorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+			}
+
+			if !hasRole(claims.Role, roles) {
+				return m.authMiddleware.deny(ctx, claims, request, "Insufficient permissions"), nil
+			}
+
+			return handler(ctx, request)
+		})
+	}
+}
+
+// RequireAnyWithScope returns middleware that accepts any authenticated user or
+// an API key with the given scope.
+func (m *RBACMiddleware) RequireAnyWithScope(scope string) func(Handler) Handler {
+	return m.RequireScope(scope, users.RoleAdmin, users.RoleOwner, users.RoleAgent)
+}
+
+// hasRole checks if role is one of roles.
+func hasRole(role string, roles []users.Role) bool {
+	for _, r := range roles {
+		if users.Role(role) == r {
+			return true
+		}
+	}
+	return false
+}
+
 // RequireAdmin returns middleware that requires admin role.
 func (m *RBACMiddleware) RequireAdmin() func(Handler) Handler {
 	return m.RequireRoles(users.RoleAdmin)
@@ -0,0 +1,29 @@
+package middleware
+
+// API key scopes. User (JWT) sessions implicitly hold every scope.
+const (
+	ScopeBookingsRead    = "bookings:read"
+	ScopeBookingsWrite   = "bookings:write"
+	ScopePropertiesRead  = "properties:read"
+	ScopeAnalyticsRead   = "analytics:read"
+	ScopeAnalyticsExport = "analytics:export"
+)
+
+// ValidScopes lists every scope that can be granted to an API key.
+var ValidScopes = []string{
+	ScopeBookingsRead,
+	ScopeBookingsWrite,
+	ScopePropertiesRead,
+	ScopeAnalyticsRead,
+	ScopeAnalyticsExport,
+}
+
+// IsValidScope checks if a scope is known.
+func IsValidScope(scope string) bool {
+	for _, s := range ValidScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
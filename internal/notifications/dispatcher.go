@@ -0,0 +1,375 @@
+// Package notifications provides in-app notification services.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
+	"github.com/booking-villa-backend/internal/i18n"
+	"github.com/booking-villa-backend/internal/realtime"
+	"github.com/booking-villa-backend/internal/sms"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/google/uuid"
+)
+
+// deliveryRetentionDays is how long delivery records are kept.
+const deliveryRetentionDays = 90
+
+// DeliveryStatus represents the result of a delivery attempt.
+type DeliveryStatus string
+
+const (
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed"
+	DeliverySkipped DeliveryStatus = "skipped"
+
+	// DeliveryDeferred means the channel was held back during quiet hours
+	// and queued for when they end.
+	DeliveryDeferred DeliveryStatus = "deferred"
+)
+
+// ErrChannelUnavailable is returned by senders that cannot deliver to a
+// recipient, e.g. because the channel is not configured or the user has no
+// email address. Such attempts are recorded as skipped rather than failed.
+var ErrChannelUnavailable = errors.New("channel unavailable")
+
+// Delivery records one attempt to deliver a notification on one channel.
+type Delivery struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // NOTIFICATION#<notificationId>
+	SK string `dynamodbav:"SK"` // DELIVERY#<timestamp>#<channel>
+
+	// Delivery fields
+	ID             string           `dynamodbav:"id" json:"id"`
+	NotificationID string           `dynamodbav:"notificationId" json:"notificationId"`
+	UserPhone      string           `dynamodbav:"userPhone" json:"userPhone"`
+	Type           NotificationType `dynamodbav:"type" json:"type"`
+	Channel        Channel          `dynamodbav:"channel" json:"channel"`
+	Status         DeliveryStatus   `dynamodbav:"status" json:"status"`
+	Reason         string           `dynamodbav:"reason,omitempty" json:"reason,omitempty"`
+
+	// Metadata
+	AttemptedAt time.Time `dynamodbav:"attemptedAt" json:"attemptedAt"`
+	TTL         int64     `dynamodbav:"TTL,omitempty" json:"-"`
+	EntityType  string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Recipient holds the contact details a sender needs.
+type Recipient struct {
+	Phone  string
+	Email  string
+	Locale i18n.Locale
+}
+
+// Sender delivers a notification on a single channel.
+type Sender interface {
+	Channel() Channel
+	Send(ctx context.Context, recipient *Recipient, notification *Notification) error
+}
+
+// Dispatcher fans a notification out to the channels the recipient has enabled
+// and records the outcome of every attempt.
+type Dispatcher struct {
+	service     *Service
+	userService *users.Service
+	senders     map[Channel]Sender
+}
+
+// NewDispatcher creates a dispatcher with the given channel senders.
+// Channels without a sender are recorded as skipped.
+func NewDispatcher(service *Service, userService *users.Service, senders ...Sender) *Dispatcher {
+	d := &Dispatcher{
+		service:     service,
+		userService: userService,
+		senders:     make(map[Channel]Sender, len(senders)),
+	}
+	for _, sender := range senders {
+		d.senders[sender.Channel()] = sender
+	}
+	return d
+}
+
+// Dispatch delivers a notification according to the recipient's preferences.
+// Failures on one channel do not stop delivery on the others; every attempt
+// is recorded. SMS during quiet hours is queued for when they end. Channels
+// the notification was already sent or queued on are skipped, so dispatching
+// it again only retries the rest. An error is returned only if preferences
+// cannot be loaded, held-back channels cannot be queued, or all enabled
+// channels failed.
+func (d *Dispatcher) Dispatch(ctx context.Context, notification *Notification) ([]*Delivery, error) {
+	done := d.handledChannels(ctx, notification.ID)
+	return d.deliver(ctx, notification, AllChannels, func(channel Channel) bool {
+		_, ok := done[channel]
+		return ok
+	})
+}
+
+// DispatchDeferred delivers a notification on channels that were held back
+// during quiet hours. Channels it has since been sent on are skipped, and
+// channels the recipient has turned off in the meantime are dropped.
+func (d *Dispatcher) DispatchDeferred(ctx context.Context, notification *Notification, channels []Channel) ([]*Delivery, error) {
+	done := d.handledChannels(ctx, notification.ID)
+	return d.deliver(ctx, notification, channels, func(channel Channel) bool {
+		return done[channel] == DeliverySent
+	})
+}
+
+// deliver sends a notification on each of channels that is enabled and not
+// skipped, deferring interruptive channels during quiet hours.
+func (d *Dispatcher) deliver(ctx context.Context, notification *Notification, channels []Channel, skip func(Channel) bool) ([]*Delivery, error) {
+	prefs, err := d.service.GetPreferences(ctx, notification.UserPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := prefs.ChannelsFor(notification.Type)
+	now := time.Now()
+	quiet := prefs.QuietHours.Contains(now)
+
+	var recipient *Recipient
+	var deliveries []*Delivery
+	var held []Channel
+	failed, attempted := 0, 0
+
+	for _, channel := range channels {
+		if !settings.Enabled(channel) || skip(channel) {
+			continue
+		}
+
+		if quiet && channel.IsInterruptive() {
+			held = append(held, channel)
+			continue
+		}
+
+		sender, ok := d.senders[channel]
+		if !ok {
+			deliveries = append(deliveries, d.record(ctx, notification, channel, DeliverySkipped, "channel not configured"))
+			continue
+		}
+
+		if recipient == nil {
+			recipient, err = d.resolveRecipient(ctx, notification.UserPhone)
+			if err != nil {
+				return deliveries, err
+			}
+		}
+
+		attempted++
+		err := sender.Send(ctx, recipient, notification)
+		switch {
+		case err == nil:
+			deliveries = append(deliveries, d.record(ctx, notification, channel, DeliverySent, ""))
+		case errors.Is(err, ErrChannelUnavailable):
+			attempted--
+			deliveries = append(deliveries, d.record(ctx, notification, channel, DeliverySkipped, err.Error()))
+		default:
+			failed++
+			log.Printf("Failed to deliver %s notification %s to %s: %v", channel, notification.ID, notification.UserPhone, err)
+			deliveries = append(deliveries, d.record(ctx, notification, channel, DeliveryFailed, err.Error()))
+		}
+	}
+
+	if len(held) > 0 {
+		until := prefs.QuietHours.EndAfter(now)
+		if err := d.deferDelivery(ctx, notification, held, until); err != nil {
+			return deliveries, err
+		}
+		reason := "quiet hours until " + until.Format("15:04")
+		for _, channel := range held {
+			deliveries = append(deliveries, d.record(ctx, notification, channel, DeliveryDeferred, reason))
+		}
+	}
+
+	if attempted > 0 && failed == attempted {
+		return deliveries, fmt.Errorf("failed to deliver notification %s on any channel", notification.ID)
+	}
+	return deliveries, nil
+}
+
+// deferDelivery queues delivery of a notification on channels for when
+// quiet hours end.
+func (d *Dispatcher) deferDelivery(ctx context.Context, notification *Notification, channels []Channel, until time.Time) error {
+	event, err := NewDeferredDeliveryEvent(notification, channels)
+	if err != nil {
+		return err
+	}
+	if err := d.service.db.TransactWrite(ctx, event.Delay(until).TransactItem()); err != nil {
+		return fmt.Errorf("failed to defer notification %s: %w", notification.ID, err)
+	}
+	return nil
+}
+
+// handledChannels returns the channels a notification has already been sent
+// or deferred on, with the latest of those outcomes for each. Lookup errors
+// are logged, and the notification is treated as unsent.
+func (d *Dispatcher) handledChannels(ctx context.Context, notificationID string) map[Channel]DeliveryStatus {
+	items, err := d.service.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition:     "PK = :pk AND begins_with(SK, :skPrefix)",
+		FilterExpression: "#status IN (:sent, :deferred)",
+		ExpressionValues: map[string]interface{}{
+			":pk":       "NOTIFICATION#" + notificationID,
+			":skPrefix": "DELIVERY#",
+			":sent":     string(DeliverySent),
+			":deferred": string(DeliveryDeferred),
+		},
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+	})
+	if err != nil {
+		log.Printf("Failed to get deliveries for notification %s: %v", notificationID, err)
+		return nil
+	}
+
+	// Deliveries sort by attempt time, so later outcomes replace earlier ones
+	handled := make(map[Channel]DeliveryStatus, len(items))
+	for _, item := range items {
+		var delivery Delivery
+		if err := attributevalue.UnmarshalMap(item, &delivery); err != nil {
+			log.Printf("Failed to unmarshal delivery for notification %s: %v", notificationID, err)
+			continue
+		}
+		handled[delivery.Channel] = delivery.Status
+	}
+	return handled
+}
+
+// resolveRecipient looks up the contact details for a user.
+func (d *Dispatcher) resolveRecipient(ctx context.Context, phone string) (*Recipient, error) {
+	recipient := &Recipient{Phone: phone, Locale: i18n.Default}
+
+	user, err := d.userService.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if user != nil {
+		recipient.Email = user.Email
+		recipient.Locale = user.Locale.OrDefault()
+	}
+
+	return recipient, nil
+}
+
+// record stores a delivery attempt. Storage errors are logged, not returned,
+// so a bookkeeping failure never blocks delivery on other channels.
+func (d *Dispatcher) record(ctx context.Context, notification *Notification, channel Channel, status DeliveryStatus, reason string) *Delivery {
+	now := time.Now()
+	delivery := &Delivery{
+		PK:             "NOTIFICATION#" + notification.ID,
+		SK:             fmt.Sprintf("DELIVERY#%s#%s", now.UTC().Format(time.RFC3339Nano), channel),
+		ID:             uuid.New().String(),
+		NotificationID: notification.ID,
+		UserPhone:      notification.UserPhone,
+		Type:           notification.Type,
+		Channel:        channel,
+		Status:         status,
+		Reason:         reason,
+		AttemptedAt:    now,
+		TTL:            db.CalculateTTL(deliveryRetentionDays * 24 * time.Hour),
+		EntityType:     "NOTIFICATION_DELIVERY",
+	}
+
+	if err := d.service.db.PutItem(ctx, delivery); err != nil {
+		log.Printf("Failed to record %s delivery for notification %s: %v", channel, notification.ID, err)
+	}
+	return delivery
+}
+
+// inAppSender stores the notification in the user's in-app feed and pushes
+// it to their connected clients.
+type inAppSender struct {
+	service  *Service
+	realtime *realtime.Service
+}
+
+func (s *inAppSender) Channel() Channel { return ChannelInApp }
+
+func (s *inAppSender) Send(ctx context.Context, _ *Recipient, notification *Notification) error {
+	if err := s.service.CreateNotification(ctx, notification); err != nil {
+		// Stored by an earlier delivery of the same event; clients have it already
+		if errors.Is(err, ErrNotificationExists) {
+			return nil
+		}
+		return err
+	}
+
+	// The notification is stored; clients that miss the push see it on their next fetch
+	err := s.realtime.PublishNotification(ctx, notification.UserPhone, realtime.NotificationCreatedData{
+		NotificationID: notification.ID,
+		Type:           string(notification.Type),
+		Title:          notification.Title,
+		Message:        notification.Message,
+		BookingID:      notification.BookingID,
+		PropertyID:     notification.PropertyID,
+	})
+	if err != nil {
+		log.Printf("Failed to push notification %s: %v", notification.ID, err)
+	}
+	return nil
+}
+
+// smsSender sends booking alerts through the DLT-registered SMS template.
+type smsSender struct {
+	client *sms.Client
+}
+
+func (s *smsSender) Channel() Channel { return ChannelSMS }
+
+func (s *smsSender) Send(ctx context.Context, recipient *Recipient, notification *Notification) error {
+	if !s.client.IsEnabled() {
+		return fmt.Errorf("%w: SMS is not configured", ErrChannelUnavailable)
+	}
+
+	// Phones are stored without the country code
+	return s.client.SendLocalized(ctx, "91"+recipient.Phone, sms.KindBookingAlert, recipient.Locale, map[string]string{
+		"title":      sms.Truncate(notification.Title),
+		"property":   sms.Truncate(fallback(notification.PropertyName, "your property")),
+		"guest":      sms.Truncate(fallback(notification.GuestName, "-")),
+		"bookingRef": sms.Truncate(fallback(shortRef(notification.BookingID), "-")),
+	})
+}
+
+// emailSender sends the notification by email to the user's address.
+type emailSender struct {
+	service *email.Service
+}
+
+func (s *emailSender) Channel() Channel { return ChannelEmail }
+
+func (s *emailSender) Send(ctx context.Context, recipient *Recipient, notification *Notification) error {
+	if !s.service.IsEnabled() {
+		return fmt.Errorf("%w: email is not configured", ErrChannelUnavailable)
+	}
+	if recipient.Email == "" {
+		return fmt.Errorf("%w: user has no email address", ErrChannelUnavailable)
+	}
+
+	if content := notification.Email; content != nil {
+		return s.service.SendTemplate(ctx, content.Kind, recipient.Email, content.SubjectArg, content.Data)
+	}
+
+	return s.service.SendTemplate(ctx, email.KindNotification, recipient.Email, notification.Title, email.NotificationData{
+		Title:   notification.Title,
+		Message: notification.Message,
+	})
+}
+
+// fallback returns value, or def if value is empty.
+func fallback(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// shortRef returns the short booking reference shown to users.
+func shortRef(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,157 @@
+// Package notifications provides in-app notification services.
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/google/uuid"
+)
+
+// NewEventNotification creates the notification an outbox event delivers to
+// userPhone. Its ID and creation time are derived from the event, so a
+// retried event stores the same notification instead of a duplicate.
+func NewEventNotification(event *outbox.Event, userPhone string, notifType NotificationType, title, message string) *Notification {
+	id := uuid.NewSHA1(uuid.NameSpaceURL, []byte(event.ID+"#"+userPhone)).String()
+	return newNotification(id, event.CreatedAt, userPhone, notifType, title, message)
+}
+
+// EventBookingNotification is the outbox event that delivers a booking
+// notification to one user.
+const EventBookingNotification outbox.EventType = "notification.booking"
+
+// BookingNotificationEvent is the payload of EventBookingNotification.
+type BookingNotificationEvent struct {
+	UserPhone    string           `json:"userPhone"`
+	Type         NotificationType `json:"type"`
+	BookingID    string           `json:"bookingId"`
+	PropertyID   string           `json:"propertyId"`
+	PropertyName string           `json:"propertyName"`
+	GuestName    string           `json:"guestName"`
+}
+
+// NewBookingNotificationEvent creates an outbox event that notifies userPhone about a booking.
+// It should be written in the same transaction as the booking change.
+func NewBookingNotificationEvent(userPhone string, notifType NotificationType, bookingID, propertyID, propertyName, guestName string) (*outbox.Event, error) {
+	return outbox.NewEvent(EventBookingNotification, BookingNotificationEvent{
+		UserPhone:    userPhone,
+		Type:         notifType,
+		BookingID:    bookingID,
+		PropertyID:   propertyID,
+		PropertyName: propertyName,
+		GuestName:    guestName,
+	})
+}
+
+// HandleBookingNotificationEvent delivers the notification described by an outbox event.
+func (s *Service) HandleBookingNotificationEvent(ctx context.Context, event *outbox.Event) error {
+	var payload BookingNotificationEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	title, message := s.renderBookingNotification(ctx, payload.UserPhone, payload.Type, payload.PropertyName, payload.GuestName)
+	notification := NewEventNotification(event, payload.UserPhone, payload.Type, title, message)
+	notification.BookingID = payload.BookingID
+	notification.PropertyID = payload.PropertyID
+	notification.PropertyName = payload.PropertyName
+	notification.GuestName = payload.GuestName
+
+	_, err := s.Dispatch(ctx, notification)
+	return err
+}
+
+// EventNotification is the outbox event that delivers a prepared notification
+// to one user, for callers that write their own title and message.
+const EventNotification outbox.EventType = "notification.send"
+
+// NotificationEvent is the payload of EventNotification.
+type NotificationEvent struct {
+	UserPhone    string           `json:"userPhone"`
+	Type         NotificationType `json:"type"`
+	Title        string           `json:"title"`
+	Message      string           `json:"message"`
+	BookingID    string           `json:"bookingId,omitempty"`
+	PropertyID   string           `json:"propertyId,omitempty"`
+	PropertyName string           `json:"propertyName,omitempty"`
+	GuestName    string           `json:"guestName,omitempty"`
+}
+
+// NewNotificationEvent creates an outbox event that delivers the notification.
+func NewNotificationEvent(payload NotificationEvent) (*outbox.Event, error) {
+	return outbox.NewEvent(EventNotification, payload)
+}
+
+// HandleNotificationEvent delivers the notification described by an outbox event.
+func (s *Service) HandleNotificationEvent(ctx context.Context, event *outbox.Event) error {
+	var payload NotificationEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	notification := NewEventNotification(event, payload.UserPhone, payload.Type, payload.Title, payload.Message)
+	notification.BookingID = payload.BookingID
+	notification.PropertyID = payload.PropertyID
+	notification.PropertyName = payload.PropertyName
+	notification.GuestName = payload.GuestName
+
+	_, err := s.Dispatch(ctx, notification)
+	return err
+}
+
+// EventDeferredDelivery is the outbox event that delivers a notification on
+// the channels quiet hours held back. It is due when they end.
+const EventDeferredDelivery outbox.EventType = "notification.deferred"
+
+// DeferredDeliveryEvent is the payload of EventDeferredDelivery. It carries
+// the notification itself, since only in-app notifications are stored.
+type DeferredDeliveryEvent struct {
+	NotificationID string           `json:"notificationId"`
+	UserPhone      string           `json:"userPhone"`
+	Type           NotificationType `json:"type"`
+	Title          string           `json:"title"`
+	Message        string           `json:"message"`
+	BookingID      string           `json:"bookingId,omitempty"`
+	PropertyID     string           `json:"propertyId,omitempty"`
+	PropertyName   string           `json:"propertyName,omitempty"`
+	GuestName      string           `json:"guestName,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	Channels       []Channel        `json:"channels"`
+}
+
+// NewDeferredDeliveryEvent creates an outbox event that delivers the
+// notification on channels. Delay it until quiet hours end.
+func NewDeferredDeliveryEvent(notification *Notification, channels []Channel) (*outbox.Event, error) {
+	return outbox.NewEvent(EventDeferredDelivery, DeferredDeliveryEvent{
+		NotificationID: notification.ID,
+		UserPhone:      notification.UserPhone,
+		Type:           notification.Type,
+		Title:          notification.Title,
+		Message:        notification.Message,
+		BookingID:      notification.BookingID,
+		PropertyID:     notification.PropertyID,
+		PropertyName:   notification.PropertyName,
+		GuestName:      notification.GuestName,
+		CreatedAt:      notification.CreatedAt,
+		Channels:       channels,
+	})
+}
+
+// HandleDeferredDeliveryEvent delivers a notification on the channels held
+// back during quiet hours.
+func (s *Service) HandleDeferredDeliveryEvent(ctx context.Context, event *outbox.Event) error {
+	var payload DeferredDeliveryEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	notification := newNotification(payload.NotificationID, payload.CreatedAt, payload.UserPhone, payload.Type, payload.Title, payload.Message)
+	notification.BookingID = payload.BookingID
+	notification.PropertyID = payload.PropertyID
+	notification.PropertyName = payload.PropertyName
+	notification.GuestName = payload.GuestName
+
+	_, err := s.dispatcher.DispatchDeferred(ctx, notification, payload.Channels)
+	return err
+}
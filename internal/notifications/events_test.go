@@ -0,0 +1,25 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/booking-villa-backend/internal/outbox"
+)
+
+func TestEventNotificationIsStable(t *testing.T) {
+	event, err := outbox.NewEvent(EventNotification, NotificationEvent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewEventNotification(event, "9876543210", TypeBookingCreated, "title", "message")
+	retry := NewEventNotification(event, "9876543210", TypeBookingCreated, "title", "message")
+	if first.ID != retry.ID || first.SK != retry.SK || first.GSI1PK != retry.GSI1PK {
+		t.Errorf("retried event gave notification %s (%s), want %s (%s)", retry.ID, retry.SK, first.ID, first.SK)
+	}
+
+	other := NewEventNotification(event, "9123456780", TypeBookingCreated, "title", "message")
+	if other.ID == first.ID {
+		t.Errorf("different recipients share notification ID %s", other.ID)
+	}
+}
@@ -4,6 +4,7 @@ package notifications
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -57,36 +58,48 @@ func (h *Handler) HandleListNotifications(ctx context.Context, request events.AP
 	}
 
 	// Parse query parameters
-	limitStr := request.QueryStringParameters["limit"]
-	unreadOnlyStr := request.QueryStringParameters["unreadOnly"]
-
-	limit := int32(50) // Default limit
-	if limitStr != "" {
+	opts := ListOptions{
+		Limit:      50, // Default limit
+		Cursor:     request.QueryStringParameters["cursor"],
+		UnreadOnly: request.QueryStringParameters["unreadOnly"] == "true",
+		Archived:   request.QueryStringParameters["archived"] == "true",
+	}
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
 		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil && l > 0 && l <= 100 {
-			limit = int32(l)
+			opts.Limit = int32(l)
 		}
 	}
 
-	unreadOnly := false
-	if unreadOnlyStr == "true" {
-		unreadOnly = true
-	}
-
-	notifications, err := h.service.GetNotificationsByUser(ctx, claims.Phone, limit, unreadOnly)
+	page, err := h.service.GetNotificationsByUser(ctx, claims.Phone, opts)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			return ErrorResponse(http.StatusBadRequest, "Invalid cursor"), nil
+		}
 		return ErrorResponse(http.StatusInternalServerError, "Failed to get notifications"), nil
 	}
 
 	// Convert to response format
-	responses := make([]NotificationResponse, 0, len(notifications))
-	for _, n := range notifications {
+	responses := make([]NotificationResponse, 0, len(page.Notifications))
+	for _, n := range page.Notifications {
 		responses = append(responses, n.ToResponse())
 	}
 
-	return APIResponse(http.StatusOK, map[string]interface{}{
+	body := map[string]interface{}{
 		"notifications": responses,
 		"count":         len(responses),
-	}), nil
+	}
+	if page.NextCursor != "" {
+		body["nextCursor"] = page.NextCursor
+	}
+	return APIResponse(http.StatusOK, body), nil
+}
+
+// notFoundResponse maps a missing notification to a 404, and anything else to a 500.
+func notFoundResponse(err error, fallback string) events.APIGatewayProxyResponse {
+	if errors.Is(err, ErrNotificationNotFound) {
+		return ErrorResponse(http.StatusNotFound, "Notification not found")
+	}
+	return ErrorResponse(http.StatusInternalServerError, fallback)
 }
 
 // HandleMarkAsRead handles the PATCH /notifications/{id}/read endpoint.
@@ -103,7 +116,7 @@ func (h *Handler) HandleMarkAsRead(ctx context.Context, request events.APIGatewa
 	}
 
 	if err := h.service.MarkAsRead(ctx, notificationID, claims.Phone); err != nil {
-		return ErrorResponse(http.StatusInternalServerError, "Failed to mark notification as read"), nil
+		return notFoundResponse(err, "Failed to mark notification as read"), nil
 	}
 
 	return APIResponse(http.StatusOK, map[string]interface{}{
@@ -112,6 +125,72 @@ func (h *Handler) HandleMarkAsRead(ctx context.Context, request events.APIGatewa
 	}), nil
 }
 
+// HandleArchive handles the PATCH /notifications/{id}/archive endpoint.
+func (h *Handler) HandleArchive(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	notificationID := request.PathParameters["id"]
+	if notificationID == "" {
+		return ErrorResponse(http.StatusBadRequest, "Notification ID is required"), nil
+	}
+
+	if err := h.service.Archive(ctx, notificationID, claims.Phone); err != nil {
+		return notFoundResponse(err, "Failed to archive notification"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"message":        "Notification archived",
+		"notificationId": notificationID,
+	}), nil
+}
+
+// HandleUnarchive handles the PATCH /notifications/{id}/unarchive endpoint.
+func (h *Handler) HandleUnarchive(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	notificationID := request.PathParameters["id"]
+	if notificationID == "" {
+		return ErrorResponse(http.StatusBadRequest, "Notification ID is required"), nil
+	}
+
+	if err := h.service.Unarchive(ctx, notificationID, claims.Phone); err != nil {
+		return notFoundResponse(err, "Failed to unarchive notification"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"message":        "Notification moved to inbox",
+		"notificationId": notificationID,
+	}), nil
+}
+
+// HandleDeleteNotification handles the DELETE /notifications/{id} endpoint.
+func (h *Handler) HandleDeleteNotification(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	notificationID := request.PathParameters["id"]
+	if notificationID == "" {
+		return ErrorResponse(http.StatusBadRequest, "Notification ID is required"), nil
+	}
+
+	if err := h.service.DeleteNotification(ctx, notificationID, claims.Phone); err != nil {
+		return notFoundResponse(err, "Failed to delete notification"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"message":        "Notification deleted",
+		"notificationId": notificationID,
+	}), nil
+}
+
 // HandleMarkAllAsRead handles the POST /notifications/mark-all-read endpoint.
 func (h *Handler) HandleMarkAllAsRead(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Get user from context
@@ -148,3 +227,38 @@ func (h *Handler) HandleGetUnreadCount(ctx context.Context, request events.APIGa
 		"unreadCount": count,
 	}), nil
 }
+
+// HandleGetPreferences handles the GET /notifications/preferences endpoint.
+func (h *Handler) HandleGetPreferences(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	prefs, err := h.service.GetPreferences(ctx, claims.Phone)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to get notification preferences"), nil
+	}
+
+	return APIResponse(http.StatusOK, prefs), nil
+}
+
+// HandleUpdatePreferences handles the PUT /notifications/preferences endpoint.
+func (h *Handler) HandleUpdatePreferences(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req UpdatePreferencesRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	prefs, err := h.service.UpdatePreferences(ctx, claims.Phone, req)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusOK, prefs), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/booking-villa-backend/internal/email"
 	"github.com/google/uuid"
 )
 
@@ -17,6 +18,14 @@ const (
 	TypeBookingPartial      NotificationType = "booking_partial"
 	TypeBookingCancelled    NotificationType = "booking_cancelled"
 	TypeBookingStatusChange NotificationType = "booking_status_changed"
+
+	// Scheduled reminders
+	TypeArrivalReminder    NotificationType = "arrival_reminder"
+	TypeCheckOutReminder   NotificationType = "checkout_reminder"
+	TypePaymentDueReminder NotificationType = "payment_due_reminder"
+
+	// TypeOwnerDigest summarises activity across an owner's properties
+	TypeOwnerDigest NotificationType = "owner_digest"
 )
 
 // Notification represents an in-app notification.
@@ -38,16 +47,35 @@ type Notification struct {
 	BookingID  string           `dynamodbav:"bookingId,omitempty" json:"bookingId,omitempty"`
 	PropertyID string           `dynamodbav:"propertyId,omitempty" json:"propertyId,omitempty"`
 	IsRead     bool             `dynamodbav:"isRead" json:"isRead"`
+	IsArchived bool             `dynamodbav:"isArchived" json:"isArchived"`
+
+	// Context used to render SMS and email deliveries
+	PropertyName string `dynamodbav:"propertyName,omitempty" json:"-"`
+	GuestName    string `dynamodbav:"guestName,omitempty" json:"-"`
+
+	// Email overrides the generic notification email; not stored
+	Email *EmailContent `dynamodbav:"-" json:"-"`
 
 	// Metadata
 	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	TTL        int64     `dynamodbav:"TTL,omitempty" json:"-"`
 	EntityType string    `dynamodbav:"entityType" json:"-"`
 }
 
+// EmailContent selects a dedicated email template for a notification.
+type EmailContent struct {
+	Kind       email.Kind
+	SubjectArg string
+	Data       interface{}
+}
+
 // NewNotification creates a new notification with initialized fields.
 func NewNotification(userPhone string, notifType NotificationType, title, message string) *Notification {
-	now := time.Now()
-	id := uuid.New().String()
+	return newNotification(uuid.New().String(), time.Now(), userPhone, notifType, title, message)
+}
+
+// newNotification creates a notification with the given ID and creation time.
+func newNotification(id string, now time.Time, userPhone string, notifType NotificationType, title, message string) *Notification {
 	// Use reverse timestamp for descending order (newest first)
 	reverseTS := 9999999999999 - now.UnixMilli()
 
@@ -76,6 +104,7 @@ type NotificationResponse struct {
 	BookingID  string           `json:"bookingId,omitempty"`
 	PropertyID string           `json:"propertyId,omitempty"`
 	IsRead     bool             `json:"isRead"`
+	IsArchived bool             `json:"isArchived"`
 	CreatedAt  time.Time        `json:"createdAt"`
 }
 
@@ -89,6 +118,23 @@ func (n *Notification) ToResponse() NotificationResponse {
 		BookingID:  n.BookingID,
 		PropertyID: n.PropertyID,
 		IsRead:     n.IsRead,
+		IsArchived: n.IsArchived,
 		CreatedAt:  n.CreatedAt,
 	}
 }
+
+// ListOptions selects a page of a user's notifications.
+type ListOptions struct {
+	Limit      int32
+	Cursor     string // from a previous page's NextCursor
+	UnreadOnly bool
+	Archived   bool // list archived notifications instead of the inbox
+}
+
+// NotificationPage is one page of a user's notifications, newest first.
+// NextCursor is empty on the last page. A page may hold fewer than Limit
+// notifications even when more follow.
+type NotificationPage struct {
+	Notifications []*Notification
+	NextCursor    string
+}
@@ -0,0 +1,243 @@
+// Package notifications provides in-app notification services.
+package notifications
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Lambda images do not ship zoneinfo; quiet hours need it
+)
+
+// Channel represents a delivery channel for notifications.
+type Channel string
+
+const (
+	ChannelInApp Channel = "in_app"
+	ChannelSMS   Channel = "sms"
+	ChannelEmail Channel = "email"
+)
+
+// AllChannels lists every channel in delivery order.
+var AllChannels = []Channel{ChannelInApp, ChannelSMS, ChannelEmail}
+
+// AllTypes lists every notification type that preferences can be set for.
+var AllTypes = []NotificationType{
+	TypeBookingCreated,
+	TypeBookingSettled,
+	TypeBookingPartial,
+	TypeBookingCancelled,
+	TypeBookingStatusChange,
+	TypeArrivalReminder,
+	TypeCheckOutReminder,
+	TypePaymentDueReminder,
+	TypeOwnerDigest,
+}
+
+// IsValid checks if the notification type is known.
+func (t NotificationType) IsValid() bool {
+	for _, known := range AllTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInterruptive reports whether a channel alerts the user on their device.
+// Interruptive channels are held back until quiet hours end.
+func (c Channel) IsInterruptive() bool {
+	return c == ChannelSMS
+}
+
+// ChannelSettings holds which channels are enabled for one notification type.
+type ChannelSettings struct {
+	InApp bool `dynamodbav:"inApp" json:"inApp"`
+	SMS   bool `dynamodbav:"sms" json:"sms"`
+	Email bool `dynamodbav:"email" json:"email"`
+}
+
+// Enabled reports whether a channel is enabled.
+func (c ChannelSettings) Enabled(channel Channel) bool {
+	switch channel {
+	case ChannelInApp:
+		return c.InApp
+	case ChannelSMS:
+		return c.SMS
+	case ChannelEmail:
+		return c.Email
+	default:
+		return false
+	}
+}
+
+// QuietHours is a daily window, in the user's time zone, during which
+// SMS deliveries are held back until it ends. The window may cross midnight.
+type QuietHours struct {
+	Enabled  bool   `dynamodbav:"enabled" json:"enabled"`
+	Start    string `dynamodbav:"start" json:"start"`       // HH:MM
+	End      string `dynamodbav:"end" json:"end"`           // HH:MM
+	Timezone string `dynamodbav:"timezone" json:"timezone"` // IANA name, e.g. Asia/Kolkata
+}
+
+const (
+	defaultTimezone   = "Asia/Kolkata"
+	defaultQuietStart = "22:00"
+	defaultQuietEnd   = "07:00"
+)
+
+// Validate checks the quiet hours times and time zone.
+func (q QuietHours) Validate() error {
+	if _, err := parseClock(q.Start); err != nil {
+		return fmt.Errorf("invalid quietHours.start: %w", err)
+	}
+	if _, err := parseClock(q.End); err != nil {
+		return fmt.Errorf("invalid quietHours.end: %w", err)
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return fmt.Errorf("invalid quietHours.timezone: %s", q.Timezone)
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the quiet hours window.
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(q.location())
+	minute := local.Hour()*60 + local.Minute()
+
+	if start == end {
+		return false
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// Window crosses midnight (e.g. 22:00-07:00)
+	return minute >= start || minute < end
+}
+
+// EndAfter returns the first time after t at which the quiet hours window
+// ends. Deliveries held back at t are due then.
+func (q QuietHours) EndAfter(t time.Time) time.Time {
+	end, err := parseClock(q.End)
+	if err != nil {
+		return t
+	}
+
+	local := t.In(q.location())
+	at := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, local.Location())
+	if !at.After(local) {
+		at = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, local.Location())
+	}
+	return at
+}
+
+// location returns the quiet hours time zone, or UTC if it is unknown.
+func (q QuietHours) location() *time.Location {
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseClock parses an HH:MM time into minutes after midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("use HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// DigestFrequency is how often a user receives the owner digest.
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// IsValid checks if the digest frequency is known.
+func (f DigestFrequency) IsValid() bool {
+	switch f {
+	case DigestOff, DigestDaily, DigestWeekly:
+		return true
+	}
+	return false
+}
+
+// Preferences holds a user's notification settings.
+type Preferences struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK" json:"-"` // USER#<phone>
+	SK string `dynamodbav:"SK" json:"-"` // NOTIFPREFS
+
+	// GSI1 for listing digest subscribers; only set while the digest is on
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty" json:"-"` // DIGEST#<frequency>
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty" json:"-"` // USER#<phone>
+
+	UserPhone  string                               `dynamodbav:"userPhone" json:"userPhone"`
+	Types      map[NotificationType]ChannelSettings `dynamodbav:"types" json:"types"`
+	QuietHours QuietHours                           `dynamodbav:"quietHours" json:"quietHours"`
+	Digest     DigestFrequency                      `dynamodbav:"digest,omitempty" json:"digest"`
+
+	// Metadata
+	UpdatedAt  time.Time `dynamodbav:"updatedAt" json:"updatedAt"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// DefaultPreferences returns the settings used until a user saves their own:
+// everything in-app, with email for new and cancelled bookings.
+func DefaultPreferences(userPhone string) *Preferences {
+	types := make(map[NotificationType]ChannelSettings, len(AllTypes))
+	for _, t := range AllTypes {
+		types[t] = ChannelSettings{InApp: true}
+	}
+	types[TypeBookingCreated] = ChannelSettings{InApp: true, Email: true}
+	types[TypeBookingCancelled] = ChannelSettings{InApp: true, Email: true}
+	types[TypeOwnerDigest] = ChannelSettings{InApp: true, Email: true}
+
+	return &Preferences{
+		PK:        "USER#" + userPhone,
+		SK:        "NOTIFPREFS",
+		UserPhone: userPhone,
+		Types:     types,
+		QuietHours: QuietHours{
+			Enabled:  false,
+			Start:    defaultQuietStart,
+			End:      defaultQuietEnd,
+			Timezone: defaultTimezone,
+		},
+		Digest:     DigestOff,
+		EntityType: "NOTIFICATION_PREFERENCES",
+	}
+}
+
+// ChannelsFor returns the channel settings for a notification type,
+// falling back to the defaults for types the user has not configured.
+func (p *Preferences) ChannelsFor(notifType NotificationType) ChannelSettings {
+	if settings, ok := p.Types[notifType]; ok {
+		return settings
+	}
+	return DefaultPreferences(p.UserPhone).Types[notifType]
+}
+
+// UpdatePreferencesRequest represents the request body for updating preferences.
+// Types that are omitted keep their current settings.
+type UpdatePreferencesRequest struct {
+	Types      map[NotificationType]ChannelSettings `json:"types,omitempty"`
+	QuietHours *QuietHours                          `json:"quietHours,omitempty"`
+	Digest     *DigestFrequency                     `json:"digest,omitempty"`
+}
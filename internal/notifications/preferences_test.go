@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursEndAfter(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	overnight := QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Asia/Kolkata"}
+	daytime := QuietHours{Enabled: true, Start: "13:00", End: "15:30", Timezone: "Asia/Kolkata"}
+
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		now   time.Time
+		want  time.Time
+	}{
+		{"before midnight", overnight, time.Date(2026, 3, 10, 23, 15, 0, 0, kolkata), time.Date(2026, 3, 11, 7, 0, 0, 0, kolkata)},
+		{"after midnight", overnight, time.Date(2026, 3, 11, 2, 0, 0, 0, kolkata), time.Date(2026, 3, 11, 7, 0, 0, 0, kolkata)},
+		{"same day", daytime, time.Date(2026, 3, 10, 14, 0, 0, 0, kolkata), time.Date(2026, 3, 10, 15, 30, 0, 0, kolkata)},
+		{"at the end", daytime, time.Date(2026, 3, 10, 15, 30, 0, 0, kolkata), time.Date(2026, 3, 11, 15, 30, 0, 0, kolkata)},
+		{"from UTC", overnight, time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 7, 0, 0, 0, kolkata)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.EndAfter(tt.now); !got.Equal(tt.want) {
+				t.Errorf("EndAfter(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,53 +3,287 @@ package notifications
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/email"
+	"github.com/booking-villa-backend/internal/i18n"
+	"github.com/booking-villa-backend/internal/realtime"
+	"github.com/booking-villa-backend/internal/sms"
+	"github.com/booking-villa-backend/internal/users"
 )
 
+const (
+	// defaultRetentionDays is how long notifications are kept when
+	// NOTIFICATION_RETENTION_DAYS is not set.
+	defaultRetentionDays = 90
+
+	// unreadCounterSK is the sort key of a user's unread counter, stored under USER#<phone>.
+	unreadCounterSK = "NOTIFCOUNT"
+)
+
+var (
+	// ErrNotificationNotFound is returned when a notification does not exist
+	// or belongs to another user.
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	// ErrNotificationExists is returned when a notification with the same ID
+	// has already been stored, e.g. by an earlier delivery of the same event.
+	ErrNotificationExists = errors.New("notification already exists")
+
+	// ErrInvalidCursor is returned for a malformed pagination cursor.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// unreadCounter holds a user's unread notification count.
+type unreadCounter struct {
+	PK          string `dynamodbav:"PK"` // USER#<phone>
+	SK          string `dynamodbav:"SK"` // NOTIFCOUNT
+	UnreadCount int    `dynamodbav:"unreadCount"`
+	EntityType  string `dynamodbav:"entityType"`
+}
+
 // Service provides notification-related operations.
 type Service struct {
-	db *db.Client
+	db         *db.Client
+	dispatcher *Dispatcher
+	retention  time.Duration
 }
 
 // NewService creates a new notification service.
+// Deliveries go in-app (pushed to connected clients), by SMS and by email.
+// Notifications expire NOTIFICATION_RETENTION_DAYS days (default 90) after they are read.
 func NewService(dbClient *db.Client) *Service {
-	return &Service{db: dbClient}
+	retentionDays := defaultRetentionDays
+	if envDays := os.Getenv("NOTIFICATION_RETENTION_DAYS"); envDays != "" {
+		if parsed, err := strconv.Atoi(envDays); err == nil && parsed > 0 {
+			retentionDays = parsed
+		}
+	}
+
+	s := &Service{
+		db:        dbClient,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+	s.dispatcher = NewDispatcher(s, users.NewService(dbClient),
+		&inAppSender{service: s, realtime: realtime.NewService(dbClient)},
+		&smsSender{client: sms.NewClient()},
+		&emailSender{service: email.NewServiceFromEnv()},
+	)
+	return s
+}
+
+// Dispatch delivers a notification on the channels the recipient has enabled.
+func (s *Service) Dispatch(ctx context.Context, notification *Notification) ([]*Delivery, error) {
+	return s.dispatcher.Dispatch(ctx, notification)
+}
+
+// GetPreferences returns a user's notification preferences, or the defaults
+// if they have not saved any.
+func (s *Service) GetPreferences(ctx context.Context, userPhone string) (*Preferences, error) {
+	var prefs Preferences
+	if err := s.db.GetItem(ctx, "USER#"+userPhone, "NOTIFPREFS", &prefs); err != nil {
+		if db.IsNotFound(err) {
+			return DefaultPreferences(userPhone), nil
+		}
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	// Saved before the digest existed
+	if prefs.Digest == "" {
+		prefs.Digest = DigestOff
+	}
+	return &prefs, nil
 }
 
-// CreateNotification stores a new notification in DynamoDB.
+// UpdatePreferences merges the request into the user's current preferences and saves them.
+func (s *Service) UpdatePreferences(ctx context.Context, userPhone string, req UpdatePreferencesRequest) (*Preferences, error) {
+	prefs, err := s.GetPreferences(ctx, userPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	for notifType, settings := range req.Types {
+		if !notifType.IsValid() {
+			return nil, fmt.Errorf("unknown notification type: %s", notifType)
+		}
+		prefs.Types[notifType] = settings
+	}
+
+	if req.QuietHours != nil {
+		quietHours := *req.QuietHours
+		if quietHours.Timezone == "" {
+			quietHours.Timezone = defaultTimezone
+		}
+		if err := quietHours.Validate(); err != nil {
+			return nil, err
+		}
+		prefs.QuietHours = quietHours
+	}
+
+	if req.Digest != nil {
+		if !req.Digest.IsValid() {
+			return nil, fmt.Errorf("invalid digest: must be off, daily or weekly")
+		}
+		prefs.Digest = *req.Digest
+	}
+	prefs.GSI1PK, prefs.GSI1SK = "", ""
+	if prefs.Digest == DigestDaily || prefs.Digest == DigestWeekly {
+		prefs.GSI1PK = "DIGEST#" + string(prefs.Digest)
+		prefs.GSI1SK = "USER#" + userPhone
+	}
+
+	prefs.UpdatedAt = time.Now()
+	if err := s.db.PutItem(ctx, prefs); err != nil {
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// ListDigestSubscribers returns the phones of users who receive the digest at the given frequency.
+func (s *Service) ListDigestSubscribers(ctx context.Context, frequency DigestFrequency) ([]string, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "DIGEST#" + string(frequency),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest subscribers: %w", err)
+	}
+
+	phones := make([]string, 0, len(items))
+	for _, item := range items {
+		var prefs Preferences
+		if err := attributevalue.UnmarshalMap(item, &prefs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notification preferences: %w", err)
+		}
+		phones = append(phones, prefs.UserPhone)
+	}
+	return phones, nil
+}
+
+// CreateNotification stores a new notification in DynamoDB and counts it as
+// unread. Unread notifications are kept until they are read, so the counter
+// never includes notifications that have expired. It returns
+// ErrNotificationExists if the notification has already been stored.
 func (s *Service) CreateNotification(ctx context.Context, notification *Notification) error {
-	if err := s.db.PutItem(ctx, notification); err != nil {
+	items := []db.TransactItem{db.TransactPut(notification, "attribute_not_exists(PK)")}
+	if notification.IsRead {
+		if notification.TTL == 0 {
+			notification.TTL = db.CalculateTTL(s.retention)
+		}
+	} else {
+		notification.TTL = 0
+		if err := s.ensureUnreadCounter(ctx, notification.UserPhone); err != nil {
+			return err
+		}
+		items = append(items, s.adjustUnread(notification.UserPhone, 1))
+	}
+
+	if err := s.db.TransactWrite(ctx, items...); err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return ErrNotificationExists
+		}
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 	return nil
 }
 
-// GetNotificationsByUser retrieves notifications for a user.
-// Results are ordered by newest first (descending).
-func (s *Service) GetNotificationsByUser(ctx context.Context, userPhone string, limit int32, unreadOnly bool) ([]*Notification, error) {
+// adjustUnread returns a transactional update of a user's unread counter.
+// The counter must already exist (see ensureUnreadCounter), so an ADD never
+// creates one that skipped counting the notifications stored before it.
+func (s *Service) adjustUnread(userPhone string, delta int) db.TransactItem {
+	return db.TransactUpdate("USER#"+userPhone, unreadCounterSK, db.UpdateParams{
+		UpdateExpression:    "ADD unreadCount :delta",
+		ConditionExpression: "attribute_exists(PK)",
+		ExpressionValues: map[string]interface{}{
+			":delta": delta,
+		},
+	})
+}
+
+// GetNotificationsByUser retrieves a page of a user's notifications, newest
+// first. Archived notifications are only listed when opts.Archived is set.
+func (s *Service) GetNotificationsByUser(ctx context.Context, userPhone string, opts ListOptions) (*NotificationPage, error) {
+	pk := "USER#" + userPhone
+
+	var startKey map[string]types.AttributeValue
+	if opts.Cursor != "" {
+		sk, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		startKey = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		}
+	}
+
 	params := db.QueryParams{
 		KeyCondition: "PK = :pk AND begins_with(SK, :skPrefix)",
 		ExpressionValues: map[string]interface{}{
-			":pk":       "USER#" + userPhone,
+			":pk":       pk,
 			":skPrefix": "NOTIFICATION#",
+			":archived": true,
 		},
-		Limit: limit,
+		Limit: opts.Limit,
 	}
 
-	if unreadOnly {
-		params.FilterExpression = "isRead = :isRead"
+	// Notifications stored before archiving existed have no isArchived attribute
+	if opts.Archived {
+		params.FilterExpression = "isArchived = :archived"
+	} else {
+		params.FilterExpression = "(attribute_not_exists(isArchived) OR isArchived <> :archived)"
+	}
+	if opts.UnreadOnly {
+		params.FilterExpression += " AND isRead = :isRead"
 		params.ExpressionValues[":isRead"] = false
 	}
 
-	items, err := s.db.Query(ctx, params)
+	items, lastKey, err := s.db.QueryPage(ctx, params, startKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get notifications: %w", err)
 	}
 
+	notifications, err := unmarshalNotifications(items)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &NotificationPage{Notifications: notifications}
+	if sk, ok := lastKey["SK"].(*types.AttributeValueMemberS); ok {
+		page.NextCursor = encodeCursor(sk.Value)
+	}
+	return page, nil
+}
+
+// encodeCursor turns a notification sort key into an opaque cursor.
+func encodeCursor(sk string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sk))
+}
+
+// decodeCursor returns the sort key a cursor was made from.
+func decodeCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), "NOTIFICATION#") {
+		return "", ErrInvalidCursor
+	}
+	return string(raw), nil
+}
+
+// unmarshalNotifications converts DynamoDB items to notifications.
+func unmarshalNotifications(items []map[string]types.AttributeValue) ([]*Notification, error) {
 	notifications := make([]*Notification, 0, len(items))
 	for _, item := range items {
 		var notif Notification
@@ -58,13 +292,11 @@ func (s *Service) GetNotificationsByUser(ctx context.Context, userPhone string,
 		}
 		notifications = append(notifications, &notif)
 	}
-
 	return notifications, nil
 }
 
-// MarkAsRead marks a notification as read.
-func (s *Service) MarkAsRead(ctx context.Context, notificationID, userPhone string) error {
-	// First, find the notification by ID using GSI1
+// getNotification finds one of a user's notifications by ID.
+func (s *Service) getNotification(ctx context.Context, notificationID, userPhone string) (*Notification, error) {
 	params := db.QueryParams{
 		IndexName:    "GSI1",
 		KeyCondition: "GSI1PK = :gsi1pk AND GSI1SK = :gsi1sk",
@@ -77,92 +309,282 @@ func (s *Service) MarkAsRead(ctx context.Context, notificationID, userPhone stri
 
 	items, err := s.db.Query(ctx, params)
 	if err != nil {
-		return fmt.Errorf("failed to find notification: %w", err)
+		return nil, fmt.Errorf("failed to find notification: %w", err)
 	}
-
 	if len(items) == 0 {
-		return fmt.Errorf("notification not found")
+		return nil, ErrNotificationNotFound
 	}
 
 	var notif Notification
 	if err := attributevalue.UnmarshalMap(items[0], &notif); err != nil {
-		return fmt.Errorf("failed to unmarshal notification: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal notification: %w", err)
 	}
+	return &notif, nil
+}
 
-	// Update the notification
-	now := time.Now().Format(time.RFC3339)
-	updateParams := db.UpdateParams{
-		UpdateExpression: "SET isRead = :isRead, updatedAt = :updatedAt",
-		ExpressionValues: map[string]interface{}{
-			":isRead":    true,
-			":updatedAt": now,
-		},
+// markRead marks a notification read and decrements the unread counter,
+// optionally archiving it too. The retention period starts once it is read.
+// It returns false if the notification was already read, in which case
+// nothing is changed.
+func (s *Service) markRead(ctx context.Context, notif *Notification, archive bool) (bool, error) {
+	if err := s.ensureUnreadCounter(ctx, notif.UserPhone); err != nil {
+		return false, err
+	}
+
+	update := "SET isRead = :isRead, updatedAt = :updatedAt, #ttl = :ttl"
+	values := map[string]interface{}{
+		":isRead":    true,
+		":unread":    false,
+		":updatedAt": time.Now().Format(time.RFC3339),
+		":ttl":       db.CalculateTTL(s.retention),
+	}
+	if archive {
+		update += ", isArchived = :archived"
+		values[":archived"] = true
+	}
+
+	err := s.db.TransactWrite(ctx,
+		db.TransactUpdate(notif.PK, notif.SK, db.UpdateParams{
+			UpdateExpression:         update,
+			ConditionExpression:      "attribute_exists(PK) AND isRead = :unread",
+			ExpressionValues:         values,
+			ExpressionAttributeNames: map[string]string{"#ttl": "TTL"},
+		}),
+		s.adjustUnread(notif.UserPhone, -1),
+	)
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	return true, nil
+}
+
+// MarkAsRead marks a notification as read.
+func (s *Service) MarkAsRead(ctx context.Context, notificationID, userPhone string) error {
+	notif, err := s.getNotification(ctx, notificationID, userPhone)
+	if err != nil {
+		return err
 	}
 
-	return s.db.UpdateItem(ctx, notif.PK, notif.SK, updateParams)
+	_, err = s.markRead(ctx, notif, false)
+	return err
 }
 
-// MarkAllAsRead marks all notifications as read for a user.
+// MarkAllAsRead marks all of a user's unread notifications as read, and
+// returns how many were marked.
 func (s *Service) MarkAllAsRead(ctx context.Context, userPhone string) (int, error) {
-	// Get all unread notifications
-	notifications, err := s.GetNotificationsByUser(ctx, userPhone, 100, true)
+	counted, err := s.GetUnreadCount(ctx, userPhone)
+	if err != nil {
+		return 0, err
+	}
+
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition:     "PK = :pk AND begins_with(SK, :skPrefix)",
+		FilterExpression: "isRead = :isRead",
+		ExpressionValues: map[string]interface{}{
+			":pk":       "USER#" + userPhone,
+			":skPrefix": "NOTIFICATION#",
+			":isRead":   false,
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unread notifications: %w", err)
+	}
+	unread, err := unmarshalNotifications(items)
 	if err != nil {
 		return 0, err
 	}
 
-	now := time.Now().Format(time.RFC3339)
 	count := 0
-	for _, notif := range notifications {
-		updateParams := db.UpdateParams{
-			UpdateExpression: "SET isRead = :isRead, updatedAt = :updatedAt",
+	for _, notif := range unread {
+		marked, err := s.markRead(ctx, notif, false)
+		if err != nil {
+			log.Printf("Failed to mark notification %s as read: %v", notif.ID, err)
+			continue
+		}
+		if marked {
+			count++
+		}
+	}
+
+	// Unread notifications stored with a TTL before they were kept until
+	// read may have expired without being decremented. The correction only
+	// applies if the counter holds what was read less what was marked here;
+	// otherwise another change moved it and the next call corrects it.
+	if drift := counted - len(unread); drift > 0 {
+		err := s.db.TransactWrite(ctx, db.TransactUpdate("USER#"+userPhone, unreadCounterSK, db.UpdateParams{
+			UpdateExpression:    "ADD unreadCount :delta",
+			ConditionExpression: "unreadCount = :expected",
 			ExpressionValues: map[string]interface{}{
-				":isRead":    true,
-				":updatedAt": now,
+				":delta":    -drift,
+				":expected": counted - count,
 			},
+		}))
+		if err != nil && !db.IsConditionalCheckFailed(err) {
+			log.Printf("Failed to correct unread count for %s: %v", userPhone, err)
 		}
+	}
 
-		if err := s.db.UpdateItem(ctx, notif.PK, notif.SK, updateParams); err != nil {
-			// Log error but continue with other notifications
-			continue
+	return count, nil
+}
+
+// Archive moves a notification out of the inbox. Archived notifications count as read.
+func (s *Service) Archive(ctx context.Context, notificationID, userPhone string) error {
+	notif, err := s.getNotification(ctx, notificationID, userPhone)
+	if err != nil {
+		return err
+	}
+
+	marked, err := s.markRead(ctx, notif, true)
+	if err != nil || marked {
+		return err
+	}
+
+	return s.setArchived(ctx, notif, true)
+}
+
+// Unarchive returns an archived notification to the inbox.
+func (s *Service) Unarchive(ctx context.Context, notificationID, userPhone string) error {
+	notif, err := s.getNotification(ctx, notificationID, userPhone)
+	if err != nil {
+		return err
+	}
+	return s.setArchived(ctx, notif, false)
+}
+
+// setArchived sets whether a notification is archived.
+func (s *Service) setArchived(ctx context.Context, notif *Notification, archived bool) error {
+	err := s.db.UpdateItem(ctx, notif.PK, notif.SK, db.UpdateParams{
+		UpdateExpression:    "SET isArchived = :archived, updatedAt = :updatedAt",
+		ConditionExpression: "attribute_exists(PK)",
+		ExpressionValues: map[string]interface{}{
+			":archived":  archived,
+			":updatedAt": time.Now().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return ErrNotificationNotFound
 		}
-		count++
+		return fmt.Errorf("failed to archive notification: %w", err)
 	}
+	return nil
+}
 
-	return count, nil
+// DeleteNotification deletes one of a user's notifications.
+func (s *Service) DeleteNotification(ctx context.Context, notificationID, userPhone string) error {
+	notif, err := s.getNotification(ctx, notificationID, userPhone)
+	if err != nil {
+		return err
+	}
+
+	if !notif.IsRead {
+		// Mark it read first, so the counter is only decremented once
+		if _, err := s.markRead(ctx, notif, false); err != nil {
+			return err
+		}
+	}
+
+	if err := s.db.DeleteItem(ctx, notif.PK, notif.SK); err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	return nil
 }
 
-// GetUnreadCount returns the count of unread notifications for a user.
+// GetUnreadCount returns the count of unread notifications for a user from
+// their counter. Users with notifications from before the counter existed
+// have it initialised from their unread notifications.
 func (s *Service) GetUnreadCount(ctx context.Context, userPhone string) (int, error) {
-	notifications, err := s.GetNotificationsByUser(ctx, userPhone, 100, true)
+	var counter unreadCounter
+	err := s.db.GetItem(ctx, "USER#"+userPhone, unreadCounterSK, &counter)
+	if err == nil {
+		return max(counter.UnreadCount, 0), nil
+	}
+	if !db.IsNotFound(err) {
+		return 0, fmt.Errorf("failed to get unread count: %w", err)
+	}
+	return s.initUnreadCounter(ctx, userPhone)
+}
+
+// ensureUnreadCounter creates a user's unread counter if it does not exist
+// yet, counting the unread notifications stored before it.
+func (s *Service) ensureUnreadCounter(ctx context.Context, userPhone string) error {
+	var counter unreadCounter
+	err := s.db.GetItem(ctx, "USER#"+userPhone, unreadCounterSK, &counter)
+	if err == nil {
+		return nil
+	}
+	if !db.IsNotFound(err) {
+		return fmt.Errorf("failed to get unread count: %w", err)
+	}
+	_, err = s.initUnreadCounter(ctx, userPhone)
+	return err
+}
+
+// initUnreadCounter counts a user's unread notifications and stores the
+// result as their counter, unless one was created concurrently.
+func (s *Service) initUnreadCounter(ctx context.Context, userPhone string) (int, error) {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition:     "PK = :pk AND begins_with(SK, :skPrefix)",
+		FilterExpression: "isRead = :isRead",
+		ExpressionValues: map[string]interface{}{
+			":pk":       "USER#" + userPhone,
+			":skPrefix": "NOTIFICATION#",
+			":isRead":   false,
+		},
+	})
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	counter := unreadCounter{
+		PK:          "USER#" + userPhone,
+		SK:          unreadCounterSK,
+		UnreadCount: len(items),
+		EntityType:  "NOTIFICATION_COUNTER",
+	}
+	if err := s.db.PutItemWithCondition(ctx, &counter, "attribute_not_exists(PK)"); err != nil {
+		// Initialised concurrently; read it back
+		if db.IsConditionalCheckFailed(err) {
+			return s.GetUnreadCount(ctx, userPhone)
+		}
+		return 0, fmt.Errorf("failed to initialise unread count: %w", err)
 	}
-	return len(notifications), nil
+	return counter.UnreadCount, nil
 }
 
-// CreateBookingNotification creates a notification for a booking event.
+// CreateBookingNotification creates a notification for a booking event and
+// delivers it on the channels the user has enabled for that type. It is
+// written in the user's language.
 func (s *Service) CreateBookingNotification(ctx context.Context, userPhone string, notifType NotificationType, bookingID, propertyID, propertyName, guestName string) error {
-	title, message := generateBookingMessage(notifType, propertyName, guestName)
+	title, message := s.renderBookingNotification(ctx, userPhone, notifType, propertyName, guestName)
 
 	notification := NewNotification(userPhone, notifType, title, message)
 	notification.BookingID = bookingID
 	notification.PropertyID = propertyID
+	notification.PropertyName = propertyName
+	notification.GuestName = guestName
 
-	return s.CreateNotification(ctx, notification)
+	_, err := s.Dispatch(ctx, notification)
+	return err
 }
 
-// generateBookingMessage generates title and message for booking notifications.
-func generateBookingMessage(notifType NotificationType, propertyName, guestName string) (string, string) {
-	switch notifType {
-	case TypeBookingCreated:
-		return "New Booking", fmt.Sprintf("New booking created for %s by %s", propertyName, guestName)
-	case TypeBookingSettled:
-		return "Booking Settled", fmt.Sprintf("Booking for %s has been fully settled", propertyName)
-	case TypeBookingPartial:
-		return "Payment Received", fmt.Sprintf("Partial payment received for %s", propertyName)
-	case TypeBookingCancelled:
-		return "Booking Cancelled", fmt.Sprintf("Booking for %s has been cancelled", propertyName)
-	default:
-		return "Booking Update", fmt.Sprintf("Booking for %s has been updated", propertyName)
+// renderBookingNotification returns the title and message of a booking
+// notification in the user's language.
+func (s *Service) renderBookingNotification(ctx context.Context, userPhone string, notifType NotificationType, propertyName, guestName string) (string, string) {
+	key := notifType
+	if !templates.Has(i18n.Default, string(key)+".title") {
+		key = TypeBookingStatusChange
 	}
+	return Render(s.LocaleFor(ctx, userPhone), string(key), map[string]string{
+		"property": propertyName,
+		"guest":    guestName,
+	})
+}
+
+// LocaleFor returns the language notifications to a user are written in.
+func (s *Service) LocaleFor(ctx context.Context, userPhone string) i18n.Locale {
+	return s.dispatcher.userService.LocaleFor(ctx, userPhone)
 }
@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"github.com/booking-villa-backend/internal/db/dbtest"
+)
+
+func TestMarkAllAsReadCorrectsDrift(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+	service := NewService(client)
+
+	const phone = "9876543210"
+	for _, title := range []string{"New booking", "Payment received"} {
+		if err := service.CreateNotification(ctx, NewNotification(phone, TypeBookingCreated, title, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two more were counted but expired without being decremented
+	table.Put(t, &unreadCounter{PK: "USER#" + phone, SK: unreadCounterSK, UnreadCount: 4, EntityType: "NOTIFICATION_COUNTER"})
+
+	marked, err := service.MarkAllAsRead(ctx, phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked != 2 {
+		t.Errorf("MarkAllAsRead() = %d, want 2", marked)
+	}
+	if count, err := service.GetUnreadCount(ctx, phone); err != nil || count != 0 {
+		t.Errorf("GetUnreadCount() = %d, %v; want 0", count, err)
+	}
+}
@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"github.com/booking-villa-backend/internal/i18n"
+)
+
+// Digest template keys, for the title that depends on the digest frequency.
+const (
+	TemplateDailyDigest  = "owner_digest.daily"
+	TemplateWeeklyDigest = "owner_digest.weekly"
+)
+
+// templates holds the title and message of every notification type, keyed
+// "<key>.title" and "<key>.message". The key is the notification type unless
+// noted. Missing translations fall back to English.
+var templates = i18n.Catalog{
+	// Variables: property, guest
+	"booking_created.title": {
+		i18n.English: "New Booking",
+		i18n.Hindi:   "नई बुकिंग",
+		i18n.Marathi: "नवीन बुकिंग",
+	},
+	"booking_created.message": {
+		i18n.English: "New booking created for {property} by {guest}",
+		i18n.Hindi:   "{property} के लिए {guest} की नई बुकिंग बनाई गई",
+		i18n.Marathi: "{property} साठी {guest} यांची नवीन बुकिंग तयार झाली",
+	},
+
+	// Variables: property
+	"booking_settled.title": {
+		i18n.English: "Booking Settled",
+		i18n.Hindi:   "बुकिंग का पूरा भुगतान",
+		i18n.Marathi: "बुकिंगचे पूर्ण पेमेंट",
+	},
+	"booking_settled.message": {
+		i18n.English: "Booking for {property} has been fully settled",
+		i18n.Hindi:   "{property} की बुकिंग का पूरा भुगतान हो गया है",
+		i18n.Marathi: "{property} च्या बुकिंगचे पूर्ण पेमेंट झाले आहे",
+	},
+
+	// Variables: property
+	"booking_partial.title": {
+		i18n.English: "Payment Received",
+		i18n.Hindi:   "भुगतान प्राप्त",
+		i18n.Marathi: "पेमेंट मिळाले",
+	},
+	"booking_partial.message": {
+		i18n.English: "Partial payment received for {property}",
+		i18n.Hindi:   "{property} के लिए आंशिक भुगतान प्राप्त हुआ",
+		i18n.Marathi: "{property} साठी आंशिक पेमेंट मिळाले",
+	},
+
+	// Variables: property
+	"booking_cancelled.title": {
+		i18n.English: "Booking Cancelled",
+		i18n.Hindi:   "बुकिंग रद्द",
+		i18n.Marathi: "बुकिंग रद्द",
+	},
+	"booking_cancelled.message": {
+		i18n.English: "Booking for {property} has been cancelled",
+		i18n.Hindi:   "{property} की बुकिंग रद्द कर दी गई है",
+		i18n.Marathi: "{property} ची बुकिंग रद्द करण्यात आली आहे",
+	},
+
+	// Variables: property
+	"booking_status_changed.title": {
+		i18n.English: "Booking Update",
+		i18n.Hindi:   "बुकिंग अपडेट",
+		i18n.Marathi: "बुकिंग अपडेट",
+	},
+	"booking_status_changed.message": {
+		i18n.English: "Booking for {property} has been updated",
+		i18n.Hindi:   "{property} की बुकिंग अपडेट की गई है",
+		i18n.Marathi: "{property} ची बुकिंग अपडेट करण्यात आली आहे",
+	},
+
+	// Variables: guest, guests, property, when
+	"arrival_reminder.title": {
+		i18n.English: "Upcoming Arrival",
+		i18n.Hindi:   "आगामी आगमन",
+		i18n.Marathi: "आगामी आगमन",
+	},
+	"arrival_reminder.message": {
+		i18n.English: "{guest} ({guests} guests) checks in at {property} {when}",
+		i18n.Hindi:   "{guest} ({guests} मेहमान) {when} {property} में चेक-इन करेंगे",
+		i18n.Marathi: "{guest} ({guests} पाहुणे) {when} {property} येथे चेक-इन करतील",
+	},
+
+	// Variables: guest, property, when
+	"checkout_reminder.title": {
+		i18n.English: "Check-out Cleaning",
+		i18n.Hindi:   "चेक-आउट सफ़ाई",
+		i18n.Marathi: "चेक-आउट स्वच्छता",
+	},
+	"checkout_reminder.message": {
+		i18n.English: "{guest} checks out of {property} {when}. Schedule cleaning before the next arrival.",
+		i18n.Hindi:   "{guest} {when} {property} से चेक-आउट करेंगे। अगले आगमन से पहले सफ़ाई करवा लें।",
+		i18n.Marathi: "{guest} {when} {property} मधून चेक-आउट करतील. पुढील आगमनापूर्वी स्वच्छता करून घ्या.",
+	},
+
+	// Variables: currency, amount, guest, property, when
+	"payment_due_reminder.title": {
+		i18n.English: "Payment Due",
+		i18n.Hindi:   "भुगतान बाकी",
+		i18n.Marathi: "पेमेंट बाकी",
+	},
+	"payment_due_reminder.message": {
+		i18n.English: "{currency} {amount} is still due from {guest}, who checks in at {property} {when}",
+		i18n.Hindi:   "{guest} से {currency} {amount} अभी बाकी है, जो {when} {property} में चेक-इन करेंगे",
+		i18n.Marathi: "{guest} यांच्याकडून {currency} {amount} अजून बाकी आहे, ते {when} {property} येथे चेक-इन करतील",
+	},
+
+	// Variables: newBookings, cancellations, currency, collected, arrivals,
+	// days, outstanding, outstandingBookings
+	"owner_digest.daily.title": {
+		i18n.English: "Daily Digest",
+		i18n.Hindi:   "दैनिक सारांश",
+		i18n.Marathi: "दैनिक सारांश",
+	},
+	"owner_digest.weekly.title": {
+		i18n.English: "Weekly Digest",
+		i18n.Hindi:   "साप्ताहिक सारांश",
+		i18n.Marathi: "साप्ताहिक सारांश",
+	},
+	"owner_digest.daily.message": {
+		i18n.English: "{newBookings} new bookings, {cancellations} cancellations, {currency} {collected} collected. " +
+			"{arrivals} arrivals in the next {days} days; {currency} {outstanding} outstanding on {outstandingBookings} bookings.",
+		i18n.Hindi: "{newBookings} नई बुकिंग, {cancellations} रद्द, {currency} {collected} प्राप्त। " +
+			"अगले {days} दिनों में {arrivals} आगमन; {outstandingBookings} बुकिंग पर {currency} {outstanding} बाकी।",
+		i18n.Marathi: "{newBookings} नवीन बुकिंग, {cancellations} रद्द, {currency} {collected} जमा. " +
+			"पुढील {days} दिवसांत {arrivals} आगमन; {outstandingBookings} बुकिंगवर {currency} {outstanding} बाकी.",
+	},
+}
+
+func init() {
+	// Both digests share a message
+	templates["owner_digest.weekly.message"] = templates["owner_digest.daily.message"]
+}
+
+// Render returns the title and message for a template key, usually a
+// notification type, in locale.
+func Render(locale i18n.Locale, key string, vars map[string]string) (string, string) {
+	return templates.Render(locale, key+".title", vars), templates.Render(locale, key+".message", vars)
+}
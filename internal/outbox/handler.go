@@ -0,0 +1,84 @@
+// Package outbox provides a transactional outbox for side effects of domain writes.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+// Handler provides HTTP handlers for outbox administration endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new outbox handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service: NewService(dbClient),
+	}
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// HandleListDeadLetters handles the GET /admin/outbox/dead-letters endpoint.
+func (h *Handler) HandleListDeadLetters(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	limit := int32(50)
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil && l > 0 && l <= 200 {
+			limit = int32(l)
+		}
+	}
+
+	deadLetters, err := h.service.ListDeadLetters(ctx, limit)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to list dead-lettered events"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"events": deadLetters,
+		"count":  len(deadLetters),
+	}), nil
+}
+
+// HandleRequeue handles the POST /admin/outbox/{id}/retry endpoint.
+func (h *Handler) HandleRequeue(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Event ID is required"), nil
+	}
+
+	if err := h.service.Requeue(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrorResponse(http.StatusNotFound, "Dead-lettered event not found"), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, "Failed to requeue event"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message": "Event requeued",
+		"id":      id,
+	}), nil
+}
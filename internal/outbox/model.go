@@ -0,0 +1,110 @@
+// Package outbox provides a transactional outbox for side effects of domain writes.
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/google/uuid"
+)
+
+// EventType identifies what kind of side effect an event triggers.
+// Consumers register a handler per type.
+type EventType string
+
+// Status represents the processing state of an outbox event.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusProcessed Status = "processed"
+	StatusDead      Status = "dead"
+)
+
+// GSI1 partitions used to find events by state.
+const (
+	pendingPartition = "OUTBOX#PENDING"
+	deadPartition    = "OUTBOX#DEAD"
+)
+
+// Event is a side effect recorded in the same transaction as the change that caused it.
+type Event struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // OUTBOX#<id>
+	SK string `dynamodbav:"SK"` // EVENT
+
+	// GSI1 for finding due and dead-lettered events. Removed once processed.
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"` // OUTBOX#PENDING or OUTBOX#DEAD
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"` // <nextAttemptAt RFC3339Nano>#<id>
+
+	// Event fields
+	ID            string    `dynamodbav:"id" json:"id"`
+	Type          EventType `dynamodbav:"type" json:"type"`
+	Payload       string    `dynamodbav:"payload" json:"payload"` // JSON
+	Status        Status    `dynamodbav:"status" json:"status"`
+	Attempts      int       `dynamodbav:"attempts" json:"attempts"`
+	LastError     string    `dynamodbav:"lastError,omitempty" json:"lastError,omitempty"`
+	NextAttemptAt time.Time `dynamodbav:"nextAttemptAt" json:"nextAttemptAt"`
+
+	// Metadata
+	CreatedAt   time.Time  `dynamodbav:"createdAt" json:"createdAt"`
+	ProcessedAt *time.Time `dynamodbav:"processedAt,omitempty" json:"processedAt,omitempty"`
+	TTL         int64      `dynamodbav:"TTL,omitempty" json:"-"` // Set once processed
+	EntityType  string     `dynamodbav:"entityType" json:"-"`
+}
+
+// NewEvent creates a pending event with the payload encoded as JSON.
+// The event is due immediately.
+func NewEvent(eventType EventType, payload interface{}) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+
+	now := time.Now().UTC()
+	id := uuid.New().String()
+
+	return &Event{
+		PK:            "OUTBOX#" + id,
+		SK:            "EVENT",
+		GSI1PK:        pendingPartition,
+		GSI1SK:        dueKey(now, id),
+		ID:            id,
+		Type:          eventType,
+		Payload:       string(data),
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		EntityType:    "OUTBOX_EVENT",
+	}, nil
+}
+
+// Delay makes a pending event due at until instead of immediately.
+func (e *Event) Delay(until time.Time) *Event {
+	e.NextAttemptAt = until.UTC()
+	e.GSI1SK = dueKey(until, e.ID)
+	return e
+}
+
+// DecodePayload decodes the event payload into v.
+func (e *Event) DecodePayload(v interface{}) error {
+	if err := json.Unmarshal([]byte(e.Payload), v); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// TransactItem returns the write that stores the event as part of a transaction.
+func (e *Event) TransactItem() db.TransactItem {
+	return db.TransactPut(e, "attribute_not_exists(PK)")
+}
+
+// dueLayout is a fixed-width timestamp so that due keys sort chronologically.
+const dueLayout = "2006-01-02T15:04:05.000000Z"
+
+// dueKey builds the GSI1 sort key for an event due at t.
+func dueKey(t time.Time, id string) string {
+	return t.UTC().Format(dueLayout) + "#" + id
+}
@@ -0,0 +1,324 @@
+// Package outbox provides a transactional outbox for side effects of domain writes.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/booking-villa-backend/internal/db"
+)
+
+const (
+	// MaxAttempts is the number of tries before an event is dead-lettered.
+	MaxAttempts = 8
+
+	baseBackoff      = 30 * time.Second
+	maxBackoff       = time.Hour
+	leaseDuration    = 2 * time.Minute
+	processedTTLDays = 7
+	defaultBatchSize = 25
+)
+
+// ErrNotFound is returned when an outbox event does not exist.
+var ErrNotFound = errors.New("outbox event not found")
+
+// HandlerFunc performs the side effect for an event. Returning an error
+// schedules a retry. Handlers may run more than once for the same event.
+type HandlerFunc func(ctx context.Context, event *Event) error
+
+// Service processes outbox events.
+type Service struct {
+	db       *db.Client
+	handlers map[EventType]HandlerFunc
+}
+
+// NewService creates a new outbox service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:       dbClient,
+		handlers: make(map[EventType]HandlerFunc),
+	}
+}
+
+// Register sets the handler for an event type.
+func (s *Service) Register(eventType EventType, handler HandlerFunc) {
+	s.handlers[eventType] = handler
+}
+
+// ProcessResult summarises one processing run.
+type ProcessResult struct {
+	Processed    int `json:"processed"`
+	Retried      int `json:"retried"`
+	DeadLettered int `json:"deadLettered"`
+	Skipped      int `json:"skipped"`
+}
+
+// ProcessDue handles events that are due, in batches, until none are left
+// or ctx is done. Each event is claimed with a short lease first so that
+// concurrent workers do not process the same event.
+func (s *Service) ProcessDue(ctx context.Context) (*ProcessResult, error) {
+	result := &ProcessResult{}
+
+	for ctx.Err() == nil {
+		events, err := s.listDue(ctx, defaultBatchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		claimed := 0
+		for _, event := range events {
+			if ctx.Err() != nil {
+				break
+			}
+
+			ok, err := s.claim(ctx, event)
+			if err != nil {
+				return result, err
+			}
+			if !ok {
+				result.Skipped++
+				continue
+			}
+			claimed++
+
+			s.process(ctx, event, result)
+		}
+
+		// Everything due was claimed by other workers
+		if claimed == 0 {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// ProcessNow handles events right after the transaction that stored them
+// commits, so their side effects don't wait for the next worker run. Only
+// events with a registered handler are processed; the rest, and those a
+// worker has already claimed, are left to the worker. A failed attempt is
+// scheduled for retry as it would be by the worker.
+func (s *Service) ProcessNow(ctx context.Context, events ...*Event) *ProcessResult {
+	result := &ProcessResult{}
+	for _, event := range events {
+		if _, ok := s.handlers[event.Type]; !ok {
+			continue
+		}
+
+		claimed, err := s.claim(ctx, event)
+		if err != nil {
+			log.Printf("Failed to claim outbox event %s for immediate processing: %v", event.ID, err)
+			continue
+		}
+		if !claimed {
+			result.Skipped++
+			continue
+		}
+		s.process(ctx, event, result)
+	}
+	return result
+}
+
+// listDue returns pending events whose next attempt time has passed, oldest first.
+func (s *Service) listDue(ctx context.Context, limit int32) ([]*Event, error) {
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk AND GSI1SK <= :now",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": pendingPartition,
+			":now":    dueKey(time.Now(), "~"),
+		},
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due outbox events: %w", err)
+	}
+	return unmarshalEvents(items)
+}
+
+// claim leases an event by pushing its next attempt time forward, on the
+// condition that no other worker has done so since it was read.
+func (s *Service) claim(ctx context.Context, event *Event) (bool, error) {
+	leaseUntil := time.Now().Add(leaseDuration)
+	newKey := dueKey(leaseUntil, event.ID)
+
+	err := s.db.UpdateItem(ctx, event.PK, event.SK, db.UpdateParams{
+		UpdateExpression:    "SET GSI1SK = :newKey, nextAttemptAt = :leaseUntil",
+		ConditionExpression: "GSI1PK = :pending AND GSI1SK = :oldKey",
+		ExpressionValues: map[string]interface{}{
+			":newKey":     newKey,
+			":leaseUntil": leaseUntil,
+			":pending":    pendingPartition,
+			":oldKey":     event.GSI1SK,
+		},
+	})
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim outbox event %s: %w", event.ID, err)
+	}
+
+	event.GSI1SK = newKey
+	return true, nil
+}
+
+// process runs the handler for a claimed event and records the outcome.
+func (s *Service) process(ctx context.Context, event *Event, result *ProcessResult) {
+	handler, ok := s.handlers[event.Type]
+	var handlerErr error
+	if !ok {
+		handlerErr = fmt.Errorf("no handler registered for %s", event.Type)
+	} else {
+		handlerErr = handler(ctx, event)
+	}
+
+	if handlerErr == nil {
+		if err := s.markProcessed(ctx, event); err != nil {
+			log.Printf("Failed to mark outbox event %s processed: %v", event.ID, err)
+		}
+		result.Processed++
+		return
+	}
+
+	attempts := event.Attempts + 1
+	log.Printf("Outbox event %s (%s) failed on attempt %d: %v", event.ID, event.Type, attempts, handlerErr)
+
+	if attempts >= MaxAttempts {
+		if err := s.markDead(ctx, event, attempts, handlerErr); err != nil {
+			log.Printf("Failed to dead-letter outbox event %s: %v", event.ID, err)
+		}
+		result.DeadLettered++
+		return
+	}
+
+	if err := s.scheduleRetry(ctx, event, attempts, handlerErr); err != nil {
+		log.Printf("Failed to schedule retry for outbox event %s: %v", event.ID, err)
+	}
+	result.Retried++
+}
+
+// backoff returns the delay before the given attempt is retried: 30s, 1m, 2m, ... capped at 1h.
+func backoff(attempts int) time.Duration {
+	delay := time.Duration(float64(baseBackoff) * math.Pow(2, float64(attempts-1)))
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// markProcessed removes the event from the pending index and lets it expire.
+func (s *Service) markProcessed(ctx context.Context, event *Event) error {
+	return s.db.UpdateItem(ctx, event.PK, event.SK, db.UpdateParams{
+		UpdateExpression: "SET #status = :status, processedAt = :now, attempts = attempts + :one, #ttl = :ttl REMOVE GSI1PK, GSI1SK",
+		ExpressionValues: map[string]interface{}{
+			":status": StatusProcessed,
+			":now":    time.Now(),
+			":one":    1,
+			":ttl":    db.CalculateTTL(processedTTLDays * 24 * time.Hour),
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#ttl":    "TTL",
+		},
+	})
+}
+
+// scheduleRetry records the failure and makes the event due again after a backoff.
+func (s *Service) scheduleRetry(ctx context.Context, event *Event, attempts int, cause error) error {
+	next := time.Now().Add(backoff(attempts))
+	return s.db.UpdateItem(ctx, event.PK, event.SK, db.UpdateParams{
+		UpdateExpression: "SET GSI1SK = :key, nextAttemptAt = :next, attempts = :attempts, lastError = :error",
+		ExpressionValues: map[string]interface{}{
+			":key":      dueKey(next, event.ID),
+			":next":     next,
+			":attempts": attempts,
+			":error":    cause.Error(),
+		},
+	})
+}
+
+// markDead moves the event to the dead-letter partition.
+func (s *Service) markDead(ctx context.Context, event *Event, attempts int, cause error) error {
+	now := time.Now()
+	return s.db.UpdateItem(ctx, event.PK, event.SK, db.UpdateParams{
+		UpdateExpression: "SET GSI1PK = :dead, GSI1SK = :key, #status = :status, attempts = :attempts, lastError = :error",
+		ExpressionValues: map[string]interface{}{
+			":dead":     deadPartition,
+			":key":      dueKey(now, event.ID),
+			":status":   StatusDead,
+			":attempts": attempts,
+			":error":    cause.Error(),
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+	})
+}
+
+// ListDeadLetters returns events that exhausted their retries, newest first.
+func (s *Service) ListDeadLetters(ctx context.Context, limit int32) ([]*Event, error) {
+	scanForward := false
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": deadPartition,
+		},
+		Limit:            limit,
+		ScanIndexForward: &scanForward,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead-lettered events: %w", err)
+	}
+	return unmarshalEvents(items)
+}
+
+// Requeue makes a dead-lettered event pending again with a fresh set of attempts.
+func (s *Service) Requeue(ctx context.Context, id string) error {
+	now := time.Now()
+	err := s.db.UpdateItem(ctx, "OUTBOX#"+id, "EVENT", db.UpdateParams{
+		UpdateExpression:    "SET GSI1PK = :pending, GSI1SK = :key, #status = :status, nextAttemptAt = :now, attempts = :zero",
+		ConditionExpression: "GSI1PK = :dead",
+		ExpressionValues: map[string]interface{}{
+			":pending": pendingPartition,
+			":key":     dueKey(now, id),
+			":status":  StatusPending,
+			":now":     now,
+			":zero":    0,
+			":dead":    deadPartition,
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+	})
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to requeue outbox event: %w", err)
+	}
+	return nil
+}
+
+// unmarshalEvents converts DynamoDB items to events.
+func unmarshalEvents(items []map[string]types.AttributeValue) ([]*Event, error) {
+	events := make([]*Event, 0, len(items))
+	for _, item := range items {
+		var event Event
+		if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal outbox event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
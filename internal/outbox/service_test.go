@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/booking-villa-backend/internal/db/dbtest"
+)
+
+func TestProcessNow(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+
+	calls := map[string]int{}
+	service := NewService(client)
+	service.Register("test.ok", func(_ context.Context, event *Event) error {
+		calls[event.ID]++
+		return nil
+	})
+	service.Register("test.fail", func(_ context.Context, event *Event) error {
+		calls[event.ID]++
+		return errors.New("endpoint down")
+	})
+
+	var events []*Event
+	for _, eventType := range []EventType{"test.ok", "test.fail", "test.later"} {
+		event, err := NewEvent(eventType, map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.TransactWrite(ctx, event.TransactItem()); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	ok, failed, later := events[0], events[1], events[2]
+
+	result := service.ProcessNow(ctx, events...)
+	if *result != (ProcessResult{Processed: 1, Retried: 1}) {
+		t.Errorf("ProcessNow() = %+v, want one processed and one retried", *result)
+	}
+
+	var stored Event
+	table.Get(t, ok.PK, ok.SK, &stored)
+	if stored.Status != StatusProcessed || stored.GSI1PK != "" {
+		t.Errorf("handled event is %s in %q, want processed and out of the pending index", stored.Status, stored.GSI1PK)
+	}
+	table.Get(t, failed.PK, failed.SK, &stored)
+	if stored.Status != StatusPending || stored.Attempts != 1 || stored.LastError == "" {
+		t.Errorf("failed event is %s after %d attempts (%q), want pending for retry", stored.Status, stored.Attempts, stored.LastError)
+	}
+	table.Get(t, later.PK, later.SK, &stored)
+	if stored.GSI1SK != later.GSI1SK {
+		t.Errorf("event without a handler was claimed, want it left for the worker")
+	}
+
+	// The worker picks up what was left, and does not run handled events again
+	if _, err := service.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls[ok.ID] != 1 || calls[failed.ID] != 1 {
+		t.Errorf("handlers ran %d and %d times, want once each", calls[ok.ID], calls[failed.ID])
+	}
+}
@@ -80,6 +80,11 @@ func (s *Service) CalculatePaymentStatus(ctx context.Context, bookingID string)
 		return nil, fmt.Errorf("booking not found")
 	}
 
+	return Summarize(booking), nil
+}
+
+// Summarize computes the payment summary for a booking that has already been loaded.
+func Summarize(booking *bookings.Booking) *PaymentSummary {
 	totalPaid := booking.AdvanceAmount
 	totalDue := booking.TotalAmount - totalPaid
 
@@ -96,7 +101,7 @@ func (s *Service) CalculatePaymentStatus(ctx context.Context, bookingID string)
 	}
 
 	return &PaymentSummary{
-		BookingID:       bookingID,
+		BookingID:       booking.ID,
 		TotalAmount:     booking.TotalAmount,
 		TotalPaid:       totalPaid,
 		TotalDue:        totalDue,
@@ -104,7 +109,7 @@ func (s *Service) CalculatePaymentStatus(ctx context.Context, bookingID string)
 		Status:          status,
 		Currency:        booking.Currency,
 		LastUpdated:     booking.UpdatedAt,
-	}, nil
+	}
 }
 
 // GetPaymentStatus returns just the payment status string.
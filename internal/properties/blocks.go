@@ -0,0 +1,141 @@
+package properties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/google/uuid"
+)
+
+// MaxBlockNights is the longest block that can be created at once.
+const MaxBlockNights = 366
+
+// blockDateFormat is the date format used in block sort keys.
+const blockDateFormat = "2006-01-02"
+
+// ErrBlockNotFound is returned when a block does not exist on the property.
+var ErrBlockNotFound = errors.New("block not found")
+
+// Block keeps a range of nights from being booked, e.g. for the owner's own
+// stays or maintenance. Dates are stored as UTC midnight, like booking dates.
+type Block struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // PROPERTY#<propertyId>
+	SK string `dynamodbav:"SK"` // BLOCK#<endDate>#<blockId>
+
+	// Block fields
+	ID         string    `dynamodbav:"id" json:"id"`
+	PropertyID string    `dynamodbav:"propertyId" json:"propertyId"`
+	StartDate  time.Time `dynamodbav:"startDate" json:"startDate"` // First blocked night
+	EndDate    time.Time `dynamodbav:"endDate" json:"endDate"`     // Day after the last blocked night
+	Reason     string    `dynamodbav:"reason,omitempty" json:"reason,omitempty"`
+
+	// Metadata
+	CreatedBy  string    `dynamodbav:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Nights returns the number of nights blocked.
+func (b *Block) Nights() int {
+	return int(b.EndDate.Sub(b.StartDate).Hours() / 24)
+}
+
+// Overlaps reports whether the block covers any night from start up to but
+// not including end.
+func (b *Block) Overlaps(start, end time.Time) bool {
+	return b.StartDate.Before(end) && start.Before(b.EndDate)
+}
+
+// Validate checks that the block covers at least one and at most
+// MaxBlockNights nights.
+func (b *Block) Validate() error {
+	nights := b.Nights()
+	if nights < 1 {
+		return fmt.Errorf("endDate must be after startDate")
+	}
+	if nights > MaxBlockNights {
+		return fmt.Errorf("a block can cover at most %d nights", MaxBlockNights)
+	}
+	return nil
+}
+
+// CreateBlock stores a new block.
+func (s *Service) CreateBlock(ctx context.Context, block *Block) error {
+	if err := block.Validate(); err != nil {
+		return err
+	}
+
+	block.ID = uuid.New().String()
+	block.PK = "PROPERTY#" + block.PropertyID
+	block.SK = "BLOCK#" + block.EndDate.Format(blockDateFormat) + "#" + block.ID
+	block.CreatedAt = time.Now()
+	block.EntityType = "PROPERTY_BLOCK"
+
+	if err := s.db.PutItem(ctx, block); err != nil {
+		return fmt.Errorf("failed to create block: %w", err)
+	}
+	return nil
+}
+
+// ListBlocks returns a property's blocks that cover any night from start up
+// to but not including end, ordered by end date.
+func (s *Service) ListBlocks(ctx context.Context, propertyID string, start, end time.Time) ([]*Block, error) {
+	// Blocks are keyed by end date, so skip those ending on or before start
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition: "PK = :pk AND SK BETWEEN :from AND :to",
+		ExpressionValues: map[string]interface{}{
+			":pk":   "PROPERTY#" + propertyID,
+			":from": "BLOCK#" + start.AddDate(0, 0, 1).Format(blockDateFormat),
+			":to":   "BLOCK#~",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocks: %w", err)
+	}
+
+	blocks := make([]*Block, 0, len(items))
+	for _, item := range items {
+		var block Block
+		if err := attributevalue.UnmarshalMap(item, &block); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal block: %w", err)
+		}
+		if block.Overlaps(start, end) {
+			blocks = append(blocks, &block)
+		}
+	}
+	return blocks, nil
+}
+
+// DeleteBlock removes a block from a property.
+func (s *Service) DeleteBlock(ctx context.Context, propertyID, blockID string) error {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition:     "PK = :pk AND begins_with(SK, :prefix)",
+		FilterExpression: "#id = :id",
+		ExpressionValues: map[string]interface{}{
+			":pk":     "PROPERTY#" + propertyID,
+			":prefix": "BLOCK#",
+			":id":     blockID,
+		},
+		ExpressionAttributeNames: map[string]string{"#id": "id"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find block: %w", err)
+	}
+	if len(items) == 0 {
+		return ErrBlockNotFound
+	}
+
+	var block Block
+	if err := attributevalue.UnmarshalMap(items[0], &block); err != nil {
+		return fmt.Errorf("failed to unmarshal block: %w", err)
+	}
+	if err := s.db.DeleteItem(ctx, block.PK, block.SK); err != nil {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
 	"github.com/booking-villa-backend/internal/db"
 	"github.com/booking-villa-backend/internal/middleware"
 	"github.com/booking-villa-backend/internal/users"
@@ -14,15 +15,17 @@ import (
 
 // Handler provides HTTP handlers for property endpoints.
 type Handler struct {
-	service     *Service
-	userService *users.Service
+	service      *Service
+	userService  *users.Service
+	auditService *audit.Service
 }
 
 // NewHandler creates a new property handler.
 func NewHandler(dbClient *db.Client) *Handler {
 	return &Handler{
-		service:     NewService(dbClient),
-		userService: users.NewService(dbClient),
+		service:      NewService(dbClient),
+		userService:  users.NewService(dbClient),
+		auditService: audit.NewService(dbClient),
 	}
 }
 
@@ -60,6 +63,11 @@ type CreatePropertyRequest struct {
 	Bathrooms     int      `json:"bathrooms"`
 	Amenities     []string `json:"amenities,omitempty"`
 	Images        []string `json:"images,omitempty"`
+
+	TimeZone            string `json:"timeZone,omitempty"`     // IANA name; default Asia/Kolkata
+	CheckInTime         string `json:"checkInTime,omitempty"`  // Format: 15:04; default 14:00
+	CheckOutTime        string `json:"checkOutTime,omitempty"` // Format: 15:04; default 11:00
+	TurnoverBufferHours int    `json:"turnoverBufferHours,omitempty"`
 }
 
 // HandleCreateProperty handles the POST /properties endpoint.
@@ -95,6 +103,15 @@ func (h *Handler) HandleCreateProperty(ctx context.Context, request events.APIGa
 		Bathrooms:     req.Bathrooms,
 		Amenities:     req.Amenities,
 		Images:        req.Images,
+
+		TimeZone:            req.TimeZone,
+		CheckInTime:         req.CheckInTime,
+		CheckOutTime:        req.CheckOutTime,
+		TurnoverBufferHours: req.TurnoverBufferHours,
+	}
+
+	if err := property.ValidateSchedule(); err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
 	}
 
 	if err := h.service.CreateProperty(ctx, property); err != nil {
@@ -120,6 +137,13 @@ type UpdatePropertyRequest struct {
 	Amenities     []string `json:"amenities,omitempty"`
 	Images        []string `json:"images,omitempty"`
 	IsActive      *bool    `json:"isActive,omitempty"`
+
+	Reminders *ReminderSettings `json:"reminders,omitempty"`
+
+	TimeZone            *string `json:"timeZone,omitempty"`
+	CheckInTime         *string `json:"checkInTime,omitempty"`
+	CheckOutTime        *string `json:"checkOutTime,omitempty"`
+	TurnoverBufferHours *int    `json:"turnoverBufferHours,omitempty"`
 }
 
 // HandleUpdateProperty handles the PATCH /properties/{id} endpoint.
@@ -199,6 +223,27 @@ func (h *Handler) HandleUpdateProperty(ctx context.Context, request events.APIGa
 	if req.IsActive != nil {
 		property.IsActive = *req.IsActive
 	}
+	if req.Reminders != nil {
+		if err := req.Reminders.Validate(); err != nil {
+			return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+		}
+		property.Reminders = req.Reminders
+	}
+	if req.TimeZone != nil {
+		property.TimeZone = *req.TimeZone
+	}
+	if req.CheckInTime != nil {
+		property.CheckInTime = *req.CheckInTime
+	}
+	if req.CheckOutTime != nil {
+		property.CheckOutTime = *req.CheckOutTime
+	}
+	if req.TurnoverBufferHours != nil {
+		property.TurnoverBufferHours = *req.TurnoverBufferHours
+	}
+	if err := property.ValidateSchedule(); err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
 
 	// Save updates
 	if err := h.service.UpdateProperty(ctx, property); err != nil {
@@ -249,6 +294,17 @@ func (h *Handler) HandleListProperties(ctx context.Context, request events.APIGa
 		return ErrorResponse(http.StatusInternalServerError, "Failed to list properties: "+err.Error()), nil
 	}
 
+	// API keys restricted to specific properties only see those properties
+	if len(claims.PropertyIDs) > 0 {
+		allowed := make([]*Property, 0, len(props))
+		for _, p := range props {
+			if claims.CanAccessProperty(p.ID) {
+				allowed = append(allowed, p)
+			}
+		}
+		props = allowed
+	}
+
 	return APIResponse(http.StatusOK, map[string]interface{}{
 		"properties": props,
 		"count":      len(props),
@@ -332,13 +388,26 @@ func (h *Handler) HandleValidateInviteCode(ctx context.Context, request events.A
 		return ErrorResponse(http.StatusBadRequest, "Invite code is required"), nil
 	}
 
+	event := audit.NewEvent(audit.EventInviteCodeRedeemed, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(req.Code)
+
 	inviteCode, err := h.service.ValidateInviteCode(ctx, req.Code)
 	if err != nil {
+		h.auditService.Log(ctx, event.WithError(err))
 		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
 	}
+	event.WithDetail("propertyId", inviteCode.PropertyID)
 
 	// Link agent to property
-	if err := h.userService.LinkProperty(ctx, claims.Phone, inviteCode.PropertyID); err != nil {
+	err = h.userService.LinkProperty(ctx, claims.Phone, inviteCode.PropertyID)
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to link property to account: "+err.Error()), nil
 	}
 
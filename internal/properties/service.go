@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	_ "time/tzdata" // Lambda images do not ship zoneinfo
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/booking-villa-backend/internal/db"
@@ -44,12 +45,124 @@ type Property struct {
 	Images        []string `dynamodbav:"images,omitempty" json:"images,omitempty"`
 	IsActive      bool     `dynamodbav:"isActive" json:"isActive"`
 
+	// Scheduled reminder windows; nil uses the defaults
+	Reminders *ReminderSettings `dynamodbav:"reminders,omitempty" json:"reminders,omitempty"`
+
+	// Local time and stay defaults; empty values use the defaults
+	TimeZone            string `dynamodbav:"timeZone,omitempty" json:"timeZone"`         // IANA name, e.g. Asia/Kolkata
+	CheckInTime         string `dynamodbav:"checkInTime,omitempty" json:"checkInTime"`   // Format: 15:04
+	CheckOutTime        string `dynamodbav:"checkOutTime,omitempty" json:"checkOutTime"` // Format: 15:04
+	TurnoverBufferHours int    `dynamodbav:"turnoverBufferHours,omitempty" json:"turnoverBufferHours"`
+
 	// Metadata
 	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
 	UpdatedAt  time.Time `dynamodbav:"updatedAt" json:"updatedAt"`
 	EntityType string    `dynamodbav:"entityType" json:"-"`
 }
 
+const (
+	DefaultTimeZone     = "Asia/Kolkata"
+	DefaultCheckInTime  = "14:00"
+	DefaultCheckOutTime = "11:00"
+
+	// MaxTurnoverBufferHours is the longest buffer that can be kept between stays.
+	MaxTurnoverBufferHours = 48
+)
+
+// Location returns the property's time zone, or the default zone if none is set.
+func (p *Property) Location() *time.Location {
+	if p.TimeZone != "" {
+		if location, err := time.LoadLocation(p.TimeZone); err == nil {
+			return location
+		}
+	}
+	location, err := time.LoadLocation(DefaultTimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Today returns the property's local date at now. Like booking dates, it is
+// expressed as UTC midnight.
+func (p *Property) Today(now time.Time) time.Time {
+	local := now.In(p.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// StayTimes returns the property's default check-in and check-out times.
+func (p *Property) StayTimes() (checkIn, checkOut string) {
+	checkIn, checkOut = p.CheckInTime, p.CheckOutTime
+	if checkIn == "" {
+		checkIn = DefaultCheckInTime
+	}
+	if checkOut == "" {
+		checkOut = DefaultCheckOutTime
+	}
+	return checkIn, checkOut
+}
+
+// TurnoverBuffer returns the time kept free between one stay's check-out and
+// the next stay's check-in.
+func (p *Property) TurnoverBuffer() time.Duration {
+	return time.Duration(p.TurnoverBufferHours) * time.Hour
+}
+
+// ValidateSchedule checks the property's time zone, stay times and turnover buffer.
+func (p *Property) ValidateSchedule() error {
+	if p.TimeZone != "" {
+		if _, err := time.LoadLocation(p.TimeZone); err != nil {
+			return fmt.Errorf("unknown timeZone %q", p.TimeZone)
+		}
+	}
+	times := []struct{ name, value string }{
+		{"checkInTime", p.CheckInTime},
+		{"checkOutTime", p.CheckOutTime},
+	}
+	for _, t := range times {
+		if t.value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", t.value); err != nil {
+			return fmt.Errorf("%s must be in HH:MM format", t.name)
+		}
+	}
+	if p.TurnoverBufferHours < 0 || p.TurnoverBufferHours > MaxTurnoverBufferHours {
+		return fmt.Errorf("turnoverBufferHours must be between 0 and %d", MaxTurnoverBufferHours)
+	}
+	return nil
+}
+
+// ReminderSettings configures when scheduled reminders are sent for a property.
+// Unset fields use the scheduler's defaults.
+type ReminderSettings struct {
+	Disabled             bool `dynamodbav:"disabled,omitempty" json:"disabled,omitempty"`
+	ArrivalDaysBefore    *int `dynamodbav:"arrivalDaysBefore,omitempty" json:"arrivalDaysBefore,omitempty"`
+	CheckOutDaysBefore   *int `dynamodbav:"checkOutDaysBefore,omitempty" json:"checkOutDaysBefore,omitempty"`
+	PaymentDueDaysBefore *int `dynamodbav:"paymentDueDaysBefore,omitempty" json:"paymentDueDaysBefore,omitempty"`
+	SendHour             *int `dynamodbav:"sendHour,omitempty" json:"sendHour,omitempty"` // Local hour, 0-23
+}
+
+// Validate checks that the reminder windows are within range.
+func (r *ReminderSettings) Validate() error {
+	checks := []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"arrivalDaysBefore", r.ArrivalDaysBefore, 0, 14},
+		{"checkOutDaysBefore", r.CheckOutDaysBefore, 0, 7},
+		{"paymentDueDaysBefore", r.PaymentDueDaysBefore, 0, 60},
+		{"sendHour", r.SendHour, 0, 23},
+	}
+	for _, c := range checks {
+		if c.value != nil && (*c.value < c.min || *c.value > c.max) {
+			return fmt.Errorf("%s must be between %d and %d", c.name, c.min, c.max)
+		}
+	}
+	return nil
+}
+
 // InviteCode represents a property-specific invite code for agents.
 type InviteCode struct {
 	// DynamoDB keys
@@ -103,6 +216,10 @@ func (s *Service) CreateProperty(ctx context.Context, property *Property) error
 	if property.Currency == "" {
 		property.Currency = "INR"
 	}
+	if property.TimeZone == "" {
+		property.TimeZone = DefaultTimeZone
+	}
+	property.CheckInTime, property.CheckOutTime = property.StayTimes()
 
 	return s.db.PutItem(ctx, property)
 }
@@ -160,17 +277,17 @@ func (s *Service) ListPropertiesByOwner(ctx context.Context, ownerID string) ([]
 }
 
 // ListAllProperties retrieves all active properties in the system.
+// It scans the whole table, following pagination, so use it for scheduled
+// jobs rather than request handling.
 func (s *Service) ListAllProperties(ctx context.Context) ([]*Property, error) {
-	// Scan for EntityType = PROPERTY
-	// Note: In production with high volume, this should use a GSI or specific partition
 	params := db.ScanParams{
-		FilterExpression: "EntityType = :entityType",
+		FilterExpression: "entityType = :entityType",
 		ExpressionValues: map[string]interface{}{
 			":entityType": "PROPERTY",
 		},
 	}
 
-	items, err := s.db.Scan(ctx, params)
+	items, err := s.db.ScanAll(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan properties: %w", err)
 	}
@@ -179,7 +296,7 @@ func (s *Service) ListAllProperties(ctx context.Context) ([]*Property, error) {
 	for _, item := range items {
 		var property Property
 		if err := attributevalue.UnmarshalMap(item, &property); err != nil {
-			continue
+			return nil, fmt.Errorf("failed to unmarshal property: %w", err)
 		}
 		if property.IsActive {
 			properties = append(properties, &property)
@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// LocalServer serves WebSocket connections with net/http for local runs.
+// It stands in for API Gateway: clients connect to GET /ws?token=..., and
+// other processes push messages through POST /@connections/{id}, so setting
+// WEBSOCKET_ENDPOINT to this server's address works as it does in AWS.
+type LocalServer struct {
+	service *Service
+	mu      sync.RWMutex
+	conns   map[string]*wsConn
+}
+
+// NewLocalServer creates a local server. Messages published by the service
+// are delivered to this server's connections.
+func NewLocalServer(service *Service) *LocalServer {
+	server := &LocalServer{
+		service: service,
+		conns:   make(map[string]*wsConn),
+	}
+	service.SetPoster(server)
+	return server
+}
+
+// Handler returns the server's HTTP routes.
+func (s *LocalServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", s.handleConnect)
+	mux.HandleFunc("POST /@connections/{id}", s.handlePost)
+	mux.HandleFunc("DELETE /@connections/{id}", s.handleDelete)
+	return mux
+}
+
+// Post sends data to a local connection.
+func (s *LocalServer) Post(_ context.Context, connectionID string, data []byte) error {
+	s.mu.RLock()
+	conn, ok := s.conns[connectionID]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrGone
+	}
+	if err := conn.writeText(data); err != nil {
+		return ErrGone
+	}
+	return nil
+}
+
+// handleConnect authenticates the client, upgrades the connection and
+// handles its messages until it closes.
+func (s *LocalServer) handleConnect(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	claims, err := s.service.Authenticate(r.Context(), token)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The request context ends with the handshake once the connection is hijacked
+	ctx := context.Background()
+	id := uuid.New().String()
+
+	if err := s.service.Connect(ctx, id, claims); err != nil {
+		log.Printf("Failed to register connection %s: %v", id, err)
+		conn.close()
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[id] = conn
+	s.mu.Unlock()
+	log.Printf("Connected %s (%s)", id, claims.Phone)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, id)
+		s.mu.Unlock()
+		conn.close()
+
+		if err := s.service.Disconnect(ctx, id); err != nil {
+			log.Printf("Failed to remove connection %s: %v", id, err)
+		}
+		log.Printf("Disconnected %s", id)
+	}()
+
+	for {
+		message, err := conn.readMessage()
+		if err != nil {
+			if !errors.Is(err, errClosed) && !errors.Is(err, io.EOF) {
+				log.Printf("Connection %s: %v", id, err)
+			}
+			return
+		}
+
+		reply := s.service.HandleMessage(ctx, id, message)
+		if err := s.service.Send(ctx, id, reply); err != nil {
+			log.Printf("Failed to reply to connection %s: %v", id, err)
+			return
+		}
+	}
+}
+
+// handlePost emulates the API Gateway PostToConnection call.
+func (s *LocalServer) handlePost(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, 128*1024))
+	if err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Post(r.Context(), r.PathValue("id"), data); err != nil {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDelete emulates the API Gateway DeleteConnection call.
+func (s *LocalServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	conn, ok := s.conns[r.PathValue("id")]
+	s.mu.RUnlock()
+	if !ok {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+
+	// The read loop sees the closed connection and cleans up
+	conn.close()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+// Package realtime pushes booking and notification events to connected
+// clients over WebSockets. Connections and subscriptions are stored in
+// DynamoDB, so any Lambda can publish to clients connected through
+// API Gateway, or to the local server during development.
+package realtime
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Channels a client can subscribe to.
+const (
+	ChannelCalendar      = "calendar"
+	ChannelNotifications = "notifications"
+)
+
+// Actions a client can send.
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ActionPing        = "ping"
+)
+
+// EventType identifies a message sent to clients.
+type EventType string
+
+const (
+	EventBookingChanged      EventType = "booking.changed"
+	EventNotificationCreated EventType = "notification.created"
+	EventSubscribed          EventType = "subscribed"
+	EventUnsubscribed        EventType = "unsubscribed"
+	EventPong                EventType = "pong"
+	EventError               EventType = "error"
+)
+
+// BookingChange describes what happened to a booking.
+type BookingChange string
+
+const (
+	ChangeCreated   BookingChange = "created"
+	ChangeUpdated   BookingChange = "updated"
+	ChangeCancelled BookingChange = "cancelled"
+	ChangeStatus    BookingChange = "status"
+)
+
+// connectionTTL outlives API Gateway's two-hour connection limit, so stale
+// connections are removed even if the disconnect route never ran.
+const connectionTTL = 3 * time.Hour
+
+// Connection is a connected client.
+type Connection struct {
+	PK          string    `dynamodbav:"PK" json:"-"` // WSCONN#<id>
+	SK          string    `dynamodbav:"SK" json:"-"` // METADATA
+	ID          string    `dynamodbav:"connectionId" json:"connectionId"`
+	Phone       string    `dynamodbav:"phone" json:"phone"`
+	Role        string    `dynamodbav:"role" json:"role"`
+	PropertyIDs []string  `dynamodbav:"propertyIds,omitempty" json:"propertyIds,omitempty"`
+	ConnectedAt time.Time `dynamodbav:"connectedAt" json:"connectedAt"`
+	TTL         int64     `dynamodbav:"TTL" json:"-"`
+	EntityType  string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Subscription links a connection to a topic. It is stored under the
+// connection, for cleanup on disconnect, and indexed by topic for publishing.
+type Subscription struct {
+	PK           string    `dynamodbav:"PK"`     // WSCONN#<id>
+	SK           string    `dynamodbav:"SK"`     // SUB#<topic>
+	GSI1PK       string    `dynamodbav:"GSI1PK"` // WSSUB#<topic>
+	GSI1SK       string    `dynamodbav:"GSI1SK"` // CONN#<id>
+	ConnectionID string    `dynamodbav:"connectionId"`
+	Topic        string    `dynamodbav:"topic"`
+	CreatedAt    time.Time `dynamodbav:"createdAt"`
+	TTL          int64     `dynamodbav:"TTL"`
+	EntityType   string    `dynamodbav:"entityType"`
+}
+
+// PropertyTopic is the topic for changes to a property's calendar.
+func PropertyTopic(propertyID string) string {
+	return "property#" + propertyID
+}
+
+// UserTopic is the topic for a user's notifications.
+func UserTopic(phone string) string {
+	return "user#" + phone
+}
+
+// ClientMessage is a message sent by a client.
+type ClientMessage struct {
+	Action     string `json:"action"`
+	Channel    string `json:"channel,omitempty"`
+	PropertyID string `json:"propertyId,omitempty"`
+}
+
+// Event is a message sent to a client.
+type Event struct {
+	Type       EventType       `json:"type"`
+	Channel    string          `json:"channel,omitempty"`
+	PropertyID string          `json:"propertyId,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	SentAt     time.Time       `json:"sentAt"`
+}
+
+// NewEvent creates an event with data encoded as JSON.
+func NewEvent(eventType EventType, data interface{}) (*Event, error) {
+	event := &Event{Type: eventType, SentAt: time.Now().UTC()}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		event.Data = raw
+	}
+	return event, nil
+}
+
+// errorEvent creates an error reply.
+func errorEvent(message string) *Event {
+	return &Event{Type: EventError, Error: message, SentAt: time.Now().UTC()}
+}
+
+// BookingChangedData is the data of a booking.changed event. It carries no
+// guest details: clients refetch the calendar, which applies their access.
+type BookingChangedData struct {
+	BookingID  string        `json:"bookingId"`
+	PropertyID string        `json:"propertyId"`
+	Change     BookingChange `json:"change"`
+	Status     string        `json:"status"`
+	CheckIn    string        `json:"checkIn"`
+	CheckOut   string        `json:"checkOut"`
+}
+
+// NotificationCreatedData is the data of a notification.created event.
+type NotificationCreatedData struct {
+	NotificationID string `json:"notificationId"`
+	Type           string `json:"type"`
+	Title          string `json:"title"`
+	Message        string `json:"message"`
+	BookingID      string `json:"bookingId,omitempty"`
+	PropertyID     string `json:"propertyId,omitempty"`
+}
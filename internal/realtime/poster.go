@@ -0,0 +1,104 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// ErrGone is returned when posting to a connection that no longer exists.
+var ErrGone = errors.New("connection is gone")
+
+// Poster sends a message to a single connection.
+type Poster interface {
+	Post(ctx context.Context, connectionID string, data []byte) error
+}
+
+// EndpointFromEnv returns the connection management endpoint from
+// WEBSOCKET_ENDPOINT, e.g. https://abc123.execute-api.ap-south-1.amazonaws.com/prod
+// or http://localhost:8081 for the local server. Empty if not configured.
+func EndpointFromEnv() string {
+	return strings.TrimSuffix(os.Getenv("WEBSOCKET_ENDPOINT"), "/")
+}
+
+// HTTPPoster posts messages through the API Gateway connection management
+// API. Requests to https endpoints are signed with the Lambda's credentials;
+// the local server accepts them unsigned.
+type HTTPPoster struct {
+	endpoint    string
+	client      *http.Client
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+}
+
+// NewHTTPPoster creates a poster for the given connection management endpoint.
+func NewHTTPPoster(endpoint string) *HTTPPoster {
+	p := &HTTPPoster{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+
+	if strings.HasPrefix(p.endpoint, "https://") {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			log.Printf("Failed to load AWS config for WebSocket posts: %v", err)
+		} else {
+			p.signer = v4.NewSigner()
+			p.credentials = cfg.Credentials
+			p.region = cfg.Region
+		}
+	}
+
+	return p
+}
+
+// Post sends data to a connection. It returns ErrGone if the client has disconnected.
+func (p *HTTPPoster) Post(ctx context.Context, connectionID string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		p.endpoint+"/@connections/"+url.PathEscape(connectionID), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if p.signer != nil {
+		creds, err := p.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+		}
+		hash := sha256.Sum256(data)
+		if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "execute-api", p.region, time.Now()); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to connection: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post to connection failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+var (
+	// ErrUnauthorized is returned when a connection's token is missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrConnectionNotFound is returned for messages from an unknown connection.
+	ErrConnectionNotFound = errors.New("connection not found")
+)
+
+// Service manages WebSocket connections and subscriptions, and publishes events.
+type Service struct {
+	db              *db.Client
+	propertyService *properties.Service
+	poster          Poster
+	validateSession middleware.SessionValidator
+}
+
+// NewService creates a new realtime service. Publishing is enabled when
+// WEBSOCKET_ENDPOINT is set; otherwise Publish does nothing.
+func NewService(dbClient *db.Client) *Service {
+	s := &Service{
+		db:              dbClient,
+		propertyService: properties.NewService(dbClient),
+	}
+	if endpoint := EndpointFromEnv(); endpoint != "" {
+		s.poster = NewHTTPPoster(endpoint)
+	}
+	return s
+}
+
+// SetPoster sets how messages reach connections.
+func (s *Service) SetPoster(poster Poster) {
+	s.poster = poster
+}
+
+// SetSessionValidator sets the check run on each connecting client's token.
+func (s *Service) SetSessionValidator(validator middleware.SessionValidator) {
+	s.validateSession = validator
+}
+
+// IsEnabled reports whether events can be published.
+func (s *Service) IsEnabled() bool {
+	return s.poster != nil
+}
+
+// Authenticate validates a session token for a new connection.
+// Restricted-purpose tokens (2FA challenges and 2FA setup) are rejected, as
+// are impersonation tokens: every impersonated request is audited, which a
+// long-lived connection would bypass.
+func (s *Service) Authenticate(ctx context.Context, token string) (*utils.TokenClaims, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	claims, err := utils.ValidateToken(token)
+	if err != nil || claims.Purpose != "" || claims.ImpersonatorPhone != "" {
+		return nil, ErrUnauthorized
+	}
+
+	if s.validateSession != nil {
+		if err := s.validateSession(ctx, claims); err != nil {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	return claims, nil
+}
+
+// Connect records a new authenticated connection.
+func (s *Service) Connect(ctx context.Context, connectionID string, claims *utils.TokenClaims) error {
+	conn := &Connection{
+		PK:          "WSCONN#" + connectionID,
+		SK:          "METADATA",
+		ID:          connectionID,
+		Phone:       claims.Phone,
+		Role:        claims.Role,
+		PropertyIDs: claims.PropertyIDs,
+		ConnectedAt: time.Now(),
+		TTL:         db.CalculateTTL(connectionTTL),
+		EntityType:  "WS_CONNECTION",
+	}
+
+	if err := s.db.PutItem(ctx, conn); err != nil {
+		return fmt.Errorf("failed to save connection: %w", err)
+	}
+	return nil
+}
+
+// Disconnect removes a connection and all of its subscriptions.
+func (s *Service) Disconnect(ctx context.Context, connectionID string) error {
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		KeyCondition: "PK = :pk",
+		ExpressionValues: map[string]interface{}{
+			":pk": "WSCONN#" + connectionID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list connection items: %w", err)
+	}
+
+	for _, item := range items {
+		var key struct {
+			PK string `dynamodbav:"PK"`
+			SK string `dynamodbav:"SK"`
+		}
+		if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+			continue
+		}
+		if err := s.db.DeleteItem(ctx, key.PK, key.SK); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getConnection retrieves a connection by ID.
+func (s *Service) getConnection(ctx context.Context, connectionID string) (*Connection, error) {
+	var conn Connection
+	if err := s.db.GetItem(ctx, "WSCONN#"+connectionID, "METADATA", &conn); err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrConnectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	return &conn, nil
+}
+
+// HandleMessage processes a message from a client and returns the reply.
+func (s *Service) HandleMessage(ctx context.Context, connectionID string, body []byte) *Event {
+	var msg ClientMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return errorEvent("Invalid message")
+	}
+
+	if msg.Action == ActionPing {
+		return &Event{Type: EventPong, SentAt: time.Now().UTC()}
+	}
+	if msg.Action != ActionSubscribe && msg.Action != ActionUnsubscribe {
+		return errorEvent("Unknown action: " + msg.Action)
+	}
+
+	conn, err := s.getConnection(ctx, connectionID)
+	if err != nil {
+		if errors.Is(err, ErrConnectionNotFound) {
+			return errorEvent("Connection not found, reconnect")
+		}
+		log.Printf("Failed to get connection %s: %v", connectionID, err)
+		return errorEvent("Failed to process message")
+	}
+
+	topic, errMessage := s.topicFor(ctx, conn, msg)
+	if errMessage != "" {
+		return errorEvent(errMessage)
+	}
+
+	reply := &Event{Channel: msg.Channel, PropertyID: msg.PropertyID, SentAt: time.Now().UTC()}
+	if msg.Action == ActionSubscribe {
+		err = s.subscribe(ctx, conn, topic)
+		reply.Type = EventSubscribed
+	} else {
+		err = s.db.DeleteItem(ctx, conn.PK, "SUB#"+topic)
+		reply.Type = EventUnsubscribed
+	}
+	if err != nil {
+		log.Printf("Failed to %s connection %s to %s: %v", msg.Action, connectionID, topic, err)
+		return errorEvent("Failed to process message")
+	}
+
+	return reply
+}
+
+// topicFor resolves the topic for a channel and checks the connection may
+// subscribe to it. Calendars follow the same access rules as the calendar
+// endpoint. It returns an error message for the client if not.
+func (s *Service) topicFor(ctx context.Context, conn *Connection, msg ClientMessage) (string, string) {
+	switch msg.Channel {
+	case ChannelNotifications:
+		return UserTopic(conn.Phone), ""
+
+	case ChannelCalendar:
+		if msg.PropertyID == "" {
+			return "", "propertyId is required"
+		}
+		claims := &utils.TokenClaims{PropertyIDs: conn.PropertyIDs}
+		if !claims.CanAccessProperty(msg.PropertyID) {
+			return "", "You do not have access to this property"
+		}
+		if msg.Action == ActionUnsubscribe {
+			return PropertyTopic(msg.PropertyID), ""
+		}
+
+		property, err := s.propertyService.GetProperty(ctx, msg.PropertyID)
+		if err != nil {
+			log.Printf("Failed to get property %s: %v", msg.PropertyID, err)
+			return "", "Failed to process message"
+		}
+		if property == nil {
+			return "", "Property not found"
+		}
+		return PropertyTopic(msg.PropertyID), ""
+
+	default:
+		return "", "Unknown channel: " + msg.Channel
+	}
+}
+
+// subscribe adds a subscription that expires with the connection.
+func (s *Service) subscribe(ctx context.Context, conn *Connection, topic string) error {
+	return s.db.PutItem(ctx, &Subscription{
+		PK:           conn.PK,
+		SK:           "SUB#" + topic,
+		GSI1PK:       "WSSUB#" + topic,
+		GSI1SK:       "CONN#" + conn.ID,
+		ConnectionID: conn.ID,
+		Topic:        topic,
+		CreatedAt:    time.Now(),
+		TTL:          conn.TTL,
+		EntityType:   "WS_SUBSCRIPTION",
+	})
+}
+
+// Send sends an event to a single connection.
+func (s *Service) Send(ctx context.Context, connectionID string, event *Event) error {
+	if s.poster == nil {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.poster.Post(ctx, connectionID, data)
+}
+
+// Publish sends an event to every connection subscribed to a topic.
+// Connections that have gone away are removed; other failures are logged,
+// so one bad connection does not hold up the rest.
+func (s *Service) Publish(ctx context.Context, topic string, event *Event) error {
+	if s.poster == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	items, err := s.db.QueryAll(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "WSSUB#" + topic,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	var subs []*Subscription
+	if err := attributevalue.UnmarshalListOfMaps(items, &subs); err != nil {
+		return fmt.Errorf("failed to unmarshal subscriptions: %w", err)
+	}
+
+	for _, sub := range subs {
+		err := s.poster.Post(ctx, sub.ConnectionID, data)
+		switch {
+		case errors.Is(err, ErrGone):
+			if err := s.Disconnect(ctx, sub.ConnectionID); err != nil {
+				log.Printf("Failed to remove stale connection %s: %v", sub.ConnectionID, err)
+			}
+		case err != nil:
+			log.Printf("Failed to post %s to connection %s: %v", event.Type, sub.ConnectionID, err)
+		}
+	}
+	return nil
+}
+
+// EventPublish is the outbox event that publishes a realtime event to a topic.
+const EventPublish outbox.EventType = "realtime.publish"
+
+// PublishEvent is the payload of EventPublish.
+type PublishEvent struct {
+	Topic string `json:"topic"`
+	Event *Event `json:"event"`
+}
+
+// NewBookingChangedEvent creates an outbox event that tells clients watching
+// a property's calendar that one of its bookings changed.
+// Returns nil if realtime updates are not configured.
+func NewBookingChangedEvent(data BookingChangedData) (*outbox.Event, error) {
+	if EndpointFromEnv() == "" {
+		return nil, nil
+	}
+
+	event, err := NewEvent(EventBookingChanged, data)
+	if err != nil {
+		return nil, err
+	}
+	event.Channel = ChannelCalendar
+	event.PropertyID = data.PropertyID
+
+	return outbox.NewEvent(EventPublish, PublishEvent{Topic: PropertyTopic(data.PropertyID), Event: event})
+}
+
+// PublishNotification tells a user's connected clients about a new notification.
+func (s *Service) PublishNotification(ctx context.Context, phone string, data NotificationCreatedData) error {
+	if s.poster == nil {
+		return nil
+	}
+
+	event, err := NewEvent(EventNotificationCreated, data)
+	if err != nil {
+		return err
+	}
+	event.Channel = ChannelNotifications
+
+	return s.Publish(ctx, UserTopic(phone), event)
+}
+
+// HandlePublishEvent publishes the event described by an outbox event.
+func (s *Service) HandlePublishEvent(ctx context.Context, event *outbox.Event) error {
+	var payload PublishEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	if payload.Event == nil {
+		return nil
+	}
+	return s.Publish(ctx, payload.Topic, payload.Event)
+}
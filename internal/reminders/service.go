@@ -0,0 +1,301 @@
+// Package reminders sends scheduled reminders about upcoming arrivals,
+// check-outs and outstanding payments.
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/i18n"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/users"
+)
+
+// Kind identifies a reminder. Each kind is sent at most once per booking.
+type Kind string
+
+const (
+	KindArrival    Kind = "arrival"
+	KindCheckOut   Kind = "checkout"
+	KindPaymentDue Kind = "payment_due"
+)
+
+const (
+	defaultArrivalDaysBefore    = 1
+	defaultCheckOutDaysBefore   = 0
+	defaultPaymentDueDaysBefore = 3
+	defaultSendHour             = 9
+
+	// Bookings that checked in this long ago are not considered for check-out reminders.
+	maxStayDays = 30
+
+	markerRetentionDays = 180
+)
+
+// marker records that a reminder was sent for a booking.
+type marker struct {
+	PK         string    `dynamodbav:"PK"` // BOOKING#<id>
+	SK         string    `dynamodbav:"SK"` // REMINDER#<kind>
+	BookingID  string    `dynamodbav:"bookingId"`
+	Kind       Kind      `dynamodbav:"kind"`
+	SentAt     time.Time `dynamodbav:"sentAt"`
+	TTL        int64     `dynamodbav:"TTL"`
+	EntityType string    `dynamodbav:"entityType"`
+}
+
+// window holds a property's resolved reminder settings.
+type window struct {
+	disabled             bool
+	arrivalDaysBefore    int
+	checkOutDaysBefore   int
+	paymentDueDaysBefore int
+	sendHour             int
+}
+
+// RunResult summarises one scheduled run.
+type RunResult struct {
+	Properties  int `json:"properties"`
+	Sent        int `json:"sent"`
+	AlreadySent int `json:"alreadySent"`
+	Failed      int `json:"failed"`
+}
+
+// Service finds bookings that are due a reminder and queues the notifications.
+type Service struct {
+	db              *db.Client
+	propertyService *properties.Service
+	bookingService  *bookings.Service
+	userService     *users.Service
+	defaults        window
+}
+
+// NewService creates a new reminder service.
+// PAYMENT_REMINDER_DAYS sets the default payment reminder window.
+func NewService(dbClient *db.Client) *Service {
+	paymentDays := defaultPaymentDueDaysBefore
+	if v, err := strconv.Atoi(os.Getenv("PAYMENT_REMINDER_DAYS")); err == nil && v >= 0 {
+		paymentDays = v
+	}
+
+	return &Service{
+		db:              dbClient,
+		propertyService: properties.NewService(dbClient),
+		bookingService:  bookings.NewService(dbClient),
+		userService:     users.NewService(dbClient),
+		defaults: window{
+			arrivalDaysBefore:    defaultArrivalDaysBefore,
+			checkOutDaysBefore:   defaultCheckOutDaysBefore,
+			paymentDueDaysBefore: paymentDays,
+			sendHour:             defaultSendHour,
+		},
+	}
+}
+
+// windowFor applies a property's reminder settings over the defaults.
+func (s *Service) windowFor(property *properties.Property) window {
+	w := s.defaults
+	settings := property.Reminders
+	if settings == nil {
+		return w
+	}
+
+	w.disabled = settings.Disabled
+	if settings.ArrivalDaysBefore != nil {
+		w.arrivalDaysBefore = *settings.ArrivalDaysBefore
+	}
+	if settings.CheckOutDaysBefore != nil {
+		w.checkOutDaysBefore = *settings.CheckOutDaysBefore
+	}
+	if settings.PaymentDueDaysBefore != nil {
+		w.paymentDueDaysBefore = *settings.PaymentDueDaysBefore
+	}
+	if settings.SendHour != nil {
+		w.sendHour = *settings.SendHour
+	}
+	return w
+}
+
+// Run queues every reminder that is due at now. It is safe to run
+// repeatedly: a reminder already sent for a booking is not sent again.
+func (s *Service) Run(ctx context.Context, now time.Time) (*RunResult, error) {
+	result := &RunResult{}
+
+	props, err := s.propertyService.ListAllProperties(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, property := range props {
+		if ctx.Err() != nil {
+			break
+		}
+
+		w := s.windowFor(property)
+		if w.disabled {
+			continue
+		}
+		result.Properties++
+
+		// Reminder days and send hours are in the property's time zone
+		local := now.In(property.Location())
+		today := property.Today(now)
+
+		lookahead := w.arrivalDaysBefore
+		if w.paymentDueDaysBefore > lookahead {
+			lookahead = w.paymentDueDaysBefore
+		}
+		propBookings, err := s.bookingService.ListBookingsByProperty(ctx, property.ID, &bookings.DateRange{
+			Start: today.AddDate(0, 0, -maxStayDays),
+			End:   today.AddDate(0, 0, lookahead),
+		})
+		if err != nil {
+			log.Printf("Failed to list bookings for property %s: %v", property.ID, err)
+			result.Failed++
+			continue
+		}
+
+		for _, booking := range propBookings {
+			for _, kind := range dueKinds(booking, w, today, local.Hour()) {
+				sent, err := s.send(ctx, kind, booking, property, today)
+				switch {
+				case err != nil:
+					log.Printf("Failed to send %s reminder for booking %s: %v", kind, booking.ID, err)
+					result.Failed++
+				case sent:
+					result.Sent++
+				default:
+					result.AlreadySent++
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// dueKinds returns the reminders a booking is due at the given local day and hour.
+func dueKinds(booking *bookings.Booking, w window, today time.Time, hour int) []Kind {
+	if booking.Status == bookings.StatusCancelled {
+		return nil
+	}
+
+	var kinds []Kind
+	untilCheckIn := daysBetween(today, booking.CheckIn)
+	untilCheckOut := daysBetween(today, booking.CheckOut)
+
+	if isDue(untilCheckIn, w.arrivalDaysBefore, hour, w.sendHour) {
+		kinds = append(kinds, KindArrival)
+	}
+	if isDue(untilCheckOut, w.checkOutDaysBefore, hour, w.sendHour) {
+		kinds = append(kinds, KindCheckOut)
+	}
+	if booking.Status != bookings.StatusSettled && balanceDue(booking) > 0 &&
+		isDue(untilCheckIn, w.paymentDueDaysBefore, hour, w.sendHour) {
+		kinds = append(kinds, KindPaymentDue)
+	}
+	return kinds
+}
+
+// isDue reports whether a reminder for an event daysUntil days away is due,
+// given it should go out daysBefore days ahead at sendHour. A run that missed
+// the send hour catches up on a later day, until the event itself.
+func isDue(daysUntil, daysBefore, hour, sendHour int) bool {
+	if daysUntil < 0 || daysUntil > daysBefore {
+		return false
+	}
+	return daysUntil < daysBefore || hour >= sendHour
+}
+
+// daysBetween returns the number of whole days from one date to another.
+func daysBetween(from, to time.Time) int {
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// balanceDue returns the amount still owed for a booking.
+func balanceDue(booking *bookings.Booking) float64 {
+	return booking.TotalAmount - booking.AdvanceAmount
+}
+
+// send records the reminder marker and queues notifications for the owner and
+// booking agent in one transaction, each in their own language. It returns
+// false if the reminder was already sent.
+func (s *Service) send(ctx context.Context, kind Kind, booking *bookings.Booking, property *properties.Property, today time.Time) (bool, error) {
+	items := []db.TransactItem{
+		db.TransactPut(&marker{
+			PK:         "BOOKING#" + booking.ID,
+			SK:         "REMINDER#" + string(kind),
+			BookingID:  booking.ID,
+			Kind:       kind,
+			SentAt:     time.Now(),
+			TTL:        db.CalculateTTL(markerRetentionDays * 24 * time.Hour),
+			EntityType: "REMINDER",
+		}, "attribute_not_exists(PK)"),
+	}
+
+	seen := map[string]bool{"": true}
+	for _, phone := range []string{property.OwnerID, booking.BookedBy} {
+		if seen[phone] {
+			continue
+		}
+		seen[phone] = true
+
+		notifType, title, message := reminderMessage(s.userService.LocaleFor(ctx, phone), kind, booking, property, today)
+		event, err := notifications.NewNotificationEvent(notifications.NotificationEvent{
+			UserPhone:    phone,
+			Type:         notifType,
+			Title:        title,
+			Message:      message,
+			BookingID:    booking.ID,
+			PropertyID:   property.ID,
+			PropertyName: property.Name,
+			GuestName:    booking.GuestName,
+		})
+		if err != nil {
+			return false, err
+		}
+		items = append(items, event.TransactItem())
+	}
+
+	if err := s.db.TransactWrite(ctx, items...); err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to queue reminder: %w", err)
+	}
+	return true, nil
+}
+
+// reminderMessage returns the notification type, title and message for a
+// reminder in locale.
+func reminderMessage(locale i18n.Locale, kind Kind, booking *bookings.Booking, property *properties.Property, today time.Time) (notifications.NotificationType, string, string) {
+	vars := map[string]string{
+		"guest":    booking.GuestName,
+		"property": property.Name,
+		"when":     i18n.RelativeDay(locale, today, booking.CheckIn),
+	}
+
+	var notifType notifications.NotificationType
+	switch kind {
+	case KindArrival:
+		notifType = notifications.TypeArrivalReminder
+		vars["guests"] = strconv.Itoa(booking.NumGuests)
+	case KindCheckOut:
+		notifType = notifications.TypeCheckOutReminder
+		vars["when"] = i18n.RelativeDay(locale, today, booking.CheckOut)
+	default:
+		notifType = notifications.TypePaymentDueReminder
+		vars["currency"] = booking.Currency
+		vars["amount"] = fmt.Sprintf("%.0f", balanceDue(booking))
+	}
+
+	title, message := notifications.Render(locale, string(notifType), vars)
+	return notifType, title, message
+}
@@ -0,0 +1,200 @@
+package reminders
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/booking-villa-backend/internal/bookings"
+	"github.com/booking-villa-backend/internal/db/dbtest"
+	"github.com/booking-villa-backend/internal/notifications"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+)
+
+func TestIsDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		daysUntil  int
+		daysBefore int
+		hour       int
+		want       bool
+	}{
+		{"too early", 3, 2, 12, false},
+		{"due day before send hour", 2, 2, 8, false},
+		{"due day at send hour", 2, 2, 9, true},
+		{"due day after send hour", 2, 2, 18, true},
+		{"catching up", 1, 2, 3, true},
+		{"event day", 0, 2, 3, true},
+		{"event passed", -1, 2, 12, false},
+		{"same day reminder", 0, 0, 10, true},
+		{"same day reminder before send hour", 0, 0, 8, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDue(tt.daysUntil, tt.daysBefore, tt.hour, 9); got != tt.want {
+				t.Errorf("isDue(%d, %d, %d, 9) = %v, want %v", tt.daysUntil, tt.daysBefore, tt.hour, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		to   time.Time
+		want int
+	}{
+		{"same day", time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC), 0},
+		{"tomorrow", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), 1},
+		{"late in the day", time.Date(2026, 3, 12, 23, 59, 0, 0, time.UTC), 2},
+		{"yesterday", time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC), -1},
+		{"across a month", time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), 23},
+		{"local date", time.Date(2026, 3, 11, 2, 0, 0, 0, kolkata), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daysBetween(today, tt.to); got != tt.want {
+				t.Errorf("daysBetween(%v, %v) = %d, want %d", today, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueKinds(t *testing.T) {
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	w := window{arrivalDaysBefore: 1, checkOutDaysBefore: 0, paymentDueDaysBefore: 3, sendHour: 9}
+	booking := func(status bookings.BookingStatus, checkIn, nights int, advance float64) *bookings.Booking {
+		return &bookings.Booking{
+			Status:        status,
+			CheckIn:       today.AddDate(0, 0, checkIn),
+			CheckOut:      today.AddDate(0, 0, checkIn+nights),
+			TotalAmount:   10000,
+			AdvanceAmount: advance,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		booking *bookings.Booking
+		hour    int
+		want    []Kind
+	}{
+		{"arriving tomorrow with a balance", booking(bookings.StatusPartial, 1, 2, 2000), 10, []Kind{KindArrival, KindPaymentDue}},
+		{"arriving tomorrow before the send hour", booking(bookings.StatusPartial, 1, 2, 2000), 8, []Kind{KindPaymentDue}},
+		{"arriving tomorrow, settled", booking(bookings.StatusSettled, 1, 2, 10000), 10, []Kind{KindArrival}},
+		{"balance due in three days", booking(bookings.StatusPending, 3, 2, 0), 10, []Kind{KindPaymentDue}},
+		{"arriving next week", booking(bookings.StatusPending, 7, 2, 0), 10, nil},
+		{"checking out today", booking(bookings.StatusSettled, -2, 2, 10000), 10, []Kind{KindCheckOut}},
+		{"cancelled", booking(bookings.StatusCancelled, 1, 2, 0), 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dueKinds(tt.booking, w, today, tt.hour)
+			if len(got) != len(tt.want) {
+				t.Fatalf("dueKinds() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("dueKinds() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	client, table := dbtest.New(t)
+	// Read one item per page, so properties past the first page are only
+	// found if the listing follows pagination
+	table.PageSize = 1
+
+	propertyService := properties.NewService(client)
+	for _, name := range []string{"Hill House", "Sea View"} {
+		if err := propertyService.CreateProperty(ctx, &properties.Property{Name: name, OwnerID: "9876543210"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	props, err := propertyService.ListAllProperties(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(props) != 2 {
+		t.Fatalf("ListAllProperties returned %d properties, want 2", len(props))
+	}
+	property := props[0]
+
+	now := time.Date(2026, 3, 10, 10, 0, 0, 0, property.Location())
+	today := property.Today(now)
+	booking := &bookings.Booking{
+		PropertyID:    property.ID,
+		GuestName:     "Asha",
+		NumGuests:     2,
+		CheckIn:       today.AddDate(0, 0, 1),
+		CheckOut:      today.AddDate(0, 0, 3),
+		TotalAmount:   12000,
+		AdvanceAmount: 2000,
+		Status:        bookings.StatusPartial,
+		BookedBy:      "9123456780",
+	}
+	if err := bookings.NewService(client).CreateBooking(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(client)
+	result, err := service.Run(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RunResult{Properties: 2, Sent: 2}
+	if *result != want {
+		t.Fatalf("Run() = %+v, want %+v", *result, want)
+	}
+
+	for _, kind := range []Kind{KindArrival, KindPaymentDue} {
+		var m marker
+		if !table.Get(t, "BOOKING#"+booking.ID, "REMINDER#"+string(kind), &m) {
+			t.Errorf("no %s reminder marker stored", kind)
+		}
+	}
+
+	// The owner and the booking agent are each notified of both reminders
+	recipients := map[string]int{}
+	for _, item := range table.Items("OUTBOX#") {
+		var event outbox.Event
+		if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != notifications.EventNotification {
+			continue
+		}
+		var payload notifications.NotificationEvent
+		if err := event.DecodePayload(&payload); err != nil {
+			t.Fatal(err)
+		}
+		recipients[payload.UserPhone]++
+	}
+	if recipients["9876543210"] != 2 || recipients["9123456780"] != 2 || len(recipients) != 2 {
+		t.Errorf("reminders queued for %v, want 2 each for the owner and agent", recipients)
+	}
+
+	result, err = service.Run(ctx, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = RunResult{Properties: 2, AlreadySent: 2}
+	if *result != want {
+		t.Errorf("second Run() = %+v, want %+v", *result, want)
+	}
+}
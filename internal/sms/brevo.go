@@ -0,0 +1,135 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const brevoSMSEndpoint = "https://api.brevo.com/v3/transactionalSMS/send"
+
+// brevoProvider sends SMS through the Brevo transactional SMS API.
+// Brevo has no DLT fields, so only the rendered content and sender are sent.
+type brevoProvider struct {
+	apiKey     string
+	sender     string
+	httpClient *http.Client
+}
+
+// smsRequest represents the request body for Brevo SMS API.
+type smsRequest struct {
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Content   string `json:"content"`
+	Type      string `json:"type"`
+	Tag       string `json:"tag,omitempty"`
+
+	// Required for non-Latin scripts such as Devanagari
+	UnicodeEnabled bool `json:"unicodeEnabled,omitempty"`
+}
+
+// smsResponse represents the response from Brevo SMS API.
+type smsResponse struct {
+	MessageID int64  `json:"messageId,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// newBrevoProvider creates a Brevo provider from BREVO_API_KEY and BREVO_SMS_SENDER.
+// Returns nil if BREVO_API_KEY is not set.
+func newBrevoProvider() *brevoProvider {
+	apiKey := os.Getenv("BREVO_API_KEY")
+	if apiKey == "" {
+		return nil
+	}
+
+	sender := os.Getenv("BREVO_SMS_SENDER")
+	if sender == "" {
+		sender = "VillaBook" // Default sender name
+	}
+
+	return &brevoProvider{
+		apiKey: apiKey,
+		sender: sender,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider name.
+func (p *brevoProvider) Name() string {
+	return "brevo"
+}
+
+// Send delivers a rendered message through Brevo.
+func (p *brevoProvider) Send(ctx context.Context, msg *Message) error {
+	// Format phone number - remove + prefix if present (Brevo expects without +)
+	formattedPhone := strings.TrimPrefix(msg.Recipient, "+")
+
+	sender := msg.Sender
+	if sender == "" {
+		sender = p.sender
+	}
+
+	log.Printf("Sending %s SMS to phone: %s, sender: %s", msg.Kind, formattedPhone, sender)
+
+	reqBody := smsRequest{
+		Sender:    sender,
+		Recipient: formattedPhone,
+		Content:   msg.Content,
+		Type:      "transactional",
+		Tag:       string(msg.Kind),
+
+		UnicodeEnabled: msg.IsUnicode(),
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", brevoSMSEndpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	log.Printf("Brevo SMS response status: %d, body: %s", resp.StatusCode, string(bodyBytes))
+
+	if resp.StatusCode >= 400 {
+		var errResp smsResponse
+		if err := json.Unmarshal(bodyBytes, &errResp); err == nil && errResp.Message != "" {
+			return fmt.Errorf("SMS API error (%d): %s - %s", resp.StatusCode, errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("SMS API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var successResp smsResponse
+	if err := json.Unmarshal(bodyBytes, &successResp); err == nil {
+		log.Printf("SMS sent successfully to %s, messageId: %d", formattedPhone, successResp.MessageID)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const kaleyraEndpointFormat = "https://api.kaleyra.io/v1/%s/messages"
+
+// kaleyraProvider sends SMS through Kaleyra, which accepts the DLT template
+// and entity IDs alongside the message so operators can match it to the
+// registered template.
+type kaleyraProvider struct {
+	sid        string
+	apiKey     string
+	sender     string
+	httpClient *http.Client
+}
+
+// newKaleyraProvider creates a Kaleyra provider from KALEYRA_SID and KALEYRA_API_KEY.
+// Returns nil if either is not set.
+func newKaleyraProvider() *kaleyraProvider {
+	sid := os.Getenv("KALEYRA_SID")
+	apiKey := os.Getenv("KALEYRA_API_KEY")
+	if sid == "" || apiKey == "" {
+		return nil
+	}
+
+	return &kaleyraProvider{
+		sid:    sid,
+		apiKey: apiKey,
+		sender: os.Getenv("KALEYRA_SENDER"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider name.
+func (p *kaleyraProvider) Name() string {
+	return "kaleyra"
+}
+
+// Send delivers a rendered message through Kaleyra with its DLT metadata.
+func (p *kaleyraProvider) Send(ctx context.Context, msg *Message) error {
+	sender := msg.Sender
+	if sender == "" {
+		sender = p.sender
+	}
+
+	form := url.Values{}
+	form.Set("to", "+"+strings.TrimPrefix(msg.Recipient, "+"))
+	form.Set("sender", sender)
+	form.Set("type", "TXN")
+	form.Set("body", msg.Content)
+	if msg.TemplateID != "" {
+		form.Set("template_id", msg.TemplateID)
+	}
+	if msg.EntityID != "" {
+		form.Set("entity_id", msg.EntityID)
+	}
+
+	endpoint := fmt.Sprintf(kaleyraEndpointFormat, p.sid)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	req.Header.Set("api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	log.Printf("Kaleyra SMS response status: %d, template: %s, body: %s", resp.StatusCode, msg.TemplateID, string(bodyBytes))
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("SMS API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
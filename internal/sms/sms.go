@@ -1,136 +1,100 @@
-// Package sms provides SMS sending functionality using the Brevo API.
+// Package sms provides transactional SMS sending with DLT-compliant templates.
 package sms
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"strings"
-	"time"
-)
-
-const brevoSMSEndpoint = "https://api.brevo.com/v3/transactionalSMS/send"
+	"strconv"
 
-// Client is a Brevo SMS client for sending transactional SMS messages.
-type Client struct {
-	apiKey     string
-	sender     string
-	httpClient *http.Client
-}
+	"github.com/booking-villa-backend/internal/i18n"
+)
 
-// smsRequest represents the request body for Brevo SMS API.
-type smsRequest struct {
-	Sender    string `json:"sender"`
-	Recipient string `json:"recipient"`
-	Content   string `json:"content"`
-	Type      string `json:"type"`
-	Tag       string `json:"tag,omitempty"`
+// Provider delivers rendered messages to an SMS gateway.
+// Providers that support DLT metadata read TemplateID and EntityID from the message.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
 }
 
-// smsResponse represents the response from Brevo SMS API.
-type smsResponse struct {
-	MessageID int64  `json:"messageId,omitempty"`
-	Code      string `json:"code,omitempty"`
-	Message   string `json:"message,omitempty"`
+// Client renders messages from the template registry and sends them through a provider.
+type Client struct {
+	provider Provider
+	registry *Registry
 }
 
-// NewClient creates a new Brevo SMS client.
-// It reads the BREVO_API_KEY and BREVO_SMS_SENDER environment variables.
-// Returns nil if BREVO_API_KEY is not set (SMS sending will be disabled).
+// NewClient creates a new SMS client.
+// SMS_PROVIDER selects the gateway ("brevo" by default, or "kaleyra").
+// Returns nil if the selected provider is not configured (SMS sending will be disabled).
 func NewClient() *Client {
-	apiKey := os.Getenv("BREVO_API_KEY")
-	if apiKey == "" {
-		return nil
+	var provider Provider
+	switch os.Getenv("SMS_PROVIDER") {
+	case "kaleyra":
+		if p := newKaleyraProvider(); p != nil {
+			provider = p
+		}
+	default:
+		if p := newBrevoProvider(); p != nil {
+			provider = p
+		}
 	}
 
-	sender := os.Getenv("BREVO_SMS_SENDER")
-	if sender == "" {
-		sender = "VillaBook" // Default sender name
+	if provider == nil {
+		return nil
 	}
 
 	return &Client{
-		apiKey: apiKey,
-		sender: sender,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		provider: provider,
+		registry: NewRegistryFromEnv(),
 	}
 }
 
-// SendOTP sends an OTP code to the specified phone number.
+// Registry returns the template registry used by the client.
+func (c *Client) Registry() *Registry {
+	return c.registry
+}
+
+// Send renders the English template for kind with vars and sends it to phone.
 // The phone number should include the country code (e.g., "91XXXXXXXXXX" for India).
-func (c *Client) SendOTP(ctx context.Context, phone, code string, expiryMinutes int) error {
+func (c *Client) Send(ctx context.Context, phone string, kind MessageKind, vars map[string]string) error {
+	return c.SendLocalized(ctx, phone, kind, i18n.English, vars)
+}
+
+// SendLocalized renders the template for kind in locale, falling back to
+// English if that translation has no DLT template ID, and sends it to phone.
+func (c *Client) SendLocalized(ctx context.Context, phone string, kind MessageKind, locale i18n.Locale, vars map[string]string) error {
 	if c == nil {
 		return fmt.Errorf("SMS client not initialized")
 	}
 
-	// Format phone number - remove + prefix if present (Brevo expects without +)
-	formattedPhone := strings.TrimPrefix(phone, "+")
-
-	log.Printf("Sending OTP to phone: %s, sender: %s", formattedPhone, c.sender)
-
-	// Format the message content
-	content := fmt.Sprintf("Your verification code is: %s. Valid for %d minutes. Do not share this code with anyone.", code, expiryMinutes)
-
-	reqBody := smsRequest{
-		Sender:    c.sender,
-		Recipient: formattedPhone,
-		Content:   content,
-		Type:      "transactional",
-		Tag:       "otp",
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	log.Printf("Brevo SMS request: %s", string(jsonBody))
-
-	req, err := http.NewRequestWithContext(ctx, "POST", brevoSMSEndpoint, bytes.NewBuffer(jsonBody))
+	msg, err := c.registry.RenderLocalized(kind, locale, vars)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
+	msg.Recipient = phone
 
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("api-key", c.apiKey)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if msg.TemplateID == "" {
+		log.Printf("Warning: no DLT template ID configured for %s SMS", kind)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	log.Printf("Brevo SMS response status: %d, body: %s", resp.StatusCode, string(bodyBytes))
-
-	if resp.StatusCode >= 400 {
-		var errResp smsResponse
-		if err := json.Unmarshal(bodyBytes, &errResp); err == nil && errResp.Message != "" {
-			return fmt.Errorf("SMS API error (%d): %s - %s", resp.StatusCode, errResp.Code, errResp.Message)
-		}
-		return fmt.Errorf("SMS API returned status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var successResp smsResponse
-	if err := json.Unmarshal(bodyBytes, &successResp); err == nil {
-		log.Printf("OTP sent successfully to %s, messageId: %d", formattedPhone, successResp.MessageID)
+	if err := c.provider.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", c.provider.Name(), err)
 	}
 
 	return nil
 }
 
+// SendOTP sends an OTP code to the specified phone number.
+// The phone number should include the country code (e.g., "91XXXXXXXXXX" for India).
+func (c *Client) SendOTP(ctx context.Context, phone, code string, expiryMinutes int) error {
+	return c.Send(ctx, phone, KindOTP, map[string]string{
+		"code":    code,
+		"minutes": strconv.Itoa(expiryMinutes),
+	})
+}
+
 // IsEnabled returns true if the SMS client is properly configured and enabled.
 func (c *Client) IsEnabled() bool {
-	return c != nil && c.apiKey != ""
+	return c != nil && c.provider != nil
 }
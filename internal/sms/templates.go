@@ -0,0 +1,234 @@
+package sms
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/booking-villa-backend/internal/i18n"
+)
+
+// MessageKind identifies a category of transactional SMS.
+type MessageKind string
+
+const (
+	KindOTP          MessageKind = "otp"
+	KindBookingAlert MessageKind = "booking_alert"
+)
+
+// maxVariableLength is the DLT limit, in characters, for a single {#var#} value.
+const maxVariableLength = 30
+
+// Truncate shortens a value to fit a template variable. The limit is in
+// characters, so Devanagari values are cut on rune boundaries.
+func Truncate(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxVariableLength {
+		return value
+	}
+	return string(runes[:maxVariableLength-3]) + "..."
+}
+
+// Template describes a DLT-registered message template.
+// Body placeholders use the {name} syntax and must match Variables exactly.
+type Template struct {
+	Kind       MessageKind
+	Locale     i18n.Locale // English if empty
+	TemplateID string      // DLT content template ID
+	EntityID   string      // DLT principal entity ID
+	Sender     string      // DLT-approved sender header
+	Body       string      // Registered content with {name} placeholders
+	Variables  []string    // Variable names in the order they appear in Body
+}
+
+// Message is a rendered SMS ready to hand to a provider.
+type Message struct {
+	Kind       MessageKind
+	Locale     i18n.Locale
+	Recipient  string
+	Content    string
+	Sender     string
+	TemplateID string
+	EntityID   string
+	Values     []string // Variable values in template order
+}
+
+// IsUnicode reports whether the content needs Unicode encoding, e.g. for Hindi.
+func (m *Message) IsUnicode() bool {
+	for _, r := range m.Content {
+		if r > 127 {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry maps message kinds and locales to their DLT templates.
+type Registry struct {
+	templates map[templateKey]*Template
+}
+
+type templateKey struct {
+	kind   MessageKind
+	locale i18n.Locale
+}
+
+// defaultTemplates holds the registered template bodies. Template IDs come
+// from the environment since they differ between DLT accounts. Each language
+// is a separate DLT registration.
+var defaultTemplates = []Template{
+	{
+		Kind:      KindOTP,
+		Body:      "Your verification code is: {code}. Valid for {minutes} minutes. Do not share this code with anyone.",
+		Variables: []string{"code", "minutes"},
+	},
+	{
+		Kind:      KindOTP,
+		Locale:    i18n.Hindi,
+		Body:      "आपका सत्यापन कोड है: {code}. यह {minutes} मिनट तक मान्य है। यह कोड किसी के साथ साझा न करें।",
+		Variables: []string{"code", "minutes"},
+	},
+	{
+		Kind:      KindOTP,
+		Locale:    i18n.Marathi,
+		Body:      "तुमचा पडताळणी कोड आहे: {code}. तो {minutes} मिनिटे वैध आहे. हा कोड कोणालाही सांगू नका.",
+		Variables: []string{"code", "minutes"},
+	},
+	{
+		Kind:      KindBookingAlert,
+		Body:      "VillaBook: {title} at {property}. Guest {guest}, booking ref {bookingRef}.",
+		Variables: []string{"title", "property", "guest", "bookingRef"},
+	},
+	{
+		Kind:      KindBookingAlert,
+		Locale:    i18n.Hindi,
+		Body:      "VillaBook: {property} में {title}. मेहमान {guest}, बुकिंग संदर्भ {bookingRef}.",
+		Variables: []string{"property", "title", "guest", "bookingRef"},
+	},
+	{
+		Kind:      KindBookingAlert,
+		Locale:    i18n.Marathi,
+		Body:      "VillaBook: {property} येथे {title}. पाहुणे {guest}, बुकिंग संदर्भ {bookingRef}.",
+		Variables: []string{"property", "title", "guest", "bookingRef"},
+	},
+}
+
+// NewRegistryFromEnv builds the template registry.
+// It reads DLT_ENTITY_ID, DLT_SENDER and DLT_TEMPLATE_<KIND> (e.g. DLT_TEMPLATE_OTP)
+// for English, and DLT_TEMPLATE_<KIND>_<LOCALE> (e.g. DLT_TEMPLATE_OTP_HI) for
+// other languages.
+func NewRegistryFromEnv() *Registry {
+	entityID := os.Getenv("DLT_ENTITY_ID")
+	sender := os.Getenv("DLT_SENDER")
+
+	r := &Registry{templates: make(map[templateKey]*Template)}
+	for _, t := range defaultTemplates {
+		tmpl := t
+		tmpl.EntityID = entityID
+		tmpl.Sender = sender
+		tmpl.TemplateID = os.Getenv(templateIDVariable(t.Kind, t.Locale))
+		r.Register(tmpl)
+	}
+	return r
+}
+
+// templateIDVariable returns the environment variable holding a template's DLT ID.
+func templateIDVariable(kind MessageKind, locale i18n.Locale) string {
+	name := "DLT_TEMPLATE_" + strings.ToUpper(string(kind))
+	if locale != "" && locale != i18n.English {
+		name += "_" + strings.ToUpper(string(locale))
+	}
+	return name
+}
+
+// Get returns the English template for a message kind.
+func (r *Registry) Get(kind MessageKind) (*Template, bool) {
+	t, ok := r.templates[templateKey{kind, i18n.English}]
+	return t, ok
+}
+
+// GetLocalized returns the template for a message kind in locale. Content
+// that is not registered with DLT is blocked by operators, so a translation
+// is only used once its template ID is configured; otherwise the English
+// template is returned.
+func (r *Registry) GetLocalized(kind MessageKind, locale i18n.Locale) (*Template, bool) {
+	if t, ok := r.templates[templateKey{kind, locale}]; ok && t.TemplateID != "" {
+		return t, true
+	}
+	return r.Get(kind)
+}
+
+// Register adds or replaces a template.
+func (r *Registry) Register(t Template) {
+	if t.Locale == "" {
+		t.Locale = i18n.English
+	}
+	r.templates[templateKey{t.Kind, t.Locale}] = &t
+}
+
+// Render validates the variables against the template schema and produces
+// the message content. Missing, empty, unknown, or over-long variables are
+// rejected so that content never drifts from the registered template.
+func (r *Registry) Render(kind MessageKind, vars map[string]string) (*Message, error) {
+	return r.RenderLocalized(kind, i18n.English, vars)
+}
+
+// RenderLocalized renders the template for a message kind in locale,
+// falling back to English as GetLocalized does.
+func (r *Registry) RenderLocalized(kind MessageKind, locale i18n.Locale, vars map[string]string) (*Message, error) {
+	tmpl, ok := r.GetLocalized(kind, locale)
+	if !ok {
+		return nil, fmt.Errorf("no SMS template registered for %s", kind)
+	}
+
+	if err := tmpl.Validate(vars); err != nil {
+		return nil, err
+	}
+
+	content := tmpl.Body
+	values := make([]string, len(tmpl.Variables))
+	for i, name := range tmpl.Variables {
+		values[i] = vars[name]
+		content = strings.ReplaceAll(content, "{"+name+"}", vars[name])
+	}
+
+	return &Message{
+		Kind:       kind,
+		Locale:     tmpl.Locale,
+		Content:    content,
+		Sender:     tmpl.Sender,
+		TemplateID: tmpl.TemplateID,
+		EntityID:   tmpl.EntityID,
+		Values:     values,
+	}, nil
+}
+
+// Validate checks that vars matches the template's variable schema.
+func (t *Template) Validate(vars map[string]string) error {
+	declared := make(map[string]bool, len(t.Variables))
+	for _, name := range t.Variables {
+		declared[name] = true
+		value, ok := vars[name]
+		if !ok || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("SMS template %s: variable %q is required", t.Kind, name)
+		}
+		if utf8.RuneCountInString(value) > maxVariableLength {
+			return fmt.Errorf("SMS template %s: variable %q exceeds %d characters", t.Kind, name, maxVariableLength)
+		}
+	}
+
+	var unknown []string
+	for name := range vars {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("SMS template %s: unknown variables %s", t.Kind, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
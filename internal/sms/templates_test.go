@@ -0,0 +1,101 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/booking-villa-backend/internal/i18n"
+)
+
+func TestRenderValidatesVariables(t *testing.T) {
+	r := &Registry{templates: make(map[templateKey]*Template)}
+	r.Register(Template{
+		Kind:       KindBookingAlert,
+		TemplateID: "1107000000000000001",
+		Body:       "VillaBook: {title} at {property}.",
+		Variables:  []string{"title", "property"},
+	})
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{"valid", map[string]string{"title": "New booking", "property": "Hill House"}, ""},
+		{"at the limit", map[string]string{"title": strings.Repeat("a", maxVariableLength), "property": "Hill House"}, ""},
+		{"Devanagari at the limit", map[string]string{"title": strings.Repeat("घ", maxVariableLength), "property": "Hill House"}, ""},
+		{"missing", map[string]string{"title": "New booking"}, `variable "property" is required`},
+		{"empty", map[string]string{"title": "New booking", "property": "  "}, `variable "property" is required`},
+		{"too long", map[string]string{"title": strings.Repeat("a", maxVariableLength+1), "property": "Hill House"}, `variable "title" exceeds 30 characters`},
+		{"unknown", map[string]string{"title": "New booking", "property": "Hill House", "guest": "Asha", "amount": "10"}, "unknown variables amount, guest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := r.Render(KindBookingAlert, tt.vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "VillaBook: " + tt.vars["title"] + " at " + tt.vars["property"] + "."
+			if msg.Content != want {
+				t.Errorf("Content = %q, want %q", msg.Content, want)
+			}
+			if len(msg.Values) != 2 || msg.Values[0] != tt.vars["title"] || msg.Values[1] != tt.vars["property"] {
+				t.Errorf("Values = %q, want them in template order", msg.Values)
+			}
+		})
+	}
+}
+
+func TestRenderUnregisteredKind(t *testing.T) {
+	r := &Registry{templates: make(map[templateKey]*Template)}
+	if _, err := r.RenderLocalized(KindOTP, i18n.Hindi, map[string]string{"code": "123456", "minutes": "5"}); err == nil {
+		t.Error("RenderLocalized() of an unregistered kind succeeded")
+	}
+}
+
+func TestDefaultTemplatesDeclareTheirPlaceholders(t *testing.T) {
+	for _, tmpl := range defaultTemplates {
+		body := tmpl.Body
+		for _, name := range tmpl.Variables {
+			if !strings.Contains(body, "{"+name+"}") {
+				t.Errorf("%s (%s): variable %q is not in the body", tmpl.Kind, tmpl.Locale, name)
+			}
+			body = strings.ReplaceAll(body, "{"+name+"}", "")
+		}
+		if strings.ContainsAny(body, "{}") {
+			t.Errorf("%s (%s): body has undeclared placeholders: %q", tmpl.Kind, tmpl.Locale, tmpl.Body)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name, value string
+		want        string
+	}{
+		{"short", "Hill House", "Hill House"},
+		{"at the limit", strings.Repeat("a", maxVariableLength), strings.Repeat("a", maxVariableLength)},
+		{"too long", strings.Repeat("a", maxVariableLength+1), strings.Repeat("a", maxVariableLength-3) + "..."},
+		{"Devanagari", strings.Repeat("घ", maxVariableLength+5), strings.Repeat("घ", maxVariableLength-3) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.value)
+			if got != tt.want {
+				t.Errorf("Truncate(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > maxVariableLength {
+				t.Errorf("Truncate(%q) is %d characters, over the limit", tt.value, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package statements
+
+import (
+	"embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fontFiles holds the TrueType fonts embedded in PDF statements for text
+// outside Latin-1, such as guest and property names in Hindi or Marathi.
+// Run `make statement-fonts` to download them.
+//
+//go:embed fonts
+var fontFiles embed.FS
+
+// loadFonts parses the embedded fonts once, in file name order. Fonts that
+// can't be parsed are logged and left out.
+var loadFonts = sync.OnceValue(func() []*trueTypeFont {
+	entries, err := fontFiles.ReadDir("fonts")
+	if err != nil {
+		log.Printf("Failed to read statement fonts: %v", err)
+		return nil
+	}
+
+	var fonts []*trueTypeFont
+	for _, entry := range entries {
+		if !strings.HasSuffix(strings.ToLower(entry.Name()), ".ttf") {
+			continue
+		}
+		data, err := fontFiles.ReadFile(path.Join("fonts", entry.Name()))
+		if err != nil {
+			log.Printf("Failed to read statement font %s: %v", entry.Name(), err)
+			continue
+		}
+		font, err := parseTrueType(strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())), data)
+		if err != nil {
+			log.Printf("Failed to parse statement font %s: %v", entry.Name(), err)
+			continue
+		}
+		fonts = append(fonts, font)
+	}
+	if len(fonts) == 0 {
+		log.Printf("No statement fonts embedded; PDF text outside Latin-1 is shown as \"?\"")
+	}
+	return fonts
+})
+
+// trueTypeFont is a TrueType font with what's needed to embed it in a PDF.
+type trueTypeFont struct {
+	name       string
+	data       []byte
+	unitsPerEm int
+	bbox       [4]int // xMin, yMin, xMax, yMax in font units
+	ascent     int
+	descent    int
+	glyphs     map[rune]uint16 // Glyph IDs by character
+	advances   []uint16        // Advance widths by glyph ID
+}
+
+var errBadFont = errors.New("not a TrueType font")
+
+// parseTrueType reads the tables of a TrueType font that a PDF needs: the
+// metrics in head, hhea and hmtx, and the Unicode character map.
+func parseTrueType(name string, data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	tables := map[string][]byte{}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errBadFont
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, errBadFont
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || cmap == nil || tables["glyf"] == nil {
+		return nil, errBadFont
+	}
+
+	font := &trueTypeFont{
+		name:       strings.Map(pdfNameRune, name),
+		data:       data,
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		ascent:     int(int16(binary.BigEndian.Uint16(hhea[4:]))),
+		descent:    int(int16(binary.BigEndian.Uint16(hhea[6:]))),
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	if font.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errBadFont
+	}
+	font.advances = make([]uint16, metrics)
+	for i := range font.advances {
+		font.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+
+	glyphs, err := parseCmap(cmap)
+	if err != nil {
+		return nil, err
+	}
+	font.glyphs = glyphs
+	return font, nil
+}
+
+// parseCmap reads the Unicode character map of a font, preferring the full
+// repertoire (format 12) over the Basic Multilingual Plane (format 4).
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errBadFont
+	}
+	var bmp, full []byte
+	for i := 0; i < int(binary.BigEndian.Uint16(cmap[2:])); i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			return nil, errBadFont
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) {
+			return nil, errBadFont
+		}
+		subtable := cmap[offset:]
+		switch format := binary.BigEndian.Uint16(subtable); {
+		case format == 12 && (platform == 0 || platform == 3 && encoding == 10):
+			full = subtable
+		case format == 4 && (platform == 0 || platform == 3 && encoding == 1):
+			bmp = subtable
+		}
+	}
+
+	glyphs := map[rune]uint16{}
+	switch {
+	case full != nil && len(full) >= 16:
+		groups := int(binary.BigEndian.Uint32(full[12:]))
+		if 16+12*groups > len(full) {
+			return nil, errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			group := full[16+12*i:]
+			first := binary.BigEndian.Uint32(group)
+			last := binary.BigEndian.Uint32(group[4:])
+			glyph := binary.BigEndian.Uint32(group[8:])
+			for c := first; c <= last && c <= 0x10FFFF; c++ {
+				glyphs[rune(c)] = uint16(glyph + c - first)
+			}
+		}
+	case bmp != nil && len(bmp) >= 14:
+		segments := int(binary.BigEndian.Uint16(bmp[6:])) / 2
+		ends, starts := 14, 16+2*segments
+		deltas, ranges := starts+2*segments, starts+4*segments
+		if ranges+2*segments > len(bmp) {
+			return nil, errBadFont
+		}
+		for i := 0; i < segments; i++ {
+			first := int(binary.BigEndian.Uint16(bmp[starts+2*i:]))
+			last := int(binary.BigEndian.Uint16(bmp[ends+2*i:]))
+			delta := int(binary.BigEndian.Uint16(bmp[deltas+2*i:]))
+			rangeOffset := int(binary.BigEndian.Uint16(bmp[ranges+2*i:]))
+			for c := first; c <= last && c != 0xFFFF; c++ {
+				glyph := (c + delta) & 0xFFFF
+				if rangeOffset != 0 {
+					at := ranges + 2*i + rangeOffset + 2*(c-first)
+					if at+2 > len(bmp) {
+						return nil, errBadFont
+					}
+					glyph = int(binary.BigEndian.Uint16(bmp[at:]))
+					if glyph != 0 {
+						glyph = (glyph + delta) & 0xFFFF
+					}
+				}
+				if glyph != 0 {
+					glyphs[rune(c)] = uint16(glyph)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("font has no Unicode character map")
+	}
+	return glyphs, nil
+}
+
+// advance returns the advance width of a glyph in thousandths of the font
+// size, as PDF widths are given.
+func (f *trueTypeFont) advance(glyph uint16) int {
+	width := f.advances[len(f.advances)-1]
+	if int(glyph) < len(f.advances) {
+		width = f.advances[glyph]
+	}
+	return f.scale(int(width))
+}
+
+// scale converts font units to thousandths of the font size.
+func (f *trueTypeFont) scale(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+// pdfObjects returns the objects that embed the font as a composite font
+// with glyph IDs as character codes, numbered from first. Widths and the
+// ToUnicode map, which lets the text be searched and copied, cover the
+// glyphs used.
+func (f *trueTypeFont) pdfObjects(first int, used map[uint16]rune) []string {
+	ids := make([]int, 0, len(used))
+	for glyph := range used {
+		ids = append(ids, int(glyph))
+	}
+	sort.Ints(ids)
+
+	var widths, unicode strings.Builder
+	for i, id := range ids {
+		fmt.Fprintf(&widths, "%d [%d] ", id, f.advance(uint16(id)))
+		// bfchar blocks hold at most 100 entries
+		if i%100 == 0 {
+			if i > 0 {
+				unicode.WriteString("endbfchar\n")
+			}
+			fmt.Fprintf(&unicode, "%d beginbfchar\n", min(100, len(ids)-i))
+		}
+		fmt.Fprintf(&unicode, "<%04X> <%s>\n", id, utf16Hex(used[uint16(id)]))
+	}
+	if len(ids) > 0 {
+		unicode.WriteString("endbfchar\n")
+	}
+
+	cmap := "/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n" +
+		unicode.String() +
+		"endcmap\nCMapName currentdict /CMapResource defineresource pop\nend\nend\n"
+
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			f.name, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>",
+			f.name, first+2, strings.TrimSpace(widths.String())),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			f.name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+			f.scale(f.ascent), f.scale(f.descent), f.scale(f.ascent), first+3),
+		pdfStream(fmt.Sprintf("/Length1 %d", len(f.data)), f.data),
+		pdfStream("", []byte(cmap)),
+	}
+}
+
+// utf16Hex returns a character as hex UTF-16, as ToUnicode maps give it.
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+// pdfNameRune keeps the characters that can appear in a PDF name unescaped.
+func pdfNameRune(r rune) rune {
+	if r > ' ' && r < 0x7F && !strings.ContainsRune("()<>[]{}/%#", r) {
+		return r
+	}
+	return -1
+}
@@ -0,0 +1,226 @@
+package statements
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+	"github.com/booking-villa-backend/internal/users"
+	"github.com/booking-villa-backend/internal/utils"
+)
+
+// Handler provides HTTP handlers for statement endpoints.
+type Handler struct {
+	service      *Service
+	auditService *audit.Service
+}
+
+// NewHandler creates a new statement handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service:      NewService(dbClient),
+		auditService: audit.NewService(dbClient),
+	}
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// statementPath splits /owners/me/statements/{yyyy-mm}[/versions] into the
+// month and whether the versions are requested.
+func statementPath(path string) (month string, versions bool) {
+	month = strings.TrimPrefix(path, "/owners/me/statements/")
+	if strings.HasSuffix(month, "/versions") {
+		return strings.TrimSuffix(month, "/versions"), true
+	}
+	return month, false
+}
+
+// statementOwner returns whose statement is requested. Admins can get any
+// owner's statement; owners always get their own.
+func statementOwner(claims *utils.TokenClaims, params map[string]string) string {
+	if claims.Role == string(users.RoleAdmin) && params["owner"] != "" {
+		return params["owner"]
+	}
+	return claims.Phone
+}
+
+// generateError converts an error from generating a statement to a response.
+func generateError(err error) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, ErrFutureMonth):
+		return ErrorResponse(http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrOwnerNotFound):
+		return ErrorResponse(http.StatusNotFound, err.Error())
+	default:
+		return ErrorResponse(http.StatusInternalServerError, "Failed to generate statement: "+err.Error())
+	}
+}
+
+// HandleRegenerateStatement handles POST /owners/me/statements/{yyyy-mm}
+// endpoint. It works the statement out again from the current bookings and
+// stores it as a new version if anything changed.
+func (h *Handler) HandleRegenerateStatement(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, "Statements are not available to property-restricted API keys"), nil
+	}
+	owner := statementOwner(claims, request.QueryStringParameters)
+
+	monthParam, versions := statementPath(request.Path)
+	if versions {
+		return ErrorResponse(http.StatusNotFound, "Owner endpoint not found"), nil
+	}
+	month, err := ParseMonth(monthParam)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid month. Use YYYY-MM"), nil
+	}
+
+	statement, err := h.service.RegenerateStatement(ctx, owner, month, claims.Phone)
+	if err != nil {
+		return generateError(err), nil
+	}
+	return APIResponse(http.StatusOK, statement), nil
+}
+
+// HandleGetStatement handles GET /owners/me/statements/{yyyy-mm} endpoint.
+func (h *Handler) HandleGetStatement(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+	if len(claims.PropertyIDs) > 0 {
+		return ErrorResponse(http.StatusForbidden, "Statements are not available to property-restricted API keys"), nil
+	}
+	params := request.QueryStringParameters
+
+	owner := statementOwner(claims, params)
+
+	monthParam, versions := statementPath(request.Path)
+	month, err := ParseMonth(monthParam)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid month. Use YYYY-MM"), nil
+	}
+
+	if versions {
+		list, err := h.service.ListVersions(ctx, owner, month)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, "Failed to list statements: "+err.Error()), nil
+		}
+		return APIResponse(http.StatusOK, map[string]interface{}{
+			"statements": list,
+			"count":      len(list),
+		}), nil
+	}
+
+	format := Format(params["format"])
+	if format == "" {
+		format = FormatJSON
+	}
+	if !format.IsValid() {
+		return ErrorResponse(http.StatusBadRequest, "format must be json, html, csv or pdf"), nil
+	}
+
+	var statement *Statement
+	if params["version"] != "" {
+		version, err := strconv.Atoi(params["version"])
+		if err != nil || version < 1 {
+			return ErrorResponse(http.StatusBadRequest, "version must be a positive number"), nil
+		}
+		statement, err = h.service.GetStatement(ctx, owner, month, version)
+		if err != nil {
+			if errors.Is(err, ErrStatementNotFound) {
+				return ErrorResponse(http.StatusNotFound, err.Error()), nil
+			}
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), nil
+		}
+	} else {
+		// Read-only sessions see a statement that has not been stored yet,
+		// but don't store it
+		statement, err = h.service.GetLatestStatement(ctx, owner, month, claims.Phone, !claims.ReadOnly)
+		if err != nil {
+			return generateError(err), nil
+		}
+	}
+
+	if format == FormatJSON {
+		return APIResponse(http.StatusOK, statement), nil
+	}
+
+	var body []byte
+	switch format {
+	case FormatHTML:
+		body, err = statement.HTML()
+	case FormatCSV:
+		body, err = statement.CSV()
+	case FormatPDF:
+		body, err = statement.PDF()
+	}
+
+	filename := statement.Filename(format)
+	event := audit.NewEvent(audit.EventDataExported, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(claims.Phone, claims.Role).
+		WithTarget(filename).
+		WithDetail("format", string(format)).
+		WithDetail("owner", owner)
+	if claims.IsAPIKey() {
+		event.WithDetail("apiKeyId", claims.APIKeyID)
+	}
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to render statement: "+err.Error()), nil
+	}
+
+	disposition := "attachment"
+	if format == FormatHTML {
+		disposition = "inline"
+	}
+	response := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":                 format.ContentType(),
+			"Content-Disposition":          fmt.Sprintf("%s; filename=\"%s\"", disposition, filename),
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(body),
+	}
+	if format == FormatPDF {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
+	}
+	return response, nil
+}
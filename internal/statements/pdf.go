@@ -0,0 +1,222 @@
+package statements
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout of PDF statements: landscape A4 in points, set in 8pt Courier
+// so the fixed-width text lines up.
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 40
+	pdfFontSize   = 8
+	pdfLeading    = 11
+)
+
+// courierWidth is the advance of every Courier character, in thousandths of
+// the font size.
+const courierWidth = 600
+
+// writePDF writes lines of text as a PDF document, starting a new page when
+// one is full. Latin-1 text is set in the standard Courier font. Other
+// characters use the first embedded font that has them, or are replaced with
+// "?" if none does.
+func writePDF(w io.Writer, lines []string) error {
+	perPage := (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	var pages [][]string
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	pages = append(pages, lines)
+
+	fonts := loadFonts()
+	used := make([]map[uint16]rune, len(fonts))
+	contents := make([]string, len(pages))
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT %d TL %d %d Td\n", pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			content.WriteString("T*")
+			for _, run := range textRuns(line, fonts) {
+				if run.font < 0 {
+					fmt.Fprintf(&content, " /F1 %d Tf (%s) Tj", pdfFontSize, pdfText(string(run.text)))
+					continue
+				}
+				if used[run.font] == nil {
+					used[run.font] = map[uint16]rune{}
+				}
+				content.WriteString(fontRun(fonts[run.font], run.font, run.text, used[run.font]))
+			}
+			content.WriteString("\n")
+		}
+		fmt.Fprintf(&content, "ET\n")
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (Page %d of %d) Tj ET\n", pdfFontSize, pdfPageWidth-pdfMargin-72, pdfMargin/2, i+1, len(pages))
+		contents[i] = content.String()
+	}
+
+	// Objects 1-3 are the catalog, page tree and Courier font; each page then
+	// takes two objects, the page and its content stream. The embedded fonts
+	// that are used come last, five objects each
+	var resources strings.Builder
+	resources.WriteString("/F1 3 0 R")
+	var fontObjects []string
+	for i, font := range fonts {
+		if used[i] == nil {
+			continue
+		}
+		first := 4 + 2*len(pages) + len(fontObjects)
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i+2, first)
+		fontObjects = append(fontObjects, font.pdfObjects(first, used[i])...)
+	}
+
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+	for i, content := range contents {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, resources.String(), 5+2*i),
+			pdfStream("", []byte(content)),
+		)
+	}
+	objects = append(objects, fontObjects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfStream returns a stream object holding data, compressed. extra is added
+// to the stream dictionary.
+func pdfStream(extra string, data []byte) string {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode %s>>\nstream\n%s\nendstream", compressed.Len(), extra, compressed.String())
+}
+
+// textRun is a run of text set in one font: an index into the embedded fonts,
+// or -1 for Courier.
+type textRun struct {
+	font int
+	text []rune
+}
+
+// textRuns splits a line into runs by the font each character is set in.
+func textRuns(line string, fonts []*trueTypeFont) []textRun {
+	var runs []textRun
+	for _, r := range devanagariOrder([]rune(line)) {
+		font := -1
+		if r >= 0x100 {
+			for i, f := range fonts {
+				if _, ok := f.glyphs[r]; ok {
+					font = i
+					break
+				}
+			}
+		}
+		if len(runs) > 0 && runs[len(runs)-1].font == font {
+			runs[len(runs)-1].text = append(runs[len(runs)-1].text, r)
+			continue
+		}
+		runs = append(runs, textRun{font: font, text: []rune{r}})
+	}
+	return runs
+}
+
+// fontRun sets a run of text in an embedded font, recording the glyphs used.
+// The run is spaced to take as much room as the same number of Courier
+// characters, so columns padded by character count stay aligned.
+func fontRun(font *trueTypeFont, index int, text []rune, used map[uint16]rune) string {
+	var glyphs strings.Builder
+	width := 0
+	for _, r := range text {
+		glyph := font.glyphs[r]
+		used[glyph] = r
+		width += font.advance(glyph)
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+	return fmt.Sprintf(" /F%d %d Tf [<%s> %d] TJ", index+2, pdfFontSize, glyphs.String(), width-courierWidth*len(text))
+}
+
+// devanagariOrder moves each short i vowel sign (ि) in front of the consonant
+// cluster it follows, where it is written. Fonts are otherwise used without
+// shaping, so conjuncts are shown with a visible virama.
+func devanagariOrder(text []rune) []rune {
+	const (
+		vowelSignI = 0x093F
+		nukta      = 0x093C
+		virama     = 0x094D
+	)
+	isConsonant := func(r rune) bool {
+		return r >= 0x0915 && r <= 0x0939 || r >= 0x0958 && r <= 0x095F || r >= 0x0978 && r <= 0x097F
+	}
+
+	for i, r := range text {
+		if r != vowelSignI {
+			continue
+		}
+		start := i - 1
+		if start >= 0 && text[start] == nukta {
+			start--
+		}
+		if start < 0 || !isConsonant(text[start]) {
+			continue
+		}
+		for start >= 2 && text[start-1] == virama && isConsonant(text[start-2]) {
+			start -= 2
+		}
+		copy(text[start+1:i+1], text[start:i])
+		text[start] = vowelSignI
+	}
+	return text
+}
+
+// pdfText escapes a line for a PDF string, in WinAnsi encoding.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package statements
+
+import "testing"
+
+func TestDevanagariOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"latin", "Villa", "Villa"},
+		{"no short i", "राम", "राम"},
+		{"short i", "हिंदी", "िहंदी"},
+		{"after conjunct", "स्थिति", "िस्थित"},
+		{"after nukta", "ज़िला", "िज़ला"},
+		{"without consonant", "ि", "ि"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(devanagariOrder([]rune(tt.text))); got != tt.want {
+				t.Errorf("devanagariOrder(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the rendering of a statement.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatHTML Format = "html"
+	FormatCSV  Format = "csv"
+	FormatPDF  Format = "pdf"
+)
+
+// IsValid checks if the format is supported.
+func (f Format) IsValid() bool {
+	switch f {
+	case FormatJSON, FormatHTML, FormatCSV, FormatPDF:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatCSV:
+		return "text/csv"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "application/json"
+	}
+}
+
+// Filename returns the name a statement is downloaded as.
+func (s *Statement) Filename(format Format) string {
+	return fmt.Sprintf("statement_%s_v%d.%s", s.Month, s.Version, format)
+}
+
+// summaryLines are the statement totals, in the order they are shown.
+func (s *Statement) summaryLines() []struct {
+	Label  string
+	Amount float64
+} {
+	t := s.Totals
+	return []struct {
+		Label  string
+		Amount float64
+	}{
+		{"Opening balance", s.OpeningBalance},
+		{"Gross revenue", t.GrossRevenue},
+		{"Less discounts", -t.Discounts},
+		{"Net revenue", t.NetRevenue},
+		{fmt.Sprintf("Less taxes (%s%%)", formatNumber(s.TaxRate)), -t.Taxes},
+		{"Less agent commissions", -t.Commissions},
+		{"Less expenses", -t.Expenses},
+		{"Net income", t.NetIncome},
+		{"Amount due", s.AmountDue},
+		{"Net payout", s.Payout},
+		{"Closing balance", s.ClosingBalance},
+	}
+}
+
+// CSV renders the statement as CSV: the summary, then a row per property and
+// per stay.
+func (s *Statement) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		{"Statement", s.Month, "Version", strconv.Itoa(s.Version)},
+		{"Owner", s.OwnerName, s.OwnerPhone},
+		{"Currency", s.Currency},
+		{},
+		{"Summary", "Amount"},
+	}
+	for _, line := range s.summaryLines() {
+		rows = append(rows, []string{line.Label, formatAmount(line.Amount)})
+	}
+
+	rows = append(rows, []string{},
+		[]string{"Property ID", "Property", "Stays", "Nights", "Gross Revenue", "Discounts", "Net Revenue", "Taxes", "Commissions", "Expenses", "Net Income"})
+	for _, p := range s.Properties {
+		rows = append(rows, []string{
+			p.PropertyID, p.PropertyName, strconv.Itoa(p.Stays), strconv.Itoa(p.Nights),
+			formatAmount(p.GrossRevenue), formatAmount(p.Discounts), formatAmount(p.NetRevenue),
+			formatAmount(p.Taxes), formatAmount(p.Commissions), formatAmount(p.Expenses), formatAmount(p.NetIncome),
+		})
+	}
+
+	rows = append(rows, []string{},
+		[]string{"Booking ID", "Property", "Guest", "Check-in", "Check-out", "Nights", "Agent", "Gross", "Discount", "Net", "Tax", "Commission"})
+	for _, stay := range s.Stays {
+		rows = append(rows, []string{
+			stay.BookingID, stay.PropertyName, stay.GuestName,
+			stay.CheckIn.Format("2006-01-02"), stay.CheckOut.Format("2006-01-02"), strconv.Itoa(stay.Nights), stay.AgentName,
+			formatAmount(stay.GrossAmount), formatAmount(stay.Discount), formatAmount(stay.NetAmount),
+			formatAmount(stay.Tax), formatAmount(stay.Commission),
+		})
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"amount": formatAmount,
+	"date":   func(t time.Time) string { return t.Format("02 Jan 2006") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Statement {{.Month}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 13px; color: #222; margin: 32px; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+h2 { font-size: 15px; margin-top: 28px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 4px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+tr.total td { font-weight: bold; }
+.meta { color: #666; }
+</style>
+</head>
+<body>
+<h1>Owner statement for {{.Period}}</h1>
+<p class="meta">{{with .OwnerName}}{{.}}, {{end}}{{.OwnerPhone}}<br>
+Version {{.Version}}, generated {{date .GeneratedAt}}. Amounts in {{.Currency}}.</p>
+
+<h2>Summary</h2>
+<table>
+{{range .Summary}}<tr><td>{{.Label}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}</table>
+
+<h2>Properties</h2>
+<table>
+<tr><th>Property</th><th class="num">Stays</th><th class="num">Nights</th><th class="num">Gross</th><th class="num">Discounts</th><th class="num">Taxes</th><th class="num">Commissions</th><th class="num">Expenses</th><th class="num">Net income</th></tr>
+{{range .Properties}}<tr><td>{{.PropertyName}}</td><td class="num">{{.Stays}}</td><td class="num">{{.Nights}}</td><td class="num">{{amount .GrossRevenue}}</td><td class="num">{{amount .Discounts}}</td><td class="num">{{amount .Taxes}}</td><td class="num">{{amount .Commissions}}</td><td class="num">{{amount .Expenses}}</td><td class="num">{{amount .NetIncome}}</td></tr>
+{{end}}{{with .Totals}}<tr class="total"><td>Total</td><td class="num">{{.Stays}}</td><td class="num">{{.Nights}}</td><td class="num">{{amount .GrossRevenue}}</td><td class="num">{{amount .Discounts}}</td><td class="num">{{amount .Taxes}}</td><td class="num">{{amount .Commissions}}</td><td class="num">{{amount .Expenses}}</td><td class="num">{{amount .NetIncome}}</td></tr>{{end}}
+</table>
+
+<h2>Stays</h2>
+{{if .Stays}}<table>
+<tr><th>Property</th><th>Guest</th><th>Check-in</th><th>Check-out</th><th class="num">Nights</th><th>Agent</th><th class="num">Gross</th><th class="num">Discount</th><th class="num">Tax</th><th class="num">Commission</th></tr>
+{{range .Stays}}<tr><td>{{.PropertyName}}</td><td>{{.GuestName}}</td><td>{{date .CheckIn}}</td><td>{{date .CheckOut}}</td><td class="num">{{.Nights}}</td><td>{{.AgentName}}</td><td class="num">{{amount .GrossAmount}}</td><td class="num">{{amount .Discount}}</td><td class="num">{{amount .Tax}}</td><td class="num">{{amount .Commission}}</td></tr>
+{{end}}</table>{{else}}<p>No stays this month.</p>{{end}}
+</body>
+</html>
+`))
+
+// HTML renders the statement as a standalone HTML page.
+func (s *Statement) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, struct {
+		*Statement
+		Period  string
+		Summary interface{}
+	}{s, s.PeriodStart.Format("January 2006"), s.summaryLines()})
+	return buf.Bytes(), err
+}
+
+// PDF renders the statement as a PDF document.
+func (s *Statement) PDF() ([]byte, error) {
+	var buf bytes.Buffer
+	err := writePDF(&buf, s.textLines())
+	return buf.Bytes(), err
+}
+
+// textLines lays the statement out as fixed-width text for the PDF.
+func (s *Statement) textLines() []string {
+	lines := []string{
+		"OWNER STATEMENT - " + strings.ToUpper(s.PeriodStart.Format("January 2006")),
+		"",
+		strings.TrimPrefix(s.OwnerName+", "+s.OwnerPhone, ", "),
+		fmt.Sprintf("Version %d, generated %s. Amounts in %s.", s.Version, s.GeneratedAt.Format("02 Jan 2006"), s.Currency),
+		"",
+		"SUMMARY",
+	}
+	for _, line := range s.summaryLines() {
+		lines = append(lines, fmt.Sprintf("  %-40s %16s", line.Label, formatAmount(line.Amount)))
+	}
+
+	lines = append(lines, "", "PROPERTIES",
+		fmt.Sprintf("  %-24s %5s %6s %12s %10s %10s %10s %10s %12s", "Property", "Stays", "Nights", "Gross", "Discounts", "Taxes", "Commission", "Expenses", "Net income"))
+	for _, p := range s.Properties {
+		lines = append(lines, fmt.Sprintf("  %-24s %5d %6d %12s %10s %10s %10s %10s %12s",
+			truncate(p.PropertyName, 24), p.Stays, p.Nights, formatAmount(p.GrossRevenue), formatAmount(p.Discounts),
+			formatAmount(p.Taxes), formatAmount(p.Commissions), formatAmount(p.Expenses), formatAmount(p.NetIncome)))
+	}
+
+	lines = append(lines, "", "STAYS")
+	if len(s.Stays) == 0 {
+		lines = append(lines, "  No stays this month.")
+	} else {
+		lines = append(lines, fmt.Sprintf("  %-18s %-18s %-6s %-6s %3s %12s %10s %10s %10s", "Property", "Guest", "In", "Out", "Nts", "Gross", "Discount", "Tax", "Commission"))
+	}
+	for _, stay := range s.Stays {
+		lines = append(lines, fmt.Sprintf("  %-18s %-18s %-6s %-6s %3d %12s %10s %10s %10s",
+			truncate(stay.PropertyName, 18), truncate(stay.GuestName, 18), stay.CheckIn.Format("02 Jan"), stay.CheckOut.Format("02 Jan"),
+			stay.Nights, formatAmount(stay.GrossAmount), formatAmount(stay.Discount), formatAmount(stay.Tax), formatAmount(stay.Commission)))
+	}
+	return lines
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "~"
+}
+
+func formatAmount(v float64) string {
+	if v == 0 {
+		v = 0 // No negative zero
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
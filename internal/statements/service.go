@@ -0,0 +1,375 @@
+// Package statements produces owners' monthly statements, stored as
+// numbered versions and rendered as JSON, HTML, CSV or PDF.
+package statements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/booking-villa-backend/internal/accounting"
+	"github.com/booking-villa-backend/internal/analytics"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/booking-villa-backend/internal/users"
+)
+
+var (
+	// ErrStatementNotFound is returned when a statement version does not exist.
+	ErrStatementNotFound = errors.New("statement not found")
+
+	// ErrFutureMonth is returned for months that have not started.
+	ErrFutureMonth = errors.New("statements are not available for future months")
+
+	// ErrOwnerNotFound is returned when the owner's account does not exist.
+	ErrOwnerNotFound = errors.New("owner not found")
+)
+
+// Statement is a stored version of an owner's monthly statement.
+//
+// The balance is what is owed to the owner. Each month's net income is added
+// to the balance carried over from the previous month's statement, and a
+// positive amount due is paid out in full. A negative amount, when costs
+// exceed income, is carried forward and deducted from the next payout.
+type Statement struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK" json:"-"` // STATEMENT#<ownerPhone>
+	SK string `dynamodbav:"SK" json:"-"` // MONTH#<yyyy-mm>#V<version>
+
+	Month   string `dynamodbav:"month" json:"month"` // yyyy-mm
+	Version int    `dynamodbav:"version" json:"version"`
+
+	analytics.OwnerStatement
+
+	OpeningBalance float64 `dynamodbav:"openingBalance" json:"openingBalance"`
+	AmountDue      float64 `dynamodbav:"amountDue" json:"amountDue"`
+	Payout         float64 `dynamodbav:"payout" json:"payout"`
+	ClosingBalance float64 `dynamodbav:"closingBalance" json:"closingBalance"`
+
+	GeneratedAt time.Time `dynamodbav:"generatedAt" json:"generatedAt"`
+	GeneratedBy string    `dynamodbav:"generatedBy" json:"generatedBy"`
+	EntityType  string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Service provides statement operations.
+type Service struct {
+	db                *db.Client
+	analyticsService  *analytics.Service
+	accountingService *accounting.Service
+	userService       *users.Service
+	propertyService   *properties.Service
+}
+
+// NewService creates a new statement service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:                dbClient,
+		analyticsService:  analytics.NewService(dbClient),
+		accountingService: accounting.NewService(dbClient),
+		userService:       users.NewService(dbClient),
+		propertyService:   properties.NewService(dbClient),
+	}
+}
+
+// ParseMonth parses a yyyy-mm month into the first day of the month.
+func ParseMonth(value string) (time.Time, error) {
+	return time.Parse("2006-01", value)
+}
+
+// GetStatement returns a stored version of an owner's statement for a month.
+func (s *Service) GetStatement(ctx context.Context, ownerPhone string, month time.Time, version int) (*Statement, error) {
+	var statement Statement
+	if err := s.db.GetItem(ctx, "STATEMENT#"+ownerPhone, versionKey(month, version), &statement); err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrStatementNotFound
+		}
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// GetLatestStatement returns the latest stored version of an owner's
+// statement for a month. If none is stored yet the statement is generated and
+// stored, unless save is false, as in read-only sessions, when it is returned
+// unstored as version 0.
+func (s *Service) GetLatestStatement(ctx context.Context, ownerPhone string, month time.Time, generatedBy string, save bool) (*Statement, error) {
+	latest, err := s.latestVersion(ctx, ownerPhone, monthStart(month))
+	if err != nil || latest != nil {
+		return latest, err
+	}
+	return s.generate(ctx, ownerPhone, month, generatedBy, save)
+}
+
+// RegenerateStatement works out an owner's statement for a month from the
+// current bookings, storing it as a new version if it differs from the latest
+// one. Later months that have statements open with this month's closing
+// balance, so they are regenerated in turn until one's balance is unchanged.
+func (s *Service) RegenerateStatement(ctx context.Context, ownerPhone string, month time.Time, generatedBy string) (*Statement, error) {
+	statement, err := s.generate(ctx, ownerPhone, month, generatedBy, true)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := statement.ClosingBalance
+	for later := monthStart(month).AddDate(0, 1, 0); ; later = later.AddDate(0, 1, 0) {
+		stored, err := s.latestVersion(ctx, ownerPhone, later)
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil || stored.OpeningBalance == balance {
+			break
+		}
+		updated, err := s.build(ctx, ownerPhone, later, balance, generatedBy)
+		if err != nil {
+			return nil, err
+		}
+		if updated, err = s.store(ctx, updated); err != nil {
+			return nil, err
+		}
+		balance = updated.ClosingBalance
+	}
+	return statement, nil
+}
+
+// generate works out an owner's statement for a month, storing it if save is
+// set.
+func (s *Service) generate(ctx context.Context, ownerPhone string, month time.Time, generatedBy string, save bool) (*Statement, error) {
+	start := monthStart(month)
+	locations, err := s.propertyLocations(ctx, ownerPhone)
+	if err != nil {
+		return nil, err
+	}
+	// A month is available once it has begun at any of the owner's properties
+	if start.After(monthStart(localDate(time.Now(), locations, false))) {
+		return nil, ErrFutureMonth
+	}
+
+	opening, err := s.openingBalance(ctx, ownerPhone, start, locations, generatedBy, save)
+	if err != nil {
+		return nil, err
+	}
+	statement, err := s.build(ctx, ownerPhone, start, opening, generatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if !save {
+		return statement, nil
+	}
+	return s.store(ctx, statement)
+}
+
+// openingBalance returns the closing balance of the latest statement for the
+// month before start. Statements missing for earlier months are generated
+// first, working forward from the last stored one or from the month the owner
+// joined, so a balance is never dropped.
+func (s *Service) openingBalance(ctx context.Context, ownerPhone string, start time.Time, locations []*time.Location, generatedBy string, save bool) (float64, error) {
+	owner, err := s.userService.GetUserByPhone(ctx, ownerPhone)
+	if err != nil {
+		return 0, err
+	}
+	if owner == nil {
+		return 0, ErrOwnerNotFound
+	}
+	joined := monthStart(localDate(owner.CreatedAt, locations, true))
+
+	var balance float64
+	var missing []time.Time
+	for month := start.AddDate(0, -1, 0); !month.Before(joined); month = month.AddDate(0, -1, 0) {
+		previous, err := s.latestVersion(ctx, ownerPhone, month)
+		if err != nil {
+			return 0, err
+		}
+		if previous != nil {
+			balance = previous.ClosingBalance
+			break
+		}
+		missing = append(missing, month)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		statement, err := s.build(ctx, ownerPhone, missing[i], balance, generatedBy)
+		if err != nil {
+			return 0, err
+		}
+		if save {
+			if statement, err = s.store(ctx, statement); err != nil {
+				return 0, err
+			}
+		}
+		balance = statement.ClosingBalance
+	}
+	return balance, nil
+}
+
+// build works out an owner's statement for the month starting at start from
+// the current bookings, opening with the given balance.
+func (s *Service) build(ctx context.Context, ownerPhone string, start time.Time, opening float64, generatedBy string) (*Statement, error) {
+	settings, err := s.accountingService.GetSettings(ctx, ownerPhone)
+	if err != nil {
+		return nil, err
+	}
+	figures, err := s.analyticsService.GetOwnerStatement(ctx, ownerPhone, start, start.AddDate(0, 1, 0), settings.TaxRate)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{
+		PK:             "STATEMENT#" + ownerPhone,
+		Month:          start.Format("2006-01"),
+		OwnerStatement: *figures,
+		OpeningBalance: opening,
+		GeneratedAt:    time.Now(),
+		GeneratedBy:    generatedBy,
+		EntityType:     "STATEMENT",
+	}
+	statement.AmountDue = round2(statement.OpeningBalance + statement.Totals.NetIncome)
+	statement.Payout = math.Max(0, statement.AmountDue)
+	statement.ClosingBalance = round2(statement.AmountDue - statement.Payout)
+	return statement, nil
+}
+
+// store saves a statement as the next version of its month. If it matches
+// the latest stored version that version is returned instead.
+func (s *Service) store(ctx context.Context, statement *Statement) (*Statement, error) {
+	start, err := ParseMonth(statement.Month)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.latestVersion(ctx, statement.OwnerPhone, start)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		if sameFigures(latest, statement) {
+			return latest, nil
+		}
+		statement.Version = latest.Version
+	}
+	statement.Version++
+	statement.SK = versionKey(start, statement.Version)
+
+	if err := s.db.PutItemWithCondition(ctx, statement, "attribute_not_exists(PK)"); err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			// Generated at the same time by another request
+			return s.latestVersion(ctx, statement.OwnerPhone, start)
+		}
+		return nil, fmt.Errorf("failed to save statement: %w", err)
+	}
+	return statement, nil
+}
+
+// ListVersions returns the stored versions of an owner's statement for a
+// month, newest first.
+func (s *Service) ListVersions(ctx context.Context, ownerPhone string, month time.Time) ([]*Statement, error) {
+	return s.queryVersions(ctx, ownerPhone, month, 0)
+}
+
+// latestVersion returns the latest stored version of a statement, or nil if
+// there is none.
+func (s *Service) latestVersion(ctx context.Context, ownerPhone string, month time.Time) (*Statement, error) {
+	statements, err := s.queryVersions(ctx, ownerPhone, month, 1)
+	if err != nil || len(statements) == 0 {
+		return nil, err
+	}
+	return statements[0], nil
+}
+
+func (s *Service) queryVersions(ctx context.Context, ownerPhone string, month time.Time, limit int32) ([]*Statement, error) {
+	forward := false
+	params := db.QueryParams{
+		KeyCondition: "PK = :pk AND begins_with(SK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":pk":     "STATEMENT#" + ownerPhone,
+			":prefix": "MONTH#" + month.Format("2006-01") + "#",
+		},
+		Limit:            limit,
+		ScanIndexForward: &forward,
+	}
+
+	var items []map[string]types.AttributeValue
+	var err error
+	if limit > 0 {
+		items, err = s.db.Query(ctx, params)
+	} else {
+		items, err = s.db.QueryAll(ctx, params)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statements: %w", err)
+	}
+
+	statements := make([]*Statement, 0, len(items))
+	for _, item := range items {
+		var statement Statement
+		if err := attributevalue.UnmarshalMap(item, &statement); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal statement: %w", err)
+		}
+		statements = append(statements, &statement)
+	}
+	return statements, nil
+}
+
+// propertyLocations returns the time zones of an owner's properties, or the
+// default time zone if they have none.
+func (s *Service) propertyLocations(ctx context.Context, ownerPhone string) ([]*time.Location, error) {
+	props, err := s.propertyService.ListPropertiesByOwner(ctx, ownerPhone)
+	if err != nil {
+		return nil, err
+	}
+	if len(props) == 0 {
+		props = []*properties.Property{{}}
+	}
+	locations := make([]*time.Location, len(props))
+	for i, prop := range props {
+		locations[i] = prop.Location()
+	}
+	return locations, nil
+}
+
+// localDate returns the calendar date of t in the given time zones, as UTC
+// midnight: the earliest of them if earliest is set, otherwise the latest.
+func localDate(t time.Time, locations []*time.Location, earliest bool) time.Time {
+	var date time.Time
+	for i, location := range locations {
+		local := t.In(location)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if i == 0 || day.Before(date) == earliest && !day.Equal(date) {
+			date = day
+		}
+	}
+	return date
+}
+
+// monthStart returns the first day of a month, as UTC midnight.
+func monthStart(month time.Time) time.Time {
+	return time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// versionKey returns the sort key of a statement version. Versions are zero
+// padded so they sort in order.
+func versionKey(month time.Time, version int) string {
+	return fmt.Sprintf("MONTH#%s#V%04d", month.Format("2006-01"), version)
+}
+
+// sameFigures reports whether two statements have the same contents, apart
+// from when and by whom they were generated.
+func sameFigures(a, b *Statement) bool {
+	left, errA := json.Marshal(contents(a))
+	right, errB := json.Marshal(contents(b))
+	return errA == nil && errB == nil && string(left) == string(right)
+}
+
+func contents(statement *Statement) interface{} {
+	return struct {
+		analytics.OwnerStatement
+		OpeningBalance float64
+	}{statement.OwnerStatement, statement.OpeningBalance}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
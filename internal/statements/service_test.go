@@ -0,0 +1,24 @@
+package statements
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalDate(t *testing.T) {
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+	newYork, _ := time.LoadLocation("America/New_York")
+	locations := []*time.Location{kolkata, newYork}
+
+	// 31 March 20:00 UTC is 1 April in Kolkata and still 31 March in New York
+	now := time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC)
+	if got, want := localDate(now, locations, false), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("latest = %v, want %v", got, want)
+	}
+	if got, want := localDate(now, locations, true), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("earliest = %v, want %v", got, want)
+	}
+	if got, want := localDate(now, []*time.Location{newYork, kolkata}, false), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("latest, other order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Package storage stores generated files in S3 for download.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// partSize is the size of each uploaded part. S3 requires every part but
+// the last to be at least 5 MiB.
+const partSize = 8 << 20
+
+// ErrClosed is returned when writing to an upload that has been closed.
+var ErrClosed = errors.New("upload is closed")
+
+// errAborted stops an upload that is being aborted.
+var errAborted = errors.New("upload aborted")
+
+// BucketFromEnv returns the bucket named by EXPORT_BUCKET, or nil if it is
+// not set.
+func BucketFromEnv(ctx context.Context) (*Bucket, error) {
+	name := os.Getenv("EXPORT_BUCKET")
+	if name == "" {
+		return nil, nil
+	}
+	return NewBucket(ctx, name)
+}
+
+// Bucket uploads objects to an S3 bucket and presigns downloads from it.
+type Bucket struct {
+	name     string
+	uploader *manager.Uploader
+	presign  *s3.PresignClient
+}
+
+// NewBucket creates a client for the named bucket in the default region.
+func NewBucket(ctx context.Context, name string) (*Bucket, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg)
+	return &Bucket{
+		name: name,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = partSize
+		}),
+		presign: s3.NewPresignClient(client),
+	}, nil
+}
+
+// PresignGet returns a URL that downloads an object as filename until it
+// expires.
+func (b *Bucket) PresignGet(ctx context.Context, key, filename string, expires time.Duration) (string, error) {
+	req, err := b.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(b.name),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(fmt.Sprintf("attachment; filename=\"%s\"", filename)),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download: %w", err)
+	}
+	return req.URL, nil
+}
+
+// Upload streams an object to S3. Writes are handed to the SDK uploader,
+// which sends them in parts as they fill, so the object is never held in
+// memory whole. Close completes the upload; Abort discards it.
+type Upload struct {
+	w      *io.PipeWriter
+	done   chan error
+	closed bool
+}
+
+// NewUpload starts an upload of an object.
+func (b *Bucket) NewUpload(ctx context.Context, key, contentType string) (*Upload, error) {
+	r, w := io.Pipe()
+	u := &Upload{w: w, done: make(chan error, 1)}
+
+	go func() {
+		_, err := b.uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(b.name),
+			Key:         aws.String(key),
+			Body:        r,
+			ContentType: aws.String(contentType),
+		})
+		if err != nil {
+			// Fail any write still waiting on the upload
+			r.CloseWithError(err)
+		}
+		u.done <- err
+	}()
+
+	return u, nil
+}
+
+// Write sends p to the upload.
+func (u *Upload) Write(p []byte) (int, error) {
+	if u.closed {
+		return 0, ErrClosed
+	}
+	return u.w.Write(p)
+}
+
+// Close ends the object and waits for the upload to complete.
+func (u *Upload) Close() error {
+	if u.closed {
+		return ErrClosed
+	}
+	u.closed = true
+
+	u.w.Close()
+	if err := <-u.done; err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	return nil
+}
+
+// Abort discards the upload. The uploader removes any parts already sent.
+func (u *Upload) Abort() error {
+	if u.closed {
+		return nil
+	}
+	u.closed = true
+
+	u.w.CloseWithError(errAborted)
+	if err := <-u.done; err != nil && !errors.Is(err, errAborted) {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/audit"
 	"github.com/booking-villa-backend/internal/db"
 )
 
@@ -16,6 +18,7 @@ type PropertyLister func(ctx context.Context, ownerPhone string) ([]string, erro
 // Handler provides HTTP handlers for user/agent endpoints.
 type Handler struct {
 	service        *Service
+	auditService   *audit.Service
 	listProperties PropertyLister
 }
 
@@ -24,6 +27,7 @@ type Handler struct {
 func NewHandler(dbClient *db.Client, propertyLister PropertyLister) *Handler {
 	return &Handler{
 		service:        NewService(dbClient),
+		auditService:   audit.NewService(dbClient),
 		listProperties: propertyLister,
 	}
 }
@@ -172,7 +176,19 @@ func (h *Handler) HandleUpdateAgentStatus(ctx context.Context, request events.AP
 	}
 
 	// Update agent status
-	if err := h.service.SetAgentActive(ctx, agentPhone, req.Active, phone); err != nil {
+	err = h.service.SetAgentActive(ctx, agentPhone, req.Active, phone)
+
+	event := audit.NewEvent(audit.EventAgentStatusChanged, audit.OutcomeSuccess).
+		WithRequest(request).
+		WithActor(phone, role).
+		WithTarget(agentPhone).
+		WithDetail("active", strconv.FormatBool(req.Active))
+	if err != nil {
+		event.WithError(err)
+	}
+	h.auditService.Log(ctx, event)
+
+	if err != nil {
 		return ErrorResponse(http.StatusInternalServerError, "Failed to update agent status"), nil
 	}
 
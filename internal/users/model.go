@@ -3,6 +3,8 @@ package users
 
 import (
 	"time"
+
+	"github.com/booking-villa-backend/internal/i18n"
 )
 
 // Role represents the user's role in the system.
@@ -60,12 +62,21 @@ type User struct {
 	PasswordHash      string     `dynamodbav:"passwordHash,omitempty" json:"-"`
 	ManagedProperties []string   `dynamodbav:"managedProperties,omitempty" json:"managedProperties,omitempty"`
 
+	// Language for notifications and SMS; English if empty
+	Locale i18n.Locale `dynamodbav:"locale,omitempty" json:"locale,omitempty"`
+
 	// Metadata
 	CreatedAt  time.Time  `dynamodbav:"createdAt" json:"createdAt"`
 	UpdatedAt  time.Time  `dynamodbav:"updatedAt" json:"updatedAt"`
 	ApprovedBy string     `dynamodbav:"approvedBy,omitempty" json:"approvedBy,omitempty"`
 	ApprovedAt *time.Time `dynamodbav:"approvedAt,omitempty" json:"approvedAt,omitempty"`
 
+	// Set once the user has activated TOTP two-factor authentication
+	TwoFactorEnabled bool `dynamodbav:"twoFactorEnabled,omitempty" json:"twoFactorEnabled"`
+
+	// Tokens issued up to this Unix time are rejected (set on password reset)
+	SessionsRevokedAt int64 `dynamodbav:"sessionsRevokedAt,omitempty" json:"-"`
+
 	// Entity type for single-table design
 	EntityType string `dynamodbav:"entityType" json:"-"`
 }
@@ -94,6 +105,14 @@ func (u *User) HasPassword() bool {
 	return u.PasswordHash != ""
 }
 
+// IsSessionRevoked checks if a token issued at issuedAt has been revoked.
+// Token issue times and the revocation time are whole seconds, so tokens
+// issued in the second of the revocation are revoked too; otherwise one
+// issued just before it would stay valid.
+func (u *User) IsSessionRevoked(issuedAt time.Time) bool {
+	return u.SessionsRevokedAt > 0 && !issuedAt.After(time.Unix(u.SessionsRevokedAt, 0))
+}
+
 // IsApproved checks if the user is approved.
 func (u *User) IsApproved() bool {
 	return u.Status == StatusApproved
@@ -101,20 +120,23 @@ func (u *User) IsApproved() bool {
 
 // CanLogin checks if the user can log in.
 func (u *User) CanLogin() bool {
-	// All users can login after OTP verification
-	return true
+	// All users can login after OTP verification, unless they were rejected
+	// or deactivated
+	return u.Status != StatusRejected
 }
 
 // UserResponse is the API response representation of a user.
 type UserResponse struct {
-	Phone             string     `json:"phone"`
-	Name              string     `json:"name"`
-	Email             string     `json:"email,omitempty"`
-	Role              Role       `json:"role"`
-	Status            UserStatus `json:"status"`
-	ManagedProperties []string   `json:"managedProperties,omitempty"`
-	CreatedAt         time.Time  `json:"createdAt"`
-	UpdatedAt         time.Time  `json:"updatedAt"`
+	Phone             string      `json:"phone"`
+	Name              string      `json:"name"`
+	Email             string      `json:"email,omitempty"`
+	Locale            i18n.Locale `json:"locale"`
+	Role              Role        `json:"role"`
+	Status            UserStatus  `json:"status"`
+	ManagedProperties []string    `json:"managedProperties,omitempty"`
+	TwoFactorEnabled  bool        `json:"twoFactorEnabled"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	UpdatedAt         time.Time   `json:"updatedAt"`
 }
 
 // ToResponse converts a User to a UserResponse.
@@ -123,9 +145,11 @@ func (u *User) ToResponse() UserResponse {
 		Phone:             u.Phone,
 		Name:              u.Name,
 		Email:             u.Email,
+		Locale:            u.Locale.OrDefault(),
 		Role:              u.Role,
 		Status:            u.Status,
 		ManagedProperties: u.ManagedProperties,
+		TwoFactorEnabled:  u.TwoFactorEnabled,
 		CreatedAt:         u.CreatedAt,
 		UpdatedAt:         u.UpdatedAt,
 	}
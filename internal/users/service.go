@@ -2,11 +2,14 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/i18n"
 )
 
 // Service provides user-related operations.
@@ -97,6 +100,35 @@ func (s *Service) UpdatePassword(ctx context.Context, phone string, hashedPasswo
 	return s.db.UpdateItem(ctx, pk, sk, params)
 }
 
+// RevokeSessions invalidates all tokens issued to the user up to now. The
+// time is stored in whole seconds, as token issue times are.
+func (s *Service) RevokeSessions(ctx context.Context, phone string) error {
+	now := time.Now()
+
+	params := db.UpdateParams{
+		UpdateExpression: "SET sessionsRevokedAt = :revokedAt, updatedAt = :updatedAt",
+		ExpressionValues: map[string]interface{}{
+			":revokedAt": now.Unix(),
+			":updatedAt": now.Format(time.RFC3339),
+		},
+	}
+
+	return s.db.UpdateItem(ctx, "USER#"+phone, "PROFILE", params)
+}
+
+// SetTwoFactorEnabled records whether the user has two-factor authentication enabled.
+func (s *Service) SetTwoFactorEnabled(ctx context.Context, phone string, enabled bool) error {
+	params := db.UpdateParams{
+		UpdateExpression: "SET twoFactorEnabled = :enabled, updatedAt = :updatedAt",
+		ExpressionValues: map[string]interface{}{
+			":enabled":   enabled,
+			":updatedAt": time.Now().Format(time.RFC3339),
+		},
+	}
+
+	return s.db.UpdateItem(ctx, "USER#"+phone, "PROFILE", params)
+}
+
 // ListUsersByRole retrieves all users with a specific role.
 func (s *Service) ListUsersByRole(ctx context.Context, role Role) ([]*User, error) {
 	params := db.QueryParams{
@@ -255,3 +287,185 @@ func (s *Service) SetAgentActive(ctx context.Context, agentPhone string, isActiv
 	}
 	return s.UpdateUserStatus(ctx, agentPhone, status, setBy)
 }
+
+// ErrEmailInUse is returned when an email address is already linked to another user.
+var ErrEmailInUse = errors.New("email is already linked to another account")
+
+// emailLookup maps an email address to the phone of the user who owns it.
+type emailLookup struct {
+	PK         string `dynamodbav:"PK"` // EMAIL#<email>
+	SK         string `dynamodbav:"SK"` // USER
+	Phone      string `dynamodbav:"phone"`
+	EntityType string `dynamodbav:"entityType"`
+}
+
+// NormalizeEmail lowercases and trims an email address for lookups.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// GetUserByEmail retrieves a user by their linked email address.
+func (s *Service) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	var lookup emailLookup
+	err := s.db.GetItem(ctx, "EMAIL#"+NormalizeEmail(email), "USER", &lookup)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email lookup: %w", err)
+	}
+
+	return s.GetUserByPhone(ctx, lookup.Phone)
+}
+
+// SetLocale sets the language a user receives notifications and SMS in.
+func (s *Service) SetLocale(ctx context.Context, phone string, locale i18n.Locale) error {
+	if !locale.IsValid() {
+		return fmt.Errorf("unsupported locale: %s", locale)
+	}
+
+	err := s.db.UpdateItem(ctx, "USER#"+phone, "PROFILE", db.UpdateParams{
+		UpdateExpression:    "SET locale = :locale, updatedAt = :updatedAt",
+		ConditionExpression: "attribute_exists(PK)",
+		ExpressionValues: map[string]interface{}{
+			":locale":    locale,
+			":updatedAt": time.Now().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		if db.IsConditionalCheckFailed(err) {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to set locale: %w", err)
+	}
+	return nil
+}
+
+// LocaleFor returns the language a user receives messages in, or the
+// default for unknown users.
+func (s *Service) LocaleFor(ctx context.Context, phone string) i18n.Locale {
+	user, err := s.GetUserByPhone(ctx, phone)
+	if err != nil || user == nil {
+		return i18n.Default
+	}
+	return user.Locale.OrDefault()
+}
+
+// UpdateEmail links an email address to a user, replacing any previous one.
+func (s *Service) UpdateEmail(ctx context.Context, phone, email string) error {
+	email = NormalizeEmail(email)
+
+	user, err := s.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	if NormalizeEmail(user.Email) == email {
+		return nil
+	}
+
+	// Claim the email first so two users can't link the same address
+	lookup := &emailLookup{
+		PK:         "EMAIL#" + email,
+		SK:         "USER",
+		Phone:      phone,
+		EntityType: "EMAIL_LOOKUP",
+	}
+	if err := s.db.PutItemWithCondition(ctx, lookup, "attribute_not_exists(PK)"); err != nil {
+		if !db.IsConditionalCheckFailed(err) {
+			return fmt.Errorf("failed to link email: %w", err)
+		}
+		existing, err := s.GetUserByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		if existing == nil || existing.Phone != phone {
+			return ErrEmailInUse
+		}
+	}
+
+	params := db.UpdateParams{
+		UpdateExpression: "SET email = :email, updatedAt = :updatedAt",
+		ExpressionValues: map[string]interface{}{
+			":email":     email,
+			":updatedAt": time.Now().Format(time.RFC3339),
+		},
+	}
+	if err := s.db.UpdateItem(ctx, "USER#"+phone, "PROFILE", params); err != nil {
+		return err
+	}
+
+	// Release the previous address
+	if user.Email != "" {
+		_ = s.db.DeleteItem(ctx, "EMAIL#"+NormalizeEmail(user.Email), "USER")
+	}
+
+	return nil
+}
+
+// EmailBackfillResult summarises a BackfillEmailLookups run.
+type EmailBackfillResult struct {
+	Users   int // Users with an email address
+	Linked  int // Lookups written
+	Present int // Lookups that already pointed at the user
+	Skipped int // Addresses already linked to another user
+}
+
+// BackfillEmailLookups writes the EMAIL# lookup for users whose email was
+// stored before email login existed, so they can log in by email. An address
+// that is already linked to another user is left alone.
+func (s *Service) BackfillEmailLookups(ctx context.Context) (*EmailBackfillResult, error) {
+	result := &EmailBackfillResult{}
+
+	items, err := s.db.ScanAll(ctx, db.ScanParams{
+		FilterExpression: "begins_with(PK, :prefix) AND SK = :sk AND attribute_exists(email)",
+		ExpressionValues: map[string]interface{}{
+			":prefix": "USER#",
+			":sk":     "PROFILE",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan users: %w", err)
+	}
+
+	for _, item := range items {
+		var user User
+		if err := attributevalue.UnmarshalMap(item, &user); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user: %w", err)
+		}
+		email := NormalizeEmail(user.Email)
+		if email == "" {
+			continue
+		}
+		result.Users++
+
+		lookup := &emailLookup{
+			PK:         "EMAIL#" + email,
+			SK:         "USER",
+			Phone:      user.Phone,
+			EntityType: "EMAIL_LOOKUP",
+		}
+		err := s.db.PutItemWithCondition(ctx, lookup, "attribute_not_exists(PK)")
+		if err == nil {
+			result.Linked++
+			continue
+		}
+		if !db.IsConditionalCheckFailed(err) {
+			return nil, fmt.Errorf("failed to link email: %w", err)
+		}
+
+		var existing emailLookup
+		if err := s.db.GetItem(ctx, lookup.PK, lookup.SK, &existing); err != nil {
+			return nil, fmt.Errorf("failed to get email lookup: %w", err)
+		}
+		if existing.Phone == user.Phone {
+			result.Present++
+		} else {
+			result.Skipped++
+		}
+	}
+
+	return result, nil
+}
@@ -10,13 +10,69 @@ import (
 )
 
 // TokenClaims represents the custom claims embedded in JWT tokens.
+// API key requests are represented with the same claims, with APIKeyID set
+// and access limited to Scopes and PropertyIDs.
 type TokenClaims struct {
-	UserID string `json:"userId"`
-	Phone  string `json:"phone"`
-	Role   string `json:"role"`
+	UserID      string   `json:"userId"`
+	Phone       string   `json:"phone"`
+	Role        string   `json:"role"`
+	APIKeyID    string   `json:"apiKeyId,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	PropertyIDs []string `json:"propertyIds,omitempty"`
+	Purpose     string   `json:"purpose,omitempty"`
+
+	// Set on impersonation tokens: the admin acting as this user.
+	// Impersonation tokens are always read-only.
+	ImpersonatorPhone string `json:"impersonator,omitempty"`
+	ReadOnly          bool   `json:"readOnly,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// Token purposes for restricted, short-lived tokens. A token with a purpose
+// is only accepted by the endpoints for that purpose, never as a session.
+const (
+	// TokenPurposeTwoFactor is issued after the first factor, to be exchanged
+	// for a session token by presenting a TOTP or recovery code.
+	TokenPurposeTwoFactor = "2fa_challenge"
+
+	// TokenPurposeTwoFactorSetup is issued to users who must enroll in 2FA
+	// before they can get a session token.
+	TokenPurposeTwoFactorSetup = "2fa_setup"
+)
+
+// IsAPIKey returns true if the claims were produced from an API key.
+func (c *TokenClaims) IsAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// HasScope checks if the claims grant a scope. User tokens have every scope.
+func (c *TokenClaims) HasScope(scope string) bool {
+	if !c.IsAPIKey() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessProperty checks if the claims are limited to a set of properties
+// and, if so, whether propertyID is one of them.
+func (c *TokenClaims) CanAccessProperty(propertyID string) bool {
+	if len(c.PropertyIDs) == 0 {
+		return true
+	}
+	for _, id := range c.PropertyIDs {
+		if id == propertyID {
+			return true
+		}
+	}
+	return false
+}
+
 // JWTConfig holds JWT configuration.
 type JWTConfig struct {
 	Secret     string
@@ -84,6 +140,58 @@ func GenerateTokenWithExpiration(userID, phone, role string, expiration time.Dur
 	return signedToken, nil
 }
 
+// GeneratePurposeToken creates a short-lived JWT token restricted to a purpose.
+func GeneratePurposeToken(userID, phone, role, purpose string, expiration time.Duration) (string, error) {
+	config := DefaultJWTConfig()
+
+	claims := TokenClaims{
+		UserID:  userID,
+		Phone:   phone,
+		Role:    role,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "booking-villa-backend",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString([]byte(config.Secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// GenerateImpersonationToken creates a short-lived, read-only JWT token that
+// lets an admin act as another user.
+func GenerateImpersonationToken(userID, phone, role, impersonatorPhone string, expiration time.Duration) (string, error) {
+	config := DefaultJWTConfig()
+
+	claims := TokenClaims{
+		UserID:            userID,
+		Phone:             phone,
+		Role:              role,
+		ImpersonatorPhone: impersonatorPhone,
+		ReadOnly:          true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "booking-villa-backend",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString([]byte(config.Secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims.
 func ValidateToken(tokenString string) (*TokenClaims, error) {
 	config := DefaultJWTConfig()
@@ -114,6 +222,12 @@ func RefreshToken(tokenString string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("cannot refresh invalid token: %w", err)
 	}
+	if claims.Purpose != "" {
+		return "", fmt.Errorf("cannot refresh a %s token", claims.Purpose)
+	}
+	if claims.ImpersonatorPhone != "" {
+		return "", fmt.Errorf("cannot refresh an impersonation token")
+	}
 
 	// Generate new token with same user info but new expiration
 	return GenerateToken(claims.UserID, claims.Phone, claims.Role)
@@ -0,0 +1,188 @@
+// Package webhooks provides signed outbound webhooks for booking and payment events.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/middleware"
+)
+
+// Handler provides HTTP handlers for webhook endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new webhook handler.
+func NewHandler(dbClient *db.Client) *Handler {
+	return &Handler{
+		service: NewService(dbClient),
+	}
+}
+
+// APIResponse creates a standardized API Gateway response.
+func APIResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	jsonBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+		},
+		Body: string(jsonBody),
+	}
+}
+
+// ErrorResponse creates a standardized error response.
+func ErrorResponse(statusCode int, message string) events.APIGatewayProxyResponse {
+	return APIResponse(statusCode, map[string]string{"error": message})
+}
+
+// notFoundResponse maps lookup errors to a 404, and anything else to a 500.
+func notFoundResponse(err error, fallback string) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ErrorResponse(http.StatusNotFound, "Webhook not found")
+	case errors.Is(err, ErrDeliveryNotFound):
+		return ErrorResponse(http.StatusNotFound, "Delivery not found")
+	default:
+		return ErrorResponse(http.StatusInternalServerError, fallback)
+	}
+}
+
+// HandleCreateWebhook handles the POST /webhooks endpoint.
+func (h *Handler) HandleCreateWebhook(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	var req CreateEndpointRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return ErrorResponse(http.StatusBadRequest, "Invalid request body"), nil
+	}
+
+	result, err := h.service.CreateEndpoint(ctx, claims.Phone, claims.Role, req)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	return APIResponse(http.StatusCreated, result), nil
+}
+
+// HandleListWebhooks handles the GET /webhooks endpoint.
+func (h *Handler) HandleListWebhooks(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	endpoints, err := h.service.ListEndpoints(ctx, claims.Phone)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, "Failed to list webhooks"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"webhooks": endpoints,
+		"count":    len(endpoints),
+	}), nil
+}
+
+// HandleDeleteWebhook handles the DELETE /webhooks/{id} endpoint.
+func (h *Handler) HandleDeleteWebhook(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Webhook ID is required"), nil
+	}
+
+	if err := h.service.DeleteEndpoint(ctx, claims.Phone, id); err != nil {
+		return notFoundResponse(err, "Failed to delete webhook"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]string{
+		"message":   "Webhook deleted",
+		"webhookId": id,
+	}), nil
+}
+
+// HandleListDeliveries handles the GET /webhooks/{id}/deliveries endpoint.
+func (h *Handler) HandleListDeliveries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Webhook ID is required"), nil
+	}
+
+	limit := int32(50)
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil && l > 0 && l <= 100 {
+			limit = int32(l)
+		}
+	}
+
+	deliveries, err := h.service.ListDeliveries(ctx, claims.Phone, id, limit)
+	if err != nil {
+		return notFoundResponse(err, "Failed to list deliveries"), nil
+	}
+
+	return APIResponse(http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	}), nil
+}
+
+// HandleSendTest handles the POST /webhooks/{id}/test endpoint.
+func (h *Handler) HandleSendTest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return ErrorResponse(http.StatusBadRequest, "Webhook ID is required"), nil
+	}
+
+	delivery, err := h.service.SendTest(ctx, claims.Phone, id)
+	if err != nil {
+		return notFoundResponse(err, "Failed to send test event"), nil
+	}
+
+	return APIResponse(http.StatusOK, delivery), nil
+}
+
+// HandleRedeliver handles the POST /webhooks/{id}/deliveries/{deliveryId}/redeliver endpoint.
+func (h *Handler) HandleRedeliver(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	claims, ok := middleware.GetClaimsFromContext(ctx)
+	if !ok {
+		return ErrorResponse(http.StatusUnauthorized, "Unauthorized"), nil
+	}
+
+	id := request.PathParameters["id"]
+	deliveryID := request.PathParameters["deliveryId"]
+	if id == "" || deliveryID == "" {
+		return ErrorResponse(http.StatusBadRequest, "Webhook ID and delivery ID are required"), nil
+	}
+
+	delivery, err := h.service.Redeliver(ctx, claims.Phone, id, deliveryID)
+	if err != nil {
+		return notFoundResponse(err, "Failed to redeliver event"), nil
+	}
+
+	return APIResponse(http.StatusOK, delivery), nil
+}
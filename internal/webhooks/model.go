@@ -0,0 +1,211 @@
+// Package webhooks provides signed outbound webhooks for booking and payment events.
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/booking-villa-backend/internal/notifications"
+)
+
+// PayloadVersion is the version of the JSON body sent to endpoints.
+// It changes only when the payload shape changes incompatibly.
+const PayloadVersion = "1"
+
+// EventType identifies a webhook event.
+type EventType string
+
+const (
+	EventBookingCreated       EventType = "booking.created"
+	EventBookingUpdated       EventType = "booking.updated"
+	EventBookingStatusChanged EventType = "booking.status_changed"
+	EventBookingPartial       EventType = "booking.partial"
+	EventBookingSettled       EventType = "booking.settled"
+	EventBookingCancelled     EventType = "booking.cancelled"
+	EventPaymentRecorded      EventType = "payment.recorded"
+
+	// EventTest is only sent by the test endpoint and cannot be subscribed to.
+	EventTest EventType = "webhook.test"
+)
+
+// SubscribableEvents lists the event types endpoints can subscribe to.
+var SubscribableEvents = []EventType{
+	EventBookingCreated,
+	EventBookingUpdated,
+	EventBookingStatusChanged,
+	EventBookingPartial,
+	EventBookingSettled,
+	EventBookingCancelled,
+	EventPaymentRecorded,
+}
+
+// IsSubscribable checks if endpoints can subscribe to the event type.
+func (t EventType) IsSubscribable() bool {
+	for _, known := range SubscribableEvents {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTypeForNotification maps a booking notification type to its webhook event.
+func EventTypeForNotification(notifType notifications.NotificationType) EventType {
+	switch notifType {
+	case notifications.TypeBookingCreated:
+		return EventBookingCreated
+	case notifications.TypeBookingSettled:
+		return EventBookingSettled
+	case notifications.TypeBookingPartial:
+		return EventBookingPartial
+	case notifications.TypeBookingCancelled:
+		return EventBookingCancelled
+	default:
+		return EventBookingStatusChanged
+	}
+}
+
+// Endpoint is a URL registered by an owner to receive webhook events.
+type Endpoint struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // WEBHOOK#<id>
+	SK string `dynamodbav:"SK"` // METADATA
+
+	// GSI1 for listing endpoints by owner
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"` // WEBHOOKS#<ownerPhone>
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"` // WEBHOOK#<id>
+
+	// Endpoint fields
+	ID          string      `dynamodbav:"id" json:"id"`
+	OwnerPhone  string      `dynamodbav:"ownerPhone" json:"ownerPhone"`
+	URL         string      `dynamodbav:"url" json:"url"`
+	Description string      `dynamodbav:"description,omitempty" json:"description,omitempty"`
+	Events      []EventType `dynamodbav:"events" json:"events"`
+	PropertyIDs []string    `dynamodbav:"propertyIds,omitempty" json:"propertyIds,omitempty"`
+	Secret      string      `dynamodbav:"secret" json:"-"` // Signing key, shown once on creation
+	IsActive    bool        `dynamodbav:"isActive" json:"isActive"`
+
+	// Metadata
+	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Subscribes reports whether the endpoint wants events of the given type.
+func (e *Endpoint) Subscribes(eventType EventType) bool {
+	for _, t := range e.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversProperty reports whether the endpoint receives events for a property.
+// Endpoints without a property list receive events for all of the owner's properties.
+func (e *Endpoint) CoversProperty(propertyID string) bool {
+	if len(e.PropertyIDs) == 0 {
+		return true
+	}
+	for _, id := range e.PropertyIDs {
+		if id == propertyID {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus represents the state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryRetrying  DeliveryStatus = "retrying"
+	DeliveryFailed    DeliveryStatus = "failed"
+	DeliverySkipped   DeliveryStatus = "skipped"
+)
+
+// Delivery is the log entry for sending one event to one endpoint.
+// The body is stored so that re-deliveries send exactly the same payload.
+type Delivery struct {
+	// DynamoDB keys
+	PK string `dynamodbav:"PK"` // WEBHOOK#<endpointId>
+	SK string `dynamodbav:"SK"` // DELIVERY#<id>
+
+	// GSI1 for listing an endpoint's deliveries, newest first
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"` // WEBHOOKDELIVERIES#<endpointId>
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"` // <createdAt>#<id>
+
+	// Delivery fields
+	ID             string         `dynamodbav:"id" json:"id"`
+	EndpointID     string         `dynamodbav:"endpointId" json:"endpointId"`
+	EventID        string         `dynamodbav:"eventId" json:"eventId"`
+	EventType      EventType      `dynamodbav:"eventType" json:"eventType"`
+	Body           string         `dynamodbav:"body" json:"body"`
+	Status         DeliveryStatus `dynamodbav:"status" json:"status"`
+	Attempts       int            `dynamodbav:"attempts" json:"attempts"`
+	LastStatusCode int            `dynamodbav:"lastStatusCode,omitempty" json:"lastStatusCode,omitempty"`
+	LastError      string         `dynamodbav:"lastError,omitempty" json:"lastError,omitempty"`
+	LastAttemptAt  *time.Time     `dynamodbav:"lastAttemptAt,omitempty" json:"lastAttemptAt,omitempty"`
+
+	// Metadata
+	CreatedAt  time.Time `dynamodbav:"createdAt" json:"createdAt"`
+	TTL        int64     `dynamodbav:"TTL,omitempty" json:"-"`
+	EntityType string    `dynamodbav:"entityType" json:"-"`
+}
+
+// Payload is the JSON body sent to endpoints.
+type Payload struct {
+	ID        string          `json:"id"`
+	Type      EventType       `json:"type"`
+	Version   string          `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// BookingData describes a booking in webhook payloads.
+type BookingData struct {
+	BookingID    string  `json:"bookingId"`
+	PropertyID   string  `json:"propertyId"`
+	PropertyName string  `json:"propertyName,omitempty"`
+	GuestName    string  `json:"guestName"`
+	GuestPhone   string  `json:"guestPhone"`
+	NumGuests    int     `json:"numGuests"`
+	CheckIn      string  `json:"checkIn"`  // YYYY-MM-DD
+	CheckOut     string  `json:"checkOut"` // YYYY-MM-DD
+	NumNights    int     `json:"numNights"`
+	Status       string  `json:"status"`
+	TotalAmount  float64 `json:"totalAmount"`
+	AmountPaid   float64 `json:"amountPaid"`
+	BalanceDue   float64 `json:"balanceDue"`
+	Currency     string  `json:"currency"`
+	BookedBy     string  `json:"bookedBy,omitempty"`
+}
+
+// BookingEventData is the data of booking.* events.
+type BookingEventData struct {
+	Booking        BookingData `json:"booking"`
+	PreviousStatus string      `json:"previousStatus,omitempty"`
+}
+
+// PaymentEventData is the data of payment.recorded events.
+type PaymentEventData struct {
+	Booking BookingData `json:"booking"`
+	Amount  float64     `json:"amount"`
+	Method  string      `json:"method,omitempty"`
+}
+
+// CreateEndpointRequest represents the request body for registering an endpoint.
+type CreateEndpointRequest struct {
+	URL         string      `json:"url"`
+	Description string      `json:"description,omitempty"`
+	Events      []EventType `json:"events"`
+	PropertyIDs []string    `json:"propertyIds,omitempty"`
+}
+
+// CreateEndpointResponse is returned once when an endpoint is registered.
+// Secret is the signing key, which cannot be retrieved again.
+type CreateEndpointResponse struct {
+	Secret   string    `json:"secret"`
+	Endpoint *Endpoint `json:"endpoint"`
+}
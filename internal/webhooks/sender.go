@@ -0,0 +1,123 @@
+// Package webhooks provides signed outbound webhooks for booking and payment events.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Request headers sent with every delivery.
+const (
+	HeaderSignature = "X-VillaBook-Signature"
+	HeaderEvent     = "X-VillaBook-Event"
+	HeaderDelivery  = "X-VillaBook-Delivery"
+	HeaderVersion   = "X-VillaBook-Version"
+)
+
+const (
+	requestTimeout   = 10 * time.Second
+	maxErrorBodySize = 512
+	userAgent        = "VillaBook-Webhooks/" + PayloadVersion
+)
+
+// Sign computes the signature header value for a body sent at time t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Including the timestamp lets receivers reject replayed requests.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sender posts signed payloads to endpoints.
+type sender struct {
+	client *http.Client
+}
+
+// newSender creates a sender whose connections cannot reach private,
+// loopback or link-local addresses, so endpoints cannot be used to probe
+// internal services.
+func newSender() *sender {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &sender{
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+			// Redirects are not followed; a 3xx counts as a failed delivery
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// isPublicIP reports whether ip is a globally routable address.
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// send posts a delivery's body to the endpoint. It returns the HTTP status
+// code (0 if no response was received) and an error unless the endpoint
+// answered with a 2xx status.
+func (s *sender) send(ctx context.Context, endpoint *Endpoint, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderVersion, PayloadVersion)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+	return resp.StatusCode, nil
+}
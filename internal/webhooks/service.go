@@ -0,0 +1,468 @@
+// Package webhooks provides signed outbound webhooks for booking and payment events.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/booking-villa-backend/internal/db"
+	"github.com/booking-villa-backend/internal/outbox"
+	"github.com/booking-villa-backend/internal/properties"
+	"github.com/google/uuid"
+)
+
+const (
+	secretPrefix          = "whsec_"
+	maxEndpointsPerOwner  = 10
+	deliveryRetentionDays = 30
+	deliveryKeyLayout     = "2006-01-02T15:04:05.000000Z"
+)
+
+// Outbox event types used to fan out and deliver webhooks.
+const (
+	EventPublish outbox.EventType = "webhook.publish"
+	EventDeliver outbox.EventType = "webhook.deliver"
+)
+
+var (
+	// ErrNotFound is returned when an endpoint or delivery does not exist for the caller.
+	ErrNotFound = errors.New("webhook not found")
+
+	// ErrDeliveryNotFound is returned when a delivery does not exist for the endpoint.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Service provides webhook operations.
+type Service struct {
+	db              *db.Client
+	propertyService *properties.Service
+	sender          *sender
+}
+
+// NewService creates a new webhook service.
+func NewService(dbClient *db.Client) *Service {
+	return &Service{
+		db:              dbClient,
+		propertyService: properties.NewService(dbClient),
+		sender:          newSender(),
+	}
+}
+
+// generateSecret creates a new random signing secret.
+func generateSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(bytes), nil
+}
+
+// CreateEndpoint registers a webhook endpoint for the caller.
+// The signing secret is only returned here.
+func (s *Service) CreateEndpoint(ctx context.Context, ownerPhone, ownerRole string, req CreateEndpointRequest) (*CreateEndpointResponse, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("at least one event type is required")
+	}
+	for _, eventType := range req.Events {
+		if !eventType.IsSubscribable() {
+			return nil, fmt.Errorf("invalid event type: %s", eventType)
+		}
+	}
+
+	for _, propertyID := range req.PropertyIDs {
+		property, err := s.propertyService.GetProperty(ctx, propertyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get property: %w", err)
+		}
+		if property == nil {
+			return nil, fmt.Errorf("property not found: %s", propertyID)
+		}
+		if ownerRole != "admin" && property.OwnerID != ownerPhone {
+			return nil, fmt.Errorf("you do not own property: %s", propertyID)
+		}
+	}
+
+	existing, err := s.ListEndpoints(ctx, ownerPhone)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxEndpointsPerOwner {
+		return nil, fmt.Errorf("at most %d webhook endpoints are allowed", maxEndpointsPerOwner)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	endpoint := &Endpoint{
+		PK:          "WEBHOOK#" + id,
+		SK:          "METADATA",
+		GSI1PK:      "WEBHOOKS#" + ownerPhone,
+		GSI1SK:      "WEBHOOK#" + id,
+		ID:          id,
+		OwnerPhone:  ownerPhone,
+		URL:         req.URL,
+		Description: strings.TrimSpace(req.Description),
+		Events:      req.Events,
+		PropertyIDs: req.PropertyIDs,
+		Secret:      secret,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+		EntityType:  "WEBHOOK_ENDPOINT",
+	}
+
+	if err := s.db.PutItemWithCondition(ctx, endpoint, "attribute_not_exists(PK)"); err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return &CreateEndpointResponse{Secret: secret, Endpoint: endpoint}, nil
+}
+
+// validateURL checks that an endpoint URL is an absolute HTTPS URL.
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute URL")
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("url must use https")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	return nil
+}
+
+// ListEndpoints lists the webhook endpoints registered by a user.
+func (s *Service) ListEndpoints(ctx context.Context, ownerPhone string) ([]*Endpoint, error) {
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk AND begins_with(GSI1SK, :prefix)",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "WEBHOOKS#" + ownerPhone,
+			":prefix": "WEBHOOK#",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	endpoints := make([]*Endpoint, 0, len(items))
+	for _, item := range items {
+		var endpoint Endpoint
+		if err := attributevalue.UnmarshalMap(item, &endpoint); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, &endpoint)
+	}
+
+	return endpoints, nil
+}
+
+// getEndpoint retrieves an endpoint by ID. Returns nil if it does not exist.
+func (s *Service) getEndpoint(ctx context.Context, id string) (*Endpoint, error) {
+	var endpoint Endpoint
+	if err := s.db.GetItem(ctx, "WEBHOOK#"+id, "METADATA", &endpoint); err != nil {
+		if db.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// GetEndpoint retrieves one of the caller's endpoints.
+func (s *Service) GetEndpoint(ctx context.Context, ownerPhone, id string) (*Endpoint, error) {
+	endpoint, err := s.getEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil || endpoint.OwnerPhone != ownerPhone {
+		return nil, ErrNotFound
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes one of the caller's endpoints. Its delivery log
+// expires on its own.
+func (s *Service) DeleteEndpoint(ctx context.Context, ownerPhone, id string) error {
+	endpoint, err := s.GetEndpoint(ctx, ownerPhone, id)
+	if err != nil {
+		return err
+	}
+	return s.db.DeleteItem(ctx, endpoint.PK, endpoint.SK)
+}
+
+// ListDeliveries lists an endpoint's delivery log, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, ownerPhone, endpointID string, limit int32) ([]*Delivery, error) {
+	if _, err := s.GetEndpoint(ctx, ownerPhone, endpointID); err != nil {
+		return nil, err
+	}
+
+	scanForward := false
+	items, err := s.db.Query(ctx, db.QueryParams{
+		IndexName:    "GSI1",
+		KeyCondition: "GSI1PK = :gsi1pk",
+		ExpressionValues: map[string]interface{}{
+			":gsi1pk": "WEBHOOKDELIVERIES#" + endpointID,
+		},
+		Limit:            limit,
+		ScanIndexForward: &scanForward,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return unmarshalDeliveries(items)
+}
+
+// getDelivery retrieves a delivery by endpoint and ID.
+func (s *Service) getDelivery(ctx context.Context, endpointID, id string) (*Delivery, error) {
+	var delivery Delivery
+	if err := s.db.GetItem(ctx, "WEBHOOK#"+endpointID, "DELIVERY#"+id, &delivery); err != nil {
+		if db.IsNotFound(err) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// newDelivery creates a pending delivery of an event to an endpoint.
+func newDelivery(endpoint *Endpoint, id, eventID string, eventType EventType, body []byte) *Delivery {
+	now := time.Now()
+	return &Delivery{
+		PK:         endpoint.PK,
+		SK:         "DELIVERY#" + id,
+		GSI1PK:     "WEBHOOKDELIVERIES#" + endpoint.ID,
+		GSI1SK:     now.UTC().Format(deliveryKeyLayout) + "#" + id,
+		ID:         id,
+		EndpointID: endpoint.ID,
+		EventID:    eventID,
+		EventType:  eventType,
+		Body:       string(body),
+		Status:     DeliveryPending,
+		CreatedAt:  now,
+		TTL:        db.CalculateTTL(deliveryRetentionDays * 24 * time.Hour),
+		EntityType: "WEBHOOK_DELIVERY",
+	}
+}
+
+// encodePayload builds the JSON body for an event.
+func encodePayload(eventID string, eventType EventType, createdAt time.Time, data json.RawMessage) ([]byte, error) {
+	body, err := json.Marshal(Payload{
+		ID:        eventID,
+		Type:      eventType,
+		Version:   PayloadVersion,
+		CreatedAt: createdAt.UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return body, nil
+}
+
+// attempt sends a delivery once and records the outcome. final marks a
+// failure as permanent; otherwise it is recorded as retrying.
+func (s *Service) attempt(ctx context.Context, endpoint *Endpoint, delivery *Delivery, final bool) error {
+	statusCode, sendErr := s.sender.send(ctx, endpoint, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	switch {
+	case sendErr == nil:
+		delivery.Status = DeliverySucceeded
+	case final:
+		delivery.Status = DeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.Status = DeliveryRetrying
+		delivery.LastError = sendErr.Error()
+	}
+
+	if err := s.db.PutItem(ctx, delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+
+	return sendErr
+}
+
+// SendTest sends a webhook.test event to one of the caller's endpoints right away.
+func (s *Service) SendTest(ctx context.Context, ownerPhone, endpointID string) (*Delivery, error) {
+	endpoint, err := s.GetEndpoint(ctx, ownerPhone, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := uuid.New().String()
+	data, _ := json.Marshal(map[string]string{
+		"message": "This is a test event from VillaBook",
+	})
+	body, err := encodePayload(eventID, EventTest, time.Now(), data)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := newDelivery(endpoint, uuid.New().String(), eventID, EventTest, body)
+	_ = s.attempt(ctx, endpoint, delivery, true)
+	return delivery, nil
+}
+
+// Redeliver sends a logged delivery again right away, with the same body.
+func (s *Service) Redeliver(ctx context.Context, ownerPhone, endpointID, deliveryID string) (*Delivery, error) {
+	endpoint, err := s.GetEndpoint(ctx, ownerPhone, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.getDelivery(ctx, endpoint.ID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.attempt(ctx, endpoint, delivery, true)
+	return delivery, nil
+}
+
+// PublishEvent is the payload of EventPublish.
+type PublishEvent struct {
+	OwnerPhone string          `json:"ownerPhone"`
+	PropertyID string          `json:"propertyId"`
+	Type       EventType       `json:"type"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// DeliverEvent is the payload of EventDeliver.
+type DeliverEvent struct {
+	EndpointID string `json:"endpointId"`
+	DeliveryID string `json:"deliveryId"`
+}
+
+// NewPublishEvent creates an outbox event that sends a webhook event to the
+// owner's subscribed endpoints. It should be written in the same transaction
+// as the change it describes.
+func NewPublishEvent(ownerPhone, propertyID string, eventType EventType, data interface{}) (*outbox.Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s data: %w", eventType, err)
+	}
+	return outbox.NewEvent(EventPublish, PublishEvent{
+		OwnerPhone: ownerPhone,
+		PropertyID: propertyID,
+		Type:       eventType,
+		Data:       encoded,
+	})
+}
+
+// HandlePublishEvent fans a webhook event out into one delivery per
+// subscribed endpoint. Each delivery is retried on its own through the
+// outbox, so a failing endpoint does not hold up the others.
+func (s *Service) HandlePublishEvent(ctx context.Context, event *outbox.Event) error {
+	var payload PublishEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	endpoints, err := s.ListEndpoints(ctx, payload.OwnerPhone)
+	if err != nil {
+		return err
+	}
+
+	body, err := encodePayload(event.ID, payload.Type, event.CreatedAt, payload.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.IsActive || !endpoint.Subscribes(payload.Type) || !endpoint.CoversProperty(payload.PropertyID) {
+			continue
+		}
+
+		// Derived from the event and endpoint, so a retried fan-out does not
+		// create the delivery twice.
+		deliveryID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(event.ID+"/"+endpoint.ID)).String()
+		delivery := newDelivery(endpoint, deliveryID, event.ID, payload.Type, body)
+
+		deliverEvent, err := outbox.NewEvent(EventDeliver, DeliverEvent{EndpointID: endpoint.ID, DeliveryID: deliveryID})
+		if err != nil {
+			return err
+		}
+
+		err = s.db.TransactWrite(ctx,
+			db.TransactPut(delivery, "attribute_not_exists(PK)"),
+			deliverEvent.TransactItem(),
+		)
+		if err != nil && !db.IsConditionalCheckFailed(err) {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// HandleDeliverEvent sends one queued delivery. Returning an error makes the
+// outbox retry it with backoff; the last attempt marks the delivery failed.
+func (s *Service) HandleDeliverEvent(ctx context.Context, event *outbox.Event) error {
+	var payload DeliverEvent
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	delivery, err := s.getDelivery(ctx, payload.EndpointID, payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			// Expired from the log; nothing left to send
+			return nil
+		}
+		return err
+	}
+	if delivery.Status == DeliverySucceeded {
+		return nil
+	}
+
+	endpoint, err := s.getEndpoint(ctx, payload.EndpointID)
+	if err != nil {
+		return err
+	}
+	if endpoint == nil || !endpoint.IsActive {
+		delivery.Status = DeliverySkipped
+		delivery.LastError = "endpoint deleted or inactive"
+		return s.db.PutItem(ctx, delivery)
+	}
+
+	final := event.Attempts+1 >= outbox.MaxAttempts
+	return s.attempt(ctx, endpoint, delivery, final)
+}
+
+// unmarshalDeliveries converts DynamoDB items to deliveries.
+func unmarshalDeliveries(items []map[string]types.AttributeValue) ([]*Delivery, error) {
+	deliveries := make([]*Delivery, 0, len(items))
+	for _, item := range items {
+		var delivery Delivery
+		if err := attributevalue.UnmarshalMap(item, &delivery); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, nil
+}